@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -54,6 +55,10 @@ var (
 		Name:  "remove.chain",
 		Usage: "If set, selects the state data for removal",
 	}
+	inspectCompressionFlag = &cli.BoolFlag{
+		Name:  "compression",
+		Usage: "If set, estimates the compression ratio of the freezer tables by sampling their items",
+	}
 
 	removedbCommand = &cli.Command{
 		Action:    removeDB,
@@ -78,11 +83,13 @@ Remove blockchain and state databases`,
 			dbPutCmd,
 			dbGetSlotsCmd,
 			dbDumpFreezerIndex,
+			dbCompressFreezerCmd,
 			dbImportCmd,
 			dbExportCmd,
 			dbMetadataCmd,
 			dbCheckStateContentCmd,
 			dbInspectHistoryCmd,
+			dbRebuildLogIndexCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -91,6 +98,7 @@ Remove blockchain and state databases`,
 		ArgsUsage: "<prefix> <start>",
 		Flags: flags.Merge([]cli.Flag{
 			utils.SyncModeFlag,
+			inspectCompressionFlag,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Usage:       "Inspect the storage size for each type of data in the database",
 		Description: `This commands iterates the entire database. If the optional 'prefix' and 'start' arguments are provided, then the iteration is limited to the given subset of data.`,
@@ -178,6 +186,18 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "This command displays information about the freezer index.",
 	}
+	dbCompressFreezerCmd = &cli.Command{
+		Action:    freezerCompress,
+		Name:      "freezer-compress",
+		Usage:     "Recompress a freezer table with zstd",
+		ArgsUsage: "<freezer-type> <table-type>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command rewrites a compressed freezer table with zstd, using a
+dictionary trained on the table's content. The compression ratio of the tables
+is reported by 'geth db inspect --compression'.`,
+	}
 	dbImportCmd = &cli.Command{
 		Action:    importLDBdata,
 		Name:      "import",
@@ -207,6 +227,21 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "Shows metadata about the chain status.",
 	}
+	dbRebuildLogIndexCmd = &cli.Command{
+		Action: rebuildLogIndex,
+		Name:   "rebuild-logindex",
+		Usage:  "Drop and regenerate the persistent log index",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			utils.GCModeFlag,
+			utils.TransactionHistoryFlag,
+			utils.TxLookupLimitFlag,
+			utils.LogHistoryFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command drops the entire log index and regenerates it for the range
+of recent blocks configured by --history.logs, which follows --history.transactions
+unless set explicitly, like when running the node. It may take a long time.`,
+	}
 	dbInspectHistoryCmd = &cli.Command{
 		Action:    inspectHistory,
 		Name:      "inspect-history",
@@ -350,7 +385,13 @@ func inspect(ctx *cli.Context) error {
 	db := utils.MakeChainDatabase(ctx, stack, true)
 	defer db.Close()
 
-	return rawdb.InspectDatabase(db, prefix, start)
+	if err := rawdb.InspectDatabase(db, prefix, start); err != nil {
+		return err
+	}
+	if ctx.Bool(inspectCompressionFlag.Name) {
+		return rawdb.InspectFreezerCompression(db)
+	}
+	return nil
 }
 
 func checkStateContent(ctx *cli.Context) error {
@@ -451,6 +492,35 @@ func dbCompact(ctx *cli.Context) error {
 	return nil
 }
 
+// rebuildLogIndex drops the log index and regenerates it up to the head block.
+func rebuildLogIndex(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	var (
+		stop  = make(chan struct{})
+		sigc  = make(chan os.Signal, 1)
+		start = time.Now()
+	)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	go func() {
+		<-sigc
+		log.Info("Interrupted during log index rebuild, stopping")
+		close(stop)
+	}()
+	// The history limit is derived from the config like when running the node,
+	// so that the rebuilt index matches the one maintained by it.
+	if err := core.RebuildLogIndex(db, cfg.Eth.LogHistory, stop); err != nil {
+		return err
+	}
+	log.Info("Rebuilt log index", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
 // dbGet shows the value of a given database key
 func dbGet(ctx *cli.Context) error {
 	if ctx.NArg() != 1 {
@@ -630,6 +700,26 @@ func freezerInspect(ctx *cli.Context) error {
 	return rawdb.InspectFreezerTable(ancient, freezer, table, start, end)
 }
 
+func freezerCompress(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var (
+		freezer = ctx.Args().Get(0)
+		table   = ctx.Args().Get(1)
+	)
+	stack, _ := makeConfigNode(ctx)
+	ancient := stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+	stack.Close()
+
+	start := time.Now()
+	if err := rawdb.CompressFreezerTable(ancient, freezer, table); err != nil {
+		return err
+	}
+	log.Info("Compressed freezer table", "freezer", freezer, "table", table, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
 func importLDBdata(ctx *cli.Context) error {
 	start := 0
 	switch ctx.NArg() {
@@ -71,6 +71,14 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolPersistFlag,
+		utils.TxPoolPersistMaxSizeFlag,
+		utils.TxPoolPersistMaxAgeFlag,
+		utils.TxPoolSenderRateFlag,
+		utils.TxPoolSenderBurstFlag,
+		utils.TxPoolPeerRateFlag,
+		utils.TxPoolPeerBurstFlag,
+		utils.TxPoolPeerDropScoreFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -81,6 +89,9 @@ var (
 		utils.BlobPoolDataDirFlag,
 		utils.BlobPoolDataCapFlag,
 		utils.BlobPoolPriceBumpFlag,
+		utils.BundlePoolEnableFlag,
+		utils.BundlePoolMaxBundlesFlag,
+		utils.BundlePoolMaxSenderBundlesFlag,
 		utils.SyncModeFlag,
 		utils.SyncTargetFlag,
 		utils.ExitWhenSyncedFlag,
@@ -89,6 +100,8 @@ var (
 		utils.TxLookupLimitFlag, // deprecated
 		utils.TransactionHistoryFlag,
 		utils.StateHistoryFlag,
+		utils.LogHistoryFlag,
+		utils.LogIndexFlag,
 		utils.LightServeFlag,    // deprecated
 		utils.LightIngressFlag,  // deprecated
 		utils.LightEgressFlag,   // deprecated
@@ -122,6 +135,17 @@ var (
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerPendingFeeRecipientFlag,
+		utils.MinerOrderingFlag,
+		utils.MinerSenderAllowlistFlag,
+		utils.MinerSenderDenylistFlag,
+		utils.MinerMaxSenderGasFlag,
+		utils.MinerLocalGasReserveFlag,
+		utils.MinerBuilderRelayFlag,
+		utils.MinerBuilderBeaconFlag,
+		utils.MinerBuilderSecretKeyFlag,
+		utils.MinerBuilderForkVersionFlag,
+		utils.MinerBuilderIntervalFlag,
+		utils.MinerBuilderDeadlineFlag,
 		utils.MinerNewPayloadTimeoutFlag, // deprecated
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
@@ -173,6 +197,7 @@ var (
 		utils.GraphQLVirtualHostsFlag,
 		utils.HTTPApiFlag,
 		utils.HTTPPathPrefixFlag,
+		utils.HTTPH2CFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
@@ -185,9 +210,29 @@ var (
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.JSTracerDisableFlag,
+		utils.JSTracerMaxHeapFlag,
+		utils.JSTracerMaxStepsFlag,
+		utils.JSTracerMaxOutputFlag,
+		utils.TraceChainDirFlag,
+		utils.TraceFilterRangeFlag,
 		utils.AllowUnprotectedTxs,
 		utils.BatchRequestLimit,
 		utils.BatchResponseMaxSize,
+		utils.RPCAPIKeyFileFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
+		utils.RPCMethodLimitsFlag,
+		utils.RPCMethodCostsFlag,
+		utils.RPCConcurrencyFlag,
+		utils.RPCAuditLogFlag,
+		utils.RPCAuditLogAllFlag,
+		utils.RPCAuditLogSlowFlag,
+		utils.RPCAuditLogFailedFlag,
+		utils.RPCAuditLogSampleFlag,
+		utils.RPCAuditLogRedactFlag,
+		utils.RPCAuditLogMaxSizeFlag,
+		utils.RPCAuditLogMaxBackupsFlag,
 	}
 
 	metricsFlags = []cli.Flag{
@@ -55,6 +55,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/remotedb"
+	"github.com/ethereum/go-ethereum/ethdb/s3"
 	"github.com/ethereum/go-ethereum/ethstats"
 	"github.com/ethereum/go-ethereum/graphql"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -64,6 +65,7 @@ import (
 	"github.com/ethereum/go-ethereum/metrics/exp"
 	"github.com/ethereum/go-ethereum/metrics/influxdb"
 	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/miner/builder"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -110,6 +112,41 @@ var (
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
 		Category: flags.EthCategory,
 	}
+	AncientRemoteFlag = &cli.StringFlag{
+		Name:     "datadir.ancient.remote",
+		Usage:    "Path-style URL of an S3 compatible bucket to offload cold ancient data to (e.g. https://s3.us-east-1.amazonaws.com/bucket/prefix)",
+		Category: flags.EthCategory,
+	}
+	AncientRemoteRegionFlag = &cli.StringFlag{
+		Name:     "datadir.ancient.remote.region",
+		Usage:    "Region of the bucket cold ancient data is offloaded to",
+		Value:    "us-east-1",
+		Category: flags.EthCategory,
+	}
+	AncientRemoteAccessKeyFlag = &cli.StringFlag{
+		Name:     "datadir.ancient.remote.accesskey",
+		Usage:    "Access key ID of the bucket cold ancient data is offloaded to",
+		EnvVars:  []string{"AWS_ACCESS_KEY_ID"},
+		Category: flags.EthCategory,
+	}
+	AncientRemoteSecretKeyFlag = &cli.StringFlag{
+		Name:     "datadir.ancient.remote.secretkey",
+		Usage:    "Secret access key of the bucket cold ancient data is offloaded to",
+		EnvVars:  []string{"AWS_SECRET_ACCESS_KEY"},
+		Category: flags.EthCategory,
+	}
+	AncientRemoteThresholdFlag = &cli.Uint64Flag{
+		Name:     "datadir.ancient.remote.threshold",
+		Usage:    "Number of most recent ancient items kept on the local disk when offloading",
+		Value:    1_000_000,
+		Category: flags.EthCategory,
+	}
+	AncientRemoteCacheFlag = &cli.IntFlag{
+		Name:     "datadir.ancient.remote.cache",
+		Usage:    "Disk space in MB used to cache the ancient data read back from the bucket",
+		Value:    4096,
+		Category: flags.EthCategory,
+	}
 	MinFreeDiskSpaceFlag = &flags.DirectoryFlag{
 		Name:     "datadir.minfreedisk",
 		Usage:    "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -283,6 +320,18 @@ var (
 		Value:    ethconfig.Defaults.TransactionHistory,
 		Category: flags.StateCategory,
 	}
+	LogHistoryFlag = &cli.Uint64Flag{
+		Name:     "history.logs",
+		Usage:    "Number of recent blocks to maintain log index for (default = same as --history.transactions, 0 = entire chain)",
+		Value:    ethconfig.Defaults.LogHistory,
+		Category: flags.StateCategory,
+	}
+	LogIndexFlag = &cli.BoolFlag{
+		Name:     "history.logs.index",
+		Usage:    "Maintain the persistent log index, otherwise filter logs using the bloom bits only",
+		Value:    ethconfig.Defaults.LogIndex,
+		Category: flags.StateCategory,
+	}
 	// Beacon client light sync settings
 	BeaconApiFlag = &cli.StringSliceFlag{
 		Name:     "beacon.api",
@@ -358,6 +407,49 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Rejournal,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolPersistFlag = &cli.StringFlag{
+		Name:     "txpool.persist",
+		Usage:    "Disk snapshot of the remote transactions to survive node restarts (disabled if empty)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPersistMaxSizeFlag = &cli.Uint64Flag{
+		Name:     "txpool.persist.maxsize",
+		Usage:    "Maximum size of the remote transaction snapshot in bytes (0 = unlimited)",
+		Value:    ethconfig.Defaults.TxPool.PersistMaxSize,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPersistMaxAgeFlag = &cli.DurationFlag{
+		Name:     "txpool.persist.maxage",
+		Usage:    "Maximum age of a remote transaction snapshot to be reloaded on startup",
+		Value:    ethconfig.Defaults.TxPool.PersistMaxAge,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolSenderRateFlag = &cli.Float64Flag{
+		Name:     "txpool.senderrate",
+		Usage:    "Remote transactions admitted per second from a single sender (0 = unlimited)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolSenderBurstFlag = &cli.Uint64Flag{
+		Name:     "txpool.senderburst",
+		Usage:    "Remote transactions admitted from a single sender in a burst",
+		Value:    ethconfig.Defaults.TxPool.SenderBurst,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPeerRateFlag = &cli.Float64Flag{
+		Name:     "txpool.peerrate",
+		Usage:    "Transactions admitted per second from a single peer (0 = unlimited)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPeerBurstFlag = &cli.IntFlag{
+		Name:     "txpool.peerburst",
+		Usage:    "Transactions admitted from a single peer in a burst (0 = default)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPeerDropScoreFlag = &cli.IntFlag{
+		Name:     "txpool.peerdropscore",
+		Usage:    "Negative reputation score below which a peer relaying bad transactions is dropped and banned for a while (0 = never dropped)",
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolPriceLimitFlag = &cli.Uint64Flag{
 		Name:     "txpool.pricelimit",
 		Usage:    "Minimum gas price tip to enforce for acceptance into the pool",
@@ -413,6 +505,24 @@ var (
 		Value:    ethconfig.Defaults.BlobPool.Datacap,
 		Category: flags.BlobPoolCategory,
 	}
+	// Bundle pool settings
+	BundlePoolEnableFlag = &cli.BoolFlag{
+		Name:     "bundlepool",
+		Usage:    "Enables the private bundle pool and the eth_sendBundle/eth_callBundle APIs",
+		Category: flags.BundlePoolCategory,
+	}
+	BundlePoolMaxBundlesFlag = &cli.IntFlag{
+		Name:     "bundlepool.maxbundles",
+		Usage:    "Maximum number of bundles maintained by the bundle pool",
+		Value:    ethconfig.Defaults.BundlePool.MaxBundles,
+		Category: flags.BundlePoolCategory,
+	}
+	BundlePoolMaxSenderBundlesFlag = &cli.IntFlag{
+		Name:     "bundlepool.maxsenderbundles",
+		Usage:    "Maximum number of bundles maintained by the bundle pool per sender",
+		Value:    ethconfig.Defaults.BundlePool.MaxSenderBundles,
+		Category: flags.BundlePoolCategory,
+	}
 	BlobPoolPriceBumpFlag = &cli.Uint64Flag{
 		Name:     "blobpool.pricebump",
 		Usage:    "Price bump percentage to replace an already existing blob transaction",
@@ -507,6 +617,67 @@ var (
 		Usage:    "0x prefixed public address for the pending block producer (not used for actual block production)",
 		Category: flags.MinerCategory,
 	}
+	MinerOrderingFlag = &cli.StringFlag{
+		Name:     "miner.ordering",
+		Usage:    "Transaction ordering of mined blocks (price, firstseen); the minimum tip is set by --miner.gasprice",
+		Value:    ethconfig.Defaults.Miner.Ordering,
+		Category: flags.MinerCategory,
+	}
+	MinerSenderAllowlistFlag = &cli.StringFlag{
+		Name:     "miner.allowlist",
+		Usage:    "Comma separated accounts whose transactions are exclusively included in mined blocks",
+		Category: flags.MinerCategory,
+	}
+	MinerSenderDenylistFlag = &cli.StringFlag{
+		Name:     "miner.denylist",
+		Usage:    "Comma separated accounts whose transactions are never included in mined blocks",
+		Category: flags.MinerCategory,
+	}
+	MinerMaxSenderGasFlag = &cli.Uint64Flag{
+		Name:     "miner.maxsendergas",
+		Usage:    "Maximum gas included from a single sender per mined block (0 = unlimited)",
+		Value:    ethconfig.Defaults.Miner.MaxSenderGas,
+		Category: flags.MinerCategory,
+	}
+	MinerBuilderRelayFlag = &cli.StringFlag{
+		Name:     "builder.relay",
+		Usage:    "Relay endpoint to submit the built blocks to, enabling the block builder",
+		Category: flags.MinerCategory,
+	}
+	MinerBuilderBeaconFlag = &cli.StringFlag{
+		Name:     "builder.beacon",
+		Usage:    "Beacon node API endpoint to receive the upcoming proposals from",
+		Category: flags.MinerCategory,
+	}
+	MinerBuilderSecretKeyFlag = &cli.PathFlag{
+		Name:      "builder.secretkey",
+		Usage:     "File containing the hex encoded BLS secret key signing the builder bids",
+		TakesFile: true,
+		Category:  flags.MinerCategory,
+	}
+	MinerBuilderForkVersionFlag = &cli.StringFlag{
+		Name:     "builder.forkversion",
+		Usage:    "Genesis fork version of the beacon network (default = derived for known networks)",
+		Category: flags.MinerCategory,
+	}
+	MinerBuilderIntervalFlag = &cli.DurationFlag{
+		Name:     "builder.interval",
+		Usage:    "Interval of submitting improved blocks to the relay",
+		Value:    builder.DefaultConfig.SubmitInterval,
+		Category: flags.MinerCategory,
+	}
+	MinerBuilderDeadlineFlag = &cli.DurationFlag{
+		Name:     "builder.deadline",
+		Usage:    "Time into the slot after which no more blocks are submitted to the relay",
+		Value:    builder.DefaultConfig.SubmitDeadline,
+		Category: flags.MinerCategory,
+	}
+	MinerLocalGasReserveFlag = &cli.Uint64Flag{
+		Name:     "miner.localgasreserve",
+		Usage:    "Block gas reserved for local transactions, not usable by remote ones",
+		Value:    ethconfig.Defaults.Miner.LocalGasReserve,
+		Category: flags.MinerCategory,
+	}
 
 	// Account settings
 	UnlockedAccountFlag = &cli.StringFlag{
@@ -568,6 +739,37 @@ var (
 		Value:    ethconfig.Defaults.RPCTxFeeCap,
 		Category: flags.APICategory,
 	}
+	JSTracerDisableFlag = &cli.BoolFlag{
+		Name:     "rpc.jstracer.disable",
+		Usage:    "Disables the JavaScript tracers in the debug APIs",
+		Category: flags.APICategory,
+	}
+	JSTracerMaxHeapFlag = &cli.Uint64Flag{
+		Name:     "rpc.jstracer.maxheap",
+		Usage:    "Sets a cap on the estimated state size and on the buffers created by a single call (in bytes) of a JavaScript tracer (0 = no cap)",
+		Category: flags.APICategory,
+	}
+	JSTracerMaxStepsFlag = &cli.Uint64Flag{
+		Name:     "rpc.jstracer.maxsteps",
+		Usage:    "Sets a cap on the number of steps (function invocations, calls and loop iterations) of a JavaScript tracer (0 = no cap)",
+		Category: flags.APICategory,
+	}
+	JSTracerMaxOutputFlag = &cli.Uint64Flag{
+		Name:     "rpc.jstracer.maxoutput",
+		Usage:    "Sets a cap on the result size (in bytes) of a JavaScript tracer (0 = no cap)",
+		Category: flags.APICategory,
+	}
+	TraceChainDirFlag = &flags.DirectoryFlag{
+		Name:     "rpc.tracechaindir",
+		Usage:    "Directory debug_traceChainToFile writes its output under (default = disabled)",
+		Category: flags.APICategory,
+	}
+	TraceFilterRangeFlag = &cli.Uint64Flag{
+		Name:     "rpc.tracefilterrange",
+		Usage:    "Sets a cap on the number of blocks trace_filter replays in a single request (0 = no cap)",
+		Value:    ethconfig.Defaults.TraceFilterRange,
+		Category: flags.APICategory,
+	}
 	// Authenticated RPC HTTP settings
 	AuthListenFlag = &cli.StringFlag{
 		Name:     "authrpc.addr",
@@ -665,6 +867,11 @@ var (
 		Value:    "",
 		Category: flags.APICategory,
 	}
+	HTTPH2CFlag = &cli.BoolFlag{
+		Name:     "http.h2c",
+		Usage:    "Enable HTTP/2 over cleartext TCP (h2c) on the HTTP-RPC server",
+		Category: flags.APICategory,
+	}
 	GraphQLEnabledFlag = &cli.BoolFlag{
 		Name:     "graphql",
 		Usage:    "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
@@ -744,6 +951,83 @@ var (
 		Value:    node.DefaultConfig.BatchResponseMaxSize,
 		Category: flags.APICategory,
 	}
+	RPCAPIKeyFileFlag = &cli.PathFlag{
+		Name:      "rpc.apikeys",
+		Usage:     "File with the API keys of the HTTP and WS RPC clients (one name=key per line), rejecting unknown clients",
+		TakesFile: true,
+		Category:  flags.APICategory,
+	}
+	RPCRateLimitFlag = &cli.Float64Flag{
+		Name:     "rpc.ratelimit",
+		Usage:    "Call cost units an HTTP or WS RPC client may spend per second (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	RPCRateBurstFlag = &cli.IntFlag{
+		Name:     "rpc.ratelimit.burst",
+		Usage:    "Call cost units an HTTP or WS RPC client may spend in a burst (default = rate)",
+		Category: flags.APICategory,
+	}
+	RPCMethodLimitsFlag = &cli.StringFlag{
+		Name:     "rpc.methodlimits",
+		Usage:    "Calls per second a client may make to methods or namespaces (e.g. eth_getLogs=5,debug=1)",
+		Category: flags.APICategory,
+	}
+	RPCMethodCostsFlag = &cli.StringFlag{
+		Name:     "rpc.methodcosts",
+		Usage:    "Cost units charged for calling methods or namespaces (e.g. eth_getLogs=20,debug=100)",
+		Category: flags.APICategory,
+	}
+	RPCConcurrencyFlag = &cli.StringFlag{
+		Name:     "rpc.concurrency",
+		Usage:    "Maximum number of concurrent calls to namespaces (e.g. debug=4,trace=4)",
+		Category: flags.APICategory,
+	}
+	RPCAuditLogFlag = &cli.PathFlag{
+		Name:      "rpc.auditlog",
+		Usage:     "File to log slow and failed HTTP and WS RPC calls to (empty = disabled)",
+		TakesFile: true,
+		Category:  flags.APICategory,
+	}
+	RPCAuditLogAllFlag = &cli.BoolFlag{
+		Name:     "rpc.auditlog.all",
+		Usage:    "Log all RPC calls to the audit log, not only slow and failed ones",
+		Category: flags.APICategory,
+	}
+	RPCAuditLogSlowFlag = &cli.DurationFlag{
+		Name:     "rpc.auditlog.slow",
+		Usage:    "Duration from which RPC calls are logged as slow (0 = disabled)",
+		Value:    node.DefaultConfig.RPCAudit.SlowThreshold,
+		Category: flags.APICategory,
+	}
+	RPCAuditLogFailedFlag = &cli.BoolFlag{
+		Name:     "rpc.auditlog.failed",
+		Usage:    "Log failed RPC calls to the audit log",
+		Value:    node.DefaultConfig.RPCAudit.Failed,
+		Category: flags.APICategory,
+	}
+	RPCAuditLogSampleFlag = &cli.IntFlag{
+		Name:     "rpc.auditlog.sample",
+		Usage:    "Log only one in this many selected RPC calls, picked at random",
+		Category: flags.APICategory,
+	}
+	RPCAuditLogRedactFlag = &cli.StringFlag{
+		Name:     "rpc.auditlog.redact",
+		Usage:    "Comma separated methods or namespaces whose parameters are not logged",
+		Value:    strings.Join(node.DefaultConfig.RPCAudit.Redact, ","),
+		Category: flags.APICategory,
+	}
+	RPCAuditLogMaxSizeFlag = &cli.IntFlag{
+		Name:     "rpc.auditlog.maxsize",
+		Usage:    "Size in megabytes from which the audit log is rotated",
+		Value:    node.DefaultConfig.RPCAudit.MaxSize,
+		Category: flags.APICategory,
+	}
+	RPCAuditLogMaxBackupsFlag = &cli.IntFlag{
+		Name:     "rpc.auditlog.maxbackups",
+		Usage:    "Maximum number of rotated audit log files to retain",
+		Value:    node.DefaultConfig.RPCAudit.MaxBackups,
+		Category: flags.APICategory,
+	}
 	EnablePersonal = &cli.BoolFlag{
 		Name:     "rpc.enabledeprecatedpersonal",
 		Usage:    "Enables the (deprecated) personal namespace",
@@ -970,6 +1254,12 @@ var (
 	DatabaseFlags = []cli.Flag{
 		DataDirFlag,
 		AncientFlag,
+		AncientRemoteFlag,
+		AncientRemoteRegionFlag,
+		AncientRemoteAccessKeyFlag,
+		AncientRemoteSecretKeyFlag,
+		AncientRemoteThresholdFlag,
+		AncientRemoteCacheFlag,
 		RemoteDBFlag,
 		DBEngineFlag,
 		StateSchemeFlag,
@@ -1174,6 +1464,9 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(HTTPPathPrefixFlag.Name) {
 		cfg.HTTPPathPrefix = ctx.String(HTTPPathPrefixFlag.Name)
 	}
+	if ctx.IsSet(HTTPH2CFlag.Name) {
+		cfg.HTTPH2C = ctx.Bool(HTTPH2CFlag.Name)
+	}
 	if ctx.IsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.Bool(AllowUnprotectedTxs.Name)
 	}
@@ -1187,6 +1480,80 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCLimits applies the RPC client authentication and limit flags to the config.
+func setRPCLimits(ctx *cli.Context, cfg *node.RPCLimitConfig) {
+	if ctx.IsSet(RPCAPIKeyFileFlag.Name) {
+		cfg.APIKeyFile = ctx.Path(RPCAPIKeyFileFlag.Name)
+	}
+	if ctx.IsSet(RPCRateLimitFlag.Name) {
+		cfg.ClientRate = ctx.Float64(RPCRateLimitFlag.Name)
+	}
+	if ctx.IsSet(RPCRateBurstFlag.Name) {
+		cfg.ClientBurst = ctx.Int(RPCRateBurstFlag.Name)
+	}
+	if ctx.IsSet(RPCMethodLimitsFlag.Name) {
+		cfg.MethodRates = parseRPCLimits(ctx, RPCMethodLimitsFlag.Name)
+	}
+	if ctx.IsSet(RPCMethodCostsFlag.Name) {
+		cfg.MethodCosts = make(map[string]int)
+		for name, cost := range parseRPCLimits(ctx, RPCMethodCostsFlag.Name) {
+			cfg.MethodCosts[name] = int(cost)
+		}
+	}
+	if ctx.IsSet(RPCConcurrencyFlag.Name) {
+		cfg.NamespaceConcurrency = make(map[string]int)
+		for name, limit := range parseRPCLimits(ctx, RPCConcurrencyFlag.Name) {
+			cfg.NamespaceConcurrency[name] = int(limit)
+		}
+	}
+}
+
+// setRPCAudit applies the RPC audit log flags to the config.
+func setRPCAudit(ctx *cli.Context, cfg *node.RPCAuditConfig) {
+	if ctx.IsSet(RPCAuditLogFlag.Name) {
+		cfg.File = ctx.Path(RPCAuditLogFlag.Name)
+	}
+	if ctx.IsSet(RPCAuditLogAllFlag.Name) {
+		cfg.All = ctx.Bool(RPCAuditLogAllFlag.Name)
+	}
+	if ctx.IsSet(RPCAuditLogSlowFlag.Name) {
+		cfg.SlowThreshold = ctx.Duration(RPCAuditLogSlowFlag.Name)
+	}
+	if ctx.IsSet(RPCAuditLogFailedFlag.Name) {
+		cfg.Failed = ctx.Bool(RPCAuditLogFailedFlag.Name)
+	}
+	if ctx.IsSet(RPCAuditLogSampleFlag.Name) {
+		cfg.SampleRatio = ctx.Int(RPCAuditLogSampleFlag.Name)
+	}
+	if ctx.IsSet(RPCAuditLogRedactFlag.Name) {
+		cfg.Redact = SplitAndTrim(ctx.String(RPCAuditLogRedactFlag.Name))
+	}
+	if ctx.IsSet(RPCAuditLogMaxSizeFlag.Name) {
+		cfg.MaxSize = ctx.Int(RPCAuditLogMaxSizeFlag.Name)
+	}
+	if ctx.IsSet(RPCAuditLogMaxBackupsFlag.Name) {
+		cfg.MaxBackups = ctx.Int(RPCAuditLogMaxBackupsFlag.Name)
+	}
+}
+
+// parseRPCLimits parses a comma separated list of name=value pairs from the
+// given flag.
+func parseRPCLimits(ctx *cli.Context, flag string) map[string]float64 {
+	limits := make(map[string]float64)
+	for _, entry := range SplitAndTrim(ctx.String(flag)) {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			Fatalf("Invalid --%s entry %q, want name=value", flag, entry)
+		}
+		limit, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || limit < 0 {
+			Fatalf("Invalid --%s value for %s: %q", flag, name, value)
+		}
+		limits[strings.TrimSpace(name)] = limit
+	}
+	return limits
+}
+
 // setGraphQL creates the GraphQL listener interface string from the set
 // command line flags, returning empty if the GraphQL endpoint is disabled.
 func setGraphQL(ctx *cli.Context, cfg *node.Config) {
@@ -1401,6 +1768,8 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCLimits(ctx, &cfg.RPCLimits)
+	setRPCAudit(ctx, &cfg.RPCAudit)
 	setNodeUserIdent(ctx, cfg)
 	SetDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1443,6 +1812,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		log.Info(fmt.Sprintf("Using %s as db engine", dbEngine))
 		cfg.DBEngine = dbEngine
 	}
+	setAncientTiering(ctx, cfg)
 	// deprecation notice for log debug flags (TODO: find a more appropriate place to put these?)
 	if ctx.IsSet(LogBacktraceAtFlag.Name) {
 		log.Warn("log.backtrace flag is deprecated")
@@ -1452,6 +1822,29 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setAncientTiering configures the offloading of cold ancient data to an S3
+// compatible bucket.
+func setAncientTiering(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.IsSet(AncientRemoteFlag.Name) {
+		return
+	}
+	store, err := s3.New(s3.Config{
+		URL:       ctx.String(AncientRemoteFlag.Name),
+		Region:    ctx.String(AncientRemoteRegionFlag.Name),
+		AccessKey: ctx.String(AncientRemoteAccessKeyFlag.Name),
+		SecretKey: ctx.String(AncientRemoteSecretKeyFlag.Name),
+	})
+	if err != nil {
+		Fatalf("Invalid --%s: %v", AncientRemoteFlag.Name, err)
+	}
+	cfg.AncientTiering = &rawdb.FreezerTiering{
+		Store:     store,
+		Threshold: ctx.Uint64(AncientRemoteThresholdFlag.Name),
+		CacheSize: int64(ctx.Int(AncientRemoteCacheFlag.Name)) * 1024 * 1024,
+	}
+	log.Info("Offloading cold ancient data", "bucket", ctx.String(AncientRemoteFlag.Name), "threshold", cfg.AncientTiering.Threshold)
+}
+
 func setSmartCard(ctx *cli.Context, cfg *node.Config) {
 	// Skip enabling smartcards if no path is set
 	path := ctx.String(SmartCardDaemonPathFlag.Name)
@@ -1502,6 +1895,18 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	}
 }
 
+func setBundlePool(ctx *cli.Context, cfg *ethconfig.Config) {
+	if ctx.IsSet(BundlePoolEnableFlag.Name) {
+		cfg.EnableBundles = ctx.Bool(BundlePoolEnableFlag.Name)
+	}
+	if ctx.IsSet(BundlePoolMaxBundlesFlag.Name) {
+		cfg.BundlePool.MaxBundles = ctx.Int(BundlePoolMaxBundlesFlag.Name)
+	}
+	if ctx.IsSet(BundlePoolMaxSenderBundlesFlag.Name) {
+		cfg.BundlePool.MaxSenderBundles = ctx.Int(BundlePoolMaxSenderBundlesFlag.Name)
+	}
+}
+
 func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolLocalsFlag.Name) {
 		locals := strings.Split(ctx.String(TxPoolLocalsFlag.Name), ",")
@@ -1522,6 +1927,21 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.Duration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.IsSet(TxPoolSenderRateFlag.Name) {
+		cfg.SenderRate = ctx.Float64(TxPoolSenderRateFlag.Name)
+	}
+	if ctx.IsSet(TxPoolSenderBurstFlag.Name) {
+		cfg.SenderBurst = ctx.Uint64(TxPoolSenderBurstFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPersistFlag.Name) {
+		cfg.Persist = ctx.String(TxPoolPersistFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPersistMaxSizeFlag.Name) {
+		cfg.PersistMaxSize = ctx.Uint64(TxPoolPersistMaxSizeFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPersistMaxAgeFlag.Name) {
+		cfg.PersistMaxAge = ctx.Duration(TxPoolPersistMaxAgeFlag.Name)
+	}
 	if ctx.IsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.Uint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1565,6 +1985,61 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 		log.Warn("The flag --miner.newpayload-timeout is deprecated and will be removed, please use --miner.recommit")
 		cfg.Recommit = ctx.Duration(MinerNewPayloadTimeoutFlag.Name)
 	}
+	if ctx.IsSet(MinerOrderingFlag.Name) {
+		switch ordering := ctx.String(MinerOrderingFlag.Name); ordering {
+		case miner.OrderingPrice, miner.OrderingFirstSeen:
+			cfg.Ordering = ordering
+		default:
+			Fatalf("Invalid transaction ordering in --%s: %s", MinerOrderingFlag.Name, ordering)
+		}
+	}
+	if ctx.IsSet(MinerSenderAllowlistFlag.Name) {
+		cfg.SenderAllowlist = parseMinerSenders(ctx, MinerSenderAllowlistFlag.Name)
+	}
+	if ctx.IsSet(MinerSenderDenylistFlag.Name) {
+		cfg.SenderDenylist = parseMinerSenders(ctx, MinerSenderDenylistFlag.Name)
+	}
+	if ctx.IsSet(MinerMaxSenderGasFlag.Name) {
+		cfg.MaxSenderGas = ctx.Uint64(MinerMaxSenderGasFlag.Name)
+	}
+	if ctx.IsSet(MinerLocalGasReserveFlag.Name) {
+		cfg.LocalGasReserve = ctx.Uint64(MinerLocalGasReserveFlag.Name)
+	}
+}
+
+// SetBuilderConfig applies the block builder related command line flags to the config.
+func SetBuilderConfig(ctx *cli.Context, cfg *builder.Config) {
+	if ctx.IsSet(MinerBuilderRelayFlag.Name) {
+		cfg.RelayEndpoint = ctx.String(MinerBuilderRelayFlag.Name)
+	}
+	if ctx.IsSet(MinerBuilderBeaconFlag.Name) {
+		cfg.BeaconEndpoint = ctx.String(MinerBuilderBeaconFlag.Name)
+	}
+	if ctx.IsSet(MinerBuilderSecretKeyFlag.Name) {
+		cfg.SecretKeyFile = ctx.Path(MinerBuilderSecretKeyFlag.Name)
+	}
+	if ctx.IsSet(MinerBuilderForkVersionFlag.Name) {
+		cfg.GenesisForkVersion = ctx.String(MinerBuilderForkVersionFlag.Name)
+	}
+	if ctx.IsSet(MinerBuilderIntervalFlag.Name) {
+		cfg.SubmitInterval = ctx.Duration(MinerBuilderIntervalFlag.Name)
+	}
+	if ctx.IsSet(MinerBuilderDeadlineFlag.Name) {
+		cfg.SubmitDeadline = ctx.Duration(MinerBuilderDeadlineFlag.Name)
+	}
+}
+
+// parseMinerSenders parses a comma separated list of accounts from the given flag.
+func parseMinerSenders(ctx *cli.Context, name string) []common.Address {
+	var senders []common.Address
+	for _, account := range strings.Split(ctx.String(name), ",") {
+		if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+			Fatalf("Invalid account in --%s: %s", name, trimmed)
+		} else {
+			senders = append(senders, common.HexToAddress(trimmed))
+		}
+	}
+	return senders
 }
 
 func setRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1646,6 +2121,16 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setEtherbase(ctx, cfg)
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
+	setBundlePool(ctx, cfg)
+	if ctx.IsSet(TxPoolPeerRateFlag.Name) {
+		cfg.TxPeerRate = ctx.Float64(TxPoolPeerRateFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPeerBurstFlag.Name) {
+		cfg.TxPeerBurst = ctx.Int(TxPoolPeerBurstFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPeerDropScoreFlag.Name) {
+		cfg.TxPeerDropScore = ctx.Int(TxPoolPeerDropScoreFlag.Name)
+	}
 	setMiner(ctx, &cfg.Miner)
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
@@ -1719,6 +2204,16 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Warn("The flag --txlookuplimit is deprecated and will be removed, please use --history.transactions")
 		cfg.TransactionHistory = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	// The logs are unindexed along with the transactions, unless configured
+	// explicitly otherwise.
+	if ctx.IsSet(LogHistoryFlag.Name) {
+		cfg.LogHistory = ctx.Uint64(LogHistoryFlag.Name)
+	} else if cfg.LogHistory == ethconfig.Defaults.LogHistory {
+		cfg.LogHistory = cfg.TransactionHistory
+	}
+	if ctx.IsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.Bool(LogIndexFlag.Name)
+	}
 	if ctx.String(GCModeFlag.Name) == "archive" && cfg.TransactionHistory != 0 {
 		cfg.TransactionHistory = 0
 		log.Warn("Disabled transaction unindexing for archive node")
@@ -1726,6 +2221,10 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		cfg.StateScheme = rawdb.HashScheme
 		log.Warn("Forcing hash state-scheme for archive mode")
 	}
+	if ctx.String(GCModeFlag.Name) == "archive" && cfg.LogHistory != 0 {
+		cfg.LogHistory = 0
+		log.Warn("Disabled log unindexing for archive node")
+	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheTrieFlag.Name) / 100
 	}
@@ -1775,6 +2274,24 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.Float64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.IsSet(JSTracerDisableFlag.Name) {
+		cfg.JSTracerDisabled = ctx.Bool(JSTracerDisableFlag.Name)
+	}
+	if ctx.IsSet(JSTracerMaxHeapFlag.Name) {
+		cfg.JSTracerMaxHeap = ctx.Uint64(JSTracerMaxHeapFlag.Name)
+	}
+	if ctx.IsSet(JSTracerMaxStepsFlag.Name) {
+		cfg.JSTracerMaxSteps = ctx.Uint64(JSTracerMaxStepsFlag.Name)
+	}
+	if ctx.IsSet(JSTracerMaxOutputFlag.Name) {
+		cfg.JSTracerMaxOutput = ctx.Uint64(JSTracerMaxOutputFlag.Name)
+	}
+	if ctx.IsSet(TraceChainDirFlag.Name) {
+		cfg.TraceChainDir = stack.ResolvePath(ctx.String(TraceChainDirFlag.Name))
+	}
+	if ctx.IsSet(TraceFilterRangeFlag.Name) {
+		cfg.TraceFilterRange = ctx.Uint64(TraceFilterRangeFlag.Name)
+	}
 	if ctx.IsSet(NoDiscoverFlag.Name) {
 		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
 	} else if ctx.IsSet(DNSDiscoveryFlag.Name) {
@@ -1935,7 +2452,10 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 	if err != nil {
 		Fatalf("Failed to register the Ethereum service: %v", err)
 	}
-	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+	stack.RegisterAPIs(tracers.APIs(backend.APIBackend, tracers.Config{
+		TraceChainDir:    cfg.TraceChainDir,
+		TraceFilterRange: cfg.TraceFilterRange,
+	}))
 	return backend.APIBackend, backend
 }
 
@@ -1946,6 +2466,13 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 	}
 }
 
+// RegisterBuilderService configures the block builder and adds it to the node.
+func RegisterBuilderService(stack *node.Node, backend *eth.Ethereum, cfg *builder.Config) {
+	if err := builder.Register(stack, backend, *cfg); err != nil {
+		Fatalf("Failed to register the block builder: %v", err)
+	}
+}
+
 // RegisterGraphQLService adds the GraphQL API to the node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cfg *node.Config) {
 	err := graphql.New(stack, backend, filterSystem, cfg.GraphQLCors, cfg.GraphQLVirtualHosts)
@@ -2061,11 +2588,12 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.
 	switch {
 	case ctx.IsSet(RemoteDBFlag.Name):
 		log.Info("Using remote db", "url", ctx.String(RemoteDBFlag.Name), "headers", len(ctx.StringSlice(HttpHeaderFlag.Name)))
-		client, err := DialRPCWithHeaders(ctx.String(RemoteDBFlag.Name), ctx.StringSlice(HttpHeaderFlag.Name))
+		var client *rpc.Client
+		client, err = DialRPCWithHeaders(ctx.String(RemoteDBFlag.Name), ctx.StringSlice(HttpHeaderFlag.Name))
 		if err != nil {
 			break
 		}
-		chainDb = remotedb.New(client)
+		chainDb = remotedb.New(client, cache)
 	case ctx.String(SyncModeFlag.Name) == "light":
 		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles, "", readonly)
 	default:
@@ -2197,7 +2725,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 			if ctx.IsSet(VMTraceJsonConfigFlag.Name) {
 				config = json.RawMessage(ctx.String(VMTraceJsonConfigFlag.Name))
 			}
-			t, err := tracers.LiveDirectory.New(name, config)
+			t, err := tracers.LiveDirectory.NewWithDB(name, config, chainDb)
 			if err != nil {
 				Fatalf("Failed to create tracer %q: %v", name, err)
 			}
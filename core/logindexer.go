@@ -0,0 +1,487 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errLogIndexInterrupted is returned if the log indexing is interrupted.
+var errLogIndexInterrupted = errors.New("log indexing interrupted")
+
+// LogIndexer is the module responsible for maintaining the persistent log index,
+// which maps the log emitters and the log topics to the positions of the logs
+// in the canonical chain. The index follows the chain head across reorgs and
+// is limited to the configured range of recent blocks.
+type LogIndexer struct {
+	// limit is the maximum number of blocks from head whose logs are indexed:
+	//  * 0: means the entire chain should be indexed
+	//  * N: means the latest N blocks [HEAD-N+1, HEAD] should be indexed
+	//       and all others shouldn't.
+	limit  uint64
+	db     ethdb.Database
+	term   chan chan struct{}
+	closed chan struct{}
+}
+
+// NewLogIndexer initializes the log indexer and starts maintaining the index in
+// the background.
+func NewLogIndexer(db ethdb.Database, limit uint64, chain *BlockChain) *LogIndexer {
+	indexer := &LogIndexer{
+		limit:  limit,
+		db:     db,
+		term:   make(chan chan struct{}),
+		closed: make(chan struct{}),
+	}
+	go indexer.loop(chain)
+
+	var msg string
+	if limit == 0 {
+		msg = "entire chain"
+	} else {
+		msg = fmt.Sprintf("last %d blocks", limit)
+	}
+	log.Info("Initialized log indexer", "range", msg)
+
+	return indexer
+}
+
+// loop is the scheduler of the indexer, running the index updates upon the
+// chain head events.
+func (indexer *LogIndexer) loop(chain *BlockChain) {
+	defer close(indexer.closed)
+
+	var (
+		stop     chan struct{} // Non-nil if background routine is active.
+		done     chan struct{} // Non-nil if background routine is active.
+		lastHead uint64        // The latest announced chain head
+		runHead  uint64        // The chain head the active routine is indexing towards
+
+		headCh = make(chan ChainHeadEvent)
+		sub    = chain.SubscribeChainHeadEvent(headCh)
+	)
+	defer sub.Unsubscribe()
+
+	start := func(head uint64) {
+		stop, done, runHead = make(chan struct{}), make(chan struct{}), head
+		go func(stop, done chan struct{}) {
+			defer close(done)
+			if err := updateLogIndex(indexer.db, indexer.limit, head, stop); err != nil && !errors.Is(err, errLogIndexInterrupted) {
+				log.Error("Failed to update log index", "err", err)
+			}
+		}(stop, done)
+	}
+	// Launch the initial processing if chain is not empty (head != genesis).
+	if head := rawdb.ReadHeadBlock(indexer.db); head != nil && head.NumberU64() != 0 {
+		lastHead = head.NumberU64()
+		start(lastHead)
+	}
+	for {
+		select {
+		case head := <-headCh:
+			lastHead = head.Block.NumberU64()
+			if done == nil {
+				start(lastHead)
+			}
+		case <-done:
+			stop, done = nil, nil
+			if lastHead != runHead {
+				start(lastHead)
+			}
+		case ch := <-indexer.term:
+			if stop != nil {
+				close(stop)
+			}
+			if done != nil {
+				log.Info("Waiting background log indexer to exit")
+				<-done
+			}
+			close(ch)
+			return
+		}
+	}
+}
+
+// Close shuts down the indexer. Safe to be called for multiple times.
+func (indexer *LogIndexer) Close() {
+	ch := make(chan struct{})
+	select {
+	case indexer.term <- ch:
+		<-ch
+	case <-indexer.closed:
+	}
+}
+
+// RebuildLogIndex drops the entire log index and regenerates it for the given
+// range of recent blocks, up to the current head block.
+func RebuildLogIndex(db ethdb.Database, limit uint64, stop chan struct{}) error {
+	if err := rawdb.DeleteLogIndex(db); err != nil {
+		return err
+	}
+	head := rawdb.ReadHeadBlock(db)
+	if head == nil {
+		return errors.New("head block not found")
+	}
+	return updateLogIndex(db, limit, head.NumberU64(), stop)
+}
+
+// LogIndexRange returns the range of blocks [tail, head] covered by the log index.
+// The index is reported as unavailable if its head is not canonical, e.g. the
+// chain is reorged but the index is not yet updated.
+func LogIndexRange(db ethdb.Reader) (uint64, uint64, bool) {
+	number, hash, ok := rawdb.ReadLogIndexHead(db)
+	if !ok {
+		return 0, 0, false
+	}
+	tail := rawdb.ReadLogIndexTail(db)
+	if tail == nil || *tail > number {
+		return 0, 0, false
+	}
+	if rawdb.ReadCanonicalHash(db, number) != hash {
+		return 0, 0, false
+	}
+	return *tail, number, true
+}
+
+// LogIndexMatch is a block containing logs which satisfy the log index query,
+// along with the positions of the matching logs within the block.
+type LogIndexMatch struct {
+	Number    uint64
+	Positions []uint32
+}
+
+// logIndexQuery is a single address or topic to be looked up in the log index.
+type logIndexQuery struct {
+	kind  byte
+	value []byte
+}
+
+// FilterLogIndex looks up the blocks in the range [from, to] which contain logs
+// emitted by any of the given addresses and carrying any of the given topics
+// at each position, with the same semantics as the log filters. At least one
+// address or topic must be specified. The matches are sorted by block number.
+func FilterLogIndex(db ethdb.Iteratee, from, to uint64, addresses []common.Address, topics [][]common.Hash) ([]LogIndexMatch, error) {
+	var groups [][]logIndexQuery
+	if len(addresses) > 0 {
+		group := make([]logIndexQuery, len(addresses))
+		for i, addr := range addresses {
+			group[i] = logIndexQuery{kind: rawdb.LogIndexAddress, value: addr.Bytes()}
+		}
+		groups = append(groups, group)
+	}
+	for i, sub := range topics {
+		if len(sub) == 0 {
+			continue // empty rule set == wildcard
+		}
+		if i >= rawdb.LogIndexMaxTopics {
+			return nil, nil // no log has that many topics
+		}
+		group := make([]logIndexQuery, len(sub))
+		for j, topic := range sub {
+			group[j] = logIndexQuery{kind: rawdb.LogIndexTopic + byte(i), value: topic.Bytes()}
+		}
+		groups = append(groups, group)
+	}
+	if len(groups) == 0 {
+		return nil, errors.New("no address or topic criteria")
+	}
+	// Resolve the logs satisfying every group of criteria. The criteria within
+	// the group are alternatives, so the union of the matches is taken, while
+	// the matches of the groups are intersected.
+	var result map[uint64]map[uint32]struct{}
+	for _, group := range groups {
+		matches := make(map[uint64]map[uint32]struct{})
+		for _, query := range group {
+			err := rawdb.IterateLogIndexEntries(db, query.kind, query.value, from, to, func(number uint64, positions []uint32) bool {
+				var prev map[uint32]struct{}
+				if result != nil {
+					if prev = result[number]; prev == nil {
+						return true
+					}
+				}
+				for _, pos := range positions {
+					if prev != nil {
+						if _, ok := prev[pos]; !ok {
+							continue
+						}
+					}
+					if matches[number] == nil {
+						matches[number] = make(map[uint32]struct{})
+					}
+					matches[number][pos] = struct{}{}
+				}
+				return true
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		result = matches
+		if len(result) == 0 {
+			return nil, nil
+		}
+	}
+	res := make([]LogIndexMatch, 0, len(result))
+	for number, positions := range result {
+		match := LogIndexMatch{Number: number, Positions: make([]uint32, 0, len(positions))}
+		for pos := range positions {
+			match.Positions = append(match.Positions, pos)
+		}
+		slices.Sort(match.Positions)
+		res = append(res, match)
+	}
+	slices.SortFunc(res, func(a, b LogIndexMatch) int {
+		return cmp.Compare(a.Number, b.Number)
+	})
+	return res, nil
+}
+
+// updateLogIndex brings the log index in line with the canonical chain ending at
+// the given head. The stale entries of the blocks reorged out are removed first,
+// then the new blocks are indexed and lastly the tail of the index is adjusted
+// according to the configured limit.
+func updateLogIndex(db ethdb.Database, limit uint64, head uint64, stop chan struct{}) error {
+	var from uint64
+	if limit != 0 && head >= limit {
+		from = head - limit + 1
+	}
+	number, ok, err := rewindLogIndex(db, stop)
+	if err != nil {
+		return err
+	}
+	// The index is empty or fully rewound, build it from scratch.
+	if !ok {
+		return indexLogs(db, from, head, true, stop)
+	}
+	tail := rawdb.ReadLogIndexTail(db)
+	if number < head {
+		if err := indexLogs(db, number+1, head, false, stop); err != nil {
+			return err
+		}
+	}
+	switch {
+	case from > *tail:
+		return unindexLogs(db, *tail, min(from, head+1), stop)
+	case from < *tail:
+		return extendLogIndexTail(db, from, *tail, stop)
+	}
+	return nil
+}
+
+// rewindLogIndex removes the index entries of the blocks which are no longer
+// canonical. It returns the number of the new index head and a flag whether
+// the index is still present at all.
+func rewindLogIndex(db ethdb.Database, stop chan struct{}) (uint64, bool, error) {
+	number, hash, ok := rawdb.ReadLogIndexHead(db)
+	tail := rawdb.ReadLogIndexTail(db)
+	if !ok || tail == nil {
+		return 0, false, nil
+	}
+	batch := db.NewBatch()
+	for rawdb.ReadCanonicalHash(db, number) != hash {
+		select {
+		case <-stop:
+			return 0, false, errLogIndexInterrupted
+		default:
+		}
+		rawdb.DeleteLogIndexBlock(db, batch, number)
+
+		header := rawdb.ReadHeader(db, hash, number)
+		if number <= *tail || header == nil {
+			// The whole index is rewound, or the chain segment can't be traversed
+			// anymore. Drop the entire index and start over.
+			if err := batch.Write(); err != nil {
+				return 0, false, err
+			}
+			if err := rawdb.DeleteLogIndex(db); err != nil {
+				return 0, false, err
+			}
+			log.Info("Log index dropped for reorg", "number", number)
+			return 0, false, nil
+		}
+		number, hash = number-1, header.ParentHash
+		rawdb.WriteLogIndexHead(batch, number, hash)
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return 0, false, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return 0, false, err
+	}
+	return number, true, nil
+}
+
+// indexLogs indexes the logs of the canonical blocks within [from, to] in the
+// ascending order, moving the index head forward. If the index is fresh, the
+// index tail is initialized as well.
+func indexLogs(db ethdb.Database, from, to uint64, fresh bool, stop chan struct{}) error {
+	var (
+		batch  = db.NewBatch()
+		start  = time.Now()
+		logged = start
+	)
+	if fresh {
+		rawdb.WriteLogIndexTail(batch, from)
+	}
+	for number := from; number <= to; number++ {
+		select {
+		case <-stop:
+			return errLogIndexInterrupted
+		default:
+		}
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return fmt.Errorf("canonical block #%d not found", number)
+		}
+		writeBlockLogIndex(batch, number, rawdb.ReadRawReceipts(db, hash, number))
+		rawdb.WriteLogIndexHead(batch, number, hash)
+
+		if batch.ValueSize() > ethdb.IdealBatchSize || number == to {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing logs", "head", number, "target", to, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	log.Debug("Indexed logs", "from", from, "to", to, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// extendLogIndexTail indexes the logs of the canonical blocks within [from, tail)
+// in the descending order, moving the index tail backward.
+func extendLogIndexTail(db ethdb.Database, from, tail uint64, stop chan struct{}) error {
+	var (
+		batch  = db.NewBatch()
+		start  = time.Now()
+		logged = start
+	)
+	for number := tail; number > from; {
+		number--
+		select {
+		case <-stop:
+			return errLogIndexInterrupted
+		default:
+		}
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return fmt.Errorf("canonical block #%d not found", number)
+		}
+		writeBlockLogIndex(batch, number, rawdb.ReadRawReceipts(db, hash, number))
+		rawdb.WriteLogIndexTail(batch, number)
+
+		if batch.ValueSize() > ethdb.IdealBatchSize || number == from {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing historical logs", "tail", number, "target", from, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return nil
+}
+
+// unindexLogs removes the index entries of the blocks within [tail, to), moving
+// the index tail forward. The entries are removed by block number, not relying
+// on the receipts which might be pruned already.
+func unindexLogs(db ethdb.Database, tail, to uint64, stop chan struct{}) error {
+	var (
+		batch  = db.NewBatch()
+		start  = time.Now()
+		logged = start
+	)
+	for number := tail; number < to; number++ {
+		select {
+		case <-stop:
+			return errLogIndexInterrupted
+		default:
+		}
+		rawdb.DeleteLogIndexBlock(db, batch, number)
+		rawdb.WriteLogIndexTail(batch, number+1)
+
+		if batch.ValueSize() > ethdb.IdealBatchSize || number == to-1 {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Unindexing logs", "tail", number, "target", to, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return nil
+}
+
+// writeBlockLogIndex writes the index entries of the logs contained in the given
+// block receipts.
+func writeBlockLogIndex(batch ethdb.KeyValueWriter, number uint64, receipts types.Receipts) {
+	type entryKey struct {
+		kind  byte
+		value string
+	}
+	var (
+		entries = make(map[entryKey][]uint32)
+		order   []entryKey
+		pos     uint32
+	)
+	add := func(key entryKey) {
+		positions, ok := entries[key]
+		if !ok {
+			order = append(order, key)
+		}
+		// Multiple topics of a log might be identical, only record it once.
+		if n := len(positions); n > 0 && positions[n-1] == pos {
+			return
+		}
+		entries[key] = append(positions, pos)
+	}
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			add(entryKey{rawdb.LogIndexAddress, string(l.Address.Bytes())})
+			for i, topic := range l.Topics {
+				add(entryKey{rawdb.LogIndexTopic + byte(i), string(topic.Bytes())})
+			}
+			pos++
+		}
+	}
+	index := make([]rawdb.LogIndexEntry, len(order))
+	for i, key := range order {
+		index[i] = rawdb.LogIndexEntry{Kind: key.kind, Value: []byte(key.value), Positions: entries[key]}
+	}
+	rawdb.WriteLogIndexBlock(batch, number, index)
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package core
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var (
+	logIndexAddrA     = common.HexToAddress("0xaaaa")
+	logIndexAddrB     = common.HexToAddress("0xbbbb")
+	logIndexTopicEven = common.HexToHash("0x01")
+	logIndexTopicOdd  = common.HexToHash("0x02")
+	logIndexTopicFork = common.HexToHash("0x03")
+)
+
+// writeLogIndexChain writes a canonical chain segment of headers and receipts
+// on top of the given parent. Every block contains a log emitted by address A,
+// with the topic depending on the parity of the block number, and the blocks
+// divisible by three contain a second log emitted by address B.
+func writeLogIndexChain(db ethdb.Database, parent *types.Header, from, to uint64, extra []byte) *types.Header {
+	for number := from; number <= to; number++ {
+		header := &types.Header{
+			Number: new(big.Int).SetUint64(number),
+			Extra:  extra,
+		}
+		if parent != nil {
+			header.ParentHash = parent.Hash()
+		}
+		topic := logIndexTopicOdd
+		if number%2 == 0 {
+			topic = logIndexTopicEven
+		}
+		if len(extra) > 0 {
+			topic = logIndexTopicFork
+		}
+		receipt := &types.Receipt{Logs: []*types.Log{{Address: logIndexAddrA, Topics: []common.Hash{topic}}}}
+		if number%3 == 0 {
+			receipt.Logs = append(receipt.Logs, &types.Log{Address: logIndexAddrB, Topics: []common.Hash{logIndexTopicEven, logIndexTopicEven}})
+		}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteReceipts(db, header.Hash(), number, types.Receipts{receipt})
+		rawdb.WriteCanonicalHash(db, header.Hash(), number)
+		rawdb.WriteHeadHeaderHash(db, header.Hash())
+		parent = header
+	}
+	return parent
+}
+
+func TestLogIndexer(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	head := writeLogIndexChain(db, nil, 0, 16, nil)
+
+	verifyRange := func(tail, head uint64) {
+		t.Helper()
+		haveTail, haveHead, ok := LogIndexRange(db)
+		if !ok {
+			t.Fatal("log index is not available")
+		}
+		if haveTail != tail || haveHead != head {
+			t.Fatalf("log index range mismatch: have [%d, %d], want [%d, %d]", haveTail, haveHead, tail, head)
+		}
+	}
+	verifyFilter := func(addresses []common.Address, topics [][]common.Hash, want []LogIndexMatch) {
+		t.Helper()
+		have, err := FilterLogIndex(db, 0, 16, addresses, topics)
+		if err != nil {
+			t.Fatalf("failed to filter log index: %v", err)
+		}
+		if len(have) == 0 && len(want) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Fatalf("log index matches mismatch: have %v, want %v", have, want)
+		}
+	}
+	// Index the entire chain
+	if err := updateLogIndex(db, 0, 16, make(chan struct{})); err != nil {
+		t.Fatalf("failed to index logs: %v", err)
+	}
+	verifyRange(0, 16)
+
+	var even, third, both []LogIndexMatch
+	for number := uint64(0); number <= 16; number++ {
+		if number%2 == 0 {
+			even = append(even, LogIndexMatch{Number: number, Positions: []uint32{0}})
+		}
+		if number%3 == 0 {
+			third = append(third, LogIndexMatch{Number: number, Positions: []uint32{1}})
+		}
+		switch {
+		case number%6 == 0:
+			both = append(both, LogIndexMatch{Number: number, Positions: []uint32{0, 1}})
+		case number%2 == 0:
+			both = append(both, LogIndexMatch{Number: number, Positions: []uint32{0}})
+		case number%3 == 0:
+			both = append(both, LogIndexMatch{Number: number, Positions: []uint32{1}})
+		}
+	}
+	verifyFilter([]common.Address{logIndexAddrA}, [][]common.Hash{{logIndexTopicEven}}, even)
+	verifyFilter([]common.Address{logIndexAddrB}, nil, third)
+	verifyFilter([]common.Address{logIndexAddrB}, [][]common.Hash{nil, {logIndexTopicEven}}, third)
+	verifyFilter([]common.Address{logIndexAddrB}, [][]common.Hash{{logIndexTopicOdd}}, nil)
+	verifyFilter(nil, [][]common.Hash{{logIndexTopicEven}}, both)
+	verifyFilter([]common.Address{logIndexAddrA, logIndexAddrB}, [][]common.Hash{{logIndexTopicEven}}, both)
+
+	// Shrink the index range, the pruned blocks must be unindexed
+	if err := updateLogIndex(db, 4, 16, make(chan struct{})); err != nil {
+		t.Fatalf("failed to index logs: %v", err)
+	}
+	verifyRange(13, 16)
+	verifyFilter(nil, [][]common.Hash{{logIndexTopicEven}}, both[len(both)-3:])
+
+	// Extend the index range backwards
+	if err := updateLogIndex(db, 8, 16, make(chan struct{})); err != nil {
+		t.Fatalf("failed to index logs: %v", err)
+	}
+	verifyRange(9, 16)
+	verifyFilter([]common.Address{logIndexAddrA}, [][]common.Hash{{logIndexTopicEven}}, even[len(even)-4:])
+
+	// Reorg the chain from block 12 onwards, the index entries of the dropped
+	// blocks must be removed even if their receipts are gone already.
+	for number := uint64(12); number <= 16; number++ {
+		rawdb.DeleteReceipts(db, rawdb.ReadCanonicalHash(db, number), number)
+	}
+	parent := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, 11), 11)
+	head = writeLogIndexChain(db, parent, 12, 18, []byte("fork"))
+	if err := updateLogIndex(db, 8, 18, make(chan struct{})); err != nil {
+		t.Fatalf("failed to index logs: %v", err)
+	}
+	verifyRange(11, head.Number.Uint64())
+	verifyFilter([]common.Address{logIndexAddrA}, [][]common.Hash{{logIndexTopicEven}}, nil)
+
+	matches, err := FilterLogIndex(db, 0, 18, nil, [][]common.Hash{{logIndexTopicFork}})
+	if err != nil {
+		t.Fatalf("failed to filter log index: %v", err)
+	}
+	if len(matches) != 7 || matches[0].Number != 12 || matches[6].Number != 18 {
+		t.Fatalf("unexpected fork matches: %v", matches)
+	}
+
+	// Drop the index on a reorg deeper than the index tail, no entries may be
+	// left behind outside the rebuilt range.
+	parent = rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, 1), 1)
+	writeLogIndexChain(db, parent, 2, 18, []byte("deep"))
+	if err := updateLogIndex(db, 4, 18, make(chan struct{})); err != nil {
+		t.Fatalf("failed to index logs: %v", err)
+	}
+	verifyRange(15, 18)
+	if matches, err := FilterLogIndex(db, 0, 14, []common.Address{logIndexAddrA}, nil); err != nil || len(matches) != 0 {
+		t.Fatalf("stale log index entries: %v, err %v", matches, err)
+	}
+}
@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -179,3 +180,188 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+const (
+	// LogIndexAddress is the kind of the log index entries keyed by the address
+	// of the log emitter.
+	LogIndexAddress byte = 0
+
+	// LogIndexTopic is the kind of the log index entries keyed by the first log
+	// topic. The entries of the i-th topic are of the kind LogIndexTopic+i.
+	LogIndexTopic byte = 1
+
+	// LogIndexMaxTopics is the maximum number of the topics of a log.
+	LogIndexMaxTopics = 4
+
+	// logIndexBlock is the kind of the per-block records listing the addresses
+	// and topics indexed in the block, used to remove the entries of a block
+	// without the receipts.
+	logIndexBlock byte = 0xff
+)
+
+// LogIndexEntry is the positions of the logs within a block which are emitted
+// by an address, or carrying a topic.
+type LogIndexEntry struct {
+	Kind      byte
+	Value     []byte
+	Positions []uint32
+}
+
+// ReadLogIndexHead retrieves the number and hash of the latest block whose logs
+// have been indexed. The returned flag reports whether the marker is present.
+func ReadLogIndexHead(db ethdb.KeyValueReader) (uint64, common.Hash, bool) {
+	data, _ := db.Get(logIndexHeadKey)
+	if len(data) != 8+common.HashLength {
+		return 0, common.Hash{}, false
+	}
+	return binary.BigEndian.Uint64(data[:8]), common.BytesToHash(data[8:]), true
+}
+
+// WriteLogIndexHead stores the number and hash of the latest block whose logs
+// have been indexed.
+func WriteLogIndexHead(db ethdb.KeyValueWriter, number uint64, hash common.Hash) {
+	if err := db.Put(logIndexHeadKey, append(encodeBlockNumber(number), hash.Bytes()...)); err != nil {
+		log.Crit("Failed to store the log index head", "err", err)
+	}
+}
+
+// ReadLogIndexTail retrieves the number of the oldest block whose logs have
+// been indexed.
+func ReadLogIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(logIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteLogIndexTail stores the number of the oldest block whose logs have been
+// indexed.
+func WriteLogIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(logIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the log index tail", "err", err)
+	}
+}
+
+// DeleteLogIndexRange removes the head and tail markers of the log index,
+// rendering all the index entries unreachable.
+func DeleteLogIndexRange(db ethdb.KeyValueWriter) {
+	if err := db.Delete(logIndexHeadKey); err != nil {
+		log.Crit("Failed to delete the log index head", "err", err)
+	}
+	if err := db.Delete(logIndexTailKey); err != nil {
+		log.Crit("Failed to delete the log index tail", "err", err)
+	}
+}
+
+// WriteLogIndexBlock stores the log index entries of the given block, along with
+// the record of their keys.
+func WriteLogIndexBlock(db ethdb.KeyValueWriter, number uint64, entries []LogIndexEntry) {
+	var keys []byte
+	for _, entry := range entries {
+		enc := make([]byte, 4*len(entry.Positions))
+		for i, pos := range entry.Positions {
+			binary.BigEndian.PutUint32(enc[4*i:], pos)
+		}
+		if err := db.Put(logIndexKey(entry.Kind, entry.Value, number), enc); err != nil {
+			log.Crit("Failed to store log index entry", "err", err)
+		}
+		keys = append(append(keys, entry.Kind), entry.Value...)
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := db.Put(logIndexBlockKey(number), keys); err != nil {
+		log.Crit("Failed to store log index block", "err", err)
+	}
+}
+
+// DeleteLogIndexBlock removes the log index entries of the given block, as listed
+// by the record of their keys. The receipts of the block are not needed, so the
+// entries can be removed even if the block is already pruned or reorged out.
+func DeleteLogIndexBlock(db ethdb.KeyValueReader, batch ethdb.KeyValueWriter, number uint64) {
+	keys, _ := db.Get(logIndexBlockKey(number))
+	for len(keys) > 0 {
+		size := common.HashLength
+		if keys[0] == LogIndexAddress {
+			size = common.AddressLength
+		}
+		if len(keys) < 1+size {
+			log.Error("Corrupted log index block", "number", number)
+			break
+		}
+		if err := batch.Delete(logIndexKey(keys[0], keys[1:1+size], number)); err != nil {
+			log.Crit("Failed to delete log index entry", "err", err)
+		}
+		keys = keys[1+size:]
+	}
+	if err := batch.Delete(logIndexBlockKey(number)); err != nil {
+		log.Crit("Failed to delete log index block", "err", err)
+	}
+}
+
+// IterateLogIndexEntries iterates over the log index entries of the given address
+// or topic within the block range [from, to] in ascending order. The iteration is
+// stopped if the callback returns false.
+func IterateLogIndexEntries(db ethdb.Iteratee, kind byte, value []byte, from, to uint64, fn func(number uint64, positions []uint32) bool) error {
+	prefix := logIndexKey(kind, value, 0)
+	prefix = prefix[:len(prefix)-8]
+
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	for it.Next() {
+		key, blob := it.Key(), it.Value()
+		if len(key) != len(prefix)+8 || len(blob)%4 != 0 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		positions := make([]uint32, len(blob)/4)
+		for i := range positions {
+			positions[i] = binary.BigEndian.Uint32(blob[4*i:])
+		}
+		if !fn(number, positions) {
+			break
+		}
+	}
+	return it.Error()
+}
+
+// DeleteLogIndex removes the markers of the log index along with all the index
+// entries. The markers are removed first, so an interrupted deletion leaves no
+// partial index behind.
+func DeleteLogIndex(db ethdb.Database) error {
+	batch := db.NewBatch()
+	DeleteLogIndexRange(batch)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	batch.Reset()
+
+	it := db.NewIterator(logIndexPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(logIndexPrefix)+1+common.AddressLength+8 && len(key) != len(logIndexPrefix)+1+common.HashLength+8 && len(key) != len(logIndexPrefix)+1+8 {
+			continue
+		}
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
@@ -137,6 +137,49 @@ func WritePersistentStateID(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// ReadStateDiff retrieves the encoded state diff of the given block.
+func ReadStateDiff(db ethdb.KeyValueReader, number uint64, hash common.Hash) []byte {
+	data, _ := db.Get(stateDiffKey(number, hash))
+	return data
+}
+
+// WriteStateDiff stores the encoded state diff of the given block.
+func WriteStateDiff(db ethdb.KeyValueWriter, number uint64, hash common.Hash, diff []byte) {
+	if err := db.Put(stateDiffKey(number, hash), diff); err != nil {
+		log.Crit("Failed to store state diff", "err", err)
+	}
+}
+
+// DeleteStateDiffRange removes the state diffs of all blocks within the range
+// [from, to), regardless of their hashes.
+func DeleteStateDiffRange(db ethdb.KeyValueStore, from, to uint64) {
+	it := db.NewIterator(stateDiffPrefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	batch := db.NewBatch()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(stateDiffPrefix)+8+common.HashLength {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(stateDiffPrefix):]) >= to {
+			break
+		}
+		if err := batch.Delete(key); err != nil {
+			log.Crit("Failed to delete state diff", "err", err)
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to delete state diffs", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete state diffs", "err", err)
+	}
+}
+
 // ReadTrieJournal retrieves the serialized in-memory trie nodes of layers saved at
 // the last shutdown.
 func ReadTrieJournal(db ethdb.KeyValueReader) []byte {
@@ -24,9 +24,14 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 )
 
+// inspectRatioSamples is the number of items sampled from a compressed table
+// to estimate its compression ratio.
+const inspectRatioSamples = 1024
+
 type tableSize struct {
-	name string
-	size common.StorageSize
+	name  string
+	size  common.StorageSize
+	ratio float64 // Estimated compression ratio, zero for uncompressed tables
 }
 
 // freezerInfo contains the basic information of the freezer.
@@ -51,15 +56,12 @@ func (info *freezerInfo) size() common.StorageSize {
 	return total
 }
 
-func inspect(name string, order map[string]bool, reader ethdb.AncientReader) (freezerInfo, error) {
+// inspect retrieves the basic information of a freezer. The compression ratio
+// of the compressed tables is only estimated if requested, as it reads a sample
+// of their items.
+func inspect(name string, order map[string]bool, reader ethdb.AncientReader, ratio bool) (freezerInfo, error) {
 	info := freezerInfo{name: name}
-	for t := range order {
-		size, err := reader.AncientSize(t)
-		if err != nil {
-			return freezerInfo{}, err
-		}
-		info.sizes = append(info.sizes, tableSize{name: t, size: common.StorageSize(size)})
-	}
+
 	// Retrieve the number of last stored item
 	ancients, err := reader.Ancients()
 	if err != nil {
@@ -73,16 +75,53 @@ func inspect(name string, order map[string]bool, reader ethdb.AncientReader) (fr
 		return freezerInfo{}, err
 	}
 	info.tail = tail
+
+	for t, noSnappy := range order {
+		size, err := reader.AncientSize(t)
+		if err != nil {
+			return freezerInfo{}, err
+		}
+		table := tableSize{name: t, size: common.StorageSize(size)}
+		if ratio && !noSnappy {
+			if table.ratio, err = compressionRatio(reader, t, tail, ancients, size); err != nil {
+				return freezerInfo{}, err
+			}
+		}
+		info.sizes = append(info.sizes, table)
+	}
 	return info, nil
 }
 
+// compressionRatio estimates the compression ratio of a table, extrapolating
+// the size of items sampled evenly across the table to the entire table and
+// comparing it to the table's storage size.
+func compressionRatio(reader ethdb.AncientReader, kind string, tail, items, size uint64) (float64, error) {
+	if items <= tail || size == 0 {
+		return 0, nil
+	}
+	var (
+		step    = max((items-tail)/inspectRatioSamples, 1)
+		sampled uint64
+		total   uint64
+	)
+	for i := tail; i < items; i += step {
+		blob, err := reader.Ancient(kind, i)
+		if err != nil {
+			return 0, err
+		}
+		sampled++
+		total += uint64(len(blob))
+	}
+	return float64(total) * float64(items-tail) / float64(sampled) / float64(size), nil
+}
+
 // inspectFreezers inspects all freezers registered in the system.
-func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
+func inspectFreezers(db ethdb.Database, ratio bool) ([]freezerInfo, error) {
 	var infos []freezerInfo
 	for _, freezer := range freezers {
 		switch freezer {
 		case ChainFreezerName:
-			info, err := inspect(ChainFreezerName, chainFreezerNoSnappy, db)
+			info, err := inspect(ChainFreezerName, chainFreezerNoSnappy, db, ratio)
 			if err != nil {
 				return nil, err
 			}
@@ -99,7 +138,7 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 			}
 			defer f.Close()
 
-			info, err := inspect(freezer, stateFreezerNoSnappy, f)
+			info, err := inspect(freezer, stateFreezerNoSnappy, f, ratio)
 			if err != nil {
 				return nil, err
 			}
@@ -144,3 +183,30 @@ func InspectFreezerTable(ancient string, freezerName string, tableName string, s
 	table.dumpIndexStdout(start, end)
 	return nil
 }
+
+// CompressFreezerTable rewrites a compressed table of a freezer with zstd
+// compression, using a dictionary trained on the table's content. The passed
+// ancient indicates the path of root ancient directory where the freezers can
+// be opened.
+func CompressFreezerTable(ancient string, freezerName string, tableName string) error {
+	var (
+		path    string
+		tables  map[string]bool
+		maxSize uint32
+	)
+	switch freezerName {
+	case ChainFreezerName:
+		path, tables, maxSize = resolveChainFreezerDir(ancient), chainFreezerNoSnappy, freezerTableSize
+	case StateFreezerName:
+		path, tables, maxSize = filepath.Join(ancient, freezerName), stateFreezerNoSnappy, stateHistoryTableSize
+	default:
+		return fmt.Errorf("unknown freezer, supported ones: %v", freezers)
+	}
+	f, err := NewFreezer(path, "", false, maxSize, tables)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.CompressTable(tableName)
+}
@@ -55,8 +55,9 @@ type chainFreezer struct {
 //   - if the empty directory is given, initializes the pure in-memory
 //     state freezer (e.g. dev mode).
 //   - if non-empty directory is given, initializes the regular file-based
-//     state freezer.
-func newChainFreezer(datadir string, namespace string, readonly bool) (*chainFreezer, error) {
+//     state freezer, offloading cold data to a remote store if tiering is
+//     configured.
+func newChainFreezer(datadir string, namespace string, readonly bool, tiering *FreezerTiering) (*chainFreezer, error) {
 	var (
 		err     error
 		freezer ethdb.AncientStore
@@ -64,7 +65,7 @@ func newChainFreezer(datadir string, namespace string, readonly bool) (*chainFre
 	if datadir == "" {
 		freezer = NewMemoryFreezer(readonly, chainFreezerNoSnappy)
 	} else {
-		freezer, err = NewFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy)
+		freezer, err = newTieredFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy, tiering)
 	}
 	if err != nil {
 		return nil, err
@@ -175,6 +176,12 @@ func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
 				return
 			}
 		}
+		// Move the data files which turned cold to the remote store
+		if freezer, ok := f.AncientStore.(*Freezer); ok {
+			if err := freezer.offload(); err != nil {
+				log.Error("Failed to offload ancient data", "err", err)
+			}
+		}
 		threshold, err := f.freezeThreshold(nfdb)
 		if err != nil {
 			backoff = true
@@ -71,6 +71,7 @@ type Freezer struct {
 	writeBatch *freezerBatch
 
 	readonly     bool
+	tiering      *FreezerTiering          // Offloading of cold data files, nil if disabled
 	tables       map[string]*freezerTable // Data tables for storing everything
 	instanceLock *flock.Flock             // File-system lock to prevent double opens
 	closeOnce    sync.Once
@@ -82,6 +83,12 @@ type Freezer struct {
 // The 'tables' argument defines the data tables. If the value of a map
 // entry is true, snappy compression is disabled for the table.
 func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	return newTieredFreezer(datadir, namespace, readonly, maxTableSize, tables, nil)
+}
+
+// newTieredFreezer creates a freezer instance like NewFreezer, offloading the
+// cold data files of its tables to a remote store if tiering is configured.
+func newTieredFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool, tiering *FreezerTiering) (*Freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -114,13 +121,25 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	// Open all the supported data tables
 	freezer := &Freezer{
 		readonly:     readonly,
+		tiering:      tiering,
 		tables:       make(map[string]*freezerTable),
 		instanceLock: lock,
 	}
-
+	var remote *remoteCache
+	if tiering != nil {
+		cacheDir := tiering.CacheDir
+		if cacheDir == "" {
+			cacheDir = filepath.Join(datadir, "remote-cache")
+		}
+		var err error
+		if remote, err = newRemoteCache(tiering.Store, cacheDir, tiering.CacheSize); err != nil {
+			lock.Unlock()
+			return nil, err
+		}
+	}
 	// Create the tables.
 	for name, disableSnappy := range tables {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, disableSnappy, readonly)
+		table, err := newTieredTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, disableSnappy, readonly, remote)
 		if err != nil {
 			for _, table := range freezer.tables {
 				table.Close()
@@ -390,12 +409,31 @@ func (f *Freezer) repair() error {
 	return nil
 }
 
+// offload moves the cold data files of the tables to the remote store, if tiering
+// is enabled. At most one file per table is offloaded in a call. The freezer is
+// only blocked while the tables switch over to the uploaded files, not during
+// the uploads themselves.
+func (f *Freezer) offload() error {
+	if f.tiering == nil || f.readonly {
+		return nil
+	}
+	for _, table := range f.tables {
+		if err := table.offload(f.tiering.Threshold, &f.writeLock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // convertLegacyFn takes a raw freezer entry in an older format and
 // returns it in the new format.
 type convertLegacyFn = func([]byte) ([]byte, error)
 
 // MigrateTable processes the entries in a given table in sequence
 // converting them to a new format if they're of an old format.
+//
+// The table is closed once migrated, the freezer needs to be reopened for the
+// migrated table to be used.
 func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	if f.readonly {
 		return errReadOnly
@@ -407,6 +445,35 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	if !ok {
 		return errUnknownTable
 	}
+	return migrateTable(table, convert, false)
+}
+
+// CompressTable rewrites a compressed table with zstd compression, using a
+// dictionary trained on the table's content.
+//
+// The table is closed once compressed, the freezer needs to be reopened for the
+// compressed table to be used.
+func (f *Freezer) CompressTable(kind string) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	table, ok := f.tables[kind]
+	if !ok {
+		return errUnknownTable
+	}
+	if table.noCompression {
+		return fmt.Errorf("table %s is not compressed", kind)
+	}
+	return migrateTable(table, func(blob []byte) ([]byte, error) { return blob, nil }, true)
+}
+
+// migrateTable rewrites the items of a table converted to a new format,
+// either keeping the table's compression or switching it over to zstd.
+// The table is closed afterwards, as its files are replaced.
+func migrateTable(table *freezerTable, convert convertLegacyFn, compress bool) error {
 	// forEach iterates every entry in the table serially and in order, calling `fn`
 	// with the item as argument. If `fn` returns an error the iteration stops
 	// and that error will be returned.
@@ -438,11 +505,42 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	if table.itemOffset.Load() > 0 || table.itemHidden.Load() > 0 {
 		return errors.New("migration not supported for tail-deleted freezers")
 	}
+	if table.offloaded > 0 {
+		return errors.New("migration not supported for offloaded tables")
+	}
 	ancientsPath := filepath.Dir(table.index.Name())
 	// Set up new dir for the migrated table, the content of which
 	// we'll at the end move over to the ancients dir.
 	migrationPath := filepath.Join(ancientsPath, "migration")
-	newTable, err := newFreezerTable(migrationPath, kind, table.noCompression, false)
+
+	// Tables compressed with zstd carry their dictionary over, while tables
+	// being switched to zstd use one trained on the converted items. The
+	// dictionary is only set up when starting a new migration, a previous
+	// attempt being resumed with the one it used.
+	dictName := zstdDictName(table.name)
+	if _, err := os.Stat(migrationPath); errors.Is(err, os.ErrNotExist) {
+		var dict []byte
+		switch {
+		case compress:
+			if dict, err = trainTableDict(table, convert); err != nil {
+				return err
+			}
+			log.Info("Trained table compression dictionary", "table", table.name, "size", len(dict))
+		case table.zstd != nil:
+			if dict, err = readZstdDict(ancientsPath, table.name); err != nil {
+				return err
+			}
+		}
+		if dict != nil {
+			if err := os.MkdirAll(migrationPath, 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(migrationPath, dictName), dict, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	newTable, err := newFreezerTable(migrationPath, table.name, table.noCompression, false)
 	if err != nil {
 		return err
 	}
@@ -477,10 +575,6 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 		return err
 	}
 	log.Info("Replacing old table files with migrated ones", "elapsed", common.PrettyDuration(time.Since(start)))
-	// Release and delete old table files. Note this won't
-	// delete the index file.
-	table.releaseFilesAfter(0, true)
-
 	if err := newTable.Close(); err != nil {
 		return err
 	}
@@ -488,12 +582,48 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	if err != nil {
 		return err
 	}
-	// Move migrated files to ancients dir.
+	// The old table refers to the files being replaced, close it to refuse any
+	// further use until the freezer is reopened with the migrated files.
+	oldSize, err := table.size()
+	if err != nil {
+		return err
+	}
+	if err := table.Close(); err != nil {
+		return err
+	}
+	table.sizeGauge.Dec(int64(oldSize))
+
+	// Move migrated files to ancients dir. The dictionary is moved last, as it
+	// switches the table over to zstd once all of its files are in place.
+	moved := make(map[string]bool)
 	for _, f := range files {
+		if f.Name() == dictName {
+			continue
+		}
 		// This will replace the old index file as a side-effect.
 		if err := os.Rename(filepath.Join(migrationPath, f.Name()), filepath.Join(ancientsPath, f.Name())); err != nil {
 			return err
 		}
+		moved[f.Name()] = true
+	}
+	if _, err := os.Stat(filepath.Join(migrationPath, dictName)); err == nil {
+		if err := os.Rename(filepath.Join(migrationPath, dictName), filepath.Join(ancientsPath, dictName)); err != nil {
+			return err
+		}
+	}
+	// Delete the old table files which weren't replaced by migrated ones, as
+	// the compression changes the file names.
+	stale := []string{table.indexFileName()}
+	for num := table.tailId; num <= table.headId; num++ {
+		stale = append(stale, table.dataFileName(num))
+	}
+	for _, name := range stale {
+		if moved[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(ancientsPath, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
 	}
 	// Delete by now empty dir.
 	if err := os.Remove(migrationPath); err != nil {
@@ -501,3 +631,27 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	}
 	return nil
 }
+
+// trainTableDict trains a zstd dictionary on items sampled evenly across the
+// table, converted to the format they are migrated to.
+func trainTableDict(t *freezerTable, convert convertLegacyFn) ([]byte, error) {
+	var (
+		items   = t.items.Load()
+		step    = max(items/zstdSampleItems, 1)
+		samples [][]byte
+		size    int
+	)
+	for i := uint64(0); i < items && size < zstdSampleBytes; i += step {
+		blob, err := t.Retrieve(i)
+		if err != nil {
+			return nil, err
+		}
+		out, err := convert(blob)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, out)
+		size += len(out)
+	}
+	return trainZstdDict(samples), nil
+}
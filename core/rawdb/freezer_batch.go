@@ -85,6 +85,7 @@ type freezerTableBatch struct {
 	t *freezerTable
 
 	sb          *snappyBuffer
+	zb          []byte // Buffer for zstd compressed items, reused across appends
 	encBuffer   writeBuffer
 	dataBuffer  []byte
 	indexBuffer []byte
@@ -95,7 +96,7 @@ type freezerTableBatch struct {
 // newBatch creates a new batch for the freezer table.
 func (t *freezerTable) newBatch() *freezerTableBatch {
 	batch := &freezerTableBatch{t: t}
-	if !t.noCompression {
+	if !t.noCompression && t.zstd == nil {
 		batch.sb = new(snappyBuffer)
 	}
 	batch.reset()
@@ -123,11 +124,7 @@ func (batch *freezerTableBatch) Append(item uint64, data interface{}) error {
 	if err := rlp.Encode(&batch.encBuffer, data); err != nil {
 		return err
 	}
-	encItem := batch.encBuffer.data
-	if batch.sb != nil {
-		encItem = batch.sb.compress(encItem)
-	}
-	return batch.appendItem(encItem)
+	return batch.appendItem(batch.compress(batch.encBuffer.data))
 }
 
 // AppendRaw injects a binary blob at the end of the freezer table. The item number is a
@@ -138,11 +135,20 @@ func (batch *freezerTableBatch) AppendRaw(item uint64, blob []byte) error {
 		return fmt.Errorf("%w: have %d want %d", errOutOrderInsertion, item, batch.curItem)
 	}
 
-	encItem := blob
-	if batch.sb != nil {
-		encItem = batch.sb.compress(blob)
+	return batch.appendItem(batch.compress(blob))
+}
+
+// compress compresses an item according to the compression of the table.
+func (batch *freezerTableBatch) compress(data []byte) []byte {
+	switch {
+	case batch.t.zstd != nil:
+		batch.zb = batch.t.zstd.compress(batch.zb[:0], data)
+		return batch.zb
+	case batch.sb != nil:
+		return batch.sb.compress(data)
+	default:
+		return data
 	}
-	return batch.appendItem(encItem)
 }
 
 func (batch *freezerTableBatch) appendItem(data []byte) error {
@@ -182,6 +188,9 @@ func (batch *freezerTableBatch) maybeCommit() error {
 
 // commit writes the batched items to the backing freezerTable.
 func (batch *freezerTableBatch) commit() error {
+	if batch.t.head == nil {
+		return errClosed
+	}
 	// Write data. The head file is fsync'd after write to ensure the
 	// data is truly transferred to disk.
 	_, err := batch.t.head.Write(batch.dataBuffer)
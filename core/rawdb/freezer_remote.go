@@ -0,0 +1,485 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// remoteChunkSize is the size of the chunks the offloaded data files are read
+// back from the remote store and cached in.
+const remoteChunkSize = 1024 * 1024
+
+// AncientObjectStore is a remote store, such as an S3 compatible bucket, holding
+// the sealed data files offloaded from the freezer tables.
+type AncientObjectStore interface {
+	// Upload stores the given content under the key.
+	Upload(key string, data io.Reader, size int64) error
+
+	// ReadRange reads at most length bytes of an object, starting at the given
+	// offset. Fewer bytes are returned if the object ends before.
+	ReadRange(key string, offset, length int64) ([]byte, error)
+
+	// Delete removes an object from the store.
+	Delete(key string) error
+}
+
+// FreezerTiering configures the offloading of cold freezer data to a remote
+// object store.
+type FreezerTiering struct {
+	Store     AncientObjectStore // Remote store to offload the sealed data files to
+	Threshold uint64             // Number of most recent items of a table kept on the local disk
+	CacheDir  string             // Directory caching the data read back, defaults to the freezer's
+	CacheSize int64              // Maximum size of the cache in bytes
+}
+
+// remoteCache is an on-disk LRU cache of the chunks of the data files read back
+// from the remote store. It's shared by the tables of a freezer.
+type remoteCache struct {
+	store AncientObjectStore
+	dir   string
+	limit int64
+
+	chunks lru.BasicLRU[string, int64] // Cached chunk files and their sizes
+	size   int64                       // Total size of the cached chunks
+	lock   sync.Mutex
+}
+
+// newRemoteCache creates a cache in the given directory, picking up the chunks
+// cached by a previous run.
+func newRemoteCache(store AncientObjectStore, dir string, limit int64) (*remoteCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	c := &remoteCache{
+		store:  store,
+		dir:    dir,
+		limit:  limit,
+		chunks: lru.NewBasicLRU[string, int64](math.MaxInt), // Bounded by size instead
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		// Drop the chunks whose writing was interrupted
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			os.Remove(filepath.Join(dir, entry.Name()))
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		c.chunks.Add(entry.Name(), info.Size())
+		c.size += info.Size()
+	}
+	c.lock.Lock()
+	c.evict()
+	c.lock.Unlock()
+	return c, nil
+}
+
+// readAt fills p with the content of an offloaded data file at the given offset,
+// retrieving the chunks missing from the cache from the remote store.
+func (c *remoteCache) readAt(file string, p []byte, off int64) error {
+	for len(p) > 0 {
+		index := off / remoteChunkSize
+		n, err := c.readChunk(file, index, p, off-index*remoteChunkSize)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return io.ErrUnexpectedEOF
+		}
+		p, off = p[n:], off+int64(n)
+	}
+	return nil
+}
+
+// readChunk reads the content of a chunk at the given offset into p, returning
+// the number of bytes read, which is less than len(p) at the end of the chunk.
+func (c *remoteCache) readChunk(file string, index int64, p []byte, off int64) (int, error) {
+	name := fmt.Sprintf("%s.%d", file, index)
+
+	c.lock.Lock()
+	_, cached := c.chunks.Get(name)
+	c.lock.Unlock()
+
+	if cached {
+		if n, ok := c.readCached(name, p, off); ok {
+			return n, nil
+		}
+	}
+	data, err := c.store.ReadRange(file, index*remoteChunkSize, remoteChunkSize)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.add(name, data); err != nil {
+		log.Warn("Failed to cache remote ancient data", "chunk", name, "err", err)
+	}
+	if off >= int64(len(data)) {
+		return 0, nil
+	}
+	return copy(p, data[off:]), nil
+}
+
+// readCached reads the content of a cached chunk at the given offset into p. The
+// reads never go beyond the end of the data files, so a cached chunk too short
+// to serve the read is corrupted (e.g. by a crash) and is dropped, reporting a
+// cache miss. A miss is also reported if the chunk was evicted concurrently.
+func (c *remoteCache) readCached(name string, p []byte, off int64) (int, bool) {
+	f, err := os.Open(filepath.Join(c.dir, name))
+	if err != nil {
+		return 0, false
+	}
+	n, err := f.ReadAt(p, off)
+	f.Close()
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, false
+	}
+	if int64(n) < min(int64(len(p)), remoteChunkSize-off) {
+		log.Warn("Dropping truncated cached ancient data", "chunk", name)
+		c.remove(name)
+		return 0, false
+	}
+	return n, true
+}
+
+// add stores a chunk in the cache, evicting the least recently used ones if the
+// cache grows above its limit.
+func (c *remoteCache) add(name string, data []byte) error {
+	// Concurrent readers may add the same chunk, write to distinct temporary
+	// files and sync them, so that only complete chunks are ever renamed.
+	f, err := os.CreateTemp(c.dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(c.dir, name))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if size, ok := c.chunks.Peek(name); ok {
+		c.size -= size
+	}
+	c.chunks.Add(name, int64(len(data)))
+	c.size += int64(len(data))
+	c.evict()
+	return nil
+}
+
+// remove deletes a chunk from the cache.
+func (c *remoteCache) remove(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if size, ok := c.chunks.Peek(name); ok {
+		c.chunks.Remove(name)
+		c.size -= size
+	}
+	os.Remove(filepath.Join(c.dir, name))
+}
+
+// evict removes the least recently used chunks until the cache fits its limit.
+// The caller must hold the lock.
+func (c *remoteCache) evict() {
+	for c.size > c.limit {
+		name, size, ok := c.chunks.RemoveOldest()
+		if !ok {
+			return
+		}
+		os.Remove(filepath.Join(c.dir, name))
+		c.size -= size
+	}
+}
+
+// drop removes the cached chunks of a data file, which got deleted or restored
+// from the remote store.
+func (c *remoteCache) drop(file string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, name := range c.chunks.Keys() {
+		if strings.HasPrefix(name, file+".") {
+			size, _ := c.chunks.Peek(name)
+			c.chunks.Remove(name)
+			os.Remove(filepath.Join(c.dir, name))
+			c.size -= size
+		}
+	}
+}
+
+// download retrieves an entire data file from the remote store.
+func (c *remoteCache) download(file string) ([]byte, error) {
+	var data []byte
+	for {
+		chunk, err := c.store.ReadRange(file, int64(len(data)), remoteChunkSize)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+		if len(chunk) < remoteChunkSize {
+			return data, nil
+		}
+	}
+}
+
+// offloadMarkerName returns the name of the file recording the number of data
+// files of a table offloaded to the remote store.
+func offloadMarkerName(name string) string {
+	return fmt.Sprintf("%s.offload", name)
+}
+
+// readOffloadMarker returns the number of the first data file of a table which
+// wasn't offloaded to the remote store, all the earlier ones being remote.
+func readOffloadMarker(path, name string) (uint32, error) {
+	blob, err := os.ReadFile(filepath.Join(path, offloadMarkerName(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(blob) != 4 {
+		return 0, fmt.Errorf("invalid offload marker of table %s", name)
+	}
+	return binary.BigEndian.Uint32(blob), nil
+}
+
+// writeOffloadMarker atomically records the number of the first data file of a
+// table which wasn't offloaded to the remote store.
+func writeOffloadMarker(path, name string, offloaded uint32) error {
+	file := filepath.Join(path, offloadMarkerName(name))
+	if err := os.WriteFile(file+".tmp", binary.BigEndian.AppendUint32(nil, offloaded), 0644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// setOffloaded updates the first local data file of the table. The caller must
+// hold the write lock.
+func (t *freezerTable) setOffloaded(offloaded uint32) error {
+	if err := writeOffloadMarker(t.path, t.name, offloaded); err != nil {
+		return err
+	}
+	t.offloaded = offloaded
+	return nil
+}
+
+// lastItemInFile returns the number of items stored in the data files up to and
+// including the given one. The caller must ensure the table isn't modified.
+func (t *freezerTable) lastItemInFile(num uint32) (uint64, error) {
+	var (
+		buffer = make([]byte, indexEntrySize)
+		lo     = uint64(1)
+		hi     = t.items.Load() - t.itemOffset.Load()
+	)
+	// Binary search for the last index entry pointing into the file
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		if _, err := t.index.ReadAt(buffer, int64(mid*indexEntrySize)); err != nil {
+			return 0, err
+		}
+		var entry indexEntry
+		entry.unmarshalBinary(buffer)
+		if entry.filenum <= num {
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return t.itemOffset.Load() + hi, nil
+}
+
+// offload uploads the oldest local data file to the remote store and evicts it
+// from the local disk, if all of its items are at least threshold items behind
+// the head of the table.
+//
+// Sealed data files aren't modified, so the upload runs without holding any
+// lock. The given lock, blocking the writes to the freezer, is only held while
+// switching the table over to the uploaded file, which is abandoned if the
+// table got truncated across it in the meantime.
+func (t *freezerTable) offload(threshold uint64, lock sync.Locker) error {
+	if t.remote == nil || t.readonly {
+		return nil
+	}
+	num, rewinds, f, err := t.offloadCandidate(threshold)
+	if f == nil || err != nil {
+		return err
+	}
+	defer f.Close()
+
+	name := t.dataFileName(num)
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := t.remote.store.Upload(name, f, stat.Size()); err != nil {
+		return err
+	}
+	lock.Lock()
+	committed, err := t.commitOffload(num, rewinds)
+	lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if !committed {
+		t.logger.Debug("Abandoned offload of truncated data file", "file", name)
+		if err := t.remote.store.Delete(name); err != nil {
+			t.logger.Warn("Failed to delete offloaded data file", "file", name, "err", err)
+		}
+		return nil
+	}
+	t.logger.Info("Offloaded freezer data file", "file", name, "size", stat.Size())
+	return nil
+}
+
+// offloadCandidate returns the oldest local data file of the table if it can be
+// offloaded, opened for reading, along with the current head rewind counter.
+func (t *freezerTable) offloadCandidate(threshold uint64) (uint32, uint64, *os.File, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	num := max(t.offloaded, t.tailId)
+	if num >= t.headId {
+		return 0, 0, nil, nil // Only sealed files are offloaded
+	}
+	last, err := t.lastItemInFile(num)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if t.items.Load()-last < threshold {
+		return 0, 0, nil, nil
+	}
+	f, err := os.Open(filepath.Join(t.path, t.dataFileName(num)))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return num, t.rewinds, f, nil
+}
+
+// commitOffload records an uploaded data file as offloaded and deletes it from
+// the local disk, unless the table was truncated into or past it since. The
+// caller must ensure no writes happen concurrently.
+func (t *freezerTable) commitOffload(num uint32, rewinds uint64) (bool, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.rewinds != rewinds || num != max(t.offloaded, t.tailId) || num >= t.headId {
+		return false, nil
+	}
+	// Record the file as offloaded before deleting it, so that a crash in
+	// between doesn't lose it.
+	if err := t.setOffloaded(num + 1); err != nil {
+		return false, err
+	}
+	t.releaseFile(num)
+	if err := os.Remove(filepath.Join(t.path, t.dataFileName(num))); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// restore downloads an offloaded data file back to the local disk to become the
+// head of the table, dropping the offloaded files after it. The caller must hold
+// the write lock.
+func (t *freezerTable) restore(num uint32) error {
+	if t.remote == nil || num >= t.offloaded {
+		return nil
+	}
+	if t.readonly {
+		return fmt.Errorf("freezer table(path: %s, name: %s, num: %d) is offloaded", t.path, t.name, num)
+	}
+	name := t.dataFileName(num)
+	data, err := t.remote.download(name)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(t.path, name)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	old := t.offloaded
+	if err := t.setOffloaded(num); err != nil {
+		return err
+	}
+	t.dropRemote(num, old)
+	t.logger.Info("Restored offloaded freezer data file", "file", name, "size", len(data))
+	return nil
+}
+
+// dropRemote schedules the offloaded data files in the range [from, to) for
+// deletion from the remote store, dropping their cached chunks. The deletions
+// are network calls, so they're only carried out by deleteRemote once the table
+// lock is released. The caller must hold the lock.
+func (t *freezerTable) dropRemote(from, to uint32) {
+	if t.remote == nil {
+		return
+	}
+	for num := from; num < to; num++ {
+		name := t.dataFileName(num)
+		t.remote.drop(name)
+		t.dropped = append(t.dropped, name)
+	}
+}
+
+// deleteRemote deletes the data files scheduled by dropRemote from the remote
+// store. Failures only leave garbage behind, so they're just logged. The caller
+// must not hold the table lock.
+func (t *freezerTable) deleteRemote() {
+	t.lock.Lock()
+	dropped := t.dropped
+	t.dropped = nil
+	t.lock.Unlock()
+
+	for _, name := range dropped {
+		if err := t.remote.store.Delete(name); err != nil {
+			t.logger.Warn("Failed to delete offloaded data file", "file", name, "err", err)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// memoryObjectStore is an in-memory object store counting the reads.
+type memoryObjectStore struct {
+	objects map[string][]byte
+	reads   int
+	lock    sync.Mutex
+}
+
+func newMemoryObjectStore() *memoryObjectStore {
+	return &memoryObjectStore{objects: make(map[string][]byte)}
+}
+
+func (s *memoryObjectStore) Upload(key string, data io.Reader, size int64) error {
+	blob, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.objects[key] = blob
+	return nil
+}
+
+func (s *memoryObjectStore) ReadRange(key string, offset, length int64) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	blob, ok := s.objects[key]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	s.reads++
+	if offset >= int64(len(blob)) {
+		return nil, nil
+	}
+	return bytes.Clone(blob[offset:min(offset+length, int64(len(blob)))]), nil
+}
+
+func (s *memoryObjectStore) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memoryObjectStore) has(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.objects[key]
+	return ok
+}
+
+// Tests that the cold data files are offloaded to the remote store and read back
+// transparently, also after truncating into them.
+func TestFreezerTiering(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir     = t.TempDir()
+		store   = newMemoryObjectStore()
+		tables  = map[string]bool{"test": true}
+		tiering = &FreezerTiering{
+			Store:     store,
+			Threshold: 40,
+			CacheDir:  filepath.Join(dir, "cache"),
+			CacheSize: 5000,
+		}
+	)
+	// Store 100 items of 100 bytes, filling 5 items per data file
+	f, err := newTieredFreezer(dir, "", false, 500, tables, tiering)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeItems := func(from, to int) {
+		t.Helper()
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				if err := op.AppendRaw("test", uint64(i), getChunk(100, i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkItems := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			blob, err := f.Ancient("test", uint64(i))
+			if err != nil {
+				t.Fatalf("failed to retrieve item %d: %v", i, err)
+			}
+			if !bytes.Equal(blob, getChunk(100, i)) {
+				t.Fatalf("item %d mismatch", i)
+			}
+		}
+		items, err := f.AncientRange("test", 0, uint64(n), 0)
+		if err != nil || len(items) != n {
+			t.Fatalf("failed to retrieve range: %d items, %v", len(items), err)
+		}
+	}
+	writeItems(0, 100)
+	for i := 0; i < 20; i++ {
+		if err := f.offload(); err != nil {
+			t.Fatalf("failed to offload: %v", err)
+		}
+	}
+	// Files 0-11 hold items 0-59, all of them at least 40 items behind the head
+	table := f.tables["test"]
+	if table.offloaded != 12 {
+		t.Fatalf("offloaded files mismatch: have %d, want %d", table.offloaded, 12)
+	}
+	for i := uint32(0); i < 13; i++ {
+		_, err := os.Stat(filepath.Join(dir, table.dataFileName(i)))
+		if local, remote := err == nil, store.has(table.dataFileName(i)); local == (i < 12) || remote != (i < 12) {
+			t.Errorf("file %d: local %t, remote %t", i, local, remote)
+		}
+	}
+	checkItems(100)
+	if store.reads == 0 {
+		t.Fatal("offloaded items not read from the remote store")
+	}
+	// Reading again must be served from the cache, which must fit its limit
+	reads := store.reads
+	blob, _ := f.Ancient("test", 99-40)
+	if !bytes.Equal(blob, getChunk(100, 59)) || store.reads != reads {
+		t.Fatalf("cached item not served locally: %d remote reads", store.reads-reads)
+	}
+	var size int64
+	entries, _ := os.ReadDir(tiering.CacheDir)
+	for _, entry := range entries {
+		info, _ := entry.Info()
+		size += info.Size()
+	}
+	if size > tiering.CacheSize {
+		t.Fatalf("cache exceeds its limit: %d > %d", size, tiering.CacheSize)
+	}
+	// The offloaded files must survive a restart, and be unreadable without tiering
+	f.Close()
+	if _, err := NewFreezer(dir, "", false, 500, tables); err == nil {
+		t.Fatal("opened offloaded freezer without tiering")
+	}
+	if f, err = newTieredFreezer(dir, "", false, 500, tables, tiering); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	checkItems(100)
+
+	// Truncating into offloaded files must restore the new head file
+	if _, err := f.TruncateHead(52); err != nil {
+		t.Fatalf("failed to truncate head: %v", err)
+	}
+	table = f.tables["test"]
+	if table.offloaded != 10 || store.has(table.dataFileName(10)) || store.has(table.dataFileName(11)) {
+		t.Fatalf("head file not restored: offloaded %d", table.offloaded)
+	}
+	writeItems(52, 60)
+	checkItems(60)
+
+	// Deleting the tail must delete the offloaded files
+	if _, err := f.TruncateTail(20); err != nil {
+		t.Fatalf("failed to truncate tail: %v", err)
+	}
+	for i := uint32(0); i < 10; i++ {
+		if store.has(table.dataFileName(i)) != (i >= 4) {
+			t.Errorf("file %d: remote %t", i, store.has(table.dataFileName(i)))
+		}
+	}
+	if blob, err := f.Ancient("test", 20); err != nil || !bytes.Equal(blob, getChunk(100, 20)) {
+		t.Fatalf("item 20 mismatch: %v", err)
+	}
+}
+
+// hookedObjectStore is an in-memory object store running callbacks within the
+// uploads, reads and deletions.
+type hookedObjectStore struct {
+	*memoryObjectStore
+	upload func(key string)
+	read   func(key string)
+	delete func(key string)
+}
+
+func (s *hookedObjectStore) Upload(key string, data io.Reader, size int64) error {
+	if s.upload != nil {
+		s.upload(key)
+	}
+	return s.memoryObjectStore.Upload(key, data, size)
+}
+
+func (s *hookedObjectStore) ReadRange(key string, offset, length int64) ([]byte, error) {
+	if s.read != nil {
+		s.read(key)
+	}
+	return s.memoryObjectStore.ReadRange(key, offset, length)
+}
+
+func (s *hookedObjectStore) Delete(key string) error {
+	if s.delete != nil {
+		s.delete(key)
+	}
+	return s.memoryObjectStore.Delete(key)
+}
+
+// Tests that the freezer isn't blocked while data files are uploaded, and that
+// uploads of files truncated in the meantime are abandoned.
+func TestFreezerOffloadUnlocked(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir     = t.TempDir()
+		store   = &hookedObjectStore{memoryObjectStore: newMemoryObjectStore()}
+		tables  = map[string]bool{"test": true}
+		tiering = &FreezerTiering{Store: store, Threshold: 10, CacheDir: filepath.Join(dir, "cache"), CacheSize: 5000}
+	)
+	f, err := newTieredFreezer(dir, "", false, 500, tables, tiering)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	writeItems := func(from, to int) error {
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				if err := op.AppendRaw("test", uint64(i), getChunk(100, i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return err
+	}
+	if err := writeItems(0, 30); err != nil {
+		t.Fatal(err)
+	}
+	// Reads and writes must go through while a file is being uploaded
+	store.upload = func(key string) {
+		if _, err := f.Ancient("test", 0); err != nil {
+			t.Errorf("failed to read during upload: %v", err)
+		}
+		if err := writeItems(30, 31); err != nil {
+			t.Errorf("failed to write during upload: %v", err)
+		}
+	}
+	done := make(chan error, 1)
+	go func() { done <- f.offload() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("failed to offload: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("freezer blocked during upload")
+	}
+	table := f.tables["test"]
+	if table.offloaded != 1 || !store.has(table.dataFileName(0)) {
+		t.Fatalf("file not offloaded: offloaded %d", table.offloaded)
+	}
+	// Truncating the tail past the file being uploaded must abandon it. The
+	// remote deletions must happen without holding the table lock.
+	var deletes int
+	store.delete = func(key string) {
+		if !table.lock.TryLock() {
+			t.Errorf("table locked while deleting %s", key)
+			return
+		}
+		table.lock.Unlock()
+		deletes++
+	}
+	store.upload = func(key string) {
+		if _, err := f.TruncateTail(10); err != nil {
+			t.Errorf("failed to truncate tail: %v", err)
+		}
+	}
+	if err := f.offload(); err != nil {
+		t.Fatalf("failed to offload: %v", err)
+	}
+	if table.offloaded != 1 || store.has(table.dataFileName(0)) || store.has(table.dataFileName(1)) {
+		t.Fatalf("truncated file offloaded: offloaded %d", table.offloaded)
+	}
+	if deletes != 2 {
+		t.Fatalf("remote deletions mismatch: have %d, want 2", deletes)
+	}
+	// Truncating the head into the file being uploaded must abandon it
+	store.upload = func(key string) {
+		if _, err := f.TruncateHead(12); err != nil {
+			t.Errorf("failed to truncate head: %v", err)
+		}
+	}
+	if err := f.offload(); err != nil {
+		t.Fatalf("failed to offload: %v", err)
+	}
+	if table.offloaded != 1 || store.has(table.dataFileName(2)) {
+		t.Fatalf("rewound file offloaded: offloaded %d", table.offloaded)
+	}
+	if blob, err := f.Ancient("test", 11); err != nil || !bytes.Equal(blob, getChunk(100, 11)) {
+		t.Fatalf("item 11 mismatch: %v", err)
+	}
+}
+
+// Tests that reads of offloaded data don't hold the table lock while fetching
+// from the remote store, and that items deleted meanwhile are not returned.
+func TestFreezerRemoteReadUnlocked(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir     = t.TempDir()
+		store   = &hookedObjectStore{memoryObjectStore: newMemoryObjectStore()}
+		tables  = map[string]bool{"test": true}
+		tiering = &FreezerTiering{Store: store, Threshold: 10, CacheDir: filepath.Join(dir, "cache"), CacheSize: 5000}
+	)
+	f, err := newTieredFreezer(dir, "", false, 500, tables, tiering)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := 0; i < 30; i++ {
+			if err := op.AppendRaw("test", uint64(i), getChunk(100, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.offload(); err != nil {
+		t.Fatalf("failed to offload: %v", err)
+	}
+	table := f.tables["test"]
+	if table.offloaded != 1 {
+		t.Fatalf("file not offloaded: offloaded %d", table.offloaded)
+	}
+	table.remote.drop(table.dataFileName(0))
+
+	// The table must not be locked while the remote data is fetched
+	var reads int
+	store.read = func(key string) {
+		if !table.lock.TryLock() {
+			t.Errorf("table locked while reading %s", key)
+			return
+		}
+		table.lock.Unlock()
+		reads++
+	}
+	if blob, err := f.Ancient("test", 0); err != nil || !bytes.Equal(blob, getChunk(100, 0)) {
+		t.Fatalf("item 0 mismatch: %v", err)
+	}
+	if reads != 1 {
+		t.Fatalf("remote reads mismatch: have %d, want 1", reads)
+	}
+	// Items deleted while being fetched must not be returned
+	table.remote.drop(table.dataFileName(0))
+	store.read = func(key string) {
+		store.read = nil
+		if _, err := f.TruncateTail(5); err != nil {
+			t.Errorf("failed to truncate tail: %v", err)
+		}
+	}
+	if _, err := f.Ancient("test", 1); !errors.Is(err, errOutOfBounds) {
+		t.Fatalf("deleted item returned: %v", err)
+	}
+}
+
+// Tests that truncated cached chunks, as left behind by a crash, are fetched
+// again from the remote store instead of failing the reads.
+func TestRemoteCacheTruncatedChunk(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir   = t.TempDir()
+		store = newMemoryObjectStore()
+		data  = make([]byte, 2*remoteChunkSize+100)
+	)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	store.Upload("file", bytes.NewReader(data), int64(len(data)))
+
+	cache, err := newRemoteCache(store, dir, 10*remoteChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fill the cache from concurrent readers
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, len(data))
+			if err := cache.readAt("file", buf, 0); err != nil || !bytes.Equal(buf, data) {
+				t.Errorf("concurrent read mismatch: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Fatalf("cached chunks mismatch: have %d, want 3", len(entries))
+	}
+	// Truncate a cached chunk and read it back
+	if err := os.Truncate(filepath.Join(dir, "file.1"), 100); err != nil {
+		t.Fatal(err)
+	}
+	reads := store.reads
+	buf := make([]byte, 200)
+	if err := cache.readAt("file", buf, remoteChunkSize+50); err != nil || !bytes.Equal(buf, data[remoteChunkSize+50:remoteChunkSize+250]) {
+		t.Fatalf("truncated chunk read mismatch: %v", err)
+	}
+	if store.reads != reads+1 {
+		t.Fatalf("truncated chunk not fetched again: %d remote reads", store.reads-reads)
+	}
+	// The final chunk is legitimately short and must be served from the cache
+	buf = make([]byte, 100)
+	if err := cache.readAt("file", buf, 2*remoteChunkSize); err != nil || !bytes.Equal(buf, data[2*remoteChunkSize:]) {
+		t.Fatalf("final chunk read mismatch: %v", err)
+	}
+	if err := cache.readAt("file", buf[:50], remoteChunkSize+50); err != nil || store.reads != reads+1 {
+		t.Fatalf("cached reads went remote: %v, %d remote reads", err, store.reads-reads)
+	}
+}
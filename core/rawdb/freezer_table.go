@@ -85,8 +85,9 @@ func (i *indexEntry) bounds(end *indexEntry) (startOffset, endOffset, fileId uin
 }
 
 // freezerTable represents a single chained data table within the freezer (e.g. blocks).
-// It consists of a data file (snappy encoded arbitrary data blobs) and an indexEntry
-// file (uncompressed 64 bit indices into the data file).
+// It consists of a data file (snappy or zstd encoded arbitrary data blobs) and an
+// indexEntry file (uncompressed 64 bit indices into the data file). Tables migrated
+// to zstd additionally have a dictionary file.
 type freezerTable struct {
 	items      atomic.Uint64 // Number of items stored in the table (including items removed from tail)
 	itemOffset atomic.Uint64 // Number of items removed from the table
@@ -98,7 +99,8 @@ type freezerTable struct {
 	// should never be lower than itemOffset.
 	itemHidden atomic.Uint64
 
-	noCompression bool // if true, disables snappy compression. Note: does not work retroactively
+	noCompression bool       // if true, disables snappy compression. Note: does not work retroactively
+	zstd          *zstdCodec // if set, items are compressed with zstd instead of snappy
 	readonly      bool
 	maxFileSize   uint32 // Max file size for data-files
 	name          string
@@ -111,6 +113,11 @@ type freezerTable struct {
 	headId uint32              // number of the currently active head file
 	tailId uint32              // number of the earliest file
 
+	remote    *remoteCache // Cache of the data files offloaded to a remote store, nil if not tiered
+	offloaded uint32       // Number of the first local data file, the earlier ones being offloaded
+	rewinds   uint64       // Number of head truncations into earlier data files, invalidating uploads
+	dropped   []string     // Offloaded data files pending deletion from the remote store
+
 	headBytes  int64         // Number of bytes written to the head file
 	readMeter  metrics.Meter // Meter for measuring the effective amount of data read
 	writeMeter metrics.Meter // Meter for measuring the effective amount of data written
@@ -129,20 +136,43 @@ func newFreezerTable(path, name string, disableSnappy, readonly bool) (*freezerT
 // non-existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
 func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
+	return newTieredTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, noCompression, readonly, nil)
+}
+
+// newTieredTable opens a freezer table like newTable, whose sealed data files
+// may be offloaded to the remote store behind the given cache.
+func newTieredTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool, remote *remoteCache) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
-	var idxName string
-	if noCompression {
-		idxName = fmt.Sprintf("%s.ridx", name) // raw index file
-	} else {
-		idxName = fmt.Sprintf("%s.cidx", name) // compressed index file
-	}
+	// Compressed tables having a dictionary file were migrated to zstd
 	var (
+		codec *zstdCodec
+		dict  []byte
 		err   error
-		index *os.File
-		meta  *os.File
+	)
+	if !noCompression {
+		if dict, err = readZstdDict(path, name); err != nil {
+			return nil, err
+		}
+		if dict != nil {
+			if codec, err = newZstdCodec(dict); err != nil {
+				return nil, err
+			}
+		}
+	}
+	offloaded, err := readOffloadMarker(path, name)
+	if err != nil {
+		return nil, err
+	}
+	if offloaded > 0 && remote == nil {
+		return nil, fmt.Errorf("freezer table(path: %s, name: %s) has data offloaded to a remote store", path, name)
+	}
+	var (
+		idxName = fmt.Sprintf("%s.%sidx", name, freezerFileKind(noCompression, codec != nil))
+		index   *os.File
+		meta    *os.File
 	)
 	if readonly {
 		// Will fail if table index file or meta file is not existent
@@ -176,13 +206,18 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 		path:          path,
 		logger:        log.New("database", path, "table", name),
 		noCompression: noCompression,
+		zstd:          codec,
 		readonly:      readonly,
 		maxFileSize:   maxFilesize,
+		remote:        remote,
+		offloaded:     offloaded,
 	}
 	if err := tab.repair(); err != nil {
 		tab.Close()
 		return nil, err
 	}
+	tab.deleteRemote()
+
 	// Initialize the starting size counter
 	size, err := tab.sizeNolock()
 	if err != nil {
@@ -265,6 +300,11 @@ func (t *freezerTable) repair() error {
 	if lastIndex.offset == 0 && offsetsSize/indexEntrySize > 1 {
 		log.Error("Corrupted index file detected", "lastOffset", lastIndex.offset, "indexes", offsetsSize/indexEntrySize)
 	}
+	// The head might have been offloaded if the table was truncated while
+	// restoring it.
+	if err := t.restore(lastIndex.filenum); err != nil {
+		return err
+	}
 	if t.readonly {
 		t.head, err = t.openFile(lastIndex.filenum, openFreezerFileForReadOnly)
 	} else {
@@ -314,6 +354,9 @@ func (t *freezerTable) repair() error {
 			if newLastIndex.filenum != lastIndex.filenum {
 				// Release earlier opened file
 				t.releaseFile(lastIndex.filenum)
+				if err := t.restore(newLastIndex.filenum); err != nil {
+					return err
+				}
 				if t.head, err = t.openFile(newLastIndex.filenum, openFreezerFileForAppend); err != nil {
 					return err
 				}
@@ -374,6 +417,14 @@ func (t *freezerTable) preopen() (err error) {
 
 	// Open all except head in RDONLY
 	for i := t.tailId; i < t.headId; i++ {
+		// Offloaded files are read from the remote store, delete any local
+		// copy left behind by a crash while offloading them.
+		if i < t.offloaded {
+			if !t.readonly {
+				os.Remove(filepath.Join(t.path, t.dataFileName(i)))
+			}
+			continue
+		}
 		if _, err = t.openFile(i, openFreezerFileForReadOnly); err != nil {
 			return err
 		}
@@ -389,9 +440,14 @@ func (t *freezerTable) preopen() (err error) {
 
 // truncateHead discards any recent data above the provided threshold number.
 func (t *freezerTable) truncateHead(items uint64) error {
+	defer t.deleteRemote() // Runs after releasing the lock
+
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if t.index == nil {
+		return errClosed
+	}
 	// Ensure the given truncate target falls in the correct range
 	existing := t.items.Load()
 	if existing <= items {
@@ -434,8 +490,13 @@ func (t *freezerTable) truncateHead(items uint64) error {
 	}
 	// We might need to truncate back to older files
 	if expected.filenum != t.headId {
+		t.rewinds++
+
 		// If already open for reading, force-reopen for writing
 		t.releaseFile(expected.filenum)
+		if err := t.restore(expected.filenum); err != nil {
+			return err
+		}
 		newHead, err := t.openFile(expected.filenum, openFreezerFileForAppend)
 		if err != nil {
 			return err
@@ -483,9 +544,14 @@ func (t *freezerTable) sizeHidden() (uint64, error) {
 
 // truncateTail discards any recent data before the provided threshold number.
 func (t *freezerTable) truncateTail(items uint64) error {
+	defer t.deleteRemote() // Runs after releasing the lock
+
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if t.index == nil {
+		return errClosed
+	}
 	// Ensure the given truncate target falls in the correct range
 	if t.itemHidden.Load() >= items {
 		return nil
@@ -577,9 +643,11 @@ func (t *freezerTable) truncateTail(items uint64) error {
 		return err
 	}
 	// Release any files before the current tail
+	oldTailId := t.tailId
 	t.tailId = newTailId
 	t.itemOffset.Store(newDeleted)
 	t.releaseFilesBefore(t.tailId, true)
+	t.dropRemote(oldTailId, min(t.tailId, t.offloaded))
 
 	// Retrieve the new size and update the total size counter
 	newSize, err := t.sizeNolock()
@@ -595,6 +663,9 @@ func (t *freezerTable) Close() error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if t.index == nil {
+		return nil // Already closed, e.g. after a migration
+	}
 	var errs []error
 	doClose := func(f *os.File, sync bool, close bool) {
 		if sync && !t.readonly {
@@ -635,13 +706,7 @@ func (t *freezerTable) Close() error {
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(filepath.Join(t.path, t.dataFileName(num)))
 		if err != nil {
 			return nil, err
 		}
@@ -650,6 +715,29 @@ func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error
 	return f, err
 }
 
+// freezerFileKind returns the letter prefixing the extension of the index and
+// data files, identifying the compression of a table.
+func freezerFileKind(noCompression, zstd bool) string {
+	switch {
+	case noCompression:
+		return "r" // raw files
+	case zstd:
+		return "z" // zstd compressed files
+	default:
+		return "c" // snappy compressed files
+	}
+}
+
+// indexFileName returns the name of the index file of the table.
+func (t *freezerTable) indexFileName() string {
+	return fmt.Sprintf("%s.%sidx", t.name, freezerFileKind(t.noCompression, t.zstd != nil))
+}
+
+// dataFileName returns the name of the data file with the given number.
+func (t *freezerTable) dataFileName(num uint32) string {
+	return fmt.Sprintf("%s.%04d.%sdat", t.name, num, freezerFileKind(t.noCompression, t.zstd != nil))
+}
+
 // releaseFile closes a file, and removes it from the open file cache.
 // Assumes that the caller holds the write lock
 func (t *freezerTable) releaseFile(num uint32) {
@@ -752,19 +840,28 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 		item := diskData[offset : offset+diskSize]
 		offset += diskSize
 		decompressedSize := diskSize
-		if !t.noCompression {
+		if t.zstd != nil {
+			decompressedSize = t.zstd.decompressedSize(item)
+		} else if !t.noCompression {
 			decompressedSize, _ = snappy.DecodedLen(item)
 		}
 		if i > 0 && maxBytes != 0 && uint64(outputSize+decompressedSize) > maxBytes {
 			break
 		}
-		if !t.noCompression {
+		switch {
+		case t.zstd != nil:
+			data, err := t.zstd.decompress(item)
+			if err != nil {
+				return nil, err
+			}
+			output = append(output, data)
+		case !t.noCompression:
 			data, err := snappy.Decode(nil, item)
 			if err != nil {
 				return nil, err
 			}
 			output = append(output, data)
-		} else {
+		default:
 			output = append(output, item)
 		}
 		outputSize += decompressedSize
@@ -777,13 +874,82 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 // will ignore the size limitation and continuously allocate memory to store
 // data if maxBytes is 0. It returns the (potentially compressed) data, and
 // the sizes.
+//
+// Data of offloaded files is fetched from the remote store after releasing the
+// table lock, as it may take long and would block appends and truncations. The
+// read is retried if the files were restored meanwhile.
 func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []int, error) {
+	for {
+		output, sizes, reads, rewinds, err := t.readItems(start, count, maxBytes)
+		if err != nil || len(reads) == 0 {
+			return output, sizes, err
+		}
+		var fetchErr error
+		for _, r := range reads {
+			if err := t.remote.readAt(t.dataFileName(r.fileId), output[r.pos:r.pos+r.length], int64(r.start)); err != nil {
+				fetchErr = fmt.Errorf("%w, fileid: %d, start: %d, length: %d", err, r.fileId, r.start, r.length)
+				break
+			}
+		}
+		// The files might have been deleted or restored while fetching, which
+		// takes precedence over a failed fetch.
+		valid, err := t.checkRemoteReads(start, uint64(len(sizes)), reads, rewinds)
+		if err != nil {
+			return nil, nil, err
+		}
+		if valid {
+			if fetchErr != nil {
+				return nil, nil, fetchErr
+			}
+			return output, sizes, nil
+		}
+	}
+}
+
+// remoteRead is a read from an offloaded data file, deferred until the table
+// lock is released.
+type remoteRead struct {
+	fileId uint32 // data file to read from
+	start  uint32 // offset in the data file
+	pos    int    // position of the data in the output buffer
+	length int    // size of the data
+}
+
+// checkRemoteReads reports whether the data fetched by the given remote reads is
+// still valid, i.e. the files weren't restored since the local data was read
+// under the given head rewind counter. It fails if the items were removed from
+// the table meanwhile.
+func (t *freezerTable) checkRemoteReads(start, count uint64, reads []remoteRead, rewinds uint64) (bool, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil || t.head == nil || t.meta == nil {
+		return false, errClosed
+	}
+	if t.items.Load() < start+count || t.itemHidden.Load() > start {
+		return false, errOutOfBounds
+	}
+	if t.rewinds != rewinds {
+		return false, nil
+	}
+	for _, r := range reads {
+		if r.fileId >= t.offloaded {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// readItems reads up to 'count' items from the table like retrieveItems, but
+// only copies the local data. The reads from offloaded data files are returned
+// along with the head rewind counter, to be carried out by the caller.
+func (t *freezerTable) readItems(start, count, maxBytes uint64) ([]byte, []int, []remoteRead, uint64, error) {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
 	// Ensure the table and the item are accessible
 	if t.index == nil || t.head == nil || t.meta == nil {
-		return nil, nil, errClosed
+		return nil, nil, nil, 0, errClosed
 	}
 	var (
 		items  = t.items.Load()      // the total items(head + 1)
@@ -792,7 +958,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	// Ensure the start is written, not deleted from the tail, and that the
 	// caller actually wants something
 	if items <= start || hidden > start || count == 0 {
-		return nil, nil, errOutOfBounds
+		return nil, nil, nil, 0, errOutOfBounds
 	}
 	if start+count > items {
 		count = items - start
@@ -803,12 +969,20 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	} else {
 		output = make([]byte, 0, 1024) // initial buffer cap
 	}
+	var reads []remoteRead // Reads deferred to the remote store
+
 	// readData is a helper method to read a single data item from disk.
 	readData := func(fileId, start uint32, length int) error {
 		output = grow(output, length)
 		dataFile, exist := t.files[fileId]
 		if !exist {
-			return fmt.Errorf("missing data file %d", fileId)
+			// Offloaded files are read back through the cache once the lock
+			// is released
+			if t.remote == nil || fileId >= t.offloaded {
+				return fmt.Errorf("missing data file %d", fileId)
+			}
+			reads = append(reads, remoteRead{fileId: fileId, start: start, pos: len(output) - length, length: length})
+			return nil
 		}
 		if _, err := dataFile.ReadAt(output[len(output)-length:], int64(start)); err != nil {
 			return fmt.Errorf("%w, fileid: %d, start: %d, length: %d", err, fileId, start, length)
@@ -818,7 +992,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	// Read all the indexes in one go
 	indices, err := t.getIndices(start, count)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, 0, err
 	}
 	var (
 		sizes      []int               // The sizes for each element
@@ -837,7 +1011,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 			// If we have unread data in the first file, we need to do that read now.
 			if unreadSize > 0 {
 				if err := readData(firstIndex.filenum, readStart, unreadSize); err != nil {
-					return nil, nil, err
+					return nil, nil, nil, 0, err
 				}
 				unreadSize = 0
 			}
@@ -848,7 +1022,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 			// read this last item, but we need to do the deferred reads now.
 			if unreadSize > 0 {
 				if err := readData(secondIndex.filenum, readStart, unreadSize); err != nil {
-					return nil, nil, err
+					return nil, nil, nil, 0, err
 				}
 			}
 			break
@@ -860,7 +1034,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 		if i == len(indices)-2 || (uint64(totalSize) > maxBytes && maxBytes != 0) {
 			// Last item, need to do the read now
 			if err := readData(secondIndex.filenum, readStart, unreadSize); err != nil {
-				return nil, nil, err
+				return nil, nil, nil, 0, err
 			}
 			break
 		}
@@ -868,7 +1042,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 
 	// Update metrics.
 	t.readMeter.Mark(int64(totalSize))
-	return output, sizes, nil
+	return output, sizes, reads, t.rewinds, nil
 }
 
 // has returns an indicator whether the specified number data is still accessible
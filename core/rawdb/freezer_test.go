@@ -482,6 +482,202 @@ func TestFreezerCloseSync(t *testing.T) {
 	}
 }
 
+// Tests that compressing a table switches it over to zstd, while migrating a
+// table keeps its compression.
+func TestFreezerCompressTable(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir    = t.TempDir()
+		tables = map[string]bool{"compressed": false, "raw": true}
+		rng    = rand.New(rand.NewSource(1))
+		items  = make([][]byte, 4000)
+	)
+	for i := range items {
+		// Items share a structure but differ in content, like receipts do
+		items[i] = []byte(fmt.Sprintf(`{"status":"0x1","cumulativeGasUsed":"%#x","logs":[{"address":"%#x","topics":["0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef","%#x"],"data":"%#x"}]}`, rng.Uint64(), rng.Uint64(), rng.Uint64(), rng.Uint64()))
+	}
+	items[10] = []byte{}
+
+	f, err := NewFreezer(dir, "", false, 1<<16, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i, item := range items {
+			if err := op.AppendRaw("compressed", uint64(i), item); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("raw", uint64(i), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snappySize, _ := f.AncientSize("compressed")
+
+	if err := f.CompressTable("raw"); err == nil {
+		t.Fatal("uncompressed table compressed")
+	}
+	if err := f.CompressTable("compressed"); err != nil {
+		t.Fatalf("failed to compress table: %v", err)
+	}
+	if err := f.MigrateTable("raw", func(blob []byte) ([]byte, error) { return blob, nil }); err != nil {
+		t.Fatalf("failed to migrate table: %v", err)
+	}
+	// The migrated tables must refuse any use until the freezer is reopened
+	if _, err := f.Ancient("compressed", 0); !errors.Is(err, errClosed) {
+		t.Fatalf("read from migrated table: have %v, want %v", err, errClosed)
+	}
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		if err := op.AppendRaw("compressed", uint64(len(items)), items[0]); err != nil {
+			return err
+		}
+		return op.AppendRaw("raw", uint64(len(items)), items[0])
+	})
+	if !errors.Is(err, errClosed) {
+		t.Fatalf("write to migrated table: have %v, want %v", err, errClosed)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("failed to close freezer: %v", err)
+	}
+
+	for _, name := range []string{"compressed.zdict", "compressed.zidx", "compressed.0000.zdat", "raw.ridx"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("missing file %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"compressed.cidx", "compressed.0000.cdat", "raw.zdict", "migration"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("file %s not removed: %v", name, err)
+		}
+	}
+	f, err = NewFreezer(dir, "", false, 1<<16, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if f.tables["compressed"].zstd == nil || f.tables["raw"].zstd != nil {
+		t.Fatal("wrong table compression after migration")
+	}
+	if size, _ := f.AncientSize("compressed"); size >= snappySize {
+		t.Errorf("zstd table not smaller than snappy one: have %d, snappy %d", size, snappySize)
+	}
+	for kind := range tables {
+		for i, want := range items {
+			have, err := f.Ancient(kind, uint64(i))
+			if err != nil {
+				t.Fatalf("table %s: failed to retrieve item %d: %v", kind, i, err)
+			}
+			if !bytes.Equal(have, want) {
+				t.Fatalf("table %s: item %d mismatch: have %q, want %q", kind, i, have, want)
+			}
+		}
+	}
+	// Items appended after the migration must be compressed with zstd too
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		if err := op.AppendRaw("compressed", uint64(len(items)), items[0]); err != nil {
+			return err
+		}
+		return op.AppendRaw("raw", uint64(len(items)), items[0])
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := f.Ancient("compressed", uint64(len(items))); err != nil || !bytes.Equal(have, items[0]) {
+		t.Fatalf("appended item mismatch: have %q (%v), want %q", have, err, items[0])
+	}
+	info, err := inspect("test", tables, f, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range info.sizes {
+		if table.name == "compressed" && table.ratio <= 1 {
+			t.Errorf("unexpected compression ratio %f", table.ratio)
+		}
+		if table.name == "raw" && table.ratio != 0 {
+			t.Errorf("compression ratio reported for raw table: %f", table.ratio)
+		}
+	}
+}
+
+// Tests that migrating a table converts its items, keeping the snappy or zstd
+// compression the table had.
+func TestFreezerMigrateKeepsCompression(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir    = t.TempDir()
+		tables = map[string]bool{"compressed": false}
+		items  = make([][]byte, 2000)
+	)
+	for i := range items {
+		items[i] = []byte(fmt.Sprintf(`{"index":%d,"payload":"%0128d"}`, i, i))
+	}
+	f, err := NewFreezer(dir, "", false, 1<<16, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i, item := range items {
+			if err := op.AppendRaw("compressed", uint64(i), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// Migrate the table with and without zstd compression, checking that the
+	// compression is retained and every migration converts the items
+	convert := func(blob []byte) ([]byte, error) { return append(blob, '!'), nil }
+	for i, compress := range []bool{false, true, false} {
+		f, err := NewFreezer(dir, "", false, 1<<16, tables)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if compress {
+			err = f.CompressTable("compressed")
+		} else {
+			err = f.MigrateTable("compressed", convert)
+		}
+		if err != nil {
+			t.Fatalf("step %d: failed to migrate table: %v", i, err)
+		}
+		f.Close()
+
+		zstd := i > 0
+		if _, err := os.Stat(filepath.Join(dir, "compressed.zdict")); (err == nil) != zstd {
+			t.Fatalf("step %d: dictionary presence mismatch: have %v, want %v", i, err == nil, zstd)
+		}
+	}
+	f, err = NewFreezer(dir, "", false, 1<<16, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if f.tables["compressed"].zstd == nil {
+		t.Fatal("table not compressed with zstd")
+	}
+	for i, item := range items {
+		have, err := f.Ancient("compressed", uint64(i))
+		if err != nil {
+			t.Fatalf("failed to retrieve item %d: %v", i, err)
+		}
+		if want := append(item, '!', '!'); !bytes.Equal(have, want) {
+			t.Fatalf("item %d mismatch: have %q, want %q", i, have, want)
+		}
+	}
+}
+
 func TestFreezerSuite(t *testing.T) {
 	ancienttest.TestAncientSuite(t, func(kinds []string) ethdb.AncientStore {
 		tables := make(map[string]bool)
@@ -0,0 +1,157 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// zstdDictSize is the maximum size of a dictionary trained for a table.
+	zstdDictSize = 64 * 1024
+
+	// zstdSampleItems is the maximum number of items sampled from a table to
+	// train its dictionary.
+	zstdSampleItems = 4096
+
+	// zstdSampleBytes is the maximum total size of the items sampled from a
+	// table to train its dictionary.
+	zstdSampleBytes = 16 * 1024 * 1024
+
+	// zstdSampleChunk is the size of the chunks the sampled items are joined
+	// into before training on them.
+	zstdSampleChunk = 128 * 1024
+)
+
+// zstdDictName returns the name of the dictionary file of a table. A table
+// having a dictionary file is compressed with zstd, an empty dictionary
+// meaning that the items are compressed without one.
+func zstdDictName(name string) string {
+	return fmt.Sprintf("%s.zdict", name)
+}
+
+// readZstdDict reads the dictionary of a table, returning nil if the table
+// isn't compressed with zstd.
+func readZstdDict(path, name string) ([]byte, error) {
+	dict, err := os.ReadFile(filepath.Join(path, zstdDictName(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dict == nil {
+		dict = []byte{}
+	}
+	return dict, nil
+}
+
+// trainZstdDict trains a dictionary on the given samples. If there are too
+// few samples to train on, an empty dictionary is returned.
+func trainZstdDict(samples [][]byte) []byte {
+	// The cost of training grows with the number of samples rather than with
+	// their size, so join the items into larger chunks to train on.
+	var (
+		chunks [][]byte
+		chunk  []byte
+		size   int
+	)
+	for _, sample := range samples {
+		chunk = append(chunk, sample...)
+		if len(chunk) >= zstdSampleChunk {
+			chunks = append(chunks, chunk)
+			chunk = nil
+		}
+		size += len(sample)
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	// A dictionary is only useful if it's considerably smaller than the data
+	// it was trained on, and the trainer requires some data to work with.
+	if size < 8*zstdDictSize {
+		return []byte{}
+	}
+	d, err := dict.BuildZstdDict(chunks, dict.Options{
+		MaxDictSize: zstdDictSize,
+		HashBytes:   6,
+	})
+	if err != nil {
+		log.Warn("Failed to train zstd dictionary", "samples", len(samples), "size", size, "err", err)
+		return []byte{}
+	}
+	return d
+}
+
+// zstdCodec compresses and decompresses the items of a table with the table's
+// dictionary. It's safe for concurrent use and, as only the stateless EncodeAll
+// and DecodeAll are used, holds no resources needing to be released.
+type zstdCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// newZstdCodec creates a codec using the given dictionary, which may be
+// empty to compress items without one.
+func newZstdCodec(dict []byte) (*zstdCodec, error) {
+	eopts := []zstd.EOption{
+		zstd.WithEncoderLevel(zstd.SpeedBetterCompression),
+		zstd.WithEncoderCRC(false), // Snappy items don't carry checksums either
+	}
+	var dopts []zstd.DOption
+	if len(dict) > 0 {
+		eopts = append(eopts, zstd.WithEncoderDict(dict))
+		dopts = append(dopts, zstd.WithDecoderDicts(dict))
+	}
+	encoder, err := zstd.NewWriter(nil, eopts...)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil, dopts...)
+	if err != nil {
+		encoder.Close()
+		return nil, err
+	}
+	return &zstdCodec{encoder: encoder, decoder: decoder}, nil
+}
+
+// compress appends the compressed data to dst.
+func (c *zstdCodec) compress(dst []byte, data []byte) []byte {
+	return c.encoder.EncodeAll(data, dst)
+}
+
+// decompress decompresses an item.
+func (c *zstdCodec) decompress(item []byte) ([]byte, error) {
+	return c.decoder.DecodeAll(item, nil)
+}
+
+// decompressedSize returns the size of an item once decompressed, or its
+// compressed size if the frame doesn't carry it.
+func (c *zstdCodec) decompressedSize(item []byte) int {
+	var header zstd.Header
+	if err := header.Decode(item); err != nil || !header.HasFCS {
+		return len(item)
+	}
+	return int(header.FrameContentSize)
+}
@@ -79,6 +79,12 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// logIndexHeadKey tracks the latest block whose logs have been indexed.
+	logIndexHeadKey = []byte("LogIndexHead")
+
+	// logIndexTailKey tracks the oldest block whose logs have been indexed.
+	logIndexTailKey = []byte("LogIndexTail")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	// This flag is deprecated, it's kept to avoid reporting errors when inspect
 	// database.
@@ -107,6 +113,8 @@ var (
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	logIndexPrefix        = []byte("E") // logIndexPrefix + kind (1 byte) + address/topic + num (uint64 big endian) -> log positions
+	stateDiffPrefix       = []byte("d") // stateDiffPrefix + num (uint64 big endian) + hash -> block state diff
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
@@ -226,6 +234,23 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// logIndexKey = logIndexPrefix + kind (1 byte) + address/topic + num (uint64 big endian)
+func logIndexKey(kind byte, value []byte, number uint64) []byte {
+	key := make([]byte, 0, len(logIndexPrefix)+1+len(value)+8)
+	key = append(append(append(key, logIndexPrefix...), kind), value...)
+	return append(key, encodeBlockNumber(number)...)
+}
+
+// logIndexBlockKey = logIndexPrefix + 0xff + num (uint64 big endian)
+func logIndexBlockKey(number uint64) []byte {
+	return append(append(append([]byte{}, logIndexPrefix...), logIndexBlock), encodeBlockNumber(number)...)
+}
+
+// stateDiffKey = stateDiffPrefix + num (uint64 big endian) + hash
+func stateDiffKey(number uint64, hash common.Hash) []byte {
+	return append(append(stateDiffPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // skeletonHeaderKey = skeletonHeaderPrefix + num (uint64 big endian)
 func skeletonHeaderKey(number uint64) []byte {
 	return append(skeletonHeaderPrefix, encodeBlockNumber(number)...)
@@ -0,0 +1,180 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/trie/utils"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+// errHistoricTrieReadOnly is returned if a mutation is attempted on the trie
+// backed by the state histories.
+var errHistoricTrieReadOnly = errors.New("historic trie is read-only")
+
+// historicDB is the state database for accessing the historical states which
+// are no longer maintained in the live trie database, but can still be rebuilt
+// from the state histories. It's only supported by the path-based scheme.
+type historicDB struct {
+	*cachingDB
+}
+
+// NewHistoricDatabase creates a state database for accessing the historical
+// states resolved from the state histories. The tries opened by the database
+// are read-only, any attempt to commit the state will result in an error.
+func NewHistoricDatabase(db ethdb.Database, triedb *triedb.Database) Database {
+	return &historicDB{
+		cachingDB: &cachingDB{
+			disk:          db,
+			codeSizeCache: lru.NewCache[common.Hash, int](codeSizeCacheSize),
+			codeCache:     lru.NewSizeConstrainedCache[common.Hash, []byte](codeCacheSize),
+			triedb:        triedb,
+			pointCache:    utils.NewPointCache(pointCacheSize),
+		},
+	}
+}
+
+// OpenTrie opens the main account trie at a specific historical root hash.
+func (db *historicDB) OpenTrie(root common.Hash) (Trie, error) {
+	reader, err := db.triedb.HistoricReader(root)
+	if err != nil {
+		return nil, err
+	}
+	return &historicTrie{reader: reader, root: reader.Root()}, nil
+}
+
+// OpenStorageTrie opens the storage trie of an account at a specific historical
+// root hash.
+func (db *historicDB) OpenStorageTrie(stateRoot common.Hash, address common.Address, root common.Hash, self Trie) (Trie, error) {
+	tr, ok := self.(*historicTrie)
+	if !ok || tr.reader.Root() != types.TrieRootHash(stateRoot) {
+		reader, err := db.triedb.HistoricReader(stateRoot)
+		if err != nil {
+			return nil, err
+		}
+		return &historicTrie{reader: reader, root: root}, nil
+	}
+	return &historicTrie{reader: tr.reader, root: root}, nil
+}
+
+// CopyTrie returns an independent copy of the given trie.
+func (db *historicDB) CopyTrie(t Trie) Trie {
+	tr, ok := t.(*historicTrie)
+	if !ok {
+		panic(fmt.Errorf("unknown trie type %T", t))
+	}
+	return tr.copy()
+}
+
+// historicTrie is a read-only Trie implementation backed by the historical state
+// reader. It can represent either the account trie or a storage trie.
+type historicTrie struct {
+	reader *pathdb.HistoricalStateReader
+	root   common.Hash // Root hash of the trie at the historical state
+}
+
+// copy returns a copy of the trie. The underlying reader is shared since it's
+// immutable from the perspective of the trie.
+func (t *historicTrie) copy() *historicTrie {
+	return &historicTrie{reader: t.reader, root: t.root}
+}
+
+// GetKey returns the sha3 preimage of a hashed key, which is not supported.
+func (t *historicTrie) GetKey([]byte) []byte {
+	return nil
+}
+
+// GetAccount retrieves the account with the provided address at the historical
+// state. If the specified account is not present, nil will be returned.
+func (t *historicTrie) GetAccount(address common.Address) (*types.StateAccount, error) {
+	return t.reader.Account(address)
+}
+
+// GetStorage returns the value for key stored in the trie at the historical state.
+func (t *historicTrie) GetStorage(addr common.Address, key []byte) ([]byte, error) {
+	enc, err := t.reader.Storage(addr, crypto.Keccak256Hash(key))
+	if err != nil || len(enc) == 0 {
+		return nil, err
+	}
+	_, content, _, err := rlp.Split(enc)
+	return content, err
+}
+
+// UpdateAccount implements Trie, but the historic trie is read-only.
+func (t *historicTrie) UpdateAccount(address common.Address, account *types.StateAccount) error {
+	return errHistoricTrieReadOnly
+}
+
+// UpdateStorage implements Trie, but the historic trie is read-only.
+func (t *historicTrie) UpdateStorage(addr common.Address, key, value []byte) error {
+	return errHistoricTrieReadOnly
+}
+
+// DeleteAccount implements Trie, but the historic trie is read-only.
+func (t *historicTrie) DeleteAccount(address common.Address) error {
+	return errHistoricTrieReadOnly
+}
+
+// DeleteStorage implements Trie, but the historic trie is read-only.
+func (t *historicTrie) DeleteStorage(addr common.Address, key []byte) error {
+	return errHistoricTrieReadOnly
+}
+
+// UpdateContractCode implements Trie, but the historic trie is read-only.
+func (t *historicTrie) UpdateContractCode(address common.Address, codeHash common.Hash, code []byte) error {
+	return errHistoricTrieReadOnly
+}
+
+// Hash returns the root hash of the trie. Since the trie is not mutable, it's
+// always the root at which the trie is opened.
+func (t *historicTrie) Hash() common.Hash {
+	return t.root
+}
+
+// Commit implements Trie, but the historic trie is read-only.
+func (t *historicTrie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet, error) {
+	return common.Hash{}, nil, errHistoricTrieReadOnly
+}
+
+// NodeIterator implements Trie, but iteration is not supported by the
+// historic trie.
+func (t *historicTrie) NodeIterator(startKey []byte) (trie.NodeIterator, error) {
+	return nil, errors.New("historic trie is not iterable")
+}
+
+// Prove implements Trie, but proof construction is not supported by the
+// historic trie.
+func (t *historicTrie) Prove(key []byte, proofDb ethdb.KeyValueWriter) error {
+	return errors.New("historic trie is not provable")
+}
+
+// IsVerkle returns false as the state histories are only maintained for the
+// merkle tree.
+func (t *historicTrie) IsVerkle() bool {
+	return false
+}
@@ -167,6 +167,33 @@ type (
 		account       *common.Address
 		key, prevalue common.Hash
 	}
+
+	// Changes made by Finalise, only journalled while a multi-transaction
+	// snapshot is held.
+	txFinaliseChange struct {
+		accessList       *accessList
+		transientStorage transientStorage
+		refund           uint64
+	}
+	objectFinaliseChange struct {
+		account      common.Address
+		mutation     *mutation
+		dirtyStorage Storage
+		pending      map[common.Hash]*common.Hash // Previous pending values of the dirty slots, nil if absent
+		newContract  bool
+	}
+	objectDeleteChange struct {
+		account       common.Address
+		object        *stateObject
+		mutation      *mutation
+		destructed    bool // whether the account was already marked as destructed
+		data          []byte
+		hasData       bool
+		storage       map[common.Hash][]byte
+		dataOrigin    []byte
+		hasDataOrigin bool
+		storageOrigin map[common.Hash][]byte
+	}
 )
 
 func (ch createObjectChange) revert(s *StateDB) {
@@ -395,3 +422,103 @@ func (ch accessListAddSlotChange) copy() journalEntry {
 		slot:    ch.slot,
 	}
 }
+
+func (ch txFinaliseChange) revert(s *StateDB) {
+	s.accessList = ch.accessList
+	s.transientStorage = ch.transientStorage
+	s.refund = ch.refund
+}
+
+func (ch txFinaliseChange) dirtied() *common.Address {
+	return nil
+}
+
+func (ch txFinaliseChange) copy() journalEntry {
+	return txFinaliseChange{
+		accessList:       ch.accessList.Copy(),
+		transientStorage: ch.transientStorage.Copy(),
+		refund:           ch.refund,
+	}
+}
+
+func (ch objectFinaliseChange) revert(s *StateDB) {
+	obj := s.getStateObject(ch.account)
+	for key, value := range ch.pending {
+		if value == nil {
+			delete(obj.pendingStorage, key)
+		} else {
+			obj.pendingStorage[key] = *value
+		}
+	}
+	obj.dirtyStorage = ch.dirtyStorage
+	obj.newContract = ch.newContract
+	revertMutation(s, ch.account, ch.mutation)
+}
+
+func (ch objectFinaliseChange) dirtied() *common.Address {
+	return nil
+}
+
+func (ch objectFinaliseChange) copy() journalEntry {
+	cpy := objectFinaliseChange{
+		account:      ch.account,
+		dirtyStorage: ch.dirtyStorage.Copy(),
+		pending:      make(map[common.Hash]*common.Hash, len(ch.pending)),
+		newContract:  ch.newContract,
+	}
+	if ch.mutation != nil {
+		cpy.mutation = ch.mutation.copy()
+	}
+	for key, value := range ch.pending {
+		if value != nil {
+			v := *value
+			value = &v
+		}
+		cpy.pending[key] = value
+	}
+	return cpy
+}
+
+func (ch objectDeleteChange) revert(s *StateDB) {
+	s.stateObjects[ch.account] = ch.object
+	if !ch.destructed {
+		delete(s.stateObjectsDestruct, ch.account)
+	}
+	if ch.hasData {
+		s.accounts[ch.object.addrHash] = ch.data
+	}
+	if ch.storage != nil {
+		s.storages[ch.object.addrHash] = ch.storage
+	}
+	if ch.hasDataOrigin {
+		s.accountsOrigin[ch.account] = ch.dataOrigin
+	}
+	if ch.storageOrigin != nil {
+		s.storagesOrigin[ch.account] = ch.storageOrigin
+	}
+	revertMutation(s, ch.account, ch.mutation)
+}
+
+func (ch objectDeleteChange) dirtied() *common.Address {
+	return nil
+}
+
+func (ch objectDeleteChange) copy() journalEntry {
+	// The object is not deep-copied, as the entry can't be bound to another
+	// state. States holding a multi-transaction snapshot must not be copied.
+	cpy := ch
+	if ch.mutation != nil {
+		cpy.mutation = ch.mutation.copy()
+	}
+	return cpy
+}
+
+// revertMutation restores the mutation marker of an account to its previous
+// value, nil meaning that the account was not marked.
+func revertMutation(s *StateDB, addr common.Address, prev *mutation) {
+	if prev == nil {
+		delete(s.mutations, addr)
+	} else {
+		s.mutations[addr] = prev
+	}
+}
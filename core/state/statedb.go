@@ -154,6 +154,10 @@ type StateDB struct {
 	validRevisions []revision
 	nextRevisionId int
 
+	// Revision kept alive across transaction boundaries, see MultiTxSnapshot.
+	multiTxRevision int
+	multiTxActive   bool
+
 	// Measurements gathered during execution for debugging purposes
 	AccountReads         time.Duration
 	AccountHashes        time.Duration
@@ -766,6 +770,35 @@ func (s *StateDB) RevertToSnapshot(revid int) {
 	// Replay the journal to undo changes and remove invalidated snapshots
 	s.journal.revert(s, snapshot)
 	s.validRevisions = s.validRevisions[:idx]
+
+	if s.multiTxActive && revid <= s.multiTxRevision {
+		s.multiTxActive = false
+	}
+}
+
+// MultiTxSnapshot returns an identifier for the current revision of the state
+// which, unlike the ones created by Snapshot, remains valid across transaction
+// boundaries. While it is held, Finalise journals its own changes instead of
+// flushing the journal, so that a group of transactions can be reverted as a
+// whole with RevertToSnapshot. Only one such snapshot can be held at a time,
+// it is released by reverting to it or by ReleaseMultiTxSnapshot.
+//
+// The snapshot does not survive IntermediateRoot, and the state must not be
+// copied while it is held.
+func (s *StateDB) MultiTxSnapshot() int {
+	if s.multiTxActive {
+		panic("multi-transaction snapshot already held")
+	}
+	s.multiTxRevision = s.Snapshot()
+	s.multiTxActive = true
+	return s.multiTxRevision
+}
+
+// ReleaseMultiTxSnapshot drops the snapshot held by MultiTxSnapshot, keeping
+// all the changes made since. It must be invoked at a transaction boundary.
+func (s *StateDB) ReleaseMultiTxSnapshot() {
+	s.multiTxActive = false
+	s.clearJournalAndRefund()
 }
 
 // GetRefund returns the current value of the refund counter.
@@ -790,6 +823,9 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 			continue
 		}
 		if obj.selfDestructed || (deleteEmptyObjects && obj.empty()) {
+			if s.multiTxActive {
+				s.journal.append(s.objectDeleteChange(obj))
+			}
 			delete(s.stateObjects, obj.address)
 			s.markDelete(addr)
 
@@ -811,6 +847,9 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 			delete(s.accountsOrigin, obj.address) // Clear out any previously updated account data (may be recreated via a resurrect)
 			delete(s.storagesOrigin, obj.address) // Clear out any previously updated storage data (may be recreated via a resurrect)
 		} else {
+			if s.multiTxActive {
+				s.journal.append(s.objectFinaliseChange(obj))
+			}
 			obj.finalise()
 			s.markUpdate(addr)
 		}
@@ -824,15 +863,72 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 			log.Error("Failed to prefetch addresses", "addresses", len(addressesToPrefetch), "err", err)
 		}
 	}
-	// Invalidate journal because reverting across transactions is not allowed.
+	// Invalidate journal because reverting across transactions is not allowed,
+	// unless a multi-transaction snapshot is held. In that case only the
+	// revisions taken during the transaction are dropped, and the per-transaction
+	// values are journalled so that the previous ones can be reverted.
+	if s.multiTxActive {
+		s.journal.append(txFinaliseChange{
+			accessList:       s.accessList,
+			transientStorage: s.transientStorage,
+			refund:           s.refund,
+		})
+		s.refund = 0
+		s.validRevisions = s.validRevisions[:sort.Search(len(s.validRevisions), func(i int) bool {
+			return s.validRevisions[i].id > s.multiTxRevision
+		})]
+		return
+	}
 	s.clearJournalAndRefund()
 }
 
+// objectDeleteChange returns the journal entry undoing the removal of the given
+// object at the end of a transaction.
+func (s *StateDB) objectDeleteChange(obj *stateObject) objectDeleteChange {
+	ch := objectDeleteChange{
+		account: obj.address,
+		object:  obj,
+	}
+	if op, ok := s.mutations[obj.address]; ok {
+		ch.mutation = op.copy()
+	}
+	_, ch.destructed = s.stateObjectsDestruct[obj.address]
+	ch.data, ch.hasData = s.accounts[obj.addrHash]
+	ch.storage = s.storages[obj.addrHash]
+	ch.dataOrigin, ch.hasDataOrigin = s.accountsOrigin[obj.address]
+	ch.storageOrigin = s.storagesOrigin[obj.address]
+	return ch
+}
+
+// objectFinaliseChange returns the journal entry undoing the finalisation of
+// the given object at the end of a transaction.
+func (s *StateDB) objectFinaliseChange(obj *stateObject) objectFinaliseChange {
+	ch := objectFinaliseChange{
+		account:      obj.address,
+		dirtyStorage: obj.dirtyStorage,
+		pending:      make(map[common.Hash]*common.Hash, len(obj.dirtyStorage)),
+		newContract:  obj.newContract,
+	}
+	if op, ok := s.mutations[obj.address]; ok {
+		ch.mutation = op.copy()
+	}
+	for key := range obj.dirtyStorage {
+		if value, ok := obj.pendingStorage[key]; ok {
+			ch.pending[key] = &value
+		} else {
+			ch.pending[key] = nil
+		}
+	}
+	return ch
+}
+
 // IntermediateRoot computes the current root hash of the state trie.
 // It is called in between transactions to get the root hash that
 // goes into transaction receipts.
 func (s *StateDB) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
-	// Finalise all the dirty storage states and write them into the tries
+	// Finalise all the dirty storage states and write them into the tries. The
+	// tries can't be reverted, so any multi-transaction snapshot is released.
+	s.multiTxActive = false
 	s.Finalise(deleteEmptyObjects)
 
 	// If there was a trie prefetcher operating, terminate it async so that the
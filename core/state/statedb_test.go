@@ -962,6 +962,61 @@ func TestDeleteCreateRevert(t *testing.T) {
 	}
 }
 
+// TestMultiTxSnapshot tests that a multi-transaction snapshot can be reverted
+// after several transactions have been finalised.
+func TestMultiTxSnapshot(t *testing.T) {
+	state, _ := New(types.EmptyRootHash, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		a = common.BytesToAddress([]byte("a"))
+		b = common.BytesToAddress([]byte("b"))
+		c = common.BytesToAddress([]byte("c"))
+	)
+	state.SetBalance(a, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	state.SetState(a, common.Hash{0x01}, common.Hash{0x01})
+	state.SetBalance(b, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+
+	root, _ := state.Commit(0, false)
+	state, _ = New(root, state.db, state.snaps)
+
+	// Apply a transaction before taking the snapshot
+	state.SetState(a, common.Hash{0x02}, common.Hash{0x02})
+	state.Finalise(true)
+	want := state.Copy().IntermediateRoot(true)
+
+	// Apply two transactions changing storage, deleting and creating accounts
+	id := state.MultiTxSnapshot()
+
+	state.AddAddressToAccessList(a)
+	state.SetState(a, common.Hash{0x01}, common.Hash{0x03})
+	state.SetState(a, common.Hash{0x02}, common.Hash{})
+	state.AddRefund(1)
+	state.Finalise(true)
+
+	state.accessList = newAccessList()
+	state.AddSlotToAccessList(b, common.Hash{0x01})
+	state.SelfDestruct(b)
+	state.SetBalance(c, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	state.Finalise(true)
+
+	state.RevertToSnapshot(id)
+	if state.GetRefund() != 0 {
+		t.Fatalf("refund mismatch: have %d, want 0", state.GetRefund())
+	}
+	if have := state.IntermediateRoot(true); have != want {
+		t.Fatalf("root mismatch: have %x, want %x", have, want)
+	}
+	// Make sure a new snapshot can be taken and released
+	state.MultiTxSnapshot()
+	state.SetBalance(c, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	state.Finalise(true)
+	state.ReleaseMultiTxSnapshot()
+
+	if state.journal.length() != 0 {
+		t.Fatalf("journal not flushed: %d entries", state.journal.length())
+	}
+}
+
 // TestMissingTrieNodes tests that if the StateDB fails to load parts of the trie,
 // the Commit operation fails with an error
 // If we are missing trie nodes, we should not continue writing to the trie
@@ -91,6 +91,7 @@ type blobTxMeta struct {
 	hash common.Hash // Transaction hash to maintain the lookup table
 	id   uint64      // Storage ID in the pool's persistent store
 	size uint32      // Byte size in the pool's persistent store
+	time time.Time   // Time the transaction was first seen (or loaded from disk)
 
 	nonce      uint64       // Needed to prioritize inclusion order within an account
 	costCap    *uint256.Int // Needed to validate cumulative balance sufficiency
@@ -115,6 +116,7 @@ func newBlobTxMeta(id uint64, size uint32, tx *types.Transaction) *blobTxMeta {
 		hash:       tx.Hash(),
 		id:         id,
 		size:       size,
+		time:       tx.Time(),
 		nonce:      tx.Nonce(),
 		costCap:    uint256.MustFromBig(tx.Cost()),
 		execTipCap: uint256.MustFromBig(tx.GasTipCap()),
@@ -313,6 +315,9 @@ type BlobPool struct {
 
 	discoverFeed event.Feed // Event feed to send out new tx events on pool discovery (reorg excluded)
 	insertFeed   event.Feed // Event feed to send out new tx events on pool inclusion (reorg included)
+	dropFeed     event.Feed // Event feed to send out tx eviction events
+
+	drops []*txpool.TxDrop // Evictions to announce once the pool lock is released
 
 	lock sync.RWMutex // Mutex protecting the pool during reorg handling
 }
@@ -430,6 +435,7 @@ func (p *BlobPool) Init(gasTip uint64, head *types.Header, reserve txpool.Addres
 	// Update the metrics and return the constructed pool
 	datacapGauge.Update(int64(p.config.Datacap))
 	p.updateStorageMetrics()
+	p.sendDrops()
 	return nil
 }
 
@@ -530,6 +536,11 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			ids = append(ids, txs[i].id)
 			nonces = append(nonces, txs[i].nonce)
 
+			if gapped {
+				p.recordDrop(addr, txs[i], txpool.DropGapped, nil)
+			} else {
+				p.recordStale(addr, txs[i], inclusions)
+			}
 			p.stored -= uint64(txs[i].size)
 			delete(p.lookup, txs[i].hash)
 
@@ -570,6 +581,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			ids = append(ids, txs[0].id)
 			nonces = append(nonces, txs[0].nonce)
 
+			p.recordStale(addr, txs[0], inclusions)
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[0].costCap)
 			p.stored -= uint64(txs[0].size)
 			delete(p.lookup, txs[0].hash)
@@ -626,6 +638,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			log.Error("Dropping repeat nonce blob transaction", "from", addr, "nonce", txs[i].nonce, "id", id)
 			dropRepeatedMeter.Mark(1)
 
+			p.recordDrop(addr, txs[i], txpool.DropReplaced, nil)
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[i].costCap)
 			p.stored -= uint64(txs[i].size)
 			delete(p.lookup, txs[i].hash)
@@ -648,6 +661,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			ids = append(ids, txs[j].id)
 			nonces = append(nonces, txs[j].nonce)
 
+			p.recordDrop(addr, txs[j], txpool.DropGapped, nil)
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[j].costCap)
 			p.stored -= uint64(txs[j].size)
 			delete(p.lookup, txs[j].hash)
@@ -686,6 +700,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			ids = append(ids, last.id)
 			nonces = append(nonces, last.nonce)
 
+			p.recordDrop(addr, last, txpool.DropUnpayable, nil)
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], last.costCap)
 			p.stored -= uint64(last.size)
 			delete(p.lookup, last.hash)
@@ -726,6 +741,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			ids = append(ids, last.id)
 			nonces = append(nonces, last.nonce)
 
+			p.recordDrop(addr, last, txpool.DropOverflow, nil)
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], last.costCap)
 			p.stored -= uint64(last.size)
 			delete(p.lookup, last.hash)
@@ -780,6 +796,8 @@ func (p *BlobPool) offload(addr common.Address, nonce uint64, id uint64, inclusi
 // Reset implements txpool.SubPool, allowing the blob pool's internal state to be
 // kept in sync with the main transaction pool's internal state.
 func (p *BlobPool) Reset(oldHead, newHead *types.Header) {
+	defer p.sendDrops()
+
 	waitStart := time.Now()
 	p.lock.Lock()
 	resetwaitHist.Update(time.Since(waitStart).Nanoseconds())
@@ -1014,6 +1032,8 @@ func (p *BlobPool) reinject(addr common.Address, txhash common.Hash) error {
 // SetGasTip implements txpool.SubPool, allowing the blob pool's gas requirements
 // to be kept in sync with the main transaction pool's gas requirements.
 func (p *BlobPool) SetGasTip(tip *big.Int) {
+	defer p.sendDrops()
+
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -1031,6 +1051,7 @@ func (p *BlobPool) SetGasTip(tip *big.Int) {
 						ids    = []uint64{tx.id}
 						nonces = []uint64{tx.nonce}
 					)
+					p.recordDrop(addr, tx, txpool.DropUnderpriced, nil)
 					p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[i].costCap)
 					p.stored -= uint64(tx.size)
 					delete(p.lookup, tx.hash)
@@ -1041,6 +1062,7 @@ func (p *BlobPool) SetGasTip(tip *big.Int) {
 						ids = append(ids, tx.id)
 						nonces = append(nonces, tx.nonce)
 
+						p.recordDrop(addr, tx, txpool.DropUnderpriced, nil)
 						p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], tx.costCap)
 						p.stored -= uint64(tx.size)
 						delete(p.lookup, tx.hash)
@@ -1223,6 +1245,7 @@ func (p *BlobPool) Add(txs []*types.Transaction, local bool, sync bool) []error
 		p.discoverFeed.Send(core.NewTxsEvent{Txs: adds})
 		p.insertFeed.Send(core.NewTxsEvent{Txs: adds})
 	}
+	p.sendDrops()
 	return errs
 }
 
@@ -1310,6 +1333,7 @@ func (p *BlobPool) add(tx *types.Transaction) (err error) {
 		dropReplacedMeter.Mark(1)
 
 		prev := p.index[from][offset]
+		p.recordDrop(from, prev, txpool.DropReplaced, tx.WithoutBlobTxSidecar())
 		if err := p.store.Delete(prev.id); err != nil {
 			// Shitty situation, but try to recover gracefully instead of going boom
 			log.Error("Failed to delete replaced transaction", "id", prev.id, "err", err)
@@ -1418,6 +1442,7 @@ func (p *BlobPool) drop() {
 		p.index[from] = txs
 		p.spent[from] = new(uint256.Int).Sub(p.spent[from], drop.costCap)
 	}
+	p.recordDrop(from, drop, txpool.DropOverflow, nil)
 	p.stored -= uint64(drop.size)
 	delete(p.lookup, drop.hash)
 
@@ -1445,6 +1470,60 @@ func (p *BlobPool) drop() {
 	}
 }
 
+// recordStale records the eviction of a transaction whose nonce was passed by
+// the account, unless the transaction itself was included by the chain. If the
+// included transactions are unknown, nothing is recorded, as most of the stale
+// transactions are the included ones.
+//
+// Note, this method assumes the pool lock is held!
+func (p *BlobPool) recordStale(from common.Address, meta *blobTxMeta, inclusions map[common.Hash]uint64) {
+	if inclusions == nil {
+		return
+	}
+	if _, ok := inclusions[meta.hash]; !ok {
+		p.recordDrop(from, meta, txpool.DropNonceTooLow, nil)
+	}
+}
+
+// recordDrop records the eviction of a transaction, to be announced once the
+// pool lock is released. Only replaced transactions are loaded from disk, for
+// the replacement history to be able to report the price bump; the rest are
+// announced by hash.
+//
+// Note, this method assumes the pool lock is held and the transaction is not
+// yet deleted from the store!
+func (p *BlobPool) recordDrop(from common.Address, meta *blobTxMeta, reason txpool.DropReason, replacement *types.Transaction) {
+	drop := &txpool.TxDrop{
+		Hash:        meta.hash,
+		From:        from,
+		Reason:      reason,
+		Replacement: replacement,
+	}
+	if replacement != nil {
+		if data, err := p.store.Get(meta.id); err == nil {
+			tx := new(types.Transaction)
+			if err := rlp.DecodeBytes(data, tx); err == nil {
+				drop.Tx = tx.WithoutBlobTxSidecar()
+			}
+		}
+	}
+	p.drops = append(p.drops, drop)
+}
+
+// sendDrops announces the evictions recorded since the last announcement.
+//
+// Note, this method assumes the pool lock is not held!
+func (p *BlobPool) sendDrops() {
+	p.lock.Lock()
+	drops := p.drops
+	p.drops = nil
+	p.lock.Unlock()
+
+	if len(drops) > 0 {
+		p.dropFeed.Send(txpool.DropTxsEvent{Drops: drops})
+	}
+}
+
 // Pending retrieves all currently processable transactions, grouped by origin
 // account and sorted by nonce.
 //
@@ -1496,7 +1575,7 @@ func (p *BlobPool) Pending(filter txpool.PendingFilter) map[common.Address][]*tx
 			lazies = append(lazies, &txpool.LazyTransaction{
 				Pool:      p,
 				Hash:      tx.hash,
-				Time:      execStart, // TODO(karalabe): Maybe save these and use that?
+				Time:      tx.time,
 				GasFeeCap: tx.execFeeCap,
 				GasTipCap: tx.execTipCap,
 				Gas:       tx.execGas,
@@ -1585,6 +1664,12 @@ func (p *BlobPool) updateLimboMetrics() {
 	limboSlotusedGauge.Update(int64(slotused))
 }
 
+// SubscribeDropTxs registers a subscription for the events of transactions
+// leaving the pool without being included.
+func (p *BlobPool) SubscribeDropTxs(ch chan<- txpool.DropTxsEvent) event.Subscription {
+	return p.dropFeed.Subscribe(ch)
+}
+
 // SubscribeTransactions registers a subscription for new transaction events,
 // supporting feeding only newly seen or also resurrected transactions.
 func (p *BlobPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool) event.Subscription {
@@ -74,6 +74,7 @@ type testBlockChain struct {
 	basefee *uint256.Int
 	blobfee *uint256.Int
 	statedb *state.StateDB
+	blocks  map[common.Hash]*types.Block // Blocks served to the pool, if any
 }
 
 func (bc *testBlockChain) Config() *params.ChainConfig {
@@ -144,7 +145,7 @@ func (bc *testBlockChain) CurrentFinalBlock() *types.Header {
 }
 
 func (bt *testBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
-	return nil
+	return bt.blocks[hash]
 }
 
 func (bc *testBlockChain) StateAt(common.Hash) (*state.StateDB, error) {
@@ -1313,6 +1314,90 @@ func TestAdd(t *testing.T) {
 	}
 }
 
+// Tests that blob transactions leaving the pool by being included are moved to
+// the limbo without being announced as dropped, contrary to the ones whose nonce
+// is taken by another transaction.
+func TestDropEvents(t *testing.T) {
+	storage, _ := os.MkdirTemp("", "blobpool-")
+	defer os.RemoveAll(storage)
+
+	var (
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+	)
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewDatabase(memorydb.New())), nil)
+	statedb.AddBalance(addr1, uint256.NewInt(1_000_000_000), tracing.BalanceChangeUnspecified)
+	statedb.AddBalance(addr2, uint256.NewInt(1_000_000_000), tracing.BalanceChangeUnspecified)
+	statedb.Commit(0, true)
+
+	chain := &testBlockChain{
+		config:  testChainConfig,
+		basefee: uint256.NewInt(1050),
+		blobfee: uint256.NewInt(105),
+		statedb: statedb,
+		blocks:  make(map[common.Hash]*types.Block),
+	}
+	pool := New(Config{Datadir: storage}, chain)
+	if err := pool.Init(1, chain.CurrentBlock(), makeAddressReserver()); err != nil {
+		t.Fatalf("failed to create blob pool: %v", err)
+	}
+	defer pool.Close()
+
+	drops := make(chan txpool.DropTxsEvent, 4)
+	sub := pool.SubscribeDropTxs(drops)
+	defer sub.Unsubscribe()
+
+	var (
+		included = makeTx(0, 10, 2000, 200, key1)
+		stale    = makeTx(0, 10, 2000, 200, key2)
+		conflict = makeTx(0, 20, 4000, 400, key2)
+	)
+	if errs := pool.Add([]*types.Transaction{included, stale}, false, true); errs[0] != nil || errs[1] != nil {
+		t.Fatalf("failed to add transactions: %v", errs)
+	}
+	// Include the transaction of the first account, and another transaction of
+	// the second account with the nonce of the pooled one
+	var (
+		oldHead = chain.CurrentBlock()
+		newHead = types.CopyHeader(oldHead)
+	)
+	newHead.Number = new(big.Int).Add(oldHead.Number, common.Big1)
+	newHead.ParentHash = oldHead.Hash()
+
+	chain.blocks[oldHead.Hash()] = types.NewBlockWithHeader(oldHead)
+	chain.blocks[newHead.Hash()] = types.NewBlockWithHeader(newHead).WithBody(types.Body{
+		Transactions: []*types.Transaction{included.WithoutBlobTxSidecar(), conflict.WithoutBlobTxSidecar()},
+	})
+	statedb.SetNonce(addr1, 1)
+	statedb.SetNonce(addr2, 1)
+	pool.Reset(oldHead, newHead)
+
+	if pool.Has(included.Hash()) || pool.Has(stale.Hash()) {
+		t.Fatalf("stale transactions not removed from the pool")
+	}
+	if _, err := pool.limbo.pull(included.Hash()); err != nil {
+		t.Fatalf("included transaction not moved to the limbo: %v", err)
+	}
+	select {
+	case ev := <-drops:
+		if len(ev.Drops) != 1 {
+			t.Fatalf("drop count mismatch: have %d, want 1", len(ev.Drops))
+		}
+		if drop := ev.Drops[0]; drop.Hash != stale.Hash() || drop.From != addr2 || drop.Reason != txpool.DropNonceTooLow {
+			t.Fatalf("drop mismatch: have %x/%v, want %x/%v", drop.Hash, drop.Reason, stale.Hash(), txpool.DropNonceTooLow)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("drop event missing for %x", stale.Hash())
+	}
+	select {
+	case ev := <-drops:
+		t.Fatalf("unexpected drop event: %d drops", len(ev.Drops))
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 // Benchmarks the time it takes to assemble the lazy pending transaction list
 // from the pool contents.
 func BenchmarkPoolPending100Mb(b *testing.B) { benchmarkPoolPending(b, 100_000_000) }
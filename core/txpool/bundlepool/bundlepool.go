@@ -0,0 +1,355 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bundlepool implements a private pool of transaction bundles.
+package bundlepool
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// txMaxSize is the maximum size a single transaction of a bundle can have.
+const txMaxSize = 128 * 1024
+
+var (
+	// ErrEmptyBundle is returned if a bundle contains no transactions.
+	ErrEmptyBundle = errors.New("empty bundle")
+
+	// ErrBundleTooLarge is returned if a bundle contains more transactions than
+	// the pool accepts in a single bundle.
+	ErrBundleTooLarge = errors.New("bundle too large")
+
+	// ErrBundleStale is returned if a bundle targets a block which is already
+	// part of the chain.
+	ErrBundleStale = errors.New("bundle targets past block")
+
+	// ErrBundleTooFarAhead is returned if a bundle targets a block too far in
+	// the future.
+	ErrBundleTooFarAhead = errors.New("bundle targets block too far ahead")
+
+	// ErrInvalidTimestamps is returned if the timestamp bounds of a bundle
+	// can't be satisfied.
+	ErrInvalidTimestamps = errors.New("invalid bundle timestamp bounds")
+
+	// ErrBundlePoolFull is returned if the pool reached its capacity.
+	ErrBundlePoolFull = errors.New("bundle pool full")
+
+	// ErrSenderBundlesFull is returned if the sender of a bundle reached the
+	// number of bundles it may have in the pool.
+	ErrSenderBundlesFull = errors.New("too many bundles from sender")
+)
+
+var bundlesGauge = metrics.NewRegisteredGauge("bundlepool/bundles", nil)
+
+// BlockChain defines the minimal set of methods needed to back a bundle pool
+// with a chain. Exists to allow mocking the live chain out of tests.
+type BlockChain interface {
+	// Config retrieves the chain's fork configuration.
+	Config() *params.ChainConfig
+
+	// CurrentBlock returns the current head of the chain.
+	CurrentBlock() *types.Header
+
+	// StateAt returns a state database for a given root hash (generally the head).
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// BundlePool is a subpool maintaining private bundles of transactions, which
+// are included atomically at the top of the block they target by the miner.
+//
+// The pool doesn't accept any public transactions, and the transactions of the
+// bundles are not exposed via the public transaction accessors, so they are
+// never announced nor served to the network.
+//
+// Every bundle is attributed to the sender of its first transaction, which has
+// to be able to pay for it, and the number of bundles of a single sender is
+// limited, so that no single client can fill the pool.
+type BundlePool struct {
+	config Config
+	chain  BlockChain
+	signer types.Signer
+	head   *types.Header
+	state  *state.StateDB // State of the head, to check the funds of the senders
+
+	bundles  map[bundleKey]*bundleEntry
+	senders  map[common.Address]int // Number of bundles of each sender
+	seq      uint64                 // Arrival counter to order the bundles
+	feed     event.Feed // Transaction feed, never fired as the bundles are private
+	dropFeed event.Feed // Eviction feed, never fired as the bundles are not transactions
+	lock     sync.RWMutex
+}
+
+// bundleKey identifies a bundle in the pool. The same transactions are often
+// submitted for multiple consecutive blocks, so the target is part of the key.
+type bundleKey struct {
+	hash   common.Hash
+	number uint64
+}
+
+// bundleEntry is a bundle along with its sender and arrival order.
+type bundleEntry struct {
+	bundle *txpool.Bundle
+	sender common.Address
+	seq    uint64
+}
+
+// New creates a new bundle pool.
+func New(config Config, chain BlockChain) *BundlePool {
+	config = (&config).sanitize()
+
+	return &BundlePool{
+		config:  config,
+		chain:   chain,
+		bundles: make(map[bundleKey]*bundleEntry),
+		senders: make(map[common.Address]int),
+	}
+}
+
+// Filter returns whether the given transaction can be consumed by the bundle
+// pool, which is never, bundles can only be added via AddBundle.
+func (p *BundlePool) Filter(tx *types.Transaction) bool {
+	return false
+}
+
+// Init sets the head of the pool.
+func (p *BundlePool) Init(gasTip uint64, head *types.Header, reserve txpool.AddressReserver) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	statedb, err := p.chain.StateAt(head.Root)
+	if err != nil {
+		statedb, err = p.chain.StateAt(types.EmptyRootHash)
+	}
+	if err != nil {
+		return err
+	}
+	p.head, p.state = head, statedb
+	p.signer = types.LatestSigner(p.chain.Config())
+	return nil
+}
+
+// Close terminates the bundle pool.
+func (p *BundlePool) Close() error {
+	return nil
+}
+
+// Reset drops the bundles targeting blocks which are not in the future anymore.
+func (p *BundlePool) Reset(oldHead, newHead *types.Header) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	statedb, err := p.chain.StateAt(newHead.Root)
+	if err != nil {
+		log.Error("Failed to reset bundlepool state", "err", err)
+	} else {
+		p.state = statedb
+	}
+	p.head = newHead
+	for key, entry := range p.bundles {
+		if key.number <= newHead.Number.Uint64() {
+			delete(p.bundles, key)
+
+			p.senders[entry.sender]--
+			if p.senders[entry.sender] == 0 {
+				delete(p.senders, entry.sender)
+			}
+		}
+	}
+	bundlesGauge.Update(int64(len(p.bundles)))
+}
+
+// SetGasTip is a no-op, bundles are not subject to the minimum gas tip.
+func (p *BundlePool) SetGasTip(tip *big.Int) {}
+
+// Has always returns false, the transactions of the bundles are private.
+func (p *BundlePool) Has(hash common.Hash) bool {
+	return false
+}
+
+// Get always returns nil, the transactions of the bundles are private.
+func (p *BundlePool) Get(hash common.Hash) *types.Transaction {
+	return nil
+}
+
+// Add rejects all the transactions, bundles can only be added via AddBundle.
+func (p *BundlePool) Add(txs []*types.Transaction, local bool, sync bool) []error {
+	errs := make([]error, len(txs))
+	for i := range txs {
+		errs[i] = core.ErrTxTypeNotSupported
+	}
+	return errs
+}
+
+// AddBundle validates a bundle and adds it to the pool.
+func (p *BundlePool) AddBundle(bundle *txpool.Bundle) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	sender, err := p.validateBundle(bundle)
+	if err != nil {
+		return err
+	}
+	hash := bundle.Hash()
+	key := bundleKey{hash: hash, number: bundle.BlockNumber}
+	if _, ok := p.bundles[key]; ok {
+		return txpool.ErrAlreadyKnown
+	}
+	if p.senders[sender] >= p.config.MaxSenderBundles {
+		return fmt.Errorf("%w: %x has %d bundles", ErrSenderBundlesFull, sender, p.senders[sender])
+	}
+	if len(p.bundles) >= p.config.MaxBundles {
+		return ErrBundlePoolFull
+	}
+	p.seq++
+	p.bundles[key] = &bundleEntry{bundle: bundle, sender: sender, seq: p.seq}
+	p.senders[sender]++
+	bundlesGauge.Update(int64(len(p.bundles)))
+
+	log.Debug("Bundle added to pool", "hash", hash, "number", bundle.BlockNumber, "txs", len(bundle.Txs), "sender", sender)
+	return nil
+}
+
+// validateBundle checks whether a bundle is acceptable for the pool, returning
+// the sender the bundle is attributed to.
+func (p *BundlePool) validateBundle(bundle *txpool.Bundle) (common.Address, error) {
+	if len(bundle.Txs) == 0 {
+		return common.Address{}, ErrEmptyBundle
+	}
+	if len(bundle.Txs) > p.config.MaxBundleTxs {
+		return common.Address{}, fmt.Errorf("%w: %d transactions, limit %d", ErrBundleTooLarge, len(bundle.Txs), p.config.MaxBundleTxs)
+	}
+	head := p.head.Number.Uint64()
+	if bundle.BlockNumber <= head {
+		return common.Address{}, fmt.Errorf("%w: target %d, head %d", ErrBundleStale, bundle.BlockNumber, head)
+	}
+	if bundle.BlockNumber > head+p.config.MaxBlocksAhead {
+		return common.Address{}, fmt.Errorf("%w: target %d, head %d", ErrBundleTooFarAhead, bundle.BlockNumber, head)
+	}
+	if bundle.MaxTimestamp != 0 && (bundle.MaxTimestamp < bundle.MinTimestamp || bundle.MaxTimestamp <= p.head.Time) {
+		return common.Address{}, ErrInvalidTimestamps
+	}
+	// Blob transactions are not supported, their sidecars can't be carried
+	// through the bundle API.
+	opts := &txpool.ValidationOptions{
+		Config: p.chain.Config(),
+		Accept: 0 |
+			1<<types.LegacyTxType |
+			1<<types.AccessListTxType |
+			1<<types.DynamicFeeTxType,
+		MaxSize: txMaxSize,
+		MinTip:  new(big.Int),
+	}
+	for i, tx := range bundle.Txs {
+		if err := txpool.ValidateTransaction(tx, p.head, p.signer, opts); err != nil {
+			return common.Address{}, fmt.Errorf("invalid transaction %d (%x): %w", i, tx.Hash(), err)
+		}
+	}
+	// The sender of the first transaction must be able to pay for it, so that
+	// bundles can't be submitted from unfunded throwaway accounts.
+	first := bundle.Txs[0]
+	sender, _ := types.Sender(p.signer, first) // already validated
+	if balance, cost := p.state.GetBalance(sender), first.Cost(); balance.ToBig().Cmp(cost) < 0 {
+		return common.Address{}, fmt.Errorf("%w: balance %v, tx cost %v", core.ErrInsufficientFunds, balance, cost)
+	}
+	return sender, nil
+}
+
+// Bundles retrieves the bundles includable in a block with the given number
+// and timestamp, in their order of arrival.
+func (p *BundlePool) Bundles(number uint64, time uint64) []*txpool.Bundle {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var entries []*bundleEntry
+	for _, entry := range p.bundles {
+		if entry.bundle.Includable(number, time) {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b *bundleEntry) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	bundles := make([]*txpool.Bundle, len(entries))
+	for i, entry := range entries {
+		bundles[i] = entry.bundle
+	}
+	return bundles
+}
+
+// Pending returns nothing, the transactions of the bundles are not meant to be
+// mined individually.
+func (p *BundlePool) Pending(filter txpool.PendingFilter) map[common.Address][]*txpool.LazyTransaction {
+	return nil
+}
+
+// SubscribeTransactions subscribes to new transaction events, which the bundle
+// pool never emits.
+func (p *BundlePool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool) event.Subscription {
+	return p.feed.Subscribe(ch)
+}
+
+// SubscribeDropTxs subscribes to transaction eviction events, which the bundle
+// pool never emits.
+func (p *BundlePool) SubscribeDropTxs(ch chan<- txpool.DropTxsEvent) event.Subscription {
+	return p.dropFeed.Subscribe(ch)
+}
+
+// Nonce returns 0, the bundle pool doesn't track account nonces.
+func (p *BundlePool) Nonce(addr common.Address) uint64 {
+	return 0
+}
+
+// Stats returns no pending or queued transactions, the bundles are not counted
+// as such.
+func (p *BundlePool) Stats() (int, int) {
+	return 0, 0
+}
+
+// Content returns no transactions, the transactions of the bundles are private.
+func (p *BundlePool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
+	return make(map[common.Address][]*types.Transaction), make(map[common.Address][]*types.Transaction)
+}
+
+// ContentFrom returns no transactions, the transactions of the bundles are private.
+func (p *BundlePool) ContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction) {
+	return []*types.Transaction{}, []*types.Transaction{}
+}
+
+// Locals returns no accounts, the bundle pool has no local accounts.
+func (p *BundlePool) Locals() []common.Address {
+	return []common.Address{}
+}
+
+// Status returns unknown for all transactions, the transactions of the bundles
+// are private.
+func (p *BundlePool) Status(hash common.Hash) txpool.TxStatus {
+	return txpool.TxStatusUnknown
+}
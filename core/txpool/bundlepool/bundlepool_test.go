@@ -0,0 +1,188 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bundlepool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+var (
+	testKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	otherKey, _  = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	unfundedKey  = mustGenerateKey()
+	testBalances = map[*ecdsa.PrivateKey]uint64{testKey: params.Ether, otherKey: params.Ether}
+)
+
+func mustGenerateKey() *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+type testBlockChain struct {
+	head  *types.Header
+	state *state.StateDB
+}
+
+func (bc *testBlockChain) Config() *params.ChainConfig { return params.MergedTestChainConfig }
+func (bc *testBlockChain) CurrentBlock() *types.Header { return bc.head }
+
+func (bc *testBlockChain) StateAt(common.Hash) (*state.StateDB, error) {
+	return bc.state, nil
+}
+
+func testHeader(number uint64) *types.Header {
+	return &types.Header{
+		Number:   new(big.Int).SetUint64(number),
+		Time:     number * 12,
+		GasLimit: 30_000_000,
+		BaseFee:  big.NewInt(params.InitialBaseFee),
+	}
+}
+
+func testBundle(t *testing.T, number uint64, nonces ...uint64) *txpool.Bundle {
+	return testKeyBundle(t, testKey, number, nonces...)
+}
+
+func testKeyBundle(t *testing.T, key *ecdsa.PrivateKey, number uint64, nonces ...uint64) *txpool.Bundle {
+	signer := types.LatestSigner(params.MergedTestChainConfig)
+
+	bundle := &txpool.Bundle{BlockNumber: number}
+	for _, nonce := range nonces {
+		tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   params.MergedTestChainConfig.ChainID,
+			Nonce:     nonce,
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(params.InitialBaseFee * 2),
+			Gas:       params.TxGas,
+			To:        &common.Address{},
+		})
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		bundle.Txs = append(bundle.Txs, tx)
+	}
+	return bundle
+}
+
+func newTestPool(t *testing.T, config Config) *BundlePool {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	for key, balance := range testBalances {
+		statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), uint256.NewInt(balance), tracing.BalanceChangeUnspecified)
+	}
+	chain := &testBlockChain{head: testHeader(10), state: statedb}
+	pool := New(config, chain)
+	if err := pool.Init(0, chain.head, nil); err != nil {
+		t.Fatalf("failed to init pool: %v", err)
+	}
+	return pool
+}
+
+func TestAddBundle(t *testing.T) {
+	pool := newTestPool(t, Config{MaxBundles: 3, MaxSenderBundles: 2, MaxBundleTxs: 2, MaxBlocksAhead: 5})
+
+	tests := []struct {
+		bundle *txpool.Bundle
+		err    error
+	}{
+		{testBundle(t, 11), ErrEmptyBundle},
+		{testBundle(t, 11, 0, 1, 2), ErrBundleTooLarge},
+		{testBundle(t, 10, 0), ErrBundleStale},
+		{testBundle(t, 16, 0), ErrBundleTooFarAhead},
+		{&txpool.Bundle{Txs: testBundle(t, 11, 0).Txs, BlockNumber: 11, MinTimestamp: 200, MaxTimestamp: 100}, ErrInvalidTimestamps},
+		{testBundle(t, 11, 0), nil},
+		{testBundle(t, 11, 0), txpool.ErrAlreadyKnown},
+		{testBundle(t, 11, 0, 1), nil},
+		{testBundle(t, 13, 1), ErrSenderBundlesFull},
+		{testKeyBundle(t, unfundedKey, 13, 0), core.ErrInsufficientFunds},
+		{&txpool.Bundle{Txs: append(testKeyBundle(t, unfundedKey, 13, 0).Txs, testBundle(t, 13, 1).Txs...), BlockNumber: 13}, core.ErrInsufficientFunds},
+		{&txpool.Bundle{Txs: append(testKeyBundle(t, otherKey, 13, 0).Txs, testBundle(t, 13, 1).Txs...), BlockNumber: 13}, nil},
+		{testKeyBundle(t, otherKey, 13, 1), ErrBundlePoolFull},
+	}
+	for i, tt := range tests {
+		if err := pool.AddBundle(tt.bundle); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// The transactions of the bundles must not be exposed
+	tx := tests[5].bundle.Txs[0]
+	if pool.Has(tx.Hash()) || pool.Get(tx.Hash()) != nil || pool.Status(tx.Hash()) != txpool.TxStatusUnknown {
+		t.Errorf("bundle transaction exposed by the pool")
+	}
+	if errs := pool.Add([]*types.Transaction{tx}, true, true); errs[0] == nil {
+		t.Errorf("public transaction accepted by the pool")
+	}
+}
+
+func TestBundlesSelection(t *testing.T) {
+	pool := newTestPool(t, DefaultConfig)
+
+	var (
+		first  = testBundle(t, 11, 0)
+		second = testBundle(t, 11, 1)
+		later  = testBundle(t, 12, 0)
+		timed  = testBundle(t, 11, 2)
+	)
+	timed.MinTimestamp, timed.MaxTimestamp = 130, 140
+	for _, bundle := range []*txpool.Bundle{first, second, later, timed} {
+		if err := pool.AddBundle(bundle); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	check := func(number, time uint64, want ...*txpool.Bundle) {
+		t.Helper()
+
+		have := pool.Bundles(number, time)
+		if len(have) != len(want) {
+			t.Fatalf("bundle count mismatch for #%d at %d: have %d, want %d", number, time, len(have), len(want))
+		}
+		for i := range want {
+			if have[i].Hash() != want[i].Hash() {
+				t.Errorf("bundle %d mismatch for #%d at %d", i, number, time)
+			}
+		}
+	}
+	check(11, 125, first, second)
+	check(11, 135, first, second, timed)
+	check(12, 144, later)
+
+	// Bundles targeting included blocks are dropped on reset
+	pool.Reset(testHeader(10), testHeader(11))
+	check(11, 135)
+	check(12, 144, later)
+
+	// The dropped bundles don't count against their sender anymore
+	if have := pool.senders[crypto.PubkeyToAddress(testKey.PublicKey)]; have != 1 {
+		t.Errorf("sender bundle count mismatch: have %d, want 1", have)
+	}
+}
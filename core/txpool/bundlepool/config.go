@@ -0,0 +1,60 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bundlepool
+
+import (
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Config are the configuration parameters of the bundle pool.
+type Config struct {
+	MaxBundles       int    // Maximum number of bundles maintained by the pool
+	MaxSenderBundles int    // Maximum number of bundles submitted by a single sender
+	MaxBundleTxs     int    // Maximum number of transactions in a single bundle
+	MaxBlocksAhead   uint64 // Maximum distance of the targeted block from the head
+}
+
+// DefaultConfig contains the default configurations for the bundle pool.
+var DefaultConfig = Config{
+	MaxBundles:       1024,
+	MaxSenderBundles: 16,
+	MaxBundleTxs:     32,
+	MaxBlocksAhead:   32,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() Config {
+	conf := *config
+	if conf.MaxBundles < 1 {
+		log.Warn("Sanitizing invalid bundlepool capacity", "provided", conf.MaxBundles, "updated", DefaultConfig.MaxBundles)
+		conf.MaxBundles = DefaultConfig.MaxBundles
+	}
+	if conf.MaxSenderBundles < 1 {
+		log.Warn("Sanitizing invalid bundlepool sender capacity", "provided", conf.MaxSenderBundles, "updated", DefaultConfig.MaxSenderBundles)
+		conf.MaxSenderBundles = DefaultConfig.MaxSenderBundles
+	}
+	if conf.MaxBundleTxs < 1 {
+		log.Warn("Sanitizing invalid bundlepool bundle size", "provided", conf.MaxBundleTxs, "updated", DefaultConfig.MaxBundleTxs)
+		conf.MaxBundleTxs = DefaultConfig.MaxBundleTxs
+	}
+	if conf.MaxBlocksAhead < 1 {
+		log.Warn("Sanitizing invalid bundlepool target distance", "provided", conf.MaxBlocksAhead, "updated", DefaultConfig.MaxBlocksAhead)
+		conf.MaxBlocksAhead = DefaultConfig.MaxBlocksAhead
+	}
+	return conf
+}
@@ -60,4 +60,12 @@ var (
 	// input transaction of non-blob type when a blob transaction from this sender
 	// remains pending (and vice-versa).
 	ErrAlreadyReserved = errors.New("address already reserved")
+
+	// ErrSenderRateLimited is returned if the sender of a transaction exceeded the
+	// admission rate allowed by the pool.
+	ErrSenderRateLimited = errors.New("sender rate limited")
+
+	// ErrBundlesNotSupported is returned if a bundle is submitted, but none of
+	// the subpools maintains bundles.
+	ErrBundlesNotSupported = errors.New("bundles not supported")
 )
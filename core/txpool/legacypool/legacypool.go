@@ -85,6 +85,7 @@ var (
 	invalidTxMeter     = metrics.NewRegisteredMeter("txpool/invalid", nil)
 	underpricedTxMeter = metrics.NewRegisteredMeter("txpool/underpriced", nil)
 	overflowedTxMeter  = metrics.NewRegisteredMeter("txpool/overflowed", nil)
+	rateLimitedTxMeter = metrics.NewRegisteredMeter("txpool/ratelimited", nil)
 
 	// throttleTxMeter counts how many transactions are rejected due to too-many-changes between
 	// txpool reorgs.
@@ -126,6 +127,10 @@ type Config struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	Persist        string        // Snapshot of the remote transactions to survive node restarts (disabled if empty)
+	PersistMaxSize uint64        // Maximum size of the remote transaction snapshot in bytes (0 = unlimited)
+	PersistMaxAge  time.Duration // Maximum age of a remote transaction snapshot to be reloaded
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -135,6 +140,9 @@ type Config struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	SenderRate  float64 // Remote transactions admitted per second from a single sender (0 = unlimited)
+	SenderBurst uint64  // Remote transactions admitted from a single sender in a burst
 }
 
 // DefaultConfig contains the default configurations for the transaction pool.
@@ -142,6 +150,9 @@ var DefaultConfig = Config{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	PersistMaxSize: 64 * 1024 * 1024,
+	PersistMaxAge:  3 * time.Hour,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -151,6 +162,8 @@ var DefaultConfig = Config{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	SenderBurst: 16,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -161,6 +174,18 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.SenderRate < 0 {
+		log.Warn("Sanitizing invalid txpool sender rate", "provided", conf.SenderRate, "updated", 0)
+		conf.SenderRate = 0
+	}
+	if conf.SenderRate > 0 && conf.SenderBurst < 1 {
+		log.Warn("Sanitizing invalid txpool sender burst", "provided", conf.SenderBurst, "updated", DefaultConfig.SenderBurst)
+		conf.SenderBurst = DefaultConfig.SenderBurst
+	}
+	if conf.Persist != "" && conf.PersistMaxSize != 0 && conf.PersistMaxSize < txMaxSize {
+		log.Warn("Sanitizing invalid txpool snapshot size", "provided", conf.PersistMaxSize, "updated", DefaultConfig.PersistMaxSize)
+		conf.PersistMaxSize = DefaultConfig.PersistMaxSize
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultConfig.PriceLimit)
 		conf.PriceLimit = DefaultConfig.PriceLimit
@@ -205,6 +230,9 @@ type LegacyPool struct {
 	chain       BlockChain
 	gasTip      atomic.Pointer[uint256.Int]
 	txFeed      event.Feed
+	dropFeed    event.Feed
+	drops       []*txpool.TxDrop         // Evictions to announce once the pool lock is released
+	included    map[common.Hash]struct{} // Transactions included by the chain during the reset, nil if unknown
 	signer      types.Signer
 	mu          sync.RWMutex
 
@@ -212,8 +240,10 @@ type LegacyPool struct {
 	currentState  *state.StateDB               // Current state in the blockchain head
 	pendingNonces *noncer                      // Pending state tracking virtual nonces
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *journal    // Journal of local transaction to back up to disk
+	locals  *accountSet    // Set of local transaction to exempt from eviction rules
+	journal *journal       // Journal of local transaction to back up to disk
+	persist *persister     // Snapshot of the remote transactions to back up to disk
+	limiter *senderLimiter // Admission rate limiter of the remote senders (nil if unlimited)
 
 	reserve txpool.AddressReserver       // Address reserver to ensure exclusivity across subpools
 	pending map[common.Address]*list     // All currently processable transactions
@@ -270,6 +300,12 @@ func New(config Config, chain BlockChain) *LegacyPool {
 	if !config.NoLocals && config.Journal != "" {
 		pool.journal = newTxJournal(config.Journal)
 	}
+	if config.SenderRate > 0 {
+		pool.limiter = newSenderLimiter(config.SenderRate, config.SenderBurst)
+	}
+	if config.Persist != "" {
+		pool.persist = newPersister(config.Persist, config.PersistMaxSize, config.PersistMaxAge)
+	}
 	return pool
 }
 
@@ -323,6 +359,14 @@ func (pool *LegacyPool) Init(gasTip uint64, head *types.Header, reserve txpool.A
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote transaction persistence is enabled, reload them from disk. They
+	// are revalidated against the current head, as any other remote transaction,
+	// but aren't throttled again as they were admitted before the restart.
+	if pool.persist != nil {
+		if err := pool.persist.load(pool.reloadRemotes); err != nil {
+			log.Warn("Failed to load remote transaction snapshot", "err", err)
+		}
+	}
 	pool.wg.Add(1)
 	go pool.loop()
 	return nil
@@ -378,12 +422,18 @@ func (pool *LegacyPool) loop() {
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
+						pool.recordDrop(tx, txpool.DropLifetime, nil)
 						pool.removeTx(tx.Hash(), true, true)
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
 			pool.mu.Unlock()
+			pool.sendDrops()
+
+			if pool.limiter != nil {
+				pool.limiter.prune()
+			}
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -394,6 +444,9 @@ func (pool *LegacyPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.persist != nil {
+				pool.saveRemotes()
+			}
 		}
 	}
 }
@@ -407,6 +460,9 @@ func (pool *LegacyPool) Close() error {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.persist != nil {
+		pool.saveRemotes()
+	}
 	log.Info("Transaction pool stopped")
 	return nil
 }
@@ -428,9 +484,46 @@ func (pool *LegacyPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs
 	return pool.txFeed.Subscribe(ch)
 }
 
+// SubscribeDropTxs registers a subscription for the events of transactions
+// leaving the pool without being included.
+func (pool *LegacyPool) SubscribeDropTxs(ch chan<- txpool.DropTxsEvent) event.Subscription {
+	return pool.dropFeed.Subscribe(ch)
+}
+
+// recordDrop records the eviction of a transaction, to be announced once the
+// pool lock is released.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) recordDrop(tx *types.Transaction, reason txpool.DropReason, replacement *types.Transaction) {
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	pool.drops = append(pool.drops, &txpool.TxDrop{
+		Hash:        tx.Hash(),
+		Tx:          tx,
+		From:        from,
+		Reason:      reason,
+		Replacement: replacement,
+	})
+}
+
+// sendDrops announces the evictions recorded since the last announcement.
+//
+// Note, this method assumes the pool lock is not held!
+func (pool *LegacyPool) sendDrops() {
+	pool.mu.Lock()
+	drops := pool.drops
+	pool.drops, pool.included = nil, nil
+	pool.mu.Unlock()
+
+	if len(drops) > 0 {
+		pool.dropFeed.Send(txpool.DropTxsEvent{Drops: drops})
+	}
+}
+
 // SetGasTip updates the minimum gas tip required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *LegacyPool) SetGasTip(tip *big.Int) {
+	defer pool.sendDrops()
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -444,6 +537,7 @@ func (pool *LegacyPool) SetGasTip(tip *big.Int) {
 		// pool.priced is sorted by GasFeeCap, so we have to iterate through pool.all instead
 		drop := pool.all.RemotesBelowTip(tip)
 		for _, tx := range drop {
+			pool.recordDrop(tx, txpool.DropUnderpriced, nil)
 			pool.removeTx(tx.Hash(), false, true)
 		}
 		pool.priced.Removed(len(drop))
@@ -599,6 +693,28 @@ func (pool *LegacyPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// saveRemotes regenerates the snapshot of the remote transactions on disk.
+func (pool *LegacyPool) saveRemotes() {
+	pool.mu.RLock()
+	pending := make(map[common.Address]types.Transactions)
+	for addr, list := range pool.pending {
+		if !pool.locals.contains(addr) {
+			pending[addr] = list.Flatten()
+		}
+	}
+	queued := make(map[common.Address]types.Transactions)
+	for addr, list := range pool.queue {
+		if !pool.locals.contains(addr) {
+			queued[addr] = list.Flatten()
+		}
+	}
+	pool.mu.RUnlock()
+
+	if err := pool.persist.save(pending, queued); err != nil {
+		log.Warn("Failed to save remote transaction snapshot", "err", err)
+	}
+}
+
 // validateTxBasics checks whether a transaction is valid according to the consensus
 // rules, but does not check state-dependent validation such as sufficient balance.
 // This check is meant as an early check which only needs to be performed once,
@@ -764,6 +880,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
 			underpricedTxMeter.Mark(1)
+			pool.recordDrop(tx, txpool.DropUnderpriced, nil)
 
 			sender, _ := types.Sender(pool.signer, tx)
 			dropped := pool.removeTx(tx.Hash(), false, sender != from) // Don't unreserve the sender of the tx being added if last from the acc
@@ -785,6 +902,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.recordDrop(old, txpool.DropReplaced, tx)
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
@@ -860,6 +978,7 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.recordDrop(old, txpool.DropReplaced, tx)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -909,6 +1028,7 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		pool.recordDrop(tx, txpool.DropUnderpriced, nil)
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -916,6 +1036,7 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.recordDrop(old, txpool.DropReplaced, tx)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
@@ -963,6 +1084,12 @@ func (pool *LegacyPool) addRemotesSync(txs []*types.Transaction) []error {
 	return pool.Add(txs, false, true)
 }
 
+// reloadRemotes is like addRemotesSync, but bypasses the sender rate limits. It's
+// used to reload the persisted remote transactions on startup.
+func (pool *LegacyPool) reloadRemotes(txs []*types.Transaction) []error {
+	return pool.addTxs(txs, false, true, false)
+}
+
 // This is like addRemotes with a single transaction, but waits for pool reorganization. Tests use this method.
 func (pool *LegacyPool) addRemoteSync(tx *types.Transaction) error {
 	return pool.Add([]*types.Transaction{tx}, false, true)[0]
@@ -974,6 +1101,12 @@ func (pool *LegacyPool) addRemoteSync(tx *types.Transaction) error {
 // If sync is set, the method will block until all internal maintenance related
 // to the add is finished. Only use this during tests for determinism!
 func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error {
+	return pool.addTxs(txs, local, sync, true)
+}
+
+// addTxs enqueues a batch of transactions into the pool like Add, throttling the
+// remote senders exceeding their admission rate if limited is set.
+func (pool *LegacyPool) addTxs(txs []*types.Transaction, local, sync, limited bool) []error {
 	// Do not treat as local if local transactions have been disabled
 	local = local && !pool.config.NoLocals
 
@@ -998,6 +1131,21 @@ func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error
 			invalidTxMeter.Mark(1)
 			continue
 		}
+		// Throttle the remote senders exceeding their admission rate
+		if limited && !local && pool.limiter != nil {
+			from, _ := types.Sender(pool.signer, tx) // already validated
+
+			pool.mu.RLock()
+			exempt := pool.locals.contains(from)
+			pool.mu.RUnlock()
+
+			if !exempt && !pool.limiter.allow(from) {
+				errs[i] = txpool.ErrSenderRateLimited
+				log.Trace("Discarding rate limited transaction", "hash", tx.Hash(), "from", from)
+				rateLimitedTxMeter.Mark(1)
+				continue
+			}
+		}
 		// Accumulate all unknown transactions for deeper processing
 		news = append(news, tx)
 	}
@@ -1318,8 +1466,15 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 
 	dropBetweenReorgHistogram.Update(int64(pool.changesSinceReorg))
 	pool.changesSinceReorg = 0 // Reset change counter
+	drops := pool.drops
+	pool.drops, pool.included = nil, nil
 	pool.mu.Unlock()
 
+	// Notify subsystems for evicted transactions
+	if len(drops) > 0 {
+		pool.dropFeed.Send(txpool.DropTxsEvent{Drops: drops})
+	}
+
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
 		addr, _ := types.Sender(pool.signer, tx)
@@ -1343,6 +1498,15 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 	// If we're reorging an old state, reinject all dropped transactions
 	var reinject types.Transactions
 
+	// Track the transactions included by the new chain segment, which leave the
+	// pool without being dropped. They are unknown if the segment is not loaded.
+	pool.included = nil
+
+	if oldHead != nil && newHead != nil && oldHead.Hash() == newHead.ParentHash {
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			pool.trackIncluded(block.Transactions())
+		}
+	}
 	if oldHead != nil && oldHead.Hash() != newHead.ParentHash {
 		// If the reorg is too deep, avoid doing it (will happen during fast sync)
 		oldNum := oldHead.Number.Uint64()
@@ -1407,6 +1571,8 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 						return
 					}
 				}
+				pool.trackIncluded(included)
+
 				lost := make([]*types.Transaction, 0, len(discarded))
 				for _, tx := range types.TxDifference(discarded, included) {
 					if pool.Filter(tx) {
@@ -1436,6 +1602,29 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 	pool.addTxsLocked(reinject, false)
 }
 
+// trackIncluded records the transactions included by the chain during a reset.
+func (pool *LegacyPool) trackIncluded(txs types.Transactions) {
+	pool.included = make(map[common.Hash]struct{}, len(txs))
+	for _, tx := range txs {
+		pool.included[tx.Hash()] = struct{}{}
+	}
+}
+
+// recordStale records the eviction of a transaction whose nonce was passed by
+// the account, unless the transaction itself was included by the chain. If the
+// included transactions are unknown, nothing is recorded, as most of the stale
+// transactions are the included ones.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) recordStale(tx *types.Transaction) {
+	if pool.included == nil {
+		return
+	}
+	if _, ok := pool.included[tx.Hash()]; !ok {
+		pool.recordDrop(tx, txpool.DropNonceTooLow, nil)
+	}
+}
+
 // promoteExecutables moves transactions that have become processable from the
 // future queue to the set of pending transactions. During this process, all
 // invalidated transactions (low nonce, low balance) are deleted.
@@ -1455,6 +1644,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 		for _, tx := range forwards {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.recordStale(tx)
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1462,6 +1652,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 		for _, tx := range drops {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.recordDrop(tx, txpool.DropUnpayable, nil)
 		}
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
@@ -1484,6 +1675,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
+				pool.recordDrop(tx, txpool.DropOverflow, nil)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 			queuedRateLimitMeter.Mark(int64(len(caps)))
@@ -1549,6 +1741,7 @@ func (pool *LegacyPool) truncatePending() {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
+						pool.recordDrop(tx, txpool.DropOverflow, nil)
 
 						// Update the account nonce to the dropped transaction
 						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
@@ -1576,6 +1769,7 @@ func (pool *LegacyPool) truncatePending() {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
 					pool.all.Remove(hash)
+					pool.recordDrop(tx, txpool.DropOverflow, nil)
 
 					// Update the account nonce to the dropped transaction
 					pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1622,6 +1816,7 @@ func (pool *LegacyPool) truncateQueue() {
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
+				pool.recordDrop(tx, txpool.DropOverflow, nil)
 				pool.removeTx(tx.Hash(), true, true)
 			}
 			drop -= size
@@ -1631,6 +1826,7 @@ func (pool *LegacyPool) truncateQueue() {
 		// Otherwise drop only last few transactions
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+			pool.recordDrop(txs[i], txpool.DropOverflow, nil)
 			pool.removeTx(txs[i].Hash(), true, true)
 			drop--
 			queuedRateLimitMeter.Mark(1)
@@ -1656,6 +1852,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 		for _, tx := range olds {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.recordStale(tx)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1664,6 +1861,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
+			pool.recordDrop(tx, txpool.DropUnpayable, nil)
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
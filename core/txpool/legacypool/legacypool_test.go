@@ -24,6 +24,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -2671,3 +2672,280 @@ func BenchmarkMultiAccountBatchInsert(b *testing.B) {
 		pool.addRemotesSync([]*types.Transaction{tx})
 	}
 }
+
+// Tests that remote transactions are persisted to disk if enabled, and that they
+// are revalidated against the current head when reloaded.
+func TestPersistRemotes(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "remotes.rlp")
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.NoLocals = true
+	config.Persist = path
+
+	pool := New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+
+	// Add two pending and a queued remote transactions of two accounts
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key1.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(key2.PublicKey), big.NewInt(1000000000))
+
+	for _, tx := range []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), key1),
+		pricedTransaction(1, 100000, big.NewInt(1), key1),
+		pricedTransaction(2, 100000, big.NewInt(1), key2),
+	} {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add remote transaction: %v", err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 2, 1)
+	}
+	// Restart the pool with the first transaction included, ensure the rest survive
+	pool.Close()
+	statedb.SetNonce(crypto.PubkeyToAddress(key1.PublicKey), 1)
+	blockchain = newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	pool = New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 1, 1)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	pool.Close()
+
+	// Restart the pool with a stale snapshot, ensure it's discarded
+	config.PersistMaxAge = time.Nanosecond
+	time.Sleep(time.Second)
+
+	pool = New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 0, 0)
+	}
+}
+
+// Tests that the remote transactions of a sender are throttled if it exceeds the
+// configured admission rate, but local ones are exempt.
+func TestSenderRateLimit(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.SenderRate = 0.001
+	config.SenderBurst = 2
+
+	pool := New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000000000))
+
+	// Replacements consume the allowance the same way as new transactions
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(2), key)); err != nil {
+		t.Fatalf("failed to replace remote transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(1, 100000, big.NewInt(1), key)); !errors.Is(err, txpool.ErrSenderRateLimited) {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+	if err := pool.addLocal(pricedTransaction(1, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), other)); err != nil {
+		t.Fatalf("failed to add remote transaction of other sender: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	}
+}
+
+// Tests that evicted transactions are announced along with the reason of their
+// eviction.
+func TestDropEvents(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	drops := make(chan txpool.DropTxsEvent, 16)
+	sub := pool.SubscribeDropTxs(drops)
+	defer sub.Unsubscribe()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	// Replace a pending transaction and ensure the replacement is announced
+	old := pricedTransaction(0, 100000, big.NewInt(1), key)
+	replacement := pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.addRemoteSync(old); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(replacement); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	checkDrop := func(tx *types.Transaction, reason txpool.DropReason, replacement *types.Transaction) {
+		t.Helper()
+		select {
+		case ev := <-drops:
+			if len(ev.Drops) != 1 {
+				t.Fatalf("dropped transaction count mismatch: have %d, want %d", len(ev.Drops), 1)
+			}
+			drop := ev.Drops[0]
+			if drop.Tx.Hash() != tx.Hash() || drop.From != from || drop.Reason != reason {
+				t.Fatalf("drop mismatch: have %x/%v, want %x/%v", drop.Tx.Hash(), drop.Reason, tx.Hash(), reason)
+			}
+			if (drop.Replacement == nil) != (replacement == nil) || (replacement != nil && drop.Replacement.Hash() != replacement.Hash()) {
+				t.Fatalf("replacement mismatch: have %v, want %v", drop.Replacement, replacement)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("drop event timeout")
+		}
+	}
+	checkDrop(old, txpool.DropReplaced, replacement)
+
+	// Raise the minimum tip and ensure the eviction is announced
+	pool.SetGasTip(big.NewInt(3))
+	checkDrop(replacement, txpool.DropUnderpriced, nil)
+}
+
+// includingBlockChain is a test chain serving a single block besides the empty
+// ones of the test chain, to simulate the inclusion of transactions.
+type includingBlockChain struct {
+	*testBlockChain
+	block *types.Block
+}
+
+func (bc *includingBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if hash == bc.block.Hash() {
+		return bc.block
+	}
+	return bc.testBlockChain.GetBlock(hash, number)
+}
+
+// Tests that transactions leaving the pool by being included are not announced
+// as dropped, contrary to the ones whose nonce is taken by another transaction.
+func TestDropEventsInclusion(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	chain := &includingBlockChain{testBlockChain: newTestBlockChain(params.TestChainConfig, 10000000, statedb, new(event.Feed))}
+
+	pool := New(testTxPoolConfig, chain)
+	if err := pool.Init(testTxPoolConfig.PriceLimit, chain.CurrentBlock(), makeAddressReserver()); err != nil {
+		t.Fatalf("failed to init pool: %v", err)
+	}
+	defer pool.Close()
+
+	drops := make(chan txpool.DropTxsEvent, 16)
+	sub := pool.SubscribeDropTxs(drops)
+	defer sub.Unsubscribe()
+
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	addr1, addr2 := crypto.PubkeyToAddress(key1.PublicKey), crypto.PubkeyToAddress(key2.PublicKey)
+	testAddBalance(pool, addr1, big.NewInt(1000000000))
+	testAddBalance(pool, addr2, big.NewInt(1000000000))
+
+	var (
+		included = []*types.Transaction{transaction(0, 100000, key1), transaction(1, 100000, key1)}
+		stale    = transaction(0, 100000, key2)
+		conflict = pricedTransaction(0, 100000, big.NewInt(2), key2)
+	)
+	for _, err := range pool.addRemotesSync(append(included, stale)) {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	// Include the transactions of the first account, and another transaction
+	// of the second account with the nonce of the pooled one
+	head := chain.CurrentBlock()
+	chain.block = types.NewBlock(&types.Header{Number: big.NewInt(1), ParentHash: head.Hash(), GasLimit: head.GasLimit, BaseFee: big.NewInt(1)}, &types.Body{Transactions: append(included, conflict)}, nil, trie.NewStackTrie(nil))
+
+	testSetNonce(pool, addr1, 2)
+	testSetNonce(pool, addr2, 1)
+	<-pool.requestReset(head, chain.block.Header())
+
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("pool not emptied: %d pending, %d queued", pending, queued)
+	}
+	select {
+	case ev := <-drops:
+		if len(ev.Drops) != 1 {
+			t.Fatalf("dropped transaction count mismatch: have %d, want %d", len(ev.Drops), 1)
+		}
+		if drop := ev.Drops[0]; drop.Hash != stale.Hash() || drop.From != addr2 || drop.Reason != txpool.DropNonceTooLow {
+			t.Fatalf("drop mismatch: have %x/%v, want %x/%v", drop.Hash, drop.Reason, stale.Hash(), txpool.DropNonceTooLow)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("drop event timeout")
+	}
+	select {
+	case ev := <-drops:
+		t.Fatalf("unexpected drop event: %d drops", len(ev.Drops))
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// Tests that the persisted remote transactions are reloaded on startup without
+// being throttled by the sender rate limits.
+func TestPersistRemotesRateLimit(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.NoLocals = true
+	config.Persist = filepath.Join(t.TempDir(), "remotes.rlp")
+
+	pool := New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	for i := uint64(0); i < 3; i++ {
+		if err := pool.addRemoteSync(pricedTransaction(i, 100000, big.NewInt(1), key)); err != nil {
+			t.Fatalf("failed to add remote transaction: %v", err)
+		}
+	}
+	pool.Close()
+
+	// Restart the pool with a rate limit below the number of persisted transactions
+	config.SenderRate = 0.001
+	config.SenderBurst = 1
+
+	pool = New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	if pending, _ := pool.Stats(); pending != 3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	}
+	// New transactions of the sender are still throttled
+	if err := pool.addRemoteSync(pricedTransaction(3, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(4, 100000, big.NewInt(1), key)); !errors.Is(err, txpool.ErrSenderRateLimited) {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+}
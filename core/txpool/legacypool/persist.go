@@ -0,0 +1,186 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package legacypool
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// persistVersion is the version of the remote transaction snapshot format.
+const persistVersion = 1
+
+// persistHeader is the leading item of the remote transaction snapshot.
+type persistHeader struct {
+	Version uint64
+	Time    uint64 // Unix timestamp the snapshot was taken at
+}
+
+// persister maintains a snapshot of the remote transactions of the pool on
+// disk, allowing the pending and queued sets to survive node restarts instead
+// of waiting for them to be gossiped again. Contrary to the local journal, the
+// snapshot is not appended to as transactions arrive, it's regenerated on every
+// rejournal interval and when the pool is closed.
+type persister struct {
+	path    string        // Filesystem path to store the transactions at
+	maxSize uint64        // Maximum size of the snapshot in bytes
+	maxAge  time.Duration // Maximum age of a snapshot to be reloaded
+}
+
+// newPersister creates a new remote transaction snapshot handler.
+func newPersister(path string, maxSize uint64, maxAge time.Duration) *persister {
+	return &persister{
+		path:    path,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+	}
+}
+
+// load parses the remote transaction snapshot from disk, loading its contents
+// into the specified pool. Snapshots that are too old are discarded.
+func (p *persister) load(add func([]*types.Transaction) []error) error {
+	input, err := os.Open(p.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	stream := rlp.NewStream(input, p.maxSize)
+
+	var head persistHeader
+	if err := stream.Decode(&head); err != nil {
+		return fmt.Errorf("invalid snapshot header: %v", err)
+	}
+	if head.Version != persistVersion {
+		return fmt.Errorf("unsupported snapshot version %d", head.Version)
+	}
+	age := time.Since(time.Unix(int64(head.Time), 0))
+	if p.maxAge > 0 && age > p.maxAge {
+		log.Info("Discarded stale remote transaction snapshot", "age", common.PrettyDuration(age), "limit", p.maxAge)
+		return nil
+	}
+	var (
+		total, dropped int
+		failure        error
+		batch          types.Transactions
+	)
+	loadBatch := func(txs types.Transactions) {
+		for _, err := range add(txs) {
+			if err != nil {
+				log.Trace("Failed to add persisted transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	for {
+		tx := new(types.Transaction)
+		if err = stream.Decode(tx); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			if batch.Len() > 0 {
+				loadBatch(batch)
+			}
+			break
+		}
+		total++
+
+		if batch = append(batch, tx); batch.Len() > 1024 {
+			loadBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	log.Info("Loaded remote transaction snapshot", "transactions", total, "dropped", dropped, "age", common.PrettyDuration(age))
+	return failure
+}
+
+// save regenerates the remote transaction snapshot from the given pending and
+// queued transactions. The pending transactions are written first, so if the
+// size limit is reached, the executable ones are retained preferentially.
+func (p *persister) save(pending, queued map[common.Address]types.Transactions) error {
+	output, err := os.OpenFile(p.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	var (
+		buf   = bufio.NewWriter(output)
+		size  uint64
+		saved int
+		full  bool
+	)
+	write := func(val interface{}) error {
+		blob, err := rlp.EncodeToBytes(val)
+		if err != nil {
+			return err
+		}
+		if p.maxSize > 0 && size+uint64(len(blob)) > p.maxSize {
+			full = true
+			return nil
+		}
+		size += uint64(len(blob))
+		_, err = buf.Write(blob)
+		return err
+	}
+	err = write(&persistHeader{Version: persistVersion, Time: uint64(time.Now().Unix())})
+	for _, set := range []map[common.Address]types.Transactions{pending, queued} {
+		for _, txs := range set {
+			// Transactions of an account are ordered by nonce, stop at the first
+			// one which doesn't fit to avoid leaving nonce gaps.
+			for _, tx := range txs {
+				if err != nil || full {
+					break
+				}
+				if err = write(tx); err == nil && !full {
+					saved++
+				}
+			}
+			full = false
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err == nil {
+		err = output.Sync()
+	}
+	output.Close()
+	if err != nil {
+		os.Remove(p.path + ".new")
+		return err
+	}
+	if err = os.Rename(p.path+".new", p.path); err != nil {
+		return err
+	}
+	log.Debug("Regenerated remote transaction snapshot", "transactions", saved, "size", common.StorageSize(size))
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package legacypool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/time/rate"
+)
+
+// senderLimiter is a token bucket rate limiter of the transaction admissions,
+// tracked per sender account. Every admission attempt consumes a token, so
+// senders churning the pool by repeatedly replacing their transactions are
+// throttled the same way as the ones flooding it with new transactions.
+type senderLimiter struct {
+	rate  rate.Limit
+	burst int
+
+	limiters map[common.Address]*rate.Limiter
+	lock     sync.Mutex
+}
+
+// newSenderLimiter creates a rate limiter admitting the given number of
+// transactions per second from a single sender, with the given burst allowance.
+func newSenderLimiter(limit float64, burst uint64) *senderLimiter {
+	return &senderLimiter{
+		rate:     rate.Limit(limit),
+		burst:    int(burst),
+		limiters: make(map[common.Address]*rate.Limiter),
+	}
+}
+
+// allow reports whether a transaction of the given sender may be admitted,
+// consuming a token from the sender's bucket if so.
+func (l *senderLimiter) allow(addr common.Address) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	limiter := l.limiters[addr]
+	if limiter == nil {
+		limiter = rate.NewLimiter(l.rate, l.burst)
+		l.limiters[addr] = limiter
+	}
+	return limiter.Allow()
+}
+
+// prune drops the buckets which are full again, they are indistinguishable from
+// the buckets of the senders not seen yet.
+func (l *senderLimiter) prune() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	for addr, limiter := range l.limiters {
+		if limiter.TokensAt(now) >= float64(l.burst) {
+			delete(l.limiters, addr)
+		}
+	}
+}
@@ -17,13 +17,16 @@
 package txpool
 
 import (
+	"fmt"
 	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/holiman/uint256"
 )
 
@@ -140,6 +143,10 @@ type SubPool interface {
 	// or also for reorged out ones.
 	SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool) event.Subscription
 
+	// SubscribeDropTxs subscribes to events of transactions leaving the pool
+	// without being included, along with the reason of their eviction.
+	SubscribeDropTxs(ch chan<- DropTxsEvent) event.Subscription
+
 	// Nonce returns the next nonce of an account, with all transactions executable
 	// by the pool already applied on top.
 	Nonce(addr common.Address) uint64
@@ -163,3 +170,114 @@ type SubPool interface {
 	// identified by their hashes.
 	Status(hash common.Hash) TxStatus
 }
+
+// DropReason is the reason of a transaction leaving the pool.
+type DropReason uint8
+
+const (
+	DropReplaced    DropReason = iota // Replaced by a transaction with the same nonce
+	DropUnderpriced                   // Evicted by better paying transactions, or below the minimum tip
+	DropLifetime                      // Non-executable for longer than the allowed lifetime
+	DropNonceTooLow                   // Account nonce moved past the transaction without including it, e.g. after a reorg
+	DropUnpayable                     // Insufficient funds of the sender, or exceeding the block gas limit
+	DropOverflow                      // Exceeding the account or global slot limits of the pool
+	DropGapped                        // Nonce gap left by an earlier eviction in a pool not queueing transactions
+)
+
+var dropReasonNames = [...]string{
+	DropReplaced:    "replaced",
+	DropUnderpriced: "underpriced",
+	DropLifetime:    "lifetime",
+	DropNonceTooLow: "nonceTooLow",
+	DropUnpayable:   "unpayable",
+	DropOverflow:    "overflow",
+	DropGapped:      "gapped",
+}
+
+// String implements fmt.Stringer.
+func (r DropReason) String() string {
+	if int(r) < len(dropReasonNames) {
+		return dropReasonNames[r]
+	}
+	return fmt.Sprintf("unknown(%d)", r)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r DropReason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// TxDrop is a transaction which left the pool without being included.
+type TxDrop struct {
+	Hash        common.Hash        // Hash of the transaction evicted from the pool
+	Tx          *types.Transaction // Transaction evicted, nil if not retained in memory by the pool
+	From        common.Address     // Sender of the transaction
+	Reason      DropReason         // Reason of the eviction
+	Replacement *types.Transaction // Transaction superseding the evicted one, if replaced
+}
+
+// DropTxsEvent is posted when transactions leave the pool without being included.
+type DropTxsEvent struct {
+	Drops []*TxDrop
+}
+
+// Bundle is an ordered list of transactions which need to be included atomically
+// at the top of a specific block. Bundles are private, they are never announced
+// to the network.
+type Bundle struct {
+	Txs          []*types.Transaction // Transactions to include, in order
+	BlockNumber  uint64               // Number of the block the bundle targets
+	MinTimestamp uint64               // Minimum timestamp of the block, 0 if unbounded
+	MaxTimestamp uint64               // Maximum timestamp of the block, 0 if unbounded
+
+	RevertingTxHashes []common.Hash // Transactions allowed to revert without invalidating the bundle
+}
+
+// Hash returns the identifier of the bundle, the hash of its transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]common.Hash, len(b.Txs))
+	for i, tx := range b.Txs {
+		hashes[i] = tx.Hash()
+	}
+	blob, _ := rlp.EncodeToBytes(hashes)
+	return crypto.Keccak256Hash(blob)
+}
+
+// CanRevert returns whether the transaction with the given hash is allowed to
+// revert without invalidating the bundle.
+func (b *Bundle) CanRevert(hash common.Hash) bool {
+	for _, h := range b.RevertingTxHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Includable returns whether the bundle may be included in a block with the
+// given number and timestamp.
+func (b *Bundle) Includable(number uint64, time uint64) bool {
+	if b.BlockNumber != number {
+		return false
+	}
+	if b.MinTimestamp != 0 && time < b.MinTimestamp {
+		return false
+	}
+	if b.MaxTimestamp != 0 && time > b.MaxTimestamp {
+		return false
+	}
+	return true
+}
+
+// BundleSubPool is a specialized subpool which also maintains private bundles
+// of transactions for block production.
+type BundleSubPool interface {
+	SubPool
+
+	// AddBundle validates a bundle and adds it to the pool.
+	AddBundle(bundle *Bundle) error
+
+	// Bundles retrieves the bundles includable in a block with the given number
+	// and timestamp, in the order they should be included.
+	Bundles(number uint64, time uint64) []*Bundle
+}
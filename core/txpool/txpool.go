@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -49,6 +50,19 @@ var (
 	reservationsGaugeName = "txpool/reservations"
 )
 
+// maxReplacements is the number of most recent transaction replacements kept
+// in the replacement history.
+const maxReplacements = 4096
+
+// Replacement is a record of a transaction replaced by another one with the
+// same nonce.
+type Replacement struct {
+	From common.Address     // Sender of the transactions
+	Old  *types.Transaction // Transaction which got replaced
+	New  *types.Transaction // Transaction replacing the old one
+	Time time.Time          // Time of the replacement
+}
+
 // BlockChain defines the minimal set of methods needed to back a tx pool with
 // a chain. Exists to allow mocking the live chain out of tests.
 type BlockChain interface {
@@ -75,6 +89,9 @@ type TxPool struct {
 	term chan struct{}           // Termination channel to detect a closed pool
 
 	sync chan chan error // Testing / simulator channel to block until internal reset is done
+
+	replacements []*Replacement // Most recent transaction replacements, oldest first
+	replaceLock  sync.RWMutex   // Lock protecting the replacement history
 }
 
 // New creates a new transaction pool to gather, sort and filter inbound
@@ -100,10 +117,48 @@ func New(gasTip uint64, chain BlockChain, subpools []SubPool) (*TxPool, error) {
 			return nil, err
 		}
 	}
+	// Subscribe to the evictions before any transaction arrives to keep track
+	// of the replacement history
+	drops := make(chan DropTxsEvent, 16)
+	go pool.recordReplacements(drops, pool.SubscribeDropTxs(drops))
+
 	go pool.loop(head, chain)
 	return pool, nil
 }
 
+// recordReplacements maintains the history of the transaction replacements,
+// until the subscription is terminated on shutdown.
+func (p *TxPool) recordReplacements(ch chan DropTxsEvent, sub event.Subscription) {
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-ch:
+			now := time.Now()
+
+			p.replaceLock.Lock()
+			for _, drop := range ev.Drops {
+				if drop.Reason != DropReplaced || drop.Tx == nil || drop.Replacement == nil {
+					continue
+				}
+				p.replacements = append(p.replacements, &Replacement{
+					From: drop.From,
+					Old:  drop.Tx,
+					New:  drop.Replacement,
+					Time: now,
+				})
+			}
+			if overflow := len(p.replacements) - maxReplacements; overflow > 0 {
+				p.replacements = append(p.replacements[:0], p.replacements[overflow:]...)
+			}
+			p.replaceLock.Unlock()
+
+		case <-sub.Err():
+			return
+		}
+	}
+}
+
 // reserver is a method to create an address reservation callback to exclusively
 // assign/deassign addresses to/from subpools. This can ensure that at any point
 // in time, only a single subpool is able to manage an account, avoiding cross
@@ -351,6 +406,29 @@ func (p *TxPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
 	return errs
 }
 
+// AddBundle validates a bundle and adds it to the subpool maintaining bundles.
+// Bundles are private, the contained transactions are not announced.
+func (p *TxPool) AddBundle(bundle *Bundle) error {
+	for _, subpool := range p.subpools {
+		if pool, ok := subpool.(BundleSubPool); ok {
+			return pool.AddBundle(bundle)
+		}
+	}
+	return ErrBundlesNotSupported
+}
+
+// Bundles retrieves the bundles includable in a block with the given number and
+// timestamp, in the order they should be included.
+func (p *TxPool) Bundles(number uint64, time uint64) []*Bundle {
+	var bundles []*Bundle
+	for _, subpool := range p.subpools {
+		if pool, ok := subpool.(BundleSubPool); ok {
+			bundles = append(bundles, pool.Bundles(number, time)...)
+		}
+	}
+	return bundles
+}
+
 // Pending retrieves all currently processable transactions, grouped by origin
 // account and sorted by nonce.
 //
@@ -376,6 +454,31 @@ func (p *TxPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool)
 	return p.subs.Track(event.JoinSubscriptions(subs...))
 }
 
+// SubscribeDropTxs registers a subscription for the events of transactions
+// leaving the pool without being included.
+func (p *TxPool) SubscribeDropTxs(ch chan<- DropTxsEvent) event.Subscription {
+	subs := make([]event.Subscription, len(p.subpools))
+	for i, subpool := range p.subpools {
+		subs[i] = subpool.SubscribeDropTxs(ch)
+	}
+	return p.subs.Track(event.JoinSubscriptions(subs...))
+}
+
+// Replacements retrieves the most recent transaction replacements, oldest first,
+// optionally filtered by the sender account.
+func (p *TxPool) Replacements(from *common.Address) []*Replacement {
+	p.replaceLock.RLock()
+	defer p.replaceLock.RUnlock()
+
+	replacements := make([]*Replacement, 0, len(p.replacements))
+	for _, replacement := range p.replacements {
+		if from == nil || replacement.From == *from {
+			replacements = append(replacements, replacement)
+		}
+	}
+	return replacements
+}
+
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (p *TxPool) Nonce(addr common.Address) uint64 {
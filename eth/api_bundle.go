@@ -0,0 +1,261 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BundleAPI provides an API to submit and simulate private transaction bundles.
+// The bundles are kept by the bundle pool, they are never broadcast to the
+// network, only included by the local miner.
+type BundleAPI struct {
+	eth *Ethereum
+}
+
+// NewBundleAPI creates a new BundleAPI instance.
+func NewBundleAPI(eth *Ethereum) *BundleAPI {
+	return &BundleAPI{eth}
+}
+
+// SendBundleArgs represents the arguments of eth_sendBundle.
+type SendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	MinTimestamp      *hexutil.Uint64 `json:"minTimestamp"`
+	MaxTimestamp      *hexutil.Uint64 `json:"maxTimestamp"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// SendBundleResult is the response of eth_sendBundle.
+type SendBundleResult struct {
+	BundleHash common.Hash `json:"bundleHash"`
+}
+
+// decodeBundleTxs decodes the binary encoded transactions of a bundle.
+func decodeBundleTxs(encoded []hexutil.Bytes) ([]*types.Transaction, error) {
+	if len(encoded) == 0 {
+		return nil, errors.New("bundle missing txs")
+	}
+	txs := make([]*types.Transaction, len(encoded))
+	for i, blob := range encoded {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(blob); err != nil {
+			return nil, fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		txs[i] = tx
+	}
+	return txs, nil
+}
+
+// SendBundle adds an atomic bundle of transactions to the bundle pool, to be
+// included at the top of the target block if all of its transactions succeed,
+// excluding the ones explicitly allowed to revert. If no target block number
+// is specified, the bundle targets the next block.
+func (api *BundleAPI) SendBundle(ctx context.Context, args SendBundleArgs) (*SendBundleResult, error) {
+	txs, err := decodeBundleTxs(args.Txs)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &txpool.Bundle{
+		Txs:               txs,
+		BlockNumber:       uint64(args.BlockNumber),
+		RevertingTxHashes: args.RevertingTxHashes,
+	}
+	if bundle.BlockNumber == 0 {
+		bundle.BlockNumber = api.eth.blockchain.CurrentBlock().Number.Uint64() + 1
+	}
+	if args.MinTimestamp != nil {
+		bundle.MinTimestamp = uint64(*args.MinTimestamp)
+	}
+	if args.MaxTimestamp != nil {
+		bundle.MaxTimestamp = uint64(*args.MaxTimestamp)
+	}
+	if err := api.eth.txPool.AddBundle(bundle); err != nil {
+		return nil, err
+	}
+	return &SendBundleResult{BundleHash: bundle.Hash()}, nil
+}
+
+// CallBundleArgs represents the arguments of eth_callBundle.
+type CallBundleArgs struct {
+	Txs              []hexutil.Bytes       `json:"txs"`
+	BlockNumber      *hexutil.Uint64       `json:"blockNumber"`
+	StateBlockNumber rpc.BlockNumberOrHash `json:"stateBlockNumber"`
+	Coinbase         *common.Address       `json:"coinbase"`
+	Timestamp        *hexutil.Uint64       `json:"timestamp"`
+	GasLimit         *hexutil.Uint64       `json:"gasLimit"`
+	BaseFee          *hexutil.Big          `json:"baseFee"`
+	BeaconRoot       *common.Hash          `json:"beaconRoot"`
+}
+
+// CallBundleTxResult is the outcome of a single transaction of a simulated bundle.
+type CallBundleTxResult struct {
+	TxHash  common.Hash    `json:"txHash"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Value   hexutil.Bytes  `json:"value,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Revert  string         `json:"revert,omitempty"`
+}
+
+// CallBundleResult is the response of eth_callBundle.
+type CallBundleResult struct {
+	BundleHash       common.Hash          `json:"bundleHash"`
+	Results          []CallBundleTxResult `json:"results"`
+	TotalGasUsed     hexutil.Uint64       `json:"totalGasUsed"`
+	CoinbaseDiff     *hexutil.Big         `json:"coinbaseDiff"`
+	StateBlockNumber hexutil.Uint64       `json:"stateBlockNumber"`
+}
+
+// CallBundle simulates a bundle of transactions on top of the given state
+// block, in a block built with the given parameters, defaulting to the ones
+// of the block following the state block. Transactions that fail or revert
+// are reported, but don't abort the simulation, only invalid transactions do.
+func (api *BundleAPI) CallBundle(ctx context.Context, args CallBundleArgs) (*CallBundleResult, error) {
+	txs, err := decodeBundleTxs(args.Txs)
+	if err != nil {
+		return nil, err
+	}
+	if args.StateBlockNumber.BlockNumber == nil && args.StateBlockNumber.BlockHash == nil {
+		args.StateBlockNumber = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	}
+	statedb, parent, err := api.eth.APIBackend.StateAndHeaderByNumberOrHash(ctx, args.StateBlockNumber)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	// Assemble the header of the block the bundle is simulated in
+	config := api.eth.blockchain.Config()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 12,
+		Coinbase:   parent.Coinbase,
+		Difficulty: new(big.Int),
+	}
+	if args.BlockNumber != nil {
+		header.Number = new(big.Int).SetUint64(uint64(*args.BlockNumber))
+	}
+	if args.Timestamp != nil {
+		header.Time = uint64(*args.Timestamp)
+	}
+	if args.GasLimit != nil {
+		header.GasLimit = uint64(*args.GasLimit)
+	}
+	if args.Coinbase != nil {
+		header.Coinbase = *args.Coinbase
+	}
+	if config.IsLondon(header.Number) {
+		header.BaseFee = eip1559.CalcBaseFee(config, parent)
+	}
+	if args.BaseFee != nil {
+		header.BaseFee = args.BaseFee.ToInt()
+	}
+	if config.IsCancun(header.Number, header.Time) {
+		var excess, used uint64
+		if parent.ExcessBlobGas != nil {
+			excess, used = *parent.ExcessBlobGas, *parent.BlobGasUsed
+		}
+		header.ExcessBlobGas = new(uint64)
+		*header.ExcessBlobGas = eip4844.CalcExcessBlobGas(excess, used)
+
+		// The beacon root of the block isn't known ahead of time, so it's zero
+		// unless specified.
+		header.ParentBeaconRoot = new(common.Hash)
+		if args.BeaconRoot != nil {
+			*header.ParentBeaconRoot = *args.BeaconRoot
+		}
+	}
+	// Setup the context so it may be cancelled when the call has completed or
+	// in case of an unmetered gas call, a timeout.
+	var cancel context.CancelFunc
+	if timeout := api.eth.config.RPCEVMTimeout; timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	var (
+		signer    = types.MakeSigner(config, header.Number, header.Time)
+		blockCtx  = core.NewEVMBlockContext(header, api.eth.blockchain, nil)
+		gp        = new(core.GasPool).AddGas(header.GasLimit)
+		coinbase  = statedb.GetBalance(header.Coinbase).ToBig()
+		evm       = vm.NewEVM(blockCtx, vm.TxContext{}, statedb, config, vm.Config{})
+		totalUsed uint64
+		results   = make([]CallBundleTxResult, 0, len(txs))
+	)
+	// Abort the simulation if the context is done
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	// Apply the system calls preceding the transactions, like the block would
+	if header.ParentBeaconRoot != nil {
+		core.ProcessBeaconBlockRoot(*header.ParentBeaconRoot, evm, statedb)
+	}
+	for i, tx := range txs {
+		msg, err := core.TransactionToMessage(tx, signer, header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%x): %v", i, tx.Hash(), err)
+		}
+		statedb.SetTxContext(tx.Hash(), i)
+		evm.Reset(core.NewEVMTxContext(msg), statedb)
+
+		res, err := core.ApplyMessage(evm, msg, gp)
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", api.eth.config.RPCEVMTimeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%x): %v", i, tx.Hash(), err)
+		}
+		statedb.Finalise(config.IsEIP158(header.Number))
+		totalUsed += res.UsedGas
+
+		result := CallBundleTxResult{TxHash: tx.Hash(), GasUsed: hexutil.Uint64(res.UsedGas)}
+		if res.Err != nil {
+			result.Error = res.Err.Error()
+			if revert := res.Revert(); len(revert) > 0 {
+				result.Revert = hexutil.Encode(revert)
+			}
+		} else {
+			result.Value = res.Return()
+		}
+		results = append(results, result)
+	}
+	diff := new(big.Int).Sub(statedb.GetBalance(header.Coinbase).ToBig(), coinbase)
+	return &CallBundleResult{
+		BundleHash:       (&txpool.Bundle{Txs: txs}).Hash(),
+		Results:          results,
+		TotalGasUsed:     hexutil.Uint64(totalUsed),
+		CoinbaseDiff:     (*hexutil.Big)(diff),
+		StateBlockNumber: hexutil.Uint64(parent.Number.Uint64()),
+	}, nil
+}
@@ -18,6 +18,7 @@ package eth
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -28,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers/live"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -129,6 +131,22 @@ func (api *DebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs, error)
 	return results, nil
 }
 
+// GetBlockStateDiff returns the prestate and poststate of the state modified by
+// the given block, as recorded by the statediff live tracer during the import.
+func (api *DebugAPI) GetBlockStateDiff(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*live.BlockStateDiff, error) {
+	if !live.MuxTracerIncludes(api.eth.config.VMTrace, json.RawMessage(api.eth.config.VMTraceJsonConfig), "statediff") {
+		return nil, live.ErrStateDiffDisabled
+	}
+	header, err := api.eth.APIBackend.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("block not found")
+	}
+	return live.ReadBlockStateDiff(api.eth.ChainDb(), header.Number.Uint64(), header.Hash())
+}
+
 // AccountRangeMaxResults is the maximum number of results to be returned per call
 const AccountRangeMaxResults = 256
 
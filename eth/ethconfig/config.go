@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
@@ -52,6 +53,8 @@ var Defaults = Config{
 	NetworkId:          0, // enable auto configuration of networkID == chainID
 	TxLookupLimit:      2350000,
 	TransactionHistory: 2350000,
+	LogHistory:         2350000,
+	LogIndex:           true,
 	StateHistory:       params.FullImmutabilityThreshold,
 	LightPeers:         100,
 	DatabaseCache:      512,
@@ -63,10 +66,12 @@ var Defaults = Config{
 	Miner:              miner.DefaultConfig,
 	TxPool:             legacypool.DefaultConfig,
 	BlobPool:           blobpool.DefaultConfig,
+	BundlePool:         bundlepool.DefaultConfig,
 	RPCGasCap:          50000000,
 	RPCEVMTimeout:      5 * time.Second,
 	GPO:                FullNodeGPO,
 	RPCTxFeeCap:        1, // 1 ether
+	TraceFilterRange:   1000,
 }
 
 //go:generate go run github.com/fjl/gencodec -type Config -formats toml -out gen_config.go
@@ -94,6 +99,8 @@ type Config struct {
 	TxLookupLimit      uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	TransactionHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	StateHistory       uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state histories are reserved.
+	LogHistory         uint64 `toml:",omitempty"` // The maximum number of blocks from head whose log indices are reserved.
+	LogIndex           bool   `toml:",omitempty"` // Whether to maintain the persistent log index.
 
 	// State scheme represents the scheme used to store ethereum states and trie
 	// nodes on top. It can be 'hash', 'path', or none which means use the scheme
@@ -135,6 +142,15 @@ type Config struct {
 	TxPool   legacypool.Config
 	BlobPool blobpool.Config
 
+	// Transaction admission limits of the remote peers
+	TxPeerRate      float64 // Transactions admitted per second from a single peer (0 = unlimited)
+	TxPeerBurst     int     // Transactions admitted from a single peer in a burst
+	TxPeerDropScore int     // Negative reputation score below which a peer is dropped and banned (0 = never)
+
+	// Bundle pool options, the private bundles are only accepted if enabled
+	EnableBundles bool
+	BundlePool    bundlepool.Config
+
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
@@ -158,6 +174,32 @@ type Config struct {
 	// send-transaction variants. The unit is ether.
 	RPCTxFeeCap float64
 
+	// JSTracerDisabled rejects the JavaScript tracers, both the bundled and
+	// the user-supplied ones.
+	JSTracerDisabled bool
+
+	// JSTracerMaxHeap is the maximum estimated size of the state of a single
+	// JavaScript tracer, and of the buffers created by one of its function
+	// calls, in bytes (0 = unlimited).
+	JSTracerMaxHeap uint64
+
+	// JSTracerMaxSteps is the maximum number of steps of a single JavaScript
+	// tracer, counting its function invocations as well as the function calls
+	// and loop iterations executed by them (0 = unlimited).
+	JSTracerMaxSteps uint64
+
+	// JSTracerMaxOutput is the maximum size of the result of a single
+	// JavaScript tracer in bytes (0 = unlimited).
+	JSTracerMaxOutput uint64
+
+	// TraceChainDir is the directory debug_traceChainToFile writes its output
+	// under. Tracing into files is disabled if it's empty.
+	TraceChainDir string
+
+	// TraceFilterRange is the maximum number of blocks trace_filter replays in
+	// a single request (0 = unlimited).
+	TraceFilterRange uint64
+
 	// OverrideCancun (TODO: remove after the fork)
 	OverrideCancun *uint64 `toml:",omitempty"`
 
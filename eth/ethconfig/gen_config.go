@@ -8,6 +8,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
@@ -27,6 +28,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TxLookupLimit           uint64                 `toml:",omitempty"`
 		TransactionHistory      uint64                 `toml:",omitempty"`
 		StateHistory            uint64                 `toml:",omitempty"`
+		LogHistory              uint64                 `toml:",omitempty"`
+		LogIndex                bool                   `toml:",omitempty"`
 		StateScheme             string                 `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
@@ -48,6 +51,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Miner                   miner.Config
 		TxPool                  legacypool.Config
 		BlobPool                blobpool.Config
+		TxPeerRate              float64
+		TxPeerBurst             int
+		TxPeerDropScore         int
+		EnableBundles           bool
+		BundlePool              bundlepool.Config
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
 		VMTrace                 string
@@ -56,6 +64,12 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		RPCGasCap               uint64
 		RPCEVMTimeout           time.Duration
 		RPCTxFeeCap             float64
+		JSTracerDisabled        bool
+		JSTracerMaxHeap         uint64
+		JSTracerMaxSteps        uint64
+		JSTracerMaxOutput       uint64
+		TraceChainDir           string
+		TraceFilterRange        uint64
 		OverrideCancun          *uint64 `toml:",omitempty"`
 		OverrideVerkle          *uint64 `toml:",omitempty"`
 	}
@@ -70,6 +84,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.TransactionHistory = c.TransactionHistory
 	enc.StateHistory = c.StateHistory
+	enc.LogHistory = c.LogHistory
+	enc.LogIndex = c.LogIndex
 	enc.StateScheme = c.StateScheme
 	enc.RequiredBlocks = c.RequiredBlocks
 	enc.LightServ = c.LightServ
@@ -91,6 +107,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.Miner = c.Miner
 	enc.TxPool = c.TxPool
 	enc.BlobPool = c.BlobPool
+	enc.TxPeerRate = c.TxPeerRate
+	enc.TxPeerBurst = c.TxPeerBurst
+	enc.TxPeerDropScore = c.TxPeerDropScore
+	enc.EnableBundles = c.EnableBundles
+	enc.BundlePool = c.BundlePool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.VMTrace = c.VMTrace
@@ -99,6 +120,12 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCEVMTimeout = c.RPCEVMTimeout
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
+	enc.JSTracerDisabled = c.JSTracerDisabled
+	enc.JSTracerMaxHeap = c.JSTracerMaxHeap
+	enc.JSTracerMaxSteps = c.JSTracerMaxSteps
+	enc.JSTracerMaxOutput = c.JSTracerMaxOutput
+	enc.TraceChainDir = c.TraceChainDir
+	enc.TraceFilterRange = c.TraceFilterRange
 	enc.OverrideCancun = c.OverrideCancun
 	enc.OverrideVerkle = c.OverrideVerkle
 	return &enc, nil
@@ -117,6 +144,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TxLookupLimit           *uint64                `toml:",omitempty"`
 		TransactionHistory      *uint64                `toml:",omitempty"`
 		StateHistory            *uint64                `toml:",omitempty"`
+		LogHistory              *uint64                `toml:",omitempty"`
+		LogIndex                *bool                  `toml:",omitempty"`
 		StateScheme             *string                `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
@@ -138,6 +167,11 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Miner                   *miner.Config
 		TxPool                  *legacypool.Config
 		BlobPool                *blobpool.Config
+		TxPeerRate              *float64
+		TxPeerBurst             *int
+		TxPeerDropScore         *int
+		EnableBundles           *bool
+		BundlePool              *bundlepool.Config
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
 		VMTrace                 *string
@@ -146,6 +180,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		RPCGasCap               *uint64
 		RPCEVMTimeout           *time.Duration
 		RPCTxFeeCap             *float64
+		JSTracerDisabled        *bool
+		JSTracerMaxHeap         *uint64
+		JSTracerMaxSteps        *uint64
+		JSTracerMaxOutput       *uint64
+		TraceChainDir           *string
+		TraceFilterRange        *uint64
 		OverrideCancun          *uint64 `toml:",omitempty"`
 		OverrideVerkle          *uint64 `toml:",omitempty"`
 	}
@@ -183,6 +223,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.StateHistory != nil {
 		c.StateHistory = *dec.StateHistory
 	}
+	if dec.LogHistory != nil {
+		c.LogHistory = *dec.LogHistory
+	}
+	if dec.LogIndex != nil {
+		c.LogIndex = *dec.LogIndex
+	}
 	if dec.StateScheme != nil {
 		c.StateScheme = *dec.StateScheme
 	}
@@ -246,6 +292,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.BlobPool != nil {
 		c.BlobPool = *dec.BlobPool
 	}
+	if dec.TxPeerRate != nil {
+		c.TxPeerRate = *dec.TxPeerRate
+	}
+	if dec.TxPeerBurst != nil {
+		c.TxPeerBurst = *dec.TxPeerBurst
+	}
+	if dec.TxPeerDropScore != nil {
+		c.TxPeerDropScore = *dec.TxPeerDropScore
+	}
+	if dec.EnableBundles != nil {
+		c.EnableBundles = *dec.EnableBundles
+	}
+	if dec.BundlePool != nil {
+		c.BundlePool = *dec.BundlePool
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
@@ -270,6 +331,24 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
+	if dec.JSTracerDisabled != nil {
+		c.JSTracerDisabled = *dec.JSTracerDisabled
+	}
+	if dec.JSTracerMaxHeap != nil {
+		c.JSTracerMaxHeap = *dec.JSTracerMaxHeap
+	}
+	if dec.JSTracerMaxSteps != nil {
+		c.JSTracerMaxSteps = *dec.JSTracerMaxSteps
+	}
+	if dec.JSTracerMaxOutput != nil {
+		c.JSTracerMaxOutput = *dec.JSTracerMaxOutput
+	}
+	if dec.TraceChainDir != nil {
+		c.TraceChainDir = *dec.TraceChainDir
+	}
+	if dec.TraceFilterRange != nil {
+		c.TraceFilterRange = *dec.TraceFilterRange
+	}
 	if dec.OverrideCancun != nil {
 		c.OverrideCancun = dec.OverrideCancun
 	}
@@ -99,6 +99,9 @@ var (
 	txReplyUnderpricedMeter = metrics.NewRegisteredMeter("eth/fetcher/transaction/replies/underpriced", nil)
 	txReplyOtherRejectMeter = metrics.NewRegisteredMeter("eth/fetcher/transaction/replies/otherreject", nil)
 
+	txRateLimitedMeter = metrics.NewRegisteredMeter("eth/fetcher/transaction/ratelimited", nil)
+	txPeerDropMeter    = metrics.NewRegisteredMeter("eth/fetcher/transaction/peerdrop", nil)
+
 	txFetcherWaitingPeers   = metrics.NewRegisteredGauge("eth/fetcher/transaction/waiting/peers", nil)
 	txFetcherWaitingHashes  = metrics.NewRegisteredGauge("eth/fetcher/transaction/waiting/hashes", nil)
 	txFetcherQueueingPeers  = metrics.NewRegisteredGauge("eth/fetcher/transaction/queueing/peers", nil)
@@ -139,6 +142,8 @@ type txDelivery struct {
 	hashes []common.Hash // Batch of transaction hashes having been delivered
 	metas  []txMetadata  // Batch of metadatas associated with the delivered hashes
 	direct bool          // Whether this is a direct reply or a broadcast
+
+	refused map[common.Hash]struct{} // Hashes sent by the peer but not accepted from it, left undelivered
 }
 
 // txDrop is the notification that a peer has disconnected.
@@ -195,6 +200,8 @@ type TxFetcher struct {
 	fetchTxs func(string, []common.Hash) error  // Retrieves a set of txs from a remote peer
 	dropPeer func(string)                       // Drops a peer in case of announcement violation
 
+	reputation *txReputation // Delivery rate limiter and reputation tracker of the peers (nil if disabled)
+
 	step  chan struct{} // Notification channel when the fetcher loop iterates
 	clock mclock.Clock  // Time wrapper to simulate in tests
 	rand  *mrand.Rand   // Randomizer to use in tests instead of map range loops (soft-random)
@@ -206,6 +213,17 @@ func NewTxFetcher(hasTx func(common.Hash) bool, addTxs func([]*types.Transaction
 	return NewTxFetcherForTests(hasTx, addTxs, fetchTxs, dropPeer, mclock.System{}, nil)
 }
 
+// NewTxFetcherWithLimits creates a transaction fetcher which additionally rate
+// limits the transactions delivered by each peer, and tracks the reputation of
+// the peers based on the outcome of their deliveries. Peers with a low score
+// are deprioritized when scheduling retrievals, and dropped if the score keeps
+// falling.
+func NewTxFetcherWithLimits(hasTx func(common.Hash) bool, addTxs func([]*types.Transaction) []error, fetchTxs func(string, []common.Hash) error, dropPeer func(string), limits TxPeerLimits) *TxFetcher {
+	f := NewTxFetcher(hasTx, addTxs, fetchTxs, dropPeer)
+	f.reputation = newTxReputation(limits)
+	return f
+}
+
 // NewTxFetcherForTests is a testing method to mock out the realtime clock with
 // a simulated version and the internal randomness with a deterministic one.
 func NewTxFetcherForTests(
@@ -316,10 +334,26 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 	// Push all the transactions into the pool, tracking underpriced ones to avoid
 	// re-requesting them and dropping the peer in case of malicious transfers.
 	var (
-		added = make([]common.Hash, 0, len(txs))
-		metas = make([]txMetadata, 0, len(txs))
+		added   = make([]common.Hash, 0, len(txs))
+		metas   = make([]txMetadata, 0, len(txs))
+		refused map[common.Hash]struct{}
 	)
+	// Discard the transactions exceeding the rate limit of the peer. They are
+	// left undelivered, so they are fetched from the other announcers instead.
+	if f.reputation != nil {
+		if allowed := f.reputation.admit(peer, len(txs), f.clock.Now()); allowed < len(txs) {
+			txRateLimitedMeter.Mark(int64(len(txs) - allowed))
+			log.Debug("Peer exceeded transaction rate limit", "peer", peer, "delivered", len(txs), "allowed", allowed)
+
+			refused = make(map[common.Hash]struct{}, len(txs)-allowed)
+			for _, tx := range txs[allowed:] {
+				refused[tx.Hash()] = struct{}{}
+			}
+			txs = txs[:allowed]
+		}
+	}
 	// proceed in batches
+	var drop bool
 	for i := 0; i < len(txs); i += 128 {
 		end := i + 128
 		if end > len(txs) {
@@ -329,9 +363,9 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 			duplicate   int64
 			underpriced int64
 			otherreject int64
+			ratelimited int64
 		)
 		batch := txs[i:end]
-
 		for j, err := range f.addTxs(batch) {
 			// Track the transaction hash if the price is too low for us.
 			// Avoid re-request this transaction when we receive another
@@ -349,6 +383,9 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 			case errors.Is(err, txpool.ErrUnderpriced) || errors.Is(err, txpool.ErrReplaceUnderpriced):
 				underpriced++
 
+			case errors.Is(err, txpool.ErrSenderRateLimited):
+				ratelimited++
+
 			default:
 				otherreject++
 			}
@@ -362,6 +399,17 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 		underpricedMeter.Mark(underpriced)
 		otherRejectMeter.Mark(otherreject)
 
+		// Feed the outcome into the reputation of the peer, dropping it if it
+		// keeps delivering junk. Senders exceeding their rate are not the fault
+		// of the peer, they are neither rewarded nor penalized.
+		if f.reputation != nil {
+			accepted := int64(len(batch)) - duplicate - underpriced - otherreject - ratelimited
+			// If the peer is being dropped, leave the rest of its transactions
+			// undelivered, so they are fetched from the other announcers instead.
+			if drop = f.reputation.update(peer, int(accepted), int(underpriced), int(otherreject), f.clock.Now()); drop {
+				break
+			}
+		}
 		// If 'other reject' is >25% of the deliveries in any batch, sleep a bit.
 		if otherreject > 128/4 {
 			time.Sleep(200 * time.Millisecond)
@@ -369,16 +417,27 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 		}
 	}
 	select {
-	case f.cleanup <- &txDelivery{origin: peer, hashes: added, metas: metas, direct: direct}:
-		return nil
+	case f.cleanup <- &txDelivery{origin: peer, hashes: added, metas: metas, direct: direct, refused: refused}:
 	case <-f.quit:
 		return errTerminated
 	}
+	// Drop the peer only after its delivery is accounted for
+	if drop {
+		txPeerDropMeter.Mark(1)
+		log.Debug("Dropping peer with low transaction reputation", "peer", peer)
+		if f.dropPeer != nil {
+			f.dropPeer(peer)
+		}
+	}
+	return nil
 }
 
 // Drop should be called when a peer disconnects. It cleans up all the internal
 // data structures of the given node.
 func (f *TxFetcher) Drop(peer string) error {
+	if f.reputation != nil {
+		f.reputation.forget(peer, f.clock.Now())
+	}
 	select {
 	case f.drop <- &txDrop{peer: peer}:
 		return nil
@@ -387,6 +446,15 @@ func (f *TxFetcher) Drop(peer string) error {
 	}
 }
 
+// Banned returns whether the peer was dropped due to its transaction reputation
+// recently, and should not be allowed to reconnect yet.
+func (f *TxFetcher) Banned(peer string) bool {
+	if f.reputation == nil {
+		return false
+	}
+	return f.reputation.isBanned(peer, f.clock.Now())
+}
+
 // Start boots up the announcement based synchroniser, accepting and processing
 // hash notifications and block fetches until termination requested.
 func (f *TxFetcher) Start() {
@@ -690,7 +758,9 @@ func (f *TxFetcher) loop() {
 						}
 					}
 					if _, ok := delivered[hash]; !ok {
-						if i < cutoff {
+						// Hashes refused from the peer are not requested from it again
+						_, refused := delivery.refused[hash]
+						if i < cutoff || refused {
 							delete(f.alternates[hash], delivery.origin)
 							delete(f.announces[delivery.origin], hash)
 							if len(f.announces[delivery.origin]) == 0 {
@@ -872,12 +942,19 @@ func (f *TxFetcher) scheduleFetches(timer *mclock.Timer, timeout chan struct{},
 		var (
 			hashes = make([]common.Hash, 0, maxTxRetrievals)
 			bytes  uint64
+
+			deprioritized = f.reputation != nil && f.reputation.deprioritized(peer)
 		)
 		f.forEachAnnounce(f.announces[peer], func(hash common.Hash, meta *txMetadata) bool {
 			// If the transaction is already fetching, skip to the next one
 			if _, ok := f.fetching[hash]; ok {
 				return true
 			}
+			// If the peer has a low reputation, leave the transaction to the
+			// reputable peers which announced it too
+			if deprioritized && f.hasReputableAnnouncer(hash, peer) {
+				return true
+			}
 			// Mark the hash as fetching and stash away possible alternates
 			f.fetching[hash] = peer
 
@@ -921,6 +998,17 @@ func (f *TxFetcher) scheduleFetches(timer *mclock.Timer, timeout chan struct{},
 	}
 }
 
+// hasReputableAnnouncer returns whether the transaction was announced by any
+// other peer than the given one, whose reputation is not low.
+func (f *TxFetcher) hasReputableAnnouncer(hash common.Hash, peer string) bool {
+	for other := range f.announced[hash] {
+		if other != peer && !f.reputation.deprioritized(other) {
+			return true
+		}
+	}
+	return false
+}
+
 // forEachPeer does a range loop over a map of peers in production, but during
 // testing it does a deterministic sorted random to allow reproducing issues.
 func (f *TxFetcher) forEachPeer(peers map[string]struct{}, do func(peer string)) {
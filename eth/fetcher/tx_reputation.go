@@ -0,0 +1,220 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// txPeerScoreMax is the maximum reputation score a peer can accumulate, to
+	// avoid long lived peers building up a credit to be spent on spamming.
+	txPeerScoreMax = 100
+
+	// txPeerScoreDeprioritize is the reputation score below which the peer is
+	// only requested transactions nobody else announced.
+	txPeerScoreDeprioritize = -100
+
+	// txPeerScoreRecovery is the number of points a negative reputation score
+	// recovers per second, so occasional misbehaviour is forgotten over time.
+	txPeerScoreRecovery = 10
+
+	// txPeerBanTimeout is the time a peer dropped due to its reputation is not
+	// allowed to reconnect.
+	txPeerBanTimeout = 10 * time.Minute
+
+	txScoreAccepted    = 1   // Score change of a transaction accepted into the pool
+	txScoreUnderpriced = -1  // Score change of an underpriced transaction
+	txScoreRejected    = -2  // Score change of an invalid transaction
+	txScoreRateLimited = -10 // Score change of a delivery exceeding the peer rate limit
+)
+
+// TxPeerLimits are the admission limits and reputation settings of the
+// transactions delivered by the remote peers.
+type TxPeerLimits struct {
+	Rate      float64 // Transactions admitted per second from a single peer (0 = unlimited)
+	Burst     int     // Transactions admitted from a single peer in a burst
+	DropScore int     // Negative reputation score below which the peer is dropped and banned (0 = never dropped)
+}
+
+// txPeerReputation is the admission and behaviour tracker of a single peer.
+type txPeerReputation struct {
+	limiter *rate.Limiter  // Admission rate limiter (nil if unlimited)
+	score   float64        // Reputation score, negative if the peer misbehaves
+	updated mclock.AbsTime // Time of the last score update for recovery
+}
+
+// txReputation tracks the reputation of the peers delivering transactions,
+// fed by the outcome of inserting their transactions into the pool, and rate
+// limits their deliveries.
+type txReputation struct {
+	limits TxPeerLimits
+	peers  map[string]*txPeerReputation
+	gone   map[string]*txPeerReputation // Disconnected peers with a negative score still recovering
+	banned map[string]mclock.AbsTime    // Peers dropped due to their reputation, until the given time
+	lock   sync.Mutex
+}
+
+// newTxReputation creates a new peer reputation tracker.
+func newTxReputation(limits TxPeerLimits) *txReputation {
+	if limits.Rate > 0 && limits.Burst < 1 {
+		limits.Burst = maxTxRetrievals
+	}
+	if limits.DropScore > 0 {
+		limits.DropScore = -limits.DropScore
+	}
+	return &txReputation{
+		limits: limits,
+		peers:  make(map[string]*txPeerReputation),
+		gone:   make(map[string]*txPeerReputation),
+		banned: make(map[string]mclock.AbsTime),
+	}
+}
+
+// peer retrieves the reputation of the given peer, creating it if needed and
+// applying the recovery since the last update. The lock must be held.
+func (r *txReputation) peer(id string, now mclock.AbsTime) *txPeerReputation {
+	rep := r.peers[id]
+	if rep == nil {
+		if rep = r.gone[id]; rep != nil {
+			delete(r.gone, id)
+		} else {
+			rep = &txPeerReputation{updated: now}
+			if r.limits.Rate > 0 {
+				rep.limiter = rate.NewLimiter(rate.Limit(r.limits.Rate), r.limits.Burst)
+			}
+		}
+		r.peers[id] = rep
+	}
+	if rep.score < 0 && now > rep.updated {
+		rep.score += time.Duration(now-rep.updated).Seconds() * txPeerScoreRecovery
+		if rep.score > 0 {
+			rep.score = 0
+		}
+	}
+	rep.updated = now
+	return rep
+}
+
+// admit returns the number of transactions admitted out of the given number
+// delivered by the peer. If not all of them are admitted, the peer's score
+// is penalized.
+func (r *txReputation) admit(id string, n int, now mclock.AbsTime) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	rep := r.peer(id, now)
+	if rep.limiter == nil {
+		return n
+	}
+	at := time.Unix(0, int64(now))
+	if rep.limiter.AllowN(at, n) {
+		return n
+	}
+	// Admit as many as the bucket currently holds
+	allowed := int(rep.limiter.TokensAt(at))
+	if allowed > 0 {
+		rep.limiter.AllowN(at, allowed)
+	}
+	rep.score += txScoreRateLimited
+	return allowed
+}
+
+// update adjusts the score of the peer by the outcome of its delivery, and
+// returns whether the peer should be dropped, if dropping is enabled.
+func (r *txReputation) update(id string, accepted, underpriced, rejected int, now mclock.AbsTime) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	rep := r.peer(id, now)
+	rep.score += float64(accepted*txScoreAccepted + underpriced*txScoreUnderpriced + rejected*txScoreRejected)
+	if rep.score > txPeerScoreMax {
+		rep.score = txPeerScoreMax
+	}
+	if r.limits.DropScore == 0 || rep.score >= float64(r.limits.DropScore) {
+		return false
+	}
+	delete(r.peers, id)
+	r.banned[id] = now.Add(txPeerBanTimeout)
+	return true
+}
+
+// deprioritized returns whether the peer's reputation is low enough to only
+// retrieve transactions from it if nobody else can deliver them.
+func (r *txReputation) deprioritized(id string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	rep := r.peers[id]
+	return rep != nil && rep.score < txPeerScoreDeprioritize
+}
+
+// isBanned returns whether the peer was dropped due to its reputation and may
+// not reconnect yet.
+func (r *txReputation) isBanned(id string, now mclock.AbsTime) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	until, ok := r.banned[id]
+	if !ok {
+		return false
+	}
+	if now >= until {
+		delete(r.banned, id)
+		return false
+	}
+	return true
+}
+
+// score returns the current reputation score of the peer.
+func (r *txReputation) score(id string, now mclock.AbsTime) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.peers[id] == nil && r.gone[id] == nil {
+		return 0
+	}
+	return r.peer(id, now).score
+}
+
+// forget drops the reputation of a disconnected peer. A negative score is kept
+// until it recovers, to avoid the peer cleaning its record by reconnecting.
+func (r *txReputation) forget(id string, now mclock.AbsTime) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if rep := r.peers[id]; rep != nil {
+		delete(r.peers, id)
+		if rep.score < 0 {
+			r.gone[id] = rep
+		}
+	}
+	for peer, rep := range r.gone {
+		if rep.score+time.Duration(now-rep.updated).Seconds()*txPeerScoreRecovery >= 0 {
+			delete(r.gone, peer)
+		}
+	}
+	for peer, until := range r.banned {
+		if now >= until {
+			delete(r.banned, peer)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that the deliveries of a peer are rate limited, and that its bucket
+// refills over time.
+func TestTxReputationRateLimit(t *testing.T) {
+	var (
+		rep   = newTxReputation(TxPeerLimits{Rate: 10, Burst: 20})
+		clock = new(mclock.Simulated)
+	)
+	if n := rep.admit("A", 15, clock.Now()); n != 15 {
+		t.Fatalf("admitted transactions mismatch: have %d, want %d", n, 15)
+	}
+	if n := rep.admit("A", 10, clock.Now()); n != 5 {
+		t.Fatalf("admitted transactions mismatch: have %d, want %d", n, 5)
+	}
+	if n := rep.admit("B", 20, clock.Now()); n != 20 {
+		t.Fatalf("admitted transactions mismatch: have %d, want %d", n, 20)
+	}
+	clock.Run(time.Second)
+	if n := rep.admit("A", 20, clock.Now()); n != 10 {
+		t.Fatalf("admitted transactions mismatch: have %d, want %d", n, 10)
+	}
+	if score := rep.score("A", clock.Now()); score != 2*txScoreRateLimited+10 {
+		t.Fatalf("score mismatch: have %v, want %v", score, 2*txScoreRateLimited+10)
+	}
+}
+
+// Tests that misbehaving peers get deprioritized, then dropped and banned, and
+// that occasional misbehaviour is forgotten over time.
+func TestTxReputationScore(t *testing.T) {
+	var (
+		rep   = newTxReputation(TxPeerLimits{DropScore: -300})
+		clock = new(mclock.Simulated)
+	)
+	if rep.update("A", 10, 0, 0, clock.Now()) {
+		t.Fatalf("well behaving peer dropped")
+	}
+	if rep.update("A", 0, 0, 60, clock.Now()) {
+		t.Fatalf("peer dropped too early")
+	}
+	if !rep.deprioritized("A") {
+		t.Fatalf("misbehaving peer not deprioritized")
+	}
+	// Wait for the score to recover, ensure the peer is not deprioritized anymore
+	clock.Run(11 * time.Second)
+	rep.update("A", 0, 0, 0, clock.Now())
+	if score := rep.score("A", clock.Now()); score != 0 {
+		t.Fatalf("score mismatch: have %v, want %v", score, 0)
+	}
+	if rep.deprioritized("A") {
+		t.Fatalf("recovered peer deprioritized")
+	}
+	// Disconnecting with a negative score should not clean the record
+	rep.update("A", 0, 100, 0, clock.Now())
+	rep.forget("A", clock.Now())
+	if score := rep.score("A", clock.Now()); score != -100 {
+		t.Fatalf("score mismatch after reconnect: have %v, want %v", score, -100)
+	}
+	// Drop the peer and ensure it's banned for a while
+	if !rep.update("A", 0, 0, 150, clock.Now()) {
+		t.Fatalf("misbehaving peer not dropped")
+	}
+	if !rep.isBanned("A", clock.Now()) {
+		t.Fatalf("dropped peer not banned")
+	}
+	clock.Run(txPeerBanTimeout)
+	if rep.isBanned("A", clock.Now()) {
+		t.Fatalf("ban not expired")
+	}
+}
+
+// Tests that peers are never dropped due to their reputation unless a drop
+// score is configured.
+func TestTxReputationNoDrop(t *testing.T) {
+	var (
+		rep   = newTxReputation(TxPeerLimits{})
+		clock = new(mclock.Simulated)
+	)
+	if rep.update("A", 0, 100000, 100000, clock.Now()) {
+		t.Fatalf("peer dropped without drop score")
+	}
+	if rep.isBanned("A", clock.Now()) {
+		t.Fatalf("peer banned without drop score")
+	}
+	if !rep.deprioritized("A") {
+		t.Fatalf("misbehaving peer not deprioritized")
+	}
+}
+
+// Tests that a peer dropped due to its reputation while delivering has its
+// delivery accounted for before being dropped.
+func TestTxFetcherReputationDrop(t *testing.T) {
+	var (
+		added   int
+		dropped = make(chan string, 1)
+		fetcher = NewTxFetcherWithLimits(
+			func(common.Hash) bool { return false },
+			func(txs []*types.Transaction) []error {
+				added += len(txs)
+				errs := make([]error, len(txs))
+				for i := range errs {
+					errs[i] = errors.New("invalid")
+				}
+				return errs
+			},
+			nil,
+			func(peer string) { dropped <- peer },
+			TxPeerLimits{DropScore: -100},
+		)
+		txs = make([]*types.Transaction, 300)
+	)
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{}, nil, 0, nil, nil)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- fetcher.Enqueue("A", txs, true) }()
+
+	// The cleanup channel is unbuffered, so the peer can't be dropped before
+	// the delivery is received
+	select {
+	case <-dropped:
+		t.Fatalf("peer dropped before its delivery was accounted")
+	case delivery := <-fetcher.cleanup:
+		// Only the transactions added before the drop are delivered, the rest
+		// is left to the other announcers.
+		if len(delivery.hashes) != 128 || len(delivery.metas) != 128 {
+			t.Fatalf("delivered hashes mismatch: have %d, want %d", len(delivery.hashes), 128)
+		}
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	if peer := <-dropped; peer != "A" {
+		t.Fatalf("dropped peer mismatch: have %s, want A", peer)
+	}
+	if added != 128 {
+		t.Fatalf("transactions added after drop: have %d, want %d", added, 128)
+	}
+}
+
+// Tests that the transactions of a delivery exceeding the peer rate limit are
+// left undelivered, so they are fetched from the other announcers.
+func TestTxFetcherRateLimitedRescheduling(t *testing.T) {
+	testTransactionFetcherParallel(t, txFetcherTest{
+		init: func() *TxFetcher {
+			return NewTxFetcherWithLimits(
+				func(common.Hash) bool { return false },
+				func(txs []*types.Transaction) []error {
+					return make([]error, len(txs))
+				},
+				func(string, []common.Hash) error { return nil },
+				nil,
+				TxPeerLimits{Rate: 0.001, Burst: 1},
+			)
+		},
+		steps: []interface{}{
+			doTxNotify{peer: "A", hashes: []common.Hash{testTxsHashes[0], testTxsHashes[1]}},
+			doWait{time: txArriveTimeout, step: true},
+			doTxNotify{peer: "B", hashes: []common.Hash{testTxsHashes[0], testTxsHashes[1]}},
+			isScheduled{
+				tracking: map[string][]common.Hash{
+					"A": {testTxsHashes[0], testTxsHashes[1]},
+					"B": {testTxsHashes[0], testTxsHashes[1]},
+				},
+				fetching: map[string][]common.Hash{
+					"A": {testTxsHashes[0], testTxsHashes[1]},
+				},
+			},
+			// Deliver both transactions from A, the second one exceeding the
+			// rate limit must be fetched from B and not requested from A again.
+			doTxEnqueue{peer: "A", txs: []*types.Transaction{testTxs[0], testTxs[1]}, direct: true},
+			isScheduled{
+				tracking: map[string][]common.Hash{
+					"B": {testTxsHashes[1]},
+				},
+				fetching: map[string][]common.Hash{
+					"B": {testTxsHashes[1]},
+				},
+			},
+		},
+	})
+}
@@ -23,11 +23,16 @@ import (
 	"slices"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// logIndexChunkSize is the number of blocks looked up in the log index at once.
+const logIndexChunkSize = 10000
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	sys *FilterSystem
@@ -96,21 +101,42 @@ func newFilter(sys *FilterSystem, addresses []common.Address, topics [][]common.
 // Logs searches the blockchain for matching log entries, returning all from the
 // first block that contains matches, updating the start of the filter accordingly.
 func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
+	var logs []*types.Log
+	err := f.StreamLogs(ctx, func(log *types.Log) error {
+		logs = append(logs, log)
+		return nil
+	})
+	return logs, err
+}
+
+// StreamLogs searches the blockchain for matching log entries like Logs, but passes
+// them to fn as they are found instead of collecting them. The search stops at the
+// first error returned by fn.
+func (f *Filter) StreamLogs(ctx context.Context, fn func(*types.Log) error) error {
 	// If we're doing singleton block filtering, execute and return
 	if f.block != nil {
 		header, err := f.sys.backend.HeaderByHash(ctx, *f.block)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if header == nil {
-			return nil, errors.New("unknown block")
+			return errors.New("unknown block")
+		}
+		logs, err := f.blockLogs(ctx, header)
+		if err != nil {
+			return err
 		}
-		return f.blockLogs(ctx, header)
+		for _, log := range logs {
+			if err := fn(log); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
 	// Disallow pending logs.
 	if f.begin == rpc.PendingBlockNumber.Int64() || f.end == rpc.PendingBlockNumber.Int64() {
-		return nil, errPendingLogsUnsupported
+		return errPendingLogsUnsupported
 	}
 
 	resolveSpecial := func(number int64) (int64, error) {
@@ -142,20 +168,32 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	var err error
 	// range query need to resolve the special begin/end block number
 	if f.begin, err = resolveSpecial(f.begin); err != nil {
-		return nil, err
+		return err
 	}
 	if f.end, err = resolveSpecial(f.end); err != nil {
-		return nil, err
+		return err
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	logChan, errChan := f.rangeLogsAsync(ctx)
-	var logs []*types.Log
 	for {
 		select {
 		case log := <-logChan:
-			logs = append(logs, log)
+			if err := fn(log); err != nil {
+				// Stop the search and wait for it to terminate
+				cancel()
+				for {
+					select {
+					case <-logChan:
+					case <-errChan:
+						return err
+					}
+				}
+			}
 		case err := <-errChan:
-			return logs, err
+			return err
 		}
 	}
 }
@@ -174,23 +212,22 @@ func (f *Filter) rangeLogsAsync(ctx context.Context) (chan *types.Log, chan erro
 			close(logChan)
 		}()
 
-		// Gather all indexed logs, and finish with non indexed ones
-		var (
-			end            = uint64(f.end)
-			size, sections = f.sys.backend.BloomStatus()
-			err            error
-		)
-		if indexed := sections * size; indexed > uint64(f.begin) {
-			if indexed > end {
-				indexed = end + 1
+		// Gather the logs covered by the persistent log index, if possible, and
+		// fall back to the bloom bits for the rest of the range.
+		end := uint64(f.end)
+		if tail, head, ok := f.logIndexRange(); ok && tail <= end && head >= uint64(f.begin) {
+			if uint64(f.begin) < tail {
+				if err := f.bloomLogs(ctx, tail-1, logChan); err != nil {
+					errChan <- err
+					return
+				}
 			}
-			if err = f.indexedLogs(ctx, indexed-1, logChan); err != nil {
+			if err := f.logIndexLogs(ctx, min(head, end), logChan); err != nil {
 				errChan <- err
 				return
 			}
 		}
-
-		if err := f.unindexedLogs(ctx, end, logChan); err != nil {
+		if err := f.bloomLogs(ctx, end, logChan); err != nil {
 			errChan <- err
 			return
 		}
@@ -201,6 +238,75 @@ func (f *Filter) rangeLogsAsync(ctx context.Context) (chan *types.Log, chan erro
 	return logChan, errChan
 }
 
+// bloomLogs returns the logs matching the filter criteria up to the given block,
+// using the bloom bits index where available and raw block iteration for the
+// rest.
+func (f *Filter) bloomLogs(ctx context.Context, end uint64, logChan chan *types.Log) error {
+	// Gather all indexed logs, and finish with non indexed ones
+	size, sections := f.sys.backend.BloomStatus()
+	if indexed := sections * size; indexed > uint64(f.begin) {
+		if indexed > end {
+			indexed = end + 1
+		}
+		if err := f.indexedLogs(ctx, indexed-1, logChan); err != nil {
+			return err
+		}
+	}
+	return f.unindexedLogs(ctx, end, logChan)
+}
+
+// logIndexRange returns the range of blocks covered by the persistent log index.
+// The index is only usable if the filter has any address or topic criteria.
+func (f *Filter) logIndexRange() (uint64, uint64, bool) {
+	db := f.sys.backend.ChainDb()
+	if db == nil {
+		return 0, 0, false
+	}
+	usable := len(f.addresses) > 0
+	for i, sub := range f.topics {
+		if len(sub) > 0 && i < rawdb.LogIndexMaxTopics {
+			usable = true
+		}
+	}
+	if !usable {
+		return 0, 0, false
+	}
+	return core.LogIndexRange(db)
+}
+
+// logIndexLogs returns the logs matching the filter criteria up to the given
+// block, based on the persistent log index.
+func (f *Filter) logIndexLogs(ctx context.Context, end uint64, logChan chan *types.Log) error {
+	db := f.sys.backend.ChainDb()
+	for f.begin <= int64(end) {
+		// Look up the index in chunks, not to hold all the matches in memory
+		last := min(uint64(f.begin)+logIndexChunkSize-1, end)
+		matches, err := core.FilterLogIndex(db, uint64(f.begin), last, f.addresses, f.topics)
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			header, err := f.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(match.Number))
+			if header == nil || err != nil {
+				return err
+			}
+			found, err := f.indexedMatches(ctx, header, match.Positions)
+			if err != nil {
+				return err
+			}
+			for _, log := range found {
+				select {
+				case logChan <- log:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+		f.begin = int64(last) + 1
+	}
+	return nil
+}
+
 // indexedLogs returns the logs matching the filter criteria based on the bloom
 // bits indexed available locally or via the network.
 func (f *Filter) indexedLogs(ctx context.Context, end uint64, logChan chan *types.Log) error {
@@ -291,16 +397,43 @@ func (f *Filter) checkMatches(ctx context.Context, header *types.Header) ([]*typ
 	if err != nil {
 		return nil, err
 	}
-	logs := filterLogs(cached.logs, nil, nil, f.addresses, f.topics)
+	return f.deriveLogs(ctx, cached, header, filterLogs(cached.logs, nil, nil, f.addresses, f.topics))
+}
+
+// indexedMatches retrieves the logs at the given positions within the block, as
+// reported by the persistent log index. The picked logs are checked against the
+// criteria again, and if the positions turn out to be stale, e.g. because the
+// block was reorged since the index was queried, the whole block is filtered.
+func (f *Filter) indexedMatches(ctx context.Context, header *types.Header, positions []uint32) ([]*types.Log, error) {
+	cached, err := f.sys.cachedLogElem(ctx, header.Hash(), header.Number.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	picked := make([]*types.Log, 0, len(positions))
+	for _, pos := range positions {
+		if int(pos) >= len(cached.logs) {
+			return f.checkMatches(ctx, header)
+		}
+		picked = append(picked, cached.logs[pos])
+	}
+	matched := filterLogs(picked, nil, nil, f.addresses, f.topics)
+	if len(matched) != len(picked) {
+		return f.checkMatches(ctx, header)
+	}
+	return f.deriveLogs(ctx, cached, header, matched)
+}
+
+// deriveLogs fills the transaction hashes of the given logs of the block, taken
+// from the cache, returning copies not to modify the cached elements.
+func (f *Filter) deriveLogs(ctx context.Context, cached *logCacheElem, header *types.Header, logs []*types.Log) ([]*types.Log, error) {
 	if len(logs) == 0 {
 		return nil, nil
 	}
 	// Most backends will deliver un-derived logs, but check nevertheless.
-	if len(logs) > 0 && logs[0].TxHash != (common.Hash{}) {
+	if logs[0].TxHash != (common.Hash{}) {
 		return logs, nil
 	}
-
-	body, err := f.sys.cachedGetBody(ctx, cached, hash, header.Number.Uint64())
+	body, err := f.sys.cachedGetBody(ctx, cached, header.Hash(), header.Number.Uint64())
 	if err != nil {
 		return nil, err
 	}
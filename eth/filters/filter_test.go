@@ -19,6 +19,7 @@ package filters
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"math/big"
 	"strings"
 	"testing"
@@ -107,6 +108,61 @@ func BenchmarkFilters(b *testing.B) {
 	}
 }
 
+// Tests that streaming the logs of a range filter passes them on in order, and
+// stops the search once the consumer fails.
+func TestStreamLogs(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		_, sys = newTestFilterSystem(t, db, Config{})
+		addr   = common.BytesToAddress([]byte("jeff"))
+		gspec  = &core.Genesis{
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Config:  params.TestChainConfig,
+		}
+	)
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 10, func(i int, gen *core.BlockGen) {
+		gen.AddUncheckedReceipt(makeReceipt(addr))
+		gen.AddUncheckedTx(types.NewTransaction(999, common.HexToAddress("0x999"), big.NewInt(999), 999, gen.BaseFee(), nil))
+	})
+	gspec.MustCommit(db, triedb.NewDatabase(db, triedb.HashDefaults))
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	var numbers []uint64
+	err := sys.NewRangeFilter(0, rpc.LatestBlockNumber.Int64(), []common.Address{addr}, nil).StreamLogs(context.Background(), func(log *types.Log) error {
+		numbers = append(numbers, log.BlockNumber)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to stream logs: %v", err)
+	}
+	for i, number := range numbers {
+		if number != uint64(i+1) {
+			t.Fatalf("log %d block mismatch: have %d, want %d", i, number, i+1)
+		}
+	}
+	if len(numbers) != len(chain) {
+		t.Fatalf("log count mismatch: have %d, want %d", len(numbers), len(chain))
+	}
+	// Fail consuming the logs midway
+	var (
+		errStop = errors.New("stop")
+		count   int
+	)
+	err = sys.NewRangeFilter(0, rpc.LatestBlockNumber.Int64(), []common.Address{addr}, nil).StreamLogs(context.Background(), func(log *types.Log) error {
+		if count++; count == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || count != 3 {
+		t.Fatalf("aborted stream mismatch: have %v after %d logs, want %v after 3", err, count, errStop)
+	}
+}
+
 func TestFilters(t *testing.T) {
 	var (
 		db           = rawdb.NewMemoryDatabase()
@@ -262,6 +318,12 @@ func TestFilters(t *testing.T) {
 	// Set block 998 as Finalized (-3)
 	bc.SetFinalized(chain[998].Header())
 
+	// Index the logs of the most recent blocks, the rest of the chain has to be
+	// filtered by iterating the blocks.
+	if err := core.RebuildLogIndex(db, 500, nil); err != nil {
+		t.Fatal(err)
+	}
+
 	// Generate pending block
 	pchain, preceipts := core.GenerateChain(gspec.Config, chain[len(chain)-1], ethash.NewFaker(), db, 1, func(i int, gen *core.BlockGen) {
 		data, err := contractABI.Pack("log1", hash5.Big())
@@ -374,6 +436,20 @@ func TestFilters(t *testing.T) {
 		}
 	}
 
+	t.Run("stale index", func(t *testing.T) {
+		// Point the index of the last block at a log that doesn't exist, the
+		// block must be filtered as a whole instead.
+		rawdb.WriteLogIndexBlock(db, 1000, []rawdb.LogIndexEntry{{Kind: rawdb.LogIndexAddress, Value: contract.Bytes(), Positions: []uint32{3}}})
+
+		logs, err := sys.NewRangeFilter(1000, 1000, []common.Address{contract}, nil).Logs(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(logs) != 1 || logs[0].Address != contract || logs[0].BlockNumber != 1000 {
+			t.Fatalf("stale index logs mismatch: %v", logs)
+		}
+	})
+
 	t.Run("timeout", func(t *testing.T) {
 		f := sys.NewRangeFilter(0, rpc.LatestBlockNumber.Int64(), nil, nil)
 		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Hour))
@@ -93,6 +93,7 @@ type handlerConfig struct {
 	BloomCache     uint64                 // Megabytes to alloc for snap sync bloom
 	EventMux       *event.TypeMux         // Legacy event mux, deprecate for `feed`
 	RequiredBlocks map[uint64]common.Hash // Hard coded map of required block hashes for sync challenges
+	TxPeerLimits   fetcher.TxPeerLimits   // Transaction admission limits of the remote peers
 }
 
 type handler struct {
@@ -192,7 +193,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 	addTxs := func(txs []*types.Transaction) []error {
 		return h.txpool.Add(txs, false, false)
 	}
-	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, addTxs, fetchTx, h.removePeer)
+	h.txFetcher = fetcher.NewTxFetcherWithLimits(h.txpool.Has, addTxs, fetchTx, h.removePeer, config.TxPeerLimits)
 	return h, nil
 }
 
@@ -272,11 +273,15 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 			}
 		}
 	}
-	// Ignore maxPeers if this is a trusted peer
+	// Ignore maxPeers and the transaction reputation if this is a trusted peer
 	if !peer.Peer.Info().Network.Trusted {
 		if reject || h.peers.len() >= h.maxPeers {
 			return p2p.DiscTooManyPeers
 		}
+		if h.txFetcher.Banned(peer.ID()) {
+			peer.Log().Debug("Rejecting peer with low transaction reputation")
+			return p2p.DiscUselessPeer
+		}
 	}
 	peer.Log().Debug("Ethereum peer connected", "name", peer.Name())
 
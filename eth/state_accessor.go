@@ -181,10 +181,13 @@ func (eth *Ethereum) pathState(block *types.Block) (*state.StateDB, func(), erro
 	if err == nil {
 		return statedb, noopReleaser, nil
 	}
-	// TODO historic state is not supported in path-based scheme.
-	// Fully archive node in pbss will be implemented by relying
-	// on state history, but needs more work on top.
-	return nil, nil, errors.New("historical state not available in path scheme yet")
+	// Otherwise rebuild the state from the retained state histories. Note
+	// the returned state is read-only, any attempt to commit it will fail.
+	statedb, err = eth.blockchain.HistoricState(block.Root())
+	if err != nil {
+		return nil, nil, fmt.Errorf("historical state unavailable: %w", err)
+	}
+	return statedb, noopReleaser, nil
 }
 
 // stateAtBlock retrieves the state database associated with a certain block.
@@ -89,9 +89,21 @@ type Backend interface {
 	StateAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (*types.Transaction, vm.BlockContext, *state.StateDB, StateReleaseFunc, error)
 }
 
+// Config holds the node operator settings of the tracing APIs.
+type Config struct {
+	// TraceChainDir is the directory debug_traceChainToFile writes its output
+	// under. The method is disabled if it's empty.
+	TraceChainDir string
+
+	// TraceFilterRange is the maximum number of blocks trace_filter replays in
+	// a single request (0 = unlimited).
+	TraceFilterRange uint64
+}
+
 // API is the collection of tracing APIs exposed over the private debugging endpoint.
 type API struct {
 	backend Backend
+	config  Config
 }
 
 // NewAPI creates a new API definition for the tracing methods of the Ethereum service.
@@ -203,6 +215,7 @@ type blockTraceResult struct {
 type txTraceTask struct {
 	statedb *state.StateDB // Intermediate state prepped for tracing
 	index   int            // Transaction offset in the block
+	result  *txTraceResult // Trace result, set once traced
 }
 
 // TraceChain returns the structured logs created during the execution of EVM
@@ -436,37 +449,37 @@ func (api *API) traceChain(start, end *types.Block, config *TraceConfig, closed
 
 // TraceBlockByNumber returns the structured logs created during the execution of
 // EVM and returns them as a JSON object.
-func (api *API) TraceBlockByNumber(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) ([]*txTraceResult, error) {
+func (api *API) TraceBlockByNumber(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) (rpc.ResultStream[*txTraceResult], error) {
 	block, err := api.blockByNumber(ctx, number)
 	if err != nil {
 		return nil, err
 	}
-	return api.traceBlock(ctx, block, config)
+	return api.streamBlock(ctx, block, config)
 }
 
 // TraceBlockByHash returns the structured logs created during the execution of
 // EVM and returns them as a JSON object.
-func (api *API) TraceBlockByHash(ctx context.Context, hash common.Hash, config *TraceConfig) ([]*txTraceResult, error) {
+func (api *API) TraceBlockByHash(ctx context.Context, hash common.Hash, config *TraceConfig) (rpc.ResultStream[*txTraceResult], error) {
 	block, err := api.blockByHash(ctx, hash)
 	if err != nil {
 		return nil, err
 	}
-	return api.traceBlock(ctx, block, config)
+	return api.streamBlock(ctx, block, config)
 }
 
 // TraceBlock returns the structured logs created during the execution of EVM
 // and returns them as a JSON object.
-func (api *API) TraceBlock(ctx context.Context, blob hexutil.Bytes, config *TraceConfig) ([]*txTraceResult, error) {
+func (api *API) TraceBlock(ctx context.Context, blob hexutil.Bytes, config *TraceConfig) (rpc.ResultStream[*txTraceResult], error) {
 	block := new(types.Block)
 	if err := rlp.DecodeBytes(blob, block); err != nil {
 		return nil, fmt.Errorf("could not decode block: %v", err)
 	}
-	return api.traceBlock(ctx, block, config)
+	return api.streamBlock(ctx, block, config)
 }
 
 // TraceBlockFromFile returns the structured logs created during the execution of
 // EVM and returns them as a JSON object.
-func (api *API) TraceBlockFromFile(ctx context.Context, file string, config *TraceConfig) ([]*txTraceResult, error) {
+func (api *API) TraceBlockFromFile(ctx context.Context, file string, config *TraceConfig) (rpc.ResultStream[*txTraceResult], error) {
 	blob, err := os.ReadFile(file)
 	if err != nil {
 		return nil, fmt.Errorf("could not read file: %v", err)
@@ -477,12 +490,12 @@ func (api *API) TraceBlockFromFile(ctx context.Context, file string, config *Tra
 // TraceBadBlock returns the structured logs created during the execution of
 // EVM against a block pulled from the pool of bad ones and returns them as a JSON
 // object.
-func (api *API) TraceBadBlock(ctx context.Context, hash common.Hash, config *TraceConfig) ([]*txTraceResult, error) {
+func (api *API) TraceBadBlock(ctx context.Context, hash common.Hash, config *TraceConfig) (rpc.ResultStream[*txTraceResult], error) {
 	block := rawdb.ReadBadBlock(api.backend.ChainDb(), hash)
 	if block == nil {
 		return nil, fmt.Errorf("bad block %#x not found", hash)
 	}
-	return api.traceBlock(ctx, block, config)
+	return api.streamBlock(ctx, block, config)
 }
 
 // StandardTraceBlockToFile dumps the structured logs created during the
@@ -576,21 +589,40 @@ func (api *API) StandardTraceBadBlockToFile(ctx context.Context, hash common.Has
 // executes all the transactions contained within. The return value will be one item
 // per transaction, dependent on the requested tracer.
 func (api *API) traceBlock(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
+	stream, err := api.streamBlock(ctx, block, config)
+	if err != nil {
+		return nil, err
+	}
+	return stream.Collect()
+}
+
+// streamBlock checks that the block can be traced, and returns a stream tracing
+// its transactions as the results are consumed, so that the results of the block
+// don't need to be held in memory at once.
+func (api *API) streamBlock(ctx context.Context, block *types.Block, config *TraceConfig) (rpc.ResultStream[*txTraceResult], error) {
 	if block.NumberU64() == 0 {
 		return nil, errors.New("genesis is not traceable")
 	}
-	// Prepare base state
 	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
 	if err != nil {
 		return nil, err
 	}
+	return func(yield func(*txTraceResult) error) error {
+		return api.traceBlockResults(ctx, block, parent, config, yield)
+	}, nil
+}
+
+// traceBlockResults executes all the transactions of the block on top of its
+// parent's state, passing the trace result of each to yield in order.
+func (api *API) traceBlockResults(ctx context.Context, block *types.Block, parent *types.Block, config *TraceConfig, yield func(*txTraceResult) error) error {
+	// Prepare base state
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
 	}
 	statedb, release, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true, false)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer release()
 	// JS tracers have high overhead. In this case run a parallel
@@ -598,7 +630,7 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	// in separate worker threads.
 	if config != nil && config.Tracer != nil && *config.Tracer != "" {
 		if isJS := DefaultDirectory.IsJS(*config.Tracer); isJS {
-			return api.traceBlockParallel(ctx, block, statedb, config)
+			return api.traceBlockParallel(ctx, block, statedb, config, yield)
 		}
 	}
 	// Native tracers have low overhead
@@ -607,7 +639,6 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 		blockHash = block.Hash()
 		blockCtx  = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
 		signer    = types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time())
-		results   = make([]*txTraceResult, len(txs))
 	)
 	if beaconRoot := block.BeaconRoot(); beaconRoot != nil {
 		vmenv := vm.NewEVM(blockCtx, vm.TxContext{}, statedb, api.backend.ChainConfig(), vm.Config{})
@@ -624,30 +655,37 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 		}
 		res, err := api.traceTx(ctx, tx, msg, txctx, blockCtx, statedb, config)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := yield(&txTraceResult{TxHash: tx.Hash(), Result: res}); err != nil {
+			return err
 		}
-		results[i] = &txTraceResult{TxHash: tx.Hash(), Result: res}
 	}
-	return results, nil
+	return nil
 }
 
 // traceBlockParallel is for tracers that have a high overhead (read JS tracers). One thread
 // runs along and executes txes without tracing enabled to generate their prestate.
-// Worker threads take the tasks and the prestate and trace them.
-func (api *API) traceBlockParallel(ctx context.Context, block *types.Block, statedb *state.StateDB, config *TraceConfig) ([]*txTraceResult, error) {
+// Worker threads take the tasks and the prestate and trace them. The results are
+// passed to yield in transaction order as they complete.
+func (api *API) traceBlockParallel(ctx context.Context, block *types.Block, statedb *state.StateDB, config *TraceConfig, yield func(*txTraceResult) error) error {
 	// Execute all the transaction contained within the block concurrently
 	var (
 		txs       = block.Transactions()
 		blockHash = block.Hash()
 		signer    = types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time())
-		results   = make([]*txTraceResult, len(txs))
 		pend      sync.WaitGroup
 	)
 	threads := runtime.NumCPU()
 	if threads > len(txs) {
 		threads = len(txs)
 	}
+	// Stop tracing if the results aren't consumed anymore
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	jobs := make(chan *txTraceTask, threads)
+	done := make(chan *txTraceTask, threads)
 	for th := 0; th < threads; th++ {
 		pend.Add(1)
 		go func() {
@@ -668,49 +706,76 @@ func (api *API) traceBlockParallel(ctx context.Context, block *types.Block, stat
 				blockCtx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
 				res, err := api.traceTx(ctx, txs[task.index], msg, txctx, blockCtx, task.statedb, config)
 				if err != nil {
-					results[task.index] = &txTraceResult{TxHash: txs[task.index].Hash(), Error: err.Error()}
-					continue
+					task.result = &txTraceResult{TxHash: txs[task.index].Hash(), Error: err.Error()}
+				} else {
+					task.result = &txTraceResult{TxHash: txs[task.index].Hash(), Result: res}
 				}
-				results[task.index] = &txTraceResult{TxHash: txs[task.index].Hash(), Result: res}
+				task.statedb = nil
+				done <- task
 			}
 		}()
 	}
+	go func() {
+		pend.Wait()
+		close(done)
+	}()
 
-	// Feed the transactions into the tracers and return
+	// Feed the transactions into the tracers in the background
 	var failed error
-	blockCtx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
-txloop:
-	for i, tx := range txs {
-		// Send the trace task over for execution
-		task := &txTraceTask{statedb: statedb.Copy(), index: i}
-		select {
-		case <-ctx.Done():
-			failed = ctx.Err()
-			break txloop
-		case jobs <- task:
+	go func() {
+		defer close(jobs)
+
+		blockCtx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		for i, tx := range txs {
+			// Send the trace task over for execution
+			task := &txTraceTask{statedb: statedb.Copy(), index: i}
+			select {
+			case <-ctx.Done():
+				failed = ctx.Err()
+				return
+			case jobs <- task:
+			}
+
+			// Generate the next state snapshot fast without tracing
+			msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
+			statedb.SetTxContext(tx.Hash(), i)
+			vmenv := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, api.backend.ChainConfig(), vm.Config{})
+			if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit)); err != nil {
+				failed = err
+				return
+			}
+			// Finalize the state so any modifications are written to the trie
+			// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+			statedb.Finalise(vmenv.ChainConfig().IsEIP158(block.Number()))
 		}
+	}()
 
-		// Generate the next state snapshot fast without tracing
-		msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
-		statedb.SetTxContext(tx.Hash(), i)
-		vmenv := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, api.backend.ChainConfig(), vm.Config{})
-		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit)); err != nil {
-			failed = err
-			break txloop
+	// Pass the results on in transaction order, waiting for all tracers to stop
+	// before returning, even if the results aren't consumed anymore
+	var (
+		pending = make(map[int]*txTraceResult)
+		next    int
+		err     error
+	)
+	for task := range done {
+		if err != nil {
+			continue
+		}
+		pending[task.index] = task.result
+		for res, ok := pending[next]; ok; res, ok = pending[next] {
+			delete(pending, next)
+			next++
+			if err = yield(res); err != nil {
+				cancel()
+				break
+			}
 		}
-		// Finalize the state so any modifications are written to the trie
-		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
-		statedb.Finalise(vmenv.ChainConfig().IsEIP158(block.Number()))
 	}
-
-	close(jobs)
-	pend.Wait()
-
-	// If execution failed in between, abort
-	if failed != nil {
-		return nil, failed
+	if err != nil {
+		return err
 	}
-	return results, nil
+	// If execution failed in between, abort
+	return failed
 }
 
 // standardTraceBlockToFile configures a new tracer which uses standard JSON output,
@@ -1012,12 +1077,18 @@ func (api *API) traceTx(ctx context.Context, tx *types.Transaction, message *cor
 }
 
 // APIs return the collection of RPC services the tracer package offers.
-func APIs(backend Backend) []rpc.API {
+func APIs(backend Backend, config Config) []rpc.API {
+	api := &API{backend: backend, config: config}
+
 	// Append all the local APIs and return
 	return []rpc.API{
 		{
 			Namespace: "debug",
-			Service:   NewAPI(backend),
+			Service:   api,
+		},
+		{
+			Namespace: "trace",
+			Service:   &TraceAPI{api: api},
 		},
 	}
 }
@@ -23,8 +23,11 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"os"
+	"path/filepath"
 	"reflect"
 	"slices"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -37,6 +40,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -996,6 +1000,124 @@ func TestTraceChain(t *testing.T) {
 	}
 }
 
+func TestTraceChainToFile(t *testing.T) {
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 50, genesis, func(i int, b *core.BlockGen) {
+		// Leave every fifth block empty
+		if i%5 == 4 {
+			return
+		}
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(accounts[0].addr), accounts[1].addr, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	})
+	defer backend.chain.Stop()
+
+	// Tracing into files is disabled without a configured directory
+	api := NewAPI(backend)
+	if _, err := api.TraceChainToFile(context.Background(), 0, 50, &TraceChainFileConfig{Dir: "traces"}); err != errTraceChainDisabled {
+		t.Fatalf("error mismatch: have %v, want %v", err, errTraceChainDisabled)
+	}
+	root := t.TempDir()
+	api = &API{backend: backend, config: Config{TraceChainDir: root}}
+
+	// Output directories escaping the configured one are rejected
+	for _, dir := range []string{root, "/tmp/traces", "../traces", "traces/../../traces"} {
+		if _, err := api.TraceChainToFile(context.Background(), 0, 50, &TraceChainFileConfig{Dir: dir}); err == nil {
+			t.Fatalf("output directory %q accepted", dir)
+		}
+	}
+	// readBlocks returns the numbers of the blocks written into the given files.
+	readBlocks := func(dir string, files []string) []uint64 {
+		var numbers []uint64
+		for _, file := range files {
+			blob, err := os.ReadFile(filepath.Join(dir, file))
+			if err != nil {
+				t.Fatalf("failed to read segment: %v", err)
+			}
+			for _, line := range strings.Split(strings.TrimSpace(string(blob)), "\n") {
+				var result blockTraceResult
+				if err := json.Unmarshal([]byte(line), &result); err != nil {
+					t.Fatalf("failed to decode trace result: %v", err)
+				}
+				numbers = append(numbers, uint64(result.Block))
+			}
+		}
+		return numbers
+	}
+	var (
+		dir    = filepath.Join(root, "first")
+		size   = uint64(10)
+		config = &TraceChainFileConfig{Dir: "first", SegmentSize: &size}
+	)
+	res, err := api.TraceChainToFile(context.Background(), 0, 50, config)
+	if err != nil {
+		t.Fatalf("failed to trace chain: %v", err)
+	}
+	if !res.Done || res.Next != 51 || len(res.Files) != 5 || res.Files[0] != "traces-000000000001-000000000010.jsonl" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	var want []uint64
+	for n := uint64(1); n <= 50; n++ {
+		if n%5 != 0 || n == 50 {
+			want = append(want, n)
+		}
+	}
+	if have := readBlocks(dir, res.Files); !slices.Equal(have, want) {
+		t.Fatalf("traced blocks mismatch: have %v, want %v", have, want)
+	}
+	// Repeating a finished run doesn't trace anything, running a different
+	// range into the same directory fails.
+	if again, err := api.TraceChainToFile(context.Background(), 0, 50, config); err != nil || !again.Done || !slices.Equal(again.Files, res.Files) {
+		t.Fatalf("unexpected result of finished run: %+v, %v", again, err)
+	}
+	if _, err := api.TraceChainToFile(context.Background(), 0, 40, config); err == nil {
+		t.Fatal("expected checkpoint mismatch error")
+	}
+	// Resume an interrupted run from its checkpoint
+	dir = filepath.Join(root, "resumed")
+	config = &TraceChainFileConfig{Dir: "resumed", SegmentSize: &size}
+	tracer, _ := json.Marshal(config.TraceConfig)
+	os.MkdirAll(dir, 0755)
+	if err := writeTraceCheckpoint(dir, &traceCheckpoint{Start: 0, End: 50, Tracer: tracer, Next: 36, Files: []string{}}); err != nil {
+		t.Fatalf("failed to write checkpoint: %v", err)
+	}
+	res, err = api.TraceChainToFile(context.Background(), 0, 50, config)
+	if err != nil {
+		t.Fatalf("failed to resume chain tracing: %v", err)
+	}
+	if !res.Done || len(res.Files) != 2 || res.Files[0] != "traces-000000000036-000000000045.jsonl" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if have := readBlocks(dir, res.Files); !slices.Equal(have, want[len(want)-13:]) {
+		t.Fatalf("traced blocks mismatch: have %v, want %v", have, want[len(want)-13:])
+	}
+	// Segments of single blocks must skip the empty blocks in between, each
+	// segment containing only the block it's named after.
+	size = 1
+	config = &TraceChainFileConfig{Dir: "single", SegmentSize: &size}
+	if res, err = api.TraceChainToFile(context.Background(), 0, 50, config); err != nil {
+		t.Fatalf("failed to trace chain: %v", err)
+	}
+	if len(res.Files) != len(want) {
+		t.Fatalf("segment count mismatch: have %d, want %d", len(res.Files), len(want))
+	}
+	for i, file := range res.Files {
+		if name := fmt.Sprintf("traces-%012d-%012d.jsonl", want[i], want[i]); file != name {
+			t.Fatalf("segment %d name mismatch: have %s, want %s", i, file, name)
+		}
+		if have := readBlocks(filepath.Join(root, "single"), []string{file}); !slices.Equal(have, want[i:i+1]) {
+			t.Fatalf("segment %s blocks mismatch: have %v", file, have)
+		}
+	}
+}
+
 // newTestMergedBackend creates a post-merge chain
 func newTestMergedBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen)) *testBackend {
 	backend := &testBackend{
@@ -1082,3 +1204,104 @@ func TestTraceBlockWithBasefee(t *testing.T) {
 		}
 	}
 }
+
+func TestTraceFilterRange(t *testing.T) {
+	genesis := &core.Genesis{Config: params.TestChainConfig}
+	backend := newTestBackend(t, 10, genesis, func(i int, b *core.BlockGen) {})
+	defer backend.chain.Stop()
+
+	api := &TraceAPI{api: &API{backend: backend, config: Config{TraceFilterRange: 4}}}
+	number := func(n rpc.BlockNumber) *rpc.BlockNumber {
+		return &n
+	}
+	for i, tt := range []struct {
+		from, to *rpc.BlockNumber
+		fail     bool
+	}{
+		{from: number(3), to: number(6)},
+		{from: number(3), to: number(7), fail: true},
+		{from: number(0), to: number(rpc.LatestBlockNumber), fail: true},
+		{from: number(7), to: number(rpc.LatestBlockNumber)},
+	} {
+		_, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: tt.from, ToBlock: tt.to})
+		if tooLarge := err != nil && strings.HasPrefix(err.Error(), "block range too large"); tooLarge != tt.fail {
+			t.Errorf("test %d: range error mismatch: have %v, want failure %t", i, err, tt.fail)
+		}
+	}
+}
+
+// Tests that the results of a block trace are streamed in transaction order,
+// both by the sequential and by the parallel tracing, and that tracing stops
+// once the results aren't consumed anymore.
+func TestTraceBlockStream(t *testing.T) {
+	t.Parallel()
+
+	// Register tracers returning the index of the traced transaction, one of
+	// them flagged as JavaScript to be run in parallel
+	indexTracer := func(ctx *Context, _ json.RawMessage) (*Tracer, error) {
+		return &Tracer{
+			Hooks:     &tracing.Hooks{},
+			GetResult: func() (json.RawMessage, error) { return json.RawMessage(fmt.Sprint(ctx.TxIndex)), nil },
+			Stop:      func(err error) {},
+		}, nil
+	}
+	DefaultDirectory.Register("streamIndexTracer", indexTracer, false)
+	DefaultDirectory.Register("streamIndexTracerJS", indexTracer, true)
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  types.GenesisAlloc{accounts[0].addr: {Balance: big.NewInt(params.Ether)}},
+	}
+	const txCount = 16
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		for nonce := uint64(0); nonce < txCount; nonce++ {
+			tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{
+				Nonce:    nonce,
+				To:       &accounts[1].addr,
+				Value:    big.NewInt(1000),
+				Gas:      params.TxGas,
+				GasPrice: b.BaseFee(),
+			}), signer, accounts[0].key)
+			b.AddTx(tx)
+		}
+	})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	for _, tracer := range []string{"streamIndexTracer", "streamIndexTracerJS"} {
+		stream, err := api.TraceBlockByNumber(context.Background(), 1, &TraceConfig{Tracer: &tracer})
+		if err != nil {
+			t.Fatalf("%s: failed to trace block: %v", tracer, err)
+		}
+		var next int
+		if err := stream(func(res *txTraceResult) error {
+			if res.Error != "" {
+				return fmt.Errorf("result %d failed: %s", next, res.Error)
+			}
+			if have := string(res.Result.(json.RawMessage)); have != fmt.Sprint(next) {
+				return fmt.Errorf("result %d mismatch: have %s", next, have)
+			}
+			next++
+			return nil
+		}); err != nil {
+			t.Fatalf("%s: %v", tracer, err)
+		}
+		if next != txCount {
+			t.Fatalf("%s: result count mismatch: have %d, want %d", tracer, next, txCount)
+		}
+		// Stop consuming the results midway
+		errStop := errors.New("stop")
+		next = 0
+		err = stream(func(res *txTraceResult) error {
+			if next++; next == 3 {
+				return errStop
+			}
+			return nil
+		})
+		if err != errStop || next != 3 {
+			t.Fatalf("%s: aborted stream mismatch: have %v after %d results, want %v after 3", tracer, err, next, errStop)
+		}
+	}
+}
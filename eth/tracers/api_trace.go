@@ -0,0 +1,375 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// traceTypeTrace is the replay output mode of the flat call traces.
+	traceTypeTrace = "trace"
+
+	// traceTypeVMTrace is the replay output mode of the executed instructions.
+	traceTypeVMTrace = "vmTrace"
+
+	// traceTypeStateDiff is the replay output mode of the state modifications.
+	traceTypeStateDiff = "stateDiff"
+)
+
+var (
+	// flatCallTracerName is the tracer producing the Parity-style call traces.
+	flatCallTracerName = "flatCallTracer"
+
+	// flatCallTracerConfig instructs the flat call tracer to report errors
+	// the way Parity does.
+	flatCallTracerConfig = json.RawMessage(`{"convertParityErrors":true}`)
+
+	// replayTracers are the tracers backing the different replay output modes.
+	replayTracers = map[string]string{
+		traceTypeTrace:     flatCallTracerName,
+		traceTypeVMTrace:   "vmTracer",
+		traceTypeStateDiff: "stateDiffTracer",
+	}
+)
+
+// TraceAPI is the collection of Parity (OpenEthereum) compatible tracing APIs
+// exposed over the trace namespace. Note, block and uncle rewards are not
+// reported in the traces.
+type TraceAPI struct {
+	api *API
+}
+
+// NewTraceAPI creates a new API definition for the Parity-compatible tracing
+// methods of the Ethereum service.
+func NewTraceAPI(backend Backend) *TraceAPI {
+	return &TraceAPI{api: NewAPI(backend)}
+}
+
+// TraceFilterArgs represents the arguments of the trace_filter method.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// traceResults is the result of replaying a single transaction.
+type traceResults struct {
+	Output          hexutil.Bytes   `json:"output"`
+	StateDiff       json.RawMessage `json:"stateDiff"`
+	Trace           json.RawMessage `json:"trace"`
+	VMTrace         json.RawMessage `json:"vmTrace"`
+	TransactionHash *common.Hash    `json:"transactionHash,omitempty"`
+}
+
+// flatTrace is the subset of the flat call trace fields needed for filtering
+// the traces by address.
+type flatTrace struct {
+	Action struct {
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`
+		RefundAddress *common.Address `json:"refundAddress"`
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"`
+	} `json:"result"`
+}
+
+// matches reports whether the trace is originated from any of the given
+// senders and is targeting any of the given recipients. An empty list
+// matches everything.
+func (t *flatTrace) matches(from, to []common.Address) bool {
+	if len(from) > 0 {
+		sender := t.Action.From
+		if sender == nil {
+			sender = t.Action.Address // selfdestruct
+		}
+		if sender == nil || !slices.Contains(from, *sender) {
+			return false
+		}
+	}
+	if len(to) > 0 {
+		recipient := t.Action.To
+		if recipient == nil && t.Result != nil {
+			recipient = t.Result.Address // contract creation
+		}
+		if recipient == nil {
+			recipient = t.Action.RefundAddress // selfdestruct
+		}
+		if recipient == nil || !slices.Contains(to, *recipient) {
+			return false
+		}
+	}
+	return true
+}
+
+// Block returns the flat call traces of all the transactions in the block.
+func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]json.RawMessage, error) {
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return api.blockTraces(ctx, block)
+}
+
+// Transaction returns the flat call traces of the transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) (interface{}, error) {
+	return api.api.TraceTransaction(ctx, hash, &TraceConfig{
+		Tracer:       &flatCallTracerName,
+		TracerConfig: flatCallTracerConfig,
+	})
+}
+
+// Filter returns the flat call traces in the given block range which match the
+// specified sender and recipient addresses.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]json.RawMessage, error) {
+	from, err := api.resolveNumber(ctx, args.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.resolveNumber(ctx, args.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range: %d > %d", from, to)
+	}
+	if limit := api.api.config.TraceFilterRange; limit > 0 && to-from >= limit {
+		return nil, fmt.Errorf("block range too large: %d blocks, limit %d", to-from+1, limit)
+	}
+	var (
+		skip    uint64
+		results = make([]json.RawMessage, 0)
+	)
+	if args.After != nil {
+		skip = *args.After
+	}
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block, err := api.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		traces, err := api.blockTraces(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			var t flatTrace
+			if err := json.Unmarshal(trace, &t); err != nil {
+				return nil, err
+			}
+			if !t.matches(args.FromAddress, args.ToAddress) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			results = append(results, trace)
+			if args.Count != nil && uint64(len(results)) >= *args.Count {
+				return results, nil
+			}
+		}
+	}
+	return results, nil
+}
+
+// ReplayTransaction re-executes the transaction and returns the requested
+// traces. The supported trace types are "trace", "vmTrace" and "stateDiff".
+func (api *TraceAPI) ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []string) (*traceResults, error) {
+	config, err := replayConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.api.TraceTransaction(ctx, hash, config)
+	if err != nil {
+		return nil, err
+	}
+	return parseReplayResult(res)
+}
+
+// ReplayBlockTransactions re-executes all the transactions in the block and
+// returns the requested traces. The supported trace types are "trace",
+// "vmTrace" and "stateDiff".
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, traceTypes []string) ([]*traceResults, error) {
+	config, err := replayConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	block, err := api.blockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return []*traceResults{}, nil
+	}
+	txs, err := api.api.traceBlock(ctx, block, config)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*traceResults, len(txs))
+	for i, tx := range txs {
+		if tx.Error != "" {
+			return nil, errors.New(tx.Error)
+		}
+		res, err := parseReplayResult(tx.Result)
+		if err != nil {
+			return nil, err
+		}
+		res.TransactionHash = &txs[i].TxHash
+		results[i] = res
+	}
+	return results, nil
+}
+
+// blockTraces returns the flat call traces of all the transactions in the block.
+func (api *TraceAPI) blockTraces(ctx context.Context, block *types.Block) ([]json.RawMessage, error) {
+	traces := make([]json.RawMessage, 0)
+	if block.NumberU64() == 0 {
+		return traces, nil
+	}
+	txs, err := api.api.traceBlock(ctx, block, &TraceConfig{
+		Tracer:       &flatCallTracerName,
+		TracerConfig: flatCallTracerConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range txs {
+		if tx.Error != "" {
+			return nil, errors.New(tx.Error)
+		}
+		var frames []json.RawMessage
+		if err := unmarshalResult(tx.Result, &frames); err != nil {
+			return nil, err
+		}
+		traces = append(traces, frames...)
+	}
+	return traces, nil
+}
+
+// blockByNumberOrHash retrieves the block specified either by number or hash.
+func (api *TraceAPI) blockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return api.api.blockByHash(ctx, hash)
+	}
+	if number, ok := blockNrOrHash.Number(); ok {
+		return api.api.blockByNumber(ctx, number)
+	}
+	return nil, errors.New("invalid arguments; neither block nor hash specified")
+}
+
+// resolveNumber converts the given block number, which might be a tag, into the
+// number of the corresponding block. Nil is interpreted as the latest block.
+func (api *TraceAPI) resolveNumber(ctx context.Context, number *rpc.BlockNumber) (uint64, error) {
+	if number == nil {
+		latest := rpc.LatestBlockNumber
+		number = &latest
+	}
+	if *number >= 0 {
+		return uint64(*number), nil
+	}
+	header, err := api.api.backend.HeaderByNumber(ctx, *number)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block #%d not found", *number)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// replayConfig assembles the trace config running all the tracers required by
+// the given trace types at once. The call tracer is always included, for the
+// output of the transaction.
+func replayConfig(traceTypes []string) (*TraceConfig, error) {
+	config := map[string]json.RawMessage{
+		"callTracer": json.RawMessage(`{"onlyTopCall":true}`),
+	}
+	for _, typ := range traceTypes {
+		name, ok := replayTracers[typ]
+		if !ok {
+			return nil, fmt.Errorf("invalid trace type %q", typ)
+		}
+		if name == flatCallTracerName {
+			config[name] = flatCallTracerConfig
+		} else {
+			config[name] = json.RawMessage(`{}`)
+		}
+	}
+	enc, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	tracer := "muxTracer"
+	return &TraceConfig{Tracer: &tracer, TracerConfig: enc}, nil
+}
+
+// parseReplayResult converts the output of the tracers assembled by replayConfig
+// into the replay result.
+func parseReplayResult(result interface{}) (*traceResults, error) {
+	var outputs map[string]json.RawMessage
+	if err := unmarshalResult(result, &outputs); err != nil {
+		return nil, err
+	}
+	var call struct {
+		Output hexutil.Bytes `json:"output"`
+	}
+	if enc, ok := outputs["callTracer"]; ok {
+		if err := json.Unmarshal(enc, &call); err != nil {
+			return nil, err
+		}
+	}
+	res := &traceResults{
+		Output:    call.Output,
+		Trace:     outputs[replayTracers[traceTypeTrace]],
+		VMTrace:   outputs[replayTracers[traceTypeVMTrace]],
+		StateDiff: outputs[replayTracers[traceTypeStateDiff]],
+	}
+	if res.Output == nil {
+		res.Output = hexutil.Bytes{}
+	}
+	if res.Trace == nil {
+		res.Trace = json.RawMessage(`[]`)
+	}
+	return res, nil
+}
+
+// unmarshalResult decodes the json-encoded tracer result into the given object.
+func unmarshalResult(result interface{}, v interface{}) error {
+	enc, ok := result.(json.RawMessage)
+	if !ok {
+		return fmt.Errorf("unexpected tracer result %T", result)
+	}
+	return json.Unmarshal(enc, v)
+}
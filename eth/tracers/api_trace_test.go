@@ -0,0 +1,160 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests the output of the trace namespace on a plain value transfer and on a
+// contract call.
+func TestTraceAPI(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		receiver = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+		contract = common.HexToAddress("0x0000000000000000000000000000000000000c0d")
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				contract: {
+					// PUSH1 0x2a PUSH1 0x01 SSTORE PUSH1 0x2a PUSH1 0x00 MSTORE PUSH1 0x20 PUSH1 0x00 RETURN
+					Code: common.FromHex("602a600155602a60005260206000f3"),
+				},
+			},
+		}
+		signer = types.HomesteadSigner{}
+		txs    []*types.Transaction
+	)
+	backend := tracers.NewTestBackend(t, 2, genesis, func(i int, b *core.BlockGen) {
+		// Block 1 transfers 1000 wei to the receiver, block 2 calls the contract
+		to, value, gas := &receiver, big.NewInt(1000), params.TxGas
+		if i == 1 {
+			to, value, gas = &contract, big.NewInt(0), 100_000
+		}
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{
+			Nonce:    uint64(i),
+			To:       to,
+			Value:    value,
+			Gas:      gas,
+			GasPrice: b.BaseFee(),
+		}), signer, key)
+		b.AddTx(tx)
+		txs = append(txs, tx)
+	})
+	api := tracers.NewTraceAPI(backend)
+
+	blockHash := func(number rpc.BlockNumber) common.Hash {
+		header, err := backend.HeaderByNumber(context.Background(), number)
+		if err != nil {
+			t.Fatalf("failed to retrieve header %d: %v", number, err)
+		}
+		return header.Hash()
+	}
+	var (
+		first         = rpc.BlockNumber(1)
+		transferTrace = fmt.Sprintf(`{"action":{"callType":"call","from":"%s","gas":"0x5208","input":"0x","to":"%s","value":"0x3e8"},"blockHash":"%s","blockNumber":1,"result":{"gasUsed":"0x5208","output":"0x"},"subtraces":0,"traceAddress":[],"transactionHash":"%s","transactionPosition":0,"type":"call"}`,
+			strings.ToLower(sender.Hex()), strings.ToLower(receiver.Hex()), blockHash(1).Hex(), txs[0].Hash().Hex())
+		callTrace = fmt.Sprintf(`{"action":{"callType":"call","from":"%s","gas":"0x186a0","input":"0x","to":"%s","value":"0x0"},"blockHash":"%s","blockNumber":2,"result":{"gasUsed":"0xa874","output":"0x000000000000000000000000000000000000000000000000000000000000002a"},"subtraces":0,"traceAddress":[],"transactionHash":"%s","transactionPosition":0,"type":"call"}`,
+			strings.ToLower(sender.Hex()), strings.ToLower(contract.Hex()), blockHash(2).Hex(), txs[1].Hash().Hex())
+	)
+	for i, tc := range []struct {
+		name string
+		call func() (interface{}, error)
+		want string
+	}{
+		{
+			name: "trace_transaction transfer",
+			call: func() (interface{}, error) { return api.Transaction(context.Background(), txs[0].Hash()) },
+			want: `[` + transferTrace + `]`,
+		},
+		{
+			name: "trace_transaction call",
+			call: func() (interface{}, error) { return api.Transaction(context.Background(), txs[1].Hash()) },
+			want: `[` + callTrace + `]`,
+		},
+		{
+			name: "trace_block",
+			call: func() (interface{}, error) { return api.Block(context.Background(), 2) },
+			want: `[` + callTrace + `]`,
+		},
+		{
+			name: "trace_filter",
+			call: func() (interface{}, error) {
+				return api.Filter(context.Background(), tracers.TraceFilterArgs{FromBlock: &first, FromAddress: []common.Address{sender}})
+			},
+			want: `[` + transferTrace + `,` + callTrace + `]`,
+		},
+		{
+			name: "trace_filter recipient",
+			call: func() (interface{}, error) {
+				return api.Filter(context.Background(), tracers.TraceFilterArgs{ToAddress: []common.Address{contract}})
+			},
+			want: `[` + callTrace + `]`,
+		},
+		{
+			name: "trace_replayTransaction transfer",
+			call: func() (interface{}, error) {
+				return api.ReplayTransaction(context.Background(), txs[0].Hash(), []string{"trace", "stateDiff"})
+			},
+			want: fmt.Sprintf(`{"output":"0x","stateDiff":{"%s":{"balance":{"+":"0x3e8"},"code":{"+":"0x"},"nonce":{"+":"0x0"},"storage":{}},"%s":{"balance":{"*":{"from":"0xde0b6b3a7640000","to":"0xde0a5fd640af618"}},"code":"=","nonce":{"*":{"from":"0x0","to":"0x1"}},"storage":{}}},"trace":[%s],"vmTrace":null}`,
+				strings.ToLower(receiver.Hex()), strings.ToLower(sender.Hex()), transferTrace),
+		},
+		{
+			name: "trace_replayTransaction call",
+			call: func() (interface{}, error) {
+				return api.ReplayTransaction(context.Background(), txs[1].Hash(), []string{"trace", "stateDiff"})
+			},
+			want: fmt.Sprintf(`{"output":"0x000000000000000000000000000000000000000000000000000000000000002a","stateDiff":{"%s":{"balance":"=","code":"=","nonce":"=","storage":{"0x0000000000000000000000000000000000000000000000000000000000000001":{"*":{"from":"0x0000000000000000000000000000000000000000000000000000000000000000","to":"0x000000000000000000000000000000000000000000000000000000000000002a"}}}},"%s":{"balance":{"*":{"from":"0xde0a5fd640af618","to":"0xde087ec46fb2464"}},"code":"=","nonce":{"*":{"from":"0x1","to":"0x2"}},"storage":{}}},"trace":[%s],"vmTrace":null}`,
+				strings.ToLower(contract.Hex()), strings.ToLower(sender.Hex()), callTrace),
+		},
+		{
+			name: "trace_replayBlockTransactions",
+			call: func() (interface{}, error) {
+				return api.ReplayBlockTransactions(context.Background(), rpc.BlockNumberOrHashWithNumber(1), []string{"trace"})
+			},
+			want: fmt.Sprintf(`[{"output":"0x","stateDiff":null,"trace":[%s],"vmTrace":null,"transactionHash":"%s"}]`, transferTrace, txs[0].Hash().Hex()),
+		},
+	} {
+		result, err := tc.call()
+		if err != nil {
+			t.Errorf("test %d (%s): failed to trace: %v", i, tc.name, err)
+			continue
+		}
+		have, _ := json.Marshal(result)
+		if string(have) != tc.want {
+			t.Errorf("test %d (%s): result mismatch\nhave: %v\nwant: %v\n", i, tc.name, string(have), tc.want)
+		}
+	}
+}
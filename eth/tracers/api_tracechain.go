@@ -0,0 +1,301 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// defaultTraceSegmentSize is the default number of blocks written into a
+	// single segment file by traceChainToFile.
+	defaultTraceSegmentSize = 1000
+
+	// traceCheckpointFile is the name of the file tracking the progress of
+	// tracing a chain segment into files.
+	traceCheckpointFile = "checkpoint.json"
+)
+
+// errTraceChainDisabled is returned by debug_traceChainToFile if the node has no
+// directory configured for its output.
+var errTraceChainDisabled = errors.New("tracing into files is disabled, no trace directory configured")
+
+// TraceChainFileConfig holds the extra parameters of tracing a chain segment
+// into files.
+type TraceChainFileConfig struct {
+	*TraceConfig
+	Dir         string  `json:"dir"`         // Directory to write the segment files and the checkpoint into, relative to the configured one
+	SegmentSize *uint64 `json:"segmentSize"` // Maximum number of blocks written into a single segment file
+}
+
+// traceCheckpoint is the persisted progress of tracing a chain segment into
+// files. All the blocks before Next are written into the listed files.
+type traceCheckpoint struct {
+	Start  hexutil.Uint64  `json:"start"`
+	End    hexutil.Uint64  `json:"end"`
+	Tracer json.RawMessage `json:"tracer"`
+	Next   hexutil.Uint64  `json:"next"`
+	Files  []string        `json:"files"`
+}
+
+// TraceChainFileResult is the outcome of tracing a chain segment into files.
+type TraceChainFileResult struct {
+	Files []string       `json:"files"` // Segment files written so far, including the earlier runs
+	Next  hexutil.Uint64 `json:"next"`  // First block which is not traced yet
+	Done  bool           `json:"done"`  // Whether the entire chain segment is traced
+}
+
+// TraceChainToFile traces the blocks in the range (start, end] the same way as
+// debug_traceChain does, and writes the results as json lines into segment
+// files in the given directory, in block order. The progress is checkpointed
+// after every segment, so an interrupted run is resumed from the checkpoint by
+// invoking the method again with the same parameters.
+//
+// The output directory is resolved under the directory configured by the node
+// operator, the method is disabled if there's none.
+func (api *API) TraceChainToFile(ctx context.Context, start, end rpc.BlockNumber, config *TraceChainFileConfig) (*TraceChainFileResult, error) {
+	if api.config.TraceChainDir == "" {
+		return nil, errTraceChainDisabled
+	}
+	if config == nil || config.Dir == "" {
+		return nil, errors.New("output directory is required")
+	}
+	if !filepath.IsLocal(config.Dir) {
+		return nil, fmt.Errorf("output directory %q must be relative and stay within the trace directory", config.Dir)
+	}
+	dir := filepath.Join(api.config.TraceChainDir, config.Dir)
+
+	from, err := api.blockByNumber(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.blockByNumber(ctx, end)
+	if err != nil {
+		return nil, err
+	}
+	if from.Number().Cmp(to.Number()) >= 0 {
+		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", end, start)
+	}
+	size := uint64(defaultTraceSegmentSize)
+	if config.SegmentSize != nil && *config.SegmentSize > 0 {
+		size = *config.SegmentSize
+	}
+	tracer, err := json.Marshal(config.TraceConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	// Load the checkpoint of the previous run, if there's any
+	checkpoint, err := readTraceCheckpoint(dir)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint == nil {
+		checkpoint = &traceCheckpoint{
+			Start:  hexutil.Uint64(from.NumberU64()),
+			End:    hexutil.Uint64(to.NumberU64()),
+			Tracer: tracer,
+			Next:   hexutil.Uint64(from.NumberU64() + 1),
+			Files:  make([]string, 0),
+		}
+	} else if uint64(checkpoint.Start) != from.NumberU64() || uint64(checkpoint.End) != to.NumberU64() || !bytes.Equal(checkpoint.Tracer, tracer) {
+		return nil, fmt.Errorf("directory contains checkpoint of a different trace run (#%d-#%d)", checkpoint.Start, checkpoint.End)
+	}
+	if uint64(checkpoint.Next) > to.NumberU64() {
+		return &TraceChainFileResult{Files: checkpoint.Files, Next: checkpoint.Next, Done: true}, nil
+	}
+	// Resume tracing from the checkpoint. The chain tracer excludes the
+	// start block, so launch it from the parent of the next block.
+	if uint64(checkpoint.Next) > from.NumberU64()+1 {
+		if from, err = api.blockByNumber(ctx, rpc.BlockNumber(checkpoint.Next-1)); err != nil {
+			return nil, err
+		}
+		log.Info("Resuming chain tracing into files", "dir", dir, "next", uint64(checkpoint.Next), "end", to.NumberU64())
+	}
+	// Abort the chain tracer if the request is cancelled or the results can't
+	// be written.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	closed := make(chan error)
+	go func() {
+		<-ctx.Done()
+		close(closed)
+	}()
+	w := &traceSegmentWriter{dir: dir, size: size, checkpoint: checkpoint}
+	defer w.abort()
+
+	var failed error
+	for result := range api.traceChain(from, to, config.TraceConfig, closed) {
+		// Keep draining the results after an abort, the chain tracer can only
+		// exit when all the results are consumed.
+		if failed != nil || ctx.Err() != nil {
+			continue
+		}
+		if failed = w.write(result); failed != nil {
+			cancel()
+		}
+	}
+	// Seal the blocks written so far, the chain tracer always reports the
+	// last block, so the run is complete if it's written.
+	if failed == nil {
+		failed = w.seal(w.last)
+	}
+	if failed != nil {
+		return nil, failed
+	}
+	res := &TraceChainFileResult{
+		Files: checkpoint.Files,
+		Next:  checkpoint.Next,
+		Done:  uint64(checkpoint.Next) > to.NumberU64(),
+	}
+	if !res.Done {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("chain tracing interrupted at block #%d", uint64(res.Next))
+	}
+	return res, nil
+}
+
+// readTraceCheckpoint loads the checkpoint from the given directory, returning
+// nil if there's none.
+func readTraceCheckpoint(dir string) (*traceCheckpoint, error) {
+	blob, err := os.ReadFile(filepath.Join(dir, traceCheckpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint traceCheckpoint
+	if err := json.Unmarshal(blob, &checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid trace checkpoint: %v", err)
+	}
+	return &checkpoint, nil
+}
+
+// writeTraceCheckpoint atomically replaces the checkpoint in the given directory.
+func writeTraceCheckpoint(dir string, checkpoint *traceCheckpoint) error {
+	blob, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, traceCheckpointFile+".tmp")
+	if err := os.WriteFile(tmp, blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, traceCheckpointFile))
+}
+
+// traceSegmentWriter writes the chain trace results into segment files. The
+// segment being written is stored in a temporary file, which is renamed after
+// the covered block range and recorded in the checkpoint once it's sealed.
+type traceSegmentWriter struct {
+	dir        string
+	size       uint64
+	checkpoint *traceCheckpoint
+
+	file *os.File
+	buf  *bufio.Writer
+	last uint64 // Number of the last block written
+}
+
+// write appends the traces of a block to the current segment, sealing the
+// segments first until the block is in range of the current one.
+func (w *traceSegmentWriter) write(result *blockTraceResult) error {
+	number := uint64(result.Block)
+	for end := uint64(w.checkpoint.Next) + w.size; number >= end; end = uint64(w.checkpoint.Next) + w.size {
+		// The results are delivered in order, all the blocks of the segment
+		// are done, even the empty ones which are not reported.
+		if w.file != nil {
+			if err := w.seal(end - 1); err != nil {
+				return err
+			}
+			continue
+		}
+		// Nothing is written into the segment, skip the empty ones without
+		// writing them, the checkpoint is moved on the next seal.
+		w.checkpoint.Next += hexutil.Uint64((number - uint64(w.checkpoint.Next)) / w.size * w.size)
+	}
+	if w.file == nil {
+		file, err := os.Create(filepath.Join(w.dir, "segment.tmp"))
+		if err != nil {
+			return err
+		}
+		w.file, w.buf = file, bufio.NewWriter(file)
+	}
+	blob, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if _, err := w.buf.Write(append(blob, '\n')); err != nil {
+		return err
+	}
+	w.last = number
+	return nil
+}
+
+// seal finalizes the current segment, covering the blocks up to the given one,
+// and moves the checkpoint past it.
+func (w *traceSegmentWriter) seal(last uint64) error {
+	if last < uint64(w.checkpoint.Next) {
+		return w.abort()
+	}
+	name := fmt.Sprintf("traces-%012d-%012d.jsonl", uint64(w.checkpoint.Next), last)
+	if w.file != nil {
+		if err := w.buf.Flush(); err != nil {
+			return err
+		}
+		if err := w.file.Sync(); err != nil {
+			return err
+		}
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file, w.buf = nil, nil
+		if err := os.Rename(filepath.Join(w.dir, "segment.tmp"), filepath.Join(w.dir, name)); err != nil {
+			return err
+		}
+		w.checkpoint.Files = append(w.checkpoint.Files, name)
+	}
+	w.checkpoint.Next = hexutil.Uint64(last + 1)
+	return writeTraceCheckpoint(w.dir, w.checkpoint)
+}
+
+// abort discards the unsealed segment, if any.
+func (w *traceSegmentWriter) abort() error {
+	if w.file == nil {
+		return nil
+	}
+	w.file.Close()
+	w.file, w.buf = nil, nil
+	return os.Remove(filepath.Join(w.dir, "segment.tmp"))
+}
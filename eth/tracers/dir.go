@@ -18,6 +18,7 @@ package tracers
 
 import (
 	"encoding/json"
+	"errors"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -44,6 +45,18 @@ type Tracer struct {
 	Stop func(err error)
 }
 
+// ErrJSTracersDisabled is returned when a JavaScript tracer is requested, but
+// the JavaScript tracers are disabled by the node operator.
+var ErrJSTracersDisabled = errors.New("javascript tracers are disabled")
+
+// JSLimits bounds the resources a single JavaScript tracer may consume. The
+// zero value of a limit means unlimited.
+type JSLimits struct {
+	MaxHeap   uint64 // Maximum estimated size of the tracer state, and of the buffers created by a single call, in bytes
+	MaxSteps  uint64 // Maximum number of tracer function invocations and executed function calls and loop iterations
+	MaxOutput uint64 // Maximum size of the json-encoded result in bytes
+}
+
 type ctorFn func(*Context, json.RawMessage) (*Tracer, error)
 type jsCtorFn func(string, *Context, json.RawMessage) (*Tracer, error)
 
@@ -61,6 +74,9 @@ var DefaultDirectory = directory{elems: make(map[string]elem)}
 type directory struct {
 	elems  map[string]elem
 	jsEval jsCtorFn
+
+	jsDisabled bool     // Whether the JavaScript tracers are rejected
+	jsLimits   JSLimits // Resource limits of the JavaScript tracers
 }
 
 // Register registers a method as a lookup for tracers, meaning that
@@ -75,10 +91,26 @@ func (d *directory) RegisterJSEval(f jsCtorFn) {
 	d.jsEval = f
 }
 
+// ConfigureJS sets whether the JavaScript tracers are enabled, and the resource
+// limits they are instantiated with. It's meant to be called once at startup,
+// before any tracer is created.
+func (d *directory) ConfigureJS(enabled bool, limits JSLimits) {
+	d.jsDisabled = !enabled
+	d.jsLimits = limits
+}
+
+// JSLimits returns the resource limits of the JavaScript tracers.
+func (d *directory) JSLimits() JSLimits {
+	return d.jsLimits
+}
+
 // New returns a new instance of a tracer, by iterating through the
 // registered lookups. Name is either name of an existing tracer
 // or an arbitrary JS code.
 func (d *directory) New(name string, ctx *Context, cfg json.RawMessage) (*Tracer, error) {
+	if d.jsDisabled && d.IsJS(name) {
+		return nil, ErrJSTracersDisabled
+	}
 	if elem, ok := d.elems[name]; ok {
 		return elem.ctor(ctx, cfg)
 	}
@@ -0,0 +1,196 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+var (
+	parityTestKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	parityTestSender   = crypto.PubkeyToAddress(parityTestKey.PublicKey)
+	parityTestContract = common.HexToAddress("0x00000000000000000000000000000000000c0de")
+)
+
+// runParityTracer executes a transaction calling a contract which overwrites
+// the storage slot 0x01 with 0x2a, and returns the result of the given tracer
+// along with the gas used by the transaction.
+func runParityTracer(t *testing.T, name string) (json.RawMessage, uint64) {
+	var (
+		config  = params.MergedTestChainConfig
+		genesis = &core.Genesis{
+			Config: config,
+			Alloc: types.GenesisAlloc{
+				parityTestSender: {Balance: big.NewInt(params.Ether)},
+				parityTestContract: {
+					// PUSH1 0x2a PUSH1 0x01 SSTORE STOP
+					Code:    common.FromHex("602a60015500"),
+					Storage: map[common.Hash]common.Hash{common.HexToHash("0x01"): common.HexToHash("0x05")},
+				},
+			},
+		}
+		context = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			BlockNumber: big.NewInt(1),
+			Time:        1,
+			Difficulty:  big.NewInt(0),
+			GasLimit:    30_000_000,
+			BaseFee:     big.NewInt(1),
+			Random:      &common.Hash{},
+		}
+		signer = types.LatestSigner(config)
+		state  = tests.MakePreState(rawdb.NewMemoryDatabase(), genesis.Alloc, false, rawdb.HashScheme)
+	)
+	defer state.Close()
+
+	tx, err := types.SignNewTx(parityTestKey, signer, &types.LegacyTx{
+		To:       &parityTestContract,
+		Gas:      100_000,
+		GasPrice: big.NewInt(1),
+	})
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	tracer, err := tracers.DefaultDirectory.New(name, new(tracers.Context), nil)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	state.StateDB.SetLogger(tracer.Hooks)
+	msg, err := core.TransactionToMessage(tx, signer, context.BaseFee)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	evm := vm.NewEVM(context, core.NewEVMTxContext(msg), state.StateDB, config, vm.Config{Tracer: tracer.Hooks})
+	tracer.OnTxStart(evm.GetVMContext(), tx, msg.From)
+	res, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	state.StateDB.Finalise(true)
+	if tracer.OnTxEnd != nil {
+		tracer.OnTxEnd(&types.Receipt{GasUsed: res.UsedGas}, nil)
+	}
+
+	result, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return result, res.UsedGas
+}
+
+func TestStateDiffTracer(t *testing.T) {
+	result, gasUsed := runParityTracer(t, "stateDiffTracer")
+
+	balance := new(big.Int).Sub(big.NewInt(params.Ether), new(big.Int).SetUint64(gasUsed))
+	want := fmt.Sprintf(`{
+		"%s": {
+			"balance": {"*": {"from": "0xde0b6b3a7640000", "to": "%#x"}},
+			"code": "=",
+			"nonce": {"*": {"from": "0x0", "to": "0x1"}},
+			"storage": {}
+		},
+		"%s": {
+			"balance": "=",
+			"code": "=",
+			"nonce": "=",
+			"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": {"*": {
+					"from": "0x0000000000000000000000000000000000000000000000000000000000000005",
+					"to": "0x000000000000000000000000000000000000000000000000000000000000002a"
+				}}
+			}
+		}
+	}`, parityTestSender.Hex(), balance, parityTestContract.Hex())
+
+	var have, expect map[common.Address]interface{}
+	if err := json.Unmarshal(result, &have); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if err := json.Unmarshal([]byte(want), &expect); err != nil {
+		t.Fatalf("failed to decode expected result: %v", err)
+	}
+	if !reflect.DeepEqual(have, expect) {
+		t.Fatalf("state diff mismatch\nhave: %s\nwant: %s", result, want)
+	}
+}
+
+func TestVMTracer(t *testing.T) {
+	result, _ := runParityTracer(t, "vmTracer")
+
+	var trace struct {
+		Code string `json:"code"`
+		Ops  []struct {
+			Cost uint64 `json:"cost"`
+			Pc   uint64 `json:"pc"`
+			Ex   *struct {
+				Push  []string `json:"push"`
+				Used  uint64   `json:"used"`
+				Store *struct {
+					Key string `json:"key"`
+					Val string `json:"val"`
+				} `json:"store"`
+			} `json:"ex"`
+		} `json:"ops"`
+	}
+	if err := json.Unmarshal(result, &trace); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if trace.Code != "0x602a60015500" {
+		t.Fatalf("code mismatch: have %s", trace.Code)
+	}
+	if len(trace.Ops) != 4 {
+		t.Fatalf("unexpected number of ops: have %d, want 4", len(trace.Ops))
+	}
+	var (
+		pcs    = []uint64{0, 2, 4, 5}
+		pushes = []string{"0x2a", "0x1", "", ""}
+		gas    = uint64(100_000 - params.TxGas)
+	)
+	for i, op := range trace.Ops {
+		if op.Pc != pcs[i] {
+			t.Errorf("op %d: pc mismatch: have %d, want %d", i, op.Pc, pcs[i])
+		}
+		if op.Ex == nil {
+			t.Fatalf("op %d: missing execution effects", i)
+		}
+		gas -= op.Cost
+		if op.Ex.Used != gas {
+			t.Errorf("op %d: used gas mismatch: have %d, want %d", i, op.Ex.Used, gas)
+		}
+		if pushes[i] == "" && len(op.Ex.Push) != 0 || pushes[i] != "" && (len(op.Ex.Push) != 1 || op.Ex.Push[0] != pushes[i]) {
+			t.Errorf("op %d: push mismatch: have %v, want %v", i, op.Ex.Push, pushes[i])
+		}
+	}
+	if store := trace.Ops[2].Ex.Store; store == nil || store.Key != "0x1" || store.Val != "0x2a" {
+		t.Errorf("sstore mismatch: have %+v", store)
+	}
+}
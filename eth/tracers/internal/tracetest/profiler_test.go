@@ -0,0 +1,167 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+	"github.com/google/pprof/profile"
+)
+
+var (
+	profilerTestKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	profilerTestCaller = common.HexToAddress("0x00000000000000000000000000000000000ca11")
+	profilerTestCallee = common.HexToAddress("0x00000000000000000000000000000000000c0de")
+)
+
+// runProfiler executes a transaction calling a contract which calls another one
+// overwriting the storage slot 0x01 with 0x2a, and returns the result of the
+// opcode profiler with the given config.
+func runProfiler(t *testing.T, cfg json.RawMessage) json.RawMessage {
+	var (
+		config  = params.MergedTestChainConfig
+		genesis = &core.Genesis{
+			Config: config,
+			Alloc: types.GenesisAlloc{
+				crypto.PubkeyToAddress(profilerTestKey.PublicKey): {Balance: big.NewInt(params.Ether)},
+				profilerTestCaller: {
+					// PUSH1 0 PUSH1 0 PUSH1 0 PUSH1 0 PUSH1 0 PUSH2 0x0c0de GAS CALL STOP
+					Code: common.FromHex("6000600060006000600061c0de5af100"),
+				},
+				profilerTestCallee: {
+					// PUSH1 0x2a PUSH1 0x01 SSTORE STOP
+					Code:    common.FromHex("602a60015500"),
+					Storage: map[common.Hash]common.Hash{common.HexToHash("0x01"): common.HexToHash("0x05")},
+				},
+			},
+		}
+		context = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			BlockNumber: big.NewInt(1),
+			Time:        1,
+			Difficulty:  big.NewInt(0),
+			GasLimit:    30_000_000,
+			BaseFee:     big.NewInt(1),
+			Random:      &common.Hash{},
+		}
+		signer = types.LatestSigner(config)
+		state  = tests.MakePreState(rawdb.NewMemoryDatabase(), genesis.Alloc, false, rawdb.HashScheme)
+	)
+	defer state.Close()
+
+	tx, err := types.SignNewTx(profilerTestKey, signer, &types.LegacyTx{
+		To:       &profilerTestCaller,
+		Gas:      100_000,
+		GasPrice: big.NewInt(1),
+	})
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	tracer, err := tracers.DefaultDirectory.New("opcodeProfiler", new(tracers.Context), cfg)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	msg, err := core.TransactionToMessage(tx, signer, context.BaseFee)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	evm := vm.NewEVM(context, core.NewEVMTxContext(msg), state.StateDB, config, vm.Config{Tracer: tracer.Hooks})
+	if _, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	result, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return result
+}
+
+func TestOpcodeProfilerFolded(t *testing.T) {
+	result := runProfiler(t, json.RawMessage(`{"withPC":true}`))
+
+	var have string
+	if err := json.Unmarshal(result, &have); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	// The call costs the cold account access only, the nested frame is profiled
+	// separately, overwriting a cold non-zero slot.
+	var (
+		caller = profilerTestCaller.Hex()
+		callee = profilerTestCallee.Hex()
+		want   = fmt.Sprintf("%[1]s;%[2]s;PUSH1@0 3\n%[1]s;%[2]s;PUSH1@2 3\n%[1]s;%[2]s;SSTORE@4 5000\n%[1]s;%[2]s;STOP@5 0\n", caller, callee)
+	)
+	want += fmt.Sprintf("%[1]s;CALL@14 2600\n%[1]s;GAS@13 2\n%[1]s;PUSH1@0 3\n%[1]s;PUSH1@2 3\n%[1]s;PUSH1@4 3\n%[1]s;PUSH1@6 3\n%[1]s;PUSH1@8 3\n%[1]s;PUSH2@10 3\n%[1]s;STOP@15 0\n", caller)
+	if have != want {
+		t.Fatalf("folded profile mismatch\nhave: %s\nwant: %s", have, want)
+	}
+}
+
+func TestOpcodeProfilerPprof(t *testing.T) {
+	result := runProfiler(t, json.RawMessage(`{"format":"pprof"}`))
+
+	var blob hexutil.Bytes
+	if err := json.Unmarshal(result, &blob); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	prof, err := profile.Parse(bytes.NewReader(blob))
+	if err != nil {
+		t.Fatalf("failed to parse profile: %v", err)
+	}
+	if len(prof.SampleType) != 3 || prof.SampleType[1].Type != "gas" {
+		t.Fatalf("unexpected sample types: %v", prof.SampleType)
+	}
+	var (
+		count int64
+		gas   = make(map[string]int64)
+	)
+	for _, sample := range prof.Sample {
+		if root := sample.Location[len(sample.Location)-1].Line[0].Function.Name; root != profilerTestCaller.Hex() {
+			t.Fatalf("unexpected root frame: %s", root)
+		}
+		var stack string
+		for i := len(sample.Location) - 1; i >= 0; i-- {
+			stack += sample.Location[i].Line[0].Function.Name + ";"
+		}
+		count += sample.Value[0]
+		gas[stack] += sample.Value[1]
+	}
+	if count != 13 {
+		t.Errorf("opcode count mismatch: have %d, want 13", count)
+	}
+	var (
+		caller = profilerTestCaller.Hex() + ";"
+		callee = caller + profilerTestCallee.Hex() + ";"
+	)
+	if gas[caller+"PUSH1;"] != 15 || gas[caller+"CALL;"] != 2600 || gas[callee+"PUSH1;"] != 6 || gas[callee+"SSTORE;"] != 5000 {
+		t.Errorf("gas mismatch: have %v", gas)
+	}
+}
@@ -39,6 +39,16 @@ import (
 
 var assetTracers = make(map[string]string)
 
+// jsHeapCheckInterval is the number of tracer steps between two estimations of
+// the tracer state size, if the heap of the tracer is limited.
+const jsHeapCheckInterval = 1024
+
+var (
+	errStepLimit   = errors.New("tracer exceeded the step limit")
+	errHeapLimit   = errors.New("tracer exceeded the heap limit")
+	errOutputLimit = errors.New("tracer exceeded the output limit")
+)
+
 // init retrieves the JavaScript transaction tracers included in go-ethereum.
 func init() {
 	var err error
@@ -111,6 +121,11 @@ type jsTracer struct {
 	traceFrame        bool                  // True if tracer object exposes the `enter()` and `exit()` methods
 	err               error                 // Any error that should stop tracing
 	obj               *goja.Object          // Trace object
+	limits            tracers.JSLimits      // Resource limits of the tracer
+	steps             uint64                // Number of tracer function invocations and executed JS steps
+	allocated         uint64                // Size of the buffers created during the current invocation
+	measuring         bool                  // Whether the tracer state is being measured
+	uint8ArrayType    goja.Value            // Constructor of the JS buffers
 
 	// Methods exposed by tracer
 	result goja.Callable
@@ -139,12 +154,19 @@ type jsTracer struct {
 // The methods `step`, `enter`, and `exit` are optional, but note that
 // `enter` and `exit` always go together.
 func newJsTracer(code string, ctx *tracers.Context, cfg json.RawMessage) (*tracers.Tracer, error) {
+	return newLimitedJsTracer(code, ctx, cfg, tracers.DefaultDirectory.JSLimits())
+}
+
+// newLimitedJsTracer instantiates a new JS tracer instance, which fails when
+// exceeding the given resource limits.
+func newLimitedJsTracer(code string, ctx *tracers.Context, cfg json.RawMessage, limits tracers.JSLimits) (*tracers.Tracer, error) {
 	vm := goja.New()
 	// By default field names are exported to JS as is, i.e. capitalized.
 	vm.SetFieldNameMapper(goja.UncapFieldNameMapper())
 	t := &jsTracer{
-		vm:  vm,
-		ctx: make(map[string]goja.Value),
+		vm:     vm,
+		ctx:    make(map[string]goja.Value),
+		limits: limits,
 	}
 
 	t.setTypeConverters()
@@ -169,7 +191,17 @@ func newJsTracer(code string, ctx *tracers.Context, cfg json.RawMessage) (*trace
 		}
 	}
 
-	ret, err := vm.RunString("(" + code + ")")
+	code = "(" + code + ")"
+	if limits.MaxSteps > 0 || limits.MaxHeap > 0 {
+		// Count the steps executed by the tracer, also enforcing the heap
+		// limit while a single tracer function executes.
+		var err error
+		if code, err = instrumentSteps(code); err != nil {
+			return nil, err
+		}
+		vm.GlobalObject().DefineDataProperty(jsStepFunction, vm.ToValue(t.jsStep), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	}
+	ret, err := vm.RunString(code)
 	if err != nil {
 		return nil, err
 	}
@@ -204,7 +236,7 @@ func newJsTracer(code string, ctx *tracers.Context, cfg json.RawMessage) (*trace
 		if cfg != nil {
 			cfgStr = string(cfg)
 		}
-		if _, err := setup(obj, vm.ToValue(cfgStr)); err != nil {
+		if _, err := t.invoke(setup, vm.ToValue(cfgStr)); err != nil {
 			return nil, err
 		}
 	}
@@ -328,7 +360,7 @@ func (t *jsTracer) OnOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.
 	log.refund = t.env.StateDB.GetRefund()
 	log.depth = depth
 	log.err = err
-	if _, err := t.step(t.obj, t.logValue, t.dbValue); err != nil {
+	if _, err := t.invoke(t.step, t.logValue, t.dbValue); err != nil {
 		t.onError("step", err)
 	}
 }
@@ -340,7 +372,7 @@ func (t *jsTracer) OnFault(pc uint64, op byte, gas, cost uint64, scope tracing.O
 	}
 	// Other log fields have been already set as part of the last OnOpcode.
 	t.log.err = err
-	if _, err := t.fault(t.obj, t.logValue, t.dbValue); err != nil {
+	if _, err := t.invoke(t.fault, t.logValue, t.dbValue); err != nil {
 		t.onError("fault", err)
 	}
 }
@@ -384,7 +416,7 @@ func (t *jsTracer) OnEnter(depth int, typ byte, from common.Address, to common.A
 		t.frame.value = new(big.Int).SetBytes(value.Bytes())
 	}
 
-	if _, err := t.enter(t.obj, t.frameValue); err != nil {
+	if _, err := t.invoke(t.enter, t.frameValue); err != nil {
 		t.onError("enter", err)
 	}
 }
@@ -407,7 +439,7 @@ func (t *jsTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, r
 	t.frameResult.output = common.CopyBytes(output)
 	t.frameResult.err = err
 
-	if _, err := t.exit(t.obj, t.frameResultValue); err != nil {
+	if _, err := t.invoke(t.exit, t.frameResultValue); err != nil {
 		t.onError("exit", err)
 	}
 }
@@ -418,17 +450,121 @@ func (t *jsTracer) GetResult() (json.RawMessage, error) {
 		return nil, t.err
 	}
 	ctx := t.vm.ToValue(t.ctx)
-	res, err := t.result(t.obj, ctx, t.dbValue)
+	res, err := t.invoke(t.result, ctx, t.dbValue)
 	if err != nil {
 		return nil, wrapError("result", err)
 	}
+	if err := t.checkHeap(); err != nil {
+		return nil, wrapError("result", err)
+	}
 	encoded, err := json.Marshal(res)
 	if err != nil {
 		return nil, err
 	}
+	if t.limits.MaxOutput > 0 && uint64(len(encoded)) > t.limits.MaxOutput {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", errOutputLimit, len(encoded), t.limits.MaxOutput)
+	}
 	return encoded, t.err
 }
 
+// invoke calls a method of the tracer object, enforcing the step and heap
+// limits of the tracer.
+func (t *jsTracer) invoke(fn goja.Callable, args ...goja.Value) (goja.Value, error) {
+	if err := t.countStep(); err != nil {
+		return nil, err
+	}
+	t.allocated = 0
+	return fn(t.obj, args...)
+}
+
+// jsStep is injected into the tracer code, and invoked on every function body,
+// loop iteration and call. It interrupts the tracer once its limits are exceeded.
+// The returned empty array is spread into the arguments of the calls.
+func (t *jsTracer) jsStep(call goja.FunctionCall) goja.Value {
+	if err := t.countStep(); err != nil {
+		t.vm.Interrupt(err)
+	}
+	return t.vm.NewArray()
+}
+
+// countStep accounts for a step of the tracer, returning an error if the step
+// limit is exceeded. The size of the tracer state is periodically checked too.
+func (t *jsTracer) countStep() error {
+	t.steps++
+	if t.limits.MaxSteps > 0 && t.steps > t.limits.MaxSteps {
+		return fmt.Errorf("%w: %d steps", errStepLimit, t.limits.MaxSteps)
+	}
+	if t.steps%jsHeapCheckInterval == 0 {
+		return t.checkHeap()
+	}
+	return nil
+}
+
+// checkHeap returns an error if the estimated size of the tracer state exceeds
+// the heap limit of the tracer.
+func (t *jsTracer) checkHeap() (err error) {
+	// The tracer object doesn't exist while its code is evaluated, and reading
+	// its properties may run accessors, which step the tracer themselves.
+	if t.limits.MaxHeap == 0 || t.obj == nil || t.measuring {
+		return nil
+	}
+	t.measuring = true
+	defer func() { t.measuring = false }()
+
+	// Reading the properties may run accessors of the tracer, which can throw
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to measure tracer state: %v", r)
+		}
+	}()
+	if size := t.heapSize(t.limits.MaxHeap); size > t.limits.MaxHeap {
+		return fmt.Errorf("%w: more than %d bytes", errHeapLimit, t.limits.MaxHeap)
+	}
+	return nil
+}
+
+// heapSize estimates the size of the state retained by the tracer object, by
+// walking the values reachable from its properties. The walk is aborted once
+// the size exceeds the given limit.
+func (t *jsTracer) heapSize(limit uint64) uint64 {
+	var (
+		size  uint64
+		seen  = make(map[*goja.Object]struct{})
+		queue = []goja.Value{t.obj}
+	)
+	for len(queue) > 0 && size <= limit {
+		val := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		// Every value carries some overhead, strings their contents too
+		size += 16
+		obj, ok := val.(*goja.Object)
+		if !ok {
+			if str, ok := val.Export().(string); ok {
+				size += uint64(len(str))
+			}
+			continue
+		}
+		if _, ok := seen[obj]; ok {
+			continue
+		}
+		seen[obj] = struct{}{}
+
+		// Buffers are accounted by their length instead of walking the bytes
+		if obj.ClassName() == "Object" && obj.Get("constructor").SameAs(t.uint8ArrayType) {
+			if buf, ok := obj.Export().([]byte); ok {
+				size += uint64(len(buf))
+				continue
+			}
+		}
+		for _, key := range obj.Keys() {
+			size += uint64(len(key))
+			queue = append(queue, obj.Get(key))
+		}
+	}
+	return size
+}
+
 // Stop terminates execution of the tracer at the first opportune moment.
 func (t *jsTracer) Stop(err error) {
 	t.vm.Interrupt(err)
@@ -574,7 +710,14 @@ func (t *jsTracer) setTypeConverters() error {
 	//
 	// Cache uint8ArrayType once to be used every time for less overhead.
 	uint8ArrayType := t.vm.Get("Uint8Array")
+	t.uint8ArrayType = uint8ArrayType
 	toBufWrapper := func(vm *goja.Runtime, val []byte) (goja.Value, error) {
+		// The buffers created during a single invocation are bounded by the
+		// heap limit, as they might not be retained in the tracer state.
+		t.allocated += uint64(len(val))
+		if t.limits.MaxHeap > 0 && t.allocated > t.limits.MaxHeap {
+			return nil, fmt.Errorf("%w: %d bytes of buffers created in a single call", errHeapLimit, t.allocated)
+		}
 		return toBuf(vm, uint8ArrayType, val)
 	}
 	t.toBuf = toBufWrapper
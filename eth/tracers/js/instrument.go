@@ -0,0 +1,231 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package js
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/parser"
+)
+
+// jsStepFunction is the name of the global function counting the steps of a
+// tracer, calls to which are injected into the tracer code.
+const jsStepFunction = "__tracerStep"
+
+// stepInsert is a snippet of code injected into the tracer code at an offset.
+type stepInsert struct {
+	pos  int
+	text string
+}
+
+// instrumentSteps injects calls to the step function into the given code, so
+// that the number of steps executed by a tracer can be counted deterministically.
+// Goja itself can't count the executed instructions. A step is counted on every
+// function body, loop iteration and call.
+//
+// Goja doesn't track parentheses in its AST, so the end positions of the nodes
+// are unreliable, and the calls are only injected at the start of the nodes. The
+// instrumented code is parsed again to verify that all injected calls are live.
+//
+// Code which fails to parse is returned unmodified, so that the error is
+// reported when the VM runs it.
+func instrumentSteps(code string) (string, error) {
+	if strings.Contains(code, jsStepFunction) {
+		return "", fmt.Errorf("tracer code must not reference %s", jsStepFunction)
+	}
+	prog, err := parser.ParseFile(nil, "", code, 0)
+	if err != nil {
+		return code, nil
+	}
+	var (
+		base    = prog.File.Base()
+		call    = jsStepFunction + "()"
+		inserts []stepInsert
+	)
+	// Positions of the AST are 1-based offsets into the code
+	insert := func(idx file.Idx, text string) {
+		inserts = append(inserts, stepInsert{pos: int(idx) - base, text: text})
+	}
+	// body instruments the body of a loop. A single statement is prefixed with
+	// an if-else statement, which retains the association of any else clause
+	// following the body.
+	body := func(stmt ast.Statement) {
+		if block, ok := stmt.(*ast.BlockStatement); ok {
+			insert(block.LeftBrace+1, call+";")
+			return
+		}
+		// The statement might start with parentheses preceding its first node,
+		// and some statements don't track the position of their keyword.
+		pos := int(firstIdx(stmt)) - base
+		for pos > 0 && strings.ContainsRune(" \t\r\n(", rune(code[pos-1])) {
+			pos--
+		}
+		if keyword := statementKeyword(stmt); keyword != "" && strings.HasSuffix(code[:pos], keyword) {
+			pos -= len(keyword)
+		}
+		inserts = append(inserts, stepInsert{pos: pos, text: " if (" + call + ", false); else "})
+	}
+	// args instruments the argument list of a call by spreading the empty
+	// array returned by the step function.
+	args := func(lparen file.Idx, list []ast.Expression) {
+		if lparen == 0 {
+			return // new expression without arguments
+		}
+		if len(list) == 0 {
+			insert(lparen+1, "..."+call)
+		} else {
+			insert(lparen+1, "..."+call+", ")
+		}
+	}
+	walkAST(reflect.ValueOf(prog), func(node ast.Node) {
+		switch n := node.(type) {
+		case *ast.ForStatement:
+			body(n.Body)
+		case *ast.ForInStatement:
+			body(n.Body)
+		case *ast.ForOfStatement:
+			body(n.Body)
+		case *ast.WhileStatement:
+			body(n.Body)
+		case *ast.DoWhileStatement:
+			body(n.Body)
+		case *ast.FunctionLiteral:
+			insert(n.Body.LeftBrace+1, call+";")
+		case *ast.ArrowFunctionLiteral:
+			// Arrow functions with an expression body are counted by their calls
+			if b, ok := n.Body.(*ast.BlockStatement); ok {
+				insert(b.LeftBrace+1, call+";")
+			}
+		case *ast.CallExpression:
+			args(n.LeftParenthesis, n.ArgumentList)
+		case *ast.NewExpression:
+			args(n.LeftParenthesis, n.ArgumentList)
+		}
+	})
+	sort.SliceStable(inserts, func(i, j int) bool {
+		return inserts[i].pos < inserts[j].pos
+	})
+	var (
+		out  strings.Builder
+		last int
+	)
+	for _, ins := range inserts {
+		out.WriteString(code[last:ins.pos])
+		out.WriteString(ins.text)
+		last = ins.pos
+	}
+	out.WriteString(code[last:])
+	instrumented := out.String()
+
+	// Make sure that no call ended up in a comment or broke the code
+	if prog, err = parser.ParseFile(nil, "", instrumented, 0); err != nil {
+		return "", fmt.Errorf("failed to instrument tracer code: %v", err)
+	}
+	var calls int
+	walkAST(reflect.ValueOf(prog), func(node ast.Node) {
+		if n, ok := node.(*ast.CallExpression); ok {
+			if id, ok := n.Callee.(*ast.Identifier); ok && id.Name == jsStepFunction {
+				calls++
+			}
+		}
+	})
+	if calls != len(inserts) {
+		return "", fmt.Errorf("failed to instrument tracer code: %d of %d step calls injected", calls, len(inserts))
+	}
+	return instrumented, nil
+}
+
+// firstIdx returns the smallest position of the nodes of the given subtree. The
+// start positions of some nodes, like postfix expressions and if statements,
+// don't point to their first token.
+func firstIdx(node ast.Node) file.Idx {
+	var first file.Idx
+	walkAST(reflect.ValueOf(node), func(node ast.Node) {
+		// Some nodes don't track their positions at all
+		if idx := node.Idx0(); idx > 0 && (first == 0 || idx < first) {
+			first = idx
+		}
+	})
+	return first
+}
+
+// statementKeyword returns the keyword starting the given statement, if any.
+func statementKeyword(stmt ast.Statement) string {
+	switch stmt.(type) {
+	case *ast.IfStatement:
+		return "if"
+	case *ast.ForStatement, *ast.ForInStatement, *ast.ForOfStatement:
+		return "for"
+	case *ast.WhileStatement:
+		return "while"
+	case *ast.DoWhileStatement:
+		return "do"
+	case *ast.SwitchStatement:
+		return "switch"
+	case *ast.TryStatement:
+		return "try"
+	case *ast.WithStatement:
+		return "with"
+	case *ast.ReturnStatement:
+		return "return"
+	case *ast.ThrowStatement:
+		return "throw"
+	case *ast.VariableStatement:
+		return "var"
+	}
+	return ""
+}
+
+// walkAST calls fn for every node reachable from the given value, in depth-first
+// order. Goja doesn't provide a visitor for its AST, so the nodes are walked via
+// reflection.
+func walkAST(val reflect.Value, fn func(ast.Node)) {
+	switch val.Kind() {
+	case reflect.Interface:
+		if !val.IsNil() {
+			walkAST(val.Elem(), fn)
+		}
+	case reflect.Pointer:
+		if val.IsNil() {
+			return
+		}
+		if node, ok := val.Interface().(ast.Node); ok {
+			fn(node)
+		}
+		walkAST(val.Elem(), fn)
+	case reflect.Slice:
+		for i := 0; i < val.Len(); i++ {
+			walkAST(val.Index(i), fn)
+		}
+	case reflect.Struct:
+		typ := val.Type()
+		for i := 0; i < val.NumField(); i++ {
+			// The declaration lists duplicate the declarations of the bodies,
+			// and the file isn't part of the AST.
+			field := typ.Field(i)
+			if !field.IsExported() || field.Name == "DeclarationList" || field.Name == "File" {
+				continue
+			}
+			walkAST(val.Field(i), fn)
+		}
+	}
+}
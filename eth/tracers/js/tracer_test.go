@@ -19,11 +19,13 @@ package js
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math/big"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/dop251/goja"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -321,3 +323,149 @@ func TestSetup(t *testing.T) {
 		t.Errorf("tracer returned wrong result. have: %s, want: \"bar\"\n", string(have))
 	}
 }
+
+func TestLimits(t *testing.T) {
+	for i, tt := range []struct {
+		code   string
+		limits tracers.JSLimits
+		fail   string
+	}{
+		{ // tests that the tracer functions can't be invoked beyond the step limit
+			code:   "{count: 0, step: function() { this.count++; }, fault: function() {}, result: function() { return this.count; }}",
+			limits: tracers.JSLimits{MaxSteps: 2},
+			fail:   "tracer exceeded the step limit: 2 steps    in server-side tracer function 'step'",
+		}, { // tests that a single tracer function call can't loop beyond the step limit
+			code:   "{step: function() { for (;;) {} }, fault: function() {}, result: function() { return null; }}",
+			limits: tracers.JSLimits{MaxSteps: 1000},
+			fail:   "tracer exceeded the step limit: 1000 steps at step",
+		}, { // tests that a single tracer function call can't recurse beyond the step limit
+			code:   "{step: function() { var f = x => x > 0 ? f(x - 1) + f(x - 1) : 0; f(64); }, fault: function() {}, result: function() { return null; }}",
+			limits: tracers.JSLimits{MaxSteps: 1000},
+			fail:   "tracer exceeded the step limit: 1000 steps at f",
+		}, { // tests that the tracer code can't loop beyond the step limit when evaluated
+			code:   "(function() { do {} while (true) })()",
+			limits: tracers.JSLimits{MaxSteps: 1000},
+			fail:   "tracer exceeded the step limit: 1000 steps at <eval>",
+		}, { // tests that the tracer can't tamper with the step counting
+			code:   "{step: function() { __tracerStep = function() {}; }, fault: function() {}, result: function() { return null; }}",
+			limits: tracers.JSLimits{MaxSteps: 1000},
+			fail:   "tracer code must not reference __tracerStep",
+		}, { // tests that the tracer state can't grow beyond the heap limit within a single call
+			code:   "{data: [], step: function() { for (;;) { this.data.push('x'.repeat(1000)); } }, fault: function() {}, result: function() { return null; }}",
+			limits: tracers.JSLimits{MaxHeap: 1024 * 1024},
+			fail:   "tracer exceeded the heap limit: more than 1048576 bytes at step",
+		}, { // tests that a single call can't create buffers beyond the heap limit
+			code:   "{step: function() { for (;;) { toWord('0x01'); } }, fault: function() {}, result: function() { return null; }}",
+			limits: tracers.JSLimits{MaxHeap: 1024},
+			fail:   "tracer exceeded the heap limit: 1056 bytes of buffers created in a single call at step",
+		}, { // tests that the tracer state can't grow beyond the heap limit
+			code:   "{data: [], step: function() { this.data.push('x'.repeat(1000)); }, fault: function() {}, result: function() { return this.data.length; }}",
+			limits: tracers.JSLimits{MaxHeap: 2000},
+			fail:   "tracer exceeded the heap limit: more than 2000 bytes    in server-side tracer function 'result'",
+		}, { // tests that the result can't exceed the output limit
+			code:   "{step: function() {}, fault: function() {}, result: function() { return 'x'.repeat(100); }}",
+			limits: tracers.JSLimits{MaxOutput: 64},
+			fail:   "tracer exceeded the output limit: 102 bytes, limit 64",
+		}, { // tests that tracers within the limits are unaffected
+			code:   "{data: [], step: function() { this.data.push('x'); }, fault: function() {}, result: function() { return this.data.length; }}",
+			limits: tracers.JSLimits{MaxSteps: 32, MaxHeap: 2000, MaxOutput: 64},
+		},
+	} {
+		tracer, err := newLimitedJsTracer(tt.code, nil, nil, tt.limits)
+		if err == nil {
+			_, err = runTrace(tracer, testCtx(), params.TestChainConfig, nil)
+		}
+		if tt.fail == "" && err != nil {
+			t.Errorf("testcase %d: unexpected error: %v", i, err)
+		}
+		if tt.fail != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.fail)) {
+			t.Errorf("testcase %d: error mismatch: have %v, want %s", i, err, tt.fail)
+		}
+	}
+}
+
+func TestInstrumentSteps(t *testing.T) {
+	for i, tt := range []struct {
+		code  string
+		want  int64 // Result of the code
+		steps int   // Number of steps counted while running it, on calls, bodies and iterations
+	}{
+		{code: "1", want: 1, steps: 0},
+		{code: "(function() { var n = 0; for (var i = 0; i < 3; i++) n++; return n; })()", want: 3, steps: 5},
+		{code: "(function() { var n = 0; while (n < 3) if (n % 2) n++; else n += 1; return n; })()", want: 3, steps: 5},
+		{code: "(function() { var n = 0; do n++; while (n < 3); return n; })()", want: 3, steps: 5},
+		{code: "(function() { var n = 0; for (var k in {a: 1, b: 2}) for (var v of [1, 2]) n += v; return n; })()", want: 6, steps: 8},
+		{code: "(function() { var n = 0; for (;;) { if (++n == 3) break; } return n; })()", want: 3, steps: 5},
+		{code: "(function() { var n = 0; while (true) { if (++n < 3) continue; return n; } })()", want: 3, steps: 5},
+		{code: "[1, 2, 3].map(x => x * 2).reduce((a, b) => a + b)", want: 12, steps: 2},
+		{code: "(() => ({a: 5}))().a", want: 5, steps: 1},
+		{code: "(function() { var f = n => { return n > 0 ? f(n - 1) + 1 : 0; }; return f(2); })()", want: 2, steps: 8},
+		{code: "(function() { /* for (;;) */ var s = 'while (true) {}'; return s.length; })()", want: 15, steps: 2},
+		{code: "(function() { var s = 'ü'; for (var i = 0; i < 2; i++) s += 'ö'; return s.length; })()", want: 3, steps: 4},
+	} {
+		code, err := instrumentSteps("(" + tt.code + ")")
+		if err != nil {
+			t.Fatalf("testcase %d: failed to instrument: %v", i, err)
+		}
+		var (
+			vm    = goja.New()
+			steps int
+		)
+		vm.Set(jsStepFunction, func() []interface{} { steps++; return []interface{}{} })
+		res, err := vm.RunString(code)
+		if err != nil {
+			t.Fatalf("testcase %d: failed to run instrumented code %q: %v", i, code, err)
+		}
+		if res.ToInteger() != tt.want {
+			t.Errorf("testcase %d: result mismatch: have %v, want %d", i, res, tt.want)
+		}
+		if steps != tt.steps {
+			t.Errorf("testcase %d: step count mismatch: have %d, want %d", i, steps, tt.steps)
+		}
+	}
+	// Check that loops with any kind of body retain their behavior
+	for _, body := range []string{
+		"n++;", "(n)++;", "!(n++);", "n = n + 1\n", ";", "{}", "lbl: n++;", "var k = n++;",
+		"if (n % 2) n++; else n += 1;", "if ((n)) { n++ } else if (n) n++; else n++\n",
+		"for (var j = 0; j < 1; j++) n++;", "while (false) ;", "do n++; while (false)\n",
+		"switch (n) { default: n++ }", "try { n++ } catch (e) {}", "(() => n++)();", "n++ // comment\n",
+	} {
+		for _, loop := range []string{
+			"for (var i = 0; i < 3; i++) %s", "for(;i<3;i++)%s", "while (i++ < 3) /* ( */ %s",
+			"do %s while (i++ < 2)", "for (var i of [1, 2, 3]) %s", "for (var i in [1, 2, 3]) %s",
+		} {
+			code := "(function() { var n = 0, i = 0; if (true) " + fmt.Sprintf(loop, body) + " else n = -1; return n; })()"
+			want, err := goja.New().RunString(code)
+			if err != nil {
+				t.Fatalf("failed to run code %q: %v", code, err)
+			}
+			instrumented, err := instrumentSteps(code)
+			if err != nil {
+				t.Fatalf("failed to instrument %q: %v", code, err)
+			}
+			vm := goja.New()
+			vm.Set(jsStepFunction, func() []interface{} { return []interface{}{} })
+			if have, err := vm.RunString(instrumented); err != nil || !have.StrictEquals(want) {
+				t.Errorf("instrumented code %q mismatch: have %v (err %v), want %v", instrumented, have, err, want)
+			}
+		}
+	}
+	// Check that the bundled tracers remain valid when instrumented
+	for name, code := range assetTracers {
+		if _, err := newLimitedJsTracer(code, nil, nil, tracers.JSLimits{MaxSteps: 1000}); err != nil {
+			t.Errorf("failed to instrument tracer %s: %v", name, err)
+		}
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	tracers.DefaultDirectory.ConfigureJS(false, tracers.JSLimits{})
+	defer tracers.DefaultDirectory.ConfigureJS(true, tracers.JSLimits{})
+
+	if _, err := tracers.DefaultDirectory.New("{step: function() {}, fault: function() {}, result: function() { return null; }}", new(tracers.Context), nil); !errors.Is(err, tracers.ErrJSTracersDisabled) {
+		t.Errorf("expected disabled error for tracer code, got %v", err)
+	}
+	if _, err := tracers.DefaultDirectory.New("bigramTracer", new(tracers.Context), nil); !errors.Is(err, tracers.ErrJSTracersDisabled) {
+		t.Errorf("expected disabled error for bundled tracer, got %v", err)
+	}
+}
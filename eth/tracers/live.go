@@ -5,27 +5,47 @@ import (
 	"errors"
 
 	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/ethdb"
 )
 
 type ctorFunc func(config json.RawMessage) (*tracing.Hooks, error)
 
+// ctorWithDBFunc is the constructor of a live tracer persisting its output
+// alongside the chain data.
+type ctorWithDBFunc func(config json.RawMessage, db ethdb.Database) (*tracing.Hooks, error)
+
 // LiveDirectory is the collection of tracers which can be used
 // during normal block import operations.
-var LiveDirectory = liveDirectory{elems: make(map[string]ctorFunc)}
+var LiveDirectory = liveDirectory{elems: make(map[string]ctorWithDBFunc)}
 
 type liveDirectory struct {
-	elems map[string]ctorFunc
+	elems map[string]ctorWithDBFunc
 }
 
 // Register registers a tracer constructor by name.
 func (d *liveDirectory) Register(name string, f ctorFunc) {
+	d.elems[name] = func(config json.RawMessage, _ ethdb.Database) (*tracing.Hooks, error) {
+		return f(config)
+	}
+}
+
+// RegisterWithDB registers a constructor by name for a tracer which needs the
+// chain database.
+func (d *liveDirectory) RegisterWithDB(name string, f ctorWithDBFunc) {
 	d.elems[name] = f
 }
 
-// New instantiates a tracer by name.
+// New instantiates a tracer by name. Tracers which need the chain database
+// can't be instantiated without it, use NewWithDB for them.
 func (d *liveDirectory) New(name string, config json.RawMessage) (*tracing.Hooks, error) {
+	return d.NewWithDB(name, config, nil)
+}
+
+// NewWithDB instantiates a tracer by name, passing the chain database to the
+// tracers which need it.
+func (d *liveDirectory) NewWithDB(name string, config json.RawMessage, db ethdb.Database) (*tracing.Hooks, error) {
 	if f, ok := d.elems[name]; ok {
-		return f(config)
+		return f(config, db)
 	}
 	return nil, errors.New("not found")
 }
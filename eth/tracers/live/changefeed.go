@@ -0,0 +1,561 @@
+package live
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func init() {
+	tracers.LiveDirectory.RegisterWithDB("changefeed", newChangefeedTracer)
+}
+
+const (
+	// changefeedBlock is the record type of an imported block.
+	changefeedBlock = "block"
+
+	// changefeedRevert is the record type of a previously reported block which
+	// is no longer part of the chain, e.g. due to a reorg.
+	changefeedRevert = "revert"
+
+	// changefeedPartial is the file suffix of the segment being written.
+	changefeedPartial = ".partial"
+)
+
+// changefeedConfig is the configuration of the changefeed tracer.
+type changefeedConfig struct {
+	Path       string `json:"path"`       // Directory to write the segment files into
+	Format     string `json:"format"`     // Encoding of the records, "json" or "rlp"
+	MaxSize    uint64 `json:"maxSize"`    // Size of the uncompressed segment in megabytes before it's rotated
+	Compress   *bool  `json:"compress"`   // Whether to gzip the segment files, enabled by default
+	ReorgDepth int    `json:"reorgDepth"` // Number of recent blocks tracked for detecting reorgs
+}
+
+// changefeedBalance is a single balance change.
+type changefeedBalance struct {
+	Address common.Address `json:"address"`
+	Prev    *big.Int       `json:"prev"`
+	New     *big.Int       `json:"new"`
+	Reason  uint8          `json:"reason"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding the balances as hex.
+func (c changefeedBalance) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Address common.Address `json:"address"`
+		Prev    *hexutil.Big   `json:"prev"`
+		New     *hexutil.Big   `json:"new"`
+		Reason  uint8          `json:"reason"`
+	}{c.Address, (*hexutil.Big)(c.Prev), (*hexutil.Big)(c.New), c.Reason})
+}
+
+// changefeedNonce is a single nonce change.
+type changefeedNonce struct {
+	Address common.Address `json:"address"`
+	Prev    hexutil.Uint64 `json:"prev"`
+	New     hexutil.Uint64 `json:"new"`
+}
+
+// changefeedCode is a single code change. The code is empty if the account is
+// destructed.
+type changefeedCode struct {
+	Address      common.Address `json:"address"`
+	PrevCodeHash common.Hash    `json:"prevCodeHash"`
+	CodeHash     common.Hash    `json:"codeHash"`
+	Code         hexutil.Bytes  `json:"code"`
+}
+
+// changefeedStorage is a single storage slot change.
+type changefeedStorage struct {
+	Address common.Address `json:"address"`
+	Slot    common.Hash    `json:"slot"`
+	Prev    common.Hash    `json:"prev"`
+	New     common.Hash    `json:"new"`
+}
+
+// changefeedLog is a single emitted log.
+type changefeedLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+// changefeedCall is a single call frame, flattened in the order of entering.
+type changefeedCall struct {
+	Depth    hexutil.Uint64 `json:"depth"`
+	Type     string         `json:"type"`
+	From     common.Address `json:"from"`
+	To       common.Address `json:"to"`
+	Input    hexutil.Bytes  `json:"input"`
+	Gas      hexutil.Uint64 `json:"gas"`
+	GasUsed  hexutil.Uint64 `json:"gasUsed"`
+	Value    *big.Int       `json:"value"`
+	Output   hexutil.Bytes  `json:"output"`
+	Error    string         `json:"error"`
+	Reverted bool           `json:"reverted"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding the value as hex.
+func (c changefeedCall) MarshalJSON() ([]byte, error) {
+	type call changefeedCall
+	return json.Marshal(struct {
+		call
+		Value *hexutil.Big `json:"value"`
+	}{call(c), (*hexutil.Big)(c.Value)})
+}
+
+// changefeedChanges is the collection of the state changes and the logs of a
+// transaction, or of the block outside of the transactions (e.g. rewards,
+// withdrawals and system calls).
+type changefeedChanges struct {
+	Balances []changefeedBalance `json:"balances"`
+	Nonces   []changefeedNonce   `json:"nonces"`
+	Codes    []changefeedCode    `json:"codes"`
+	Storage  []changefeedStorage `json:"storage"`
+	Logs     []changefeedLog     `json:"logs"`
+}
+
+// changefeedTx is the collection of the changes made by a transaction.
+type changefeedTx struct {
+	Index hexutil.Uint64    `json:"index"`
+	Hash  common.Hash       `json:"hash"`
+	State changefeedChanges `json:"state"`
+	Calls []changefeedCall  `json:"calls"`
+}
+
+// changefeedRecord is a single entry of the changefeed. Revert records only
+// carry the number and hash of the reverted block.
+type changefeedRecord struct {
+	Type       string            `json:"type"`
+	Number     hexutil.Uint64    `json:"number"`
+	Hash       common.Hash       `json:"hash"`
+	ParentHash common.Hash       `json:"parentHash"`
+	State      changefeedChanges `json:"state"`
+	Txs        []*changefeedTx   `json:"txs"`
+}
+
+// changefeedTracer is a live tracer which records the state changes, logs and
+// call frames of the canonical blocks into rotating segment files.
+//
+// Blocks are executed before they are selected as canonical (e.g. sidechain and
+// optimistic payloads), so the records of the executed blocks are held back until
+// the canonical chain reaches them. If the canonical chain changes, the reported
+// blocks which are no longer canonical are reported as reverted first. The chain
+// is checked whenever a block is executed, so the records lag one block behind.
+type changefeedTracer struct {
+	config changefeedConfig
+	writer *changefeedWriter
+	db     ethdb.Database
+
+	block    *changefeedRecord                 // Record of the block being processed
+	tx       *changefeedTx                     // Record of the transaction being processed
+	calls    []int                             // Indices of the open call frames of the transaction
+	pending  map[common.Hash]*changefeedRecord // Executed blocks not reported as canonical
+	reported []blockID                         // Recently reported canonical blocks
+}
+
+// blockID identifies a reported block.
+type blockID struct {
+	number uint64
+	hash   common.Hash
+}
+
+func newChangefeedTracer(cfg json.RawMessage, db ethdb.Database) (*tracing.Hooks, error) {
+	var config changefeedConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %v", err)
+		}
+	}
+	if config.Path == "" {
+		return nil, errors.New("changefeed output path is required")
+	}
+	if db == nil {
+		return nil, errors.New("changefeed tracer requires the chain database")
+	}
+	if config.Format == "" {
+		config.Format = "json"
+	}
+	if config.Format != "json" && config.Format != "rlp" {
+		return nil, fmt.Errorf("unknown changefeed format %q", config.Format)
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = 64
+	}
+	if config.ReorgDepth <= 0 {
+		config.ReorgDepth = 128
+	}
+	compress := config.Compress == nil || *config.Compress
+	if err := os.MkdirAll(config.Path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create changefeed directory: %v", err)
+	}
+	// Continue the sequence of the segments written previously. The leftover
+	// partial segments of an unclean shutdown are kept intact.
+	seq, err := nextChangefeedSeq(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	t := &changefeedTracer{
+		config:  config,
+		db:      db,
+		pending: make(map[common.Hash]*changefeedRecord),
+		writer: &changefeedWriter{
+			dir:      config.Path,
+			format:   config.Format,
+			compress: compress,
+			maxSize:  config.MaxSize * 1024 * 1024,
+			seq:      seq,
+		},
+	}
+	return &tracing.Hooks{
+		OnBlockStart:    t.OnBlockStart,
+		OnBlockEnd:      t.OnBlockEnd,
+		OnTxStart:       t.OnTxStart,
+		OnTxEnd:         t.OnTxEnd,
+		OnEnter:         t.OnEnter,
+		OnExit:          t.OnExit,
+		OnBalanceChange: t.OnBalanceChange,
+		OnNonceChange:   t.OnNonceChange,
+		OnCodeChange:    t.OnCodeChange,
+		OnStorageChange: t.OnStorageChange,
+		OnLog:           t.OnLog,
+		OnClose:         t.OnClose,
+	}, nil
+}
+
+func (t *changefeedTracer) OnBlockStart(ev tracing.BlockEvent) {
+	t.block = &changefeedRecord{
+		Type:       changefeedBlock,
+		Number:     hexutil.Uint64(ev.Block.NumberU64()),
+		Hash:       ev.Block.Hash(),
+		ParentHash: ev.Block.ParentHash(),
+		Txs:        make([]*changefeedTx, 0, len(ev.Block.Transactions())),
+	}
+	t.tx, t.calls = nil, nil
+}
+
+func (t *changefeedTracer) OnBlockEnd(err error) {
+	block := t.block
+	t.block, t.tx, t.calls = nil, nil, nil
+	if block == nil || err != nil {
+		return
+	}
+	t.pending[block.Hash] = block
+	t.sync()
+}
+
+// sync reports the executed blocks which became canonical since the last sync,
+// reverting the reported blocks which are no longer canonical first.
+func (t *changefeedTracer) sync() {
+	headHash := rawdb.ReadHeadBlockHash(t.db)
+	head := rawdb.ReadHeaderNumber(t.db, headHash)
+	if head == nil {
+		return
+	}
+	// Revert the reported blocks which left the canonical chain
+	for len(t.reported) > 0 {
+		last := t.reported[len(t.reported)-1]
+		if last.number <= *head && rawdb.ReadCanonicalHash(t.db, last.number) == last.hash {
+			break
+		}
+		t.write(&changefeedRecord{Type: changefeedRevert, Number: hexutil.Uint64(last.number), Hash: last.hash})
+		t.reported = t.reported[:len(t.reported)-1]
+	}
+	// Report the canonical blocks following the last reported one. Canonical
+	// blocks which weren't executed by the tracer, e.g. before it was enabled,
+	// are skipped.
+	var first uint64
+	if len(t.reported) > 0 {
+		first = t.reported[len(t.reported)-1].number + 1
+	} else {
+		first = *head + 1
+		for _, record := range t.pending {
+			first = min(first, uint64(record.Number))
+		}
+	}
+	if depth := uint64(t.config.ReorgDepth); *head > depth {
+		first = max(first, *head-depth)
+	}
+	for number := first; number <= *head; number++ {
+		hash := rawdb.ReadCanonicalHash(t.db, number)
+		record, ok := t.pending[hash]
+		if !ok {
+			continue
+		}
+		t.write(record)
+		delete(t.pending, hash)
+
+		t.reported = append(t.reported, blockID{number: number, hash: hash})
+		if len(t.reported) > t.config.ReorgDepth {
+			t.reported = t.reported[len(t.reported)-t.config.ReorgDepth:]
+		}
+	}
+	// Drop the executed blocks which are too old to become canonical
+	for hash, record := range t.pending {
+		if uint64(record.Number)+uint64(t.config.ReorgDepth) < *head {
+			delete(t.pending, hash)
+		}
+	}
+}
+
+func (t *changefeedTracer) OnTxStart(vm *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	if t.block == nil {
+		return
+	}
+	t.tx = &changefeedTx{
+		Index: hexutil.Uint64(len(t.block.Txs)),
+		Hash:  tx.Hash(),
+		Calls: make([]changefeedCall, 0),
+	}
+	t.calls = t.calls[:0]
+}
+
+func (t *changefeedTracer) OnTxEnd(receipt *types.Receipt, err error) {
+	if t.block == nil || t.tx == nil {
+		return
+	}
+	if err == nil {
+		t.block.Txs = append(t.block.Txs, t.tx)
+	}
+	t.tx, t.calls = nil, nil
+}
+
+func (t *changefeedTracer) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	// Only the call frames of the transactions are reported, the system calls
+	// are omitted.
+	if t.tx == nil {
+		return
+	}
+	call := changefeedCall{
+		Depth: hexutil.Uint64(depth),
+		Type:  vm.OpCode(typ).String(),
+		From:  from,
+		To:    to,
+		Input: common.CopyBytes(input),
+		Gas:   hexutil.Uint64(gas),
+	}
+	if value != nil {
+		call.Value = new(big.Int).Set(value)
+	}
+	t.calls = append(t.calls, len(t.tx.Calls))
+	t.tx.Calls = append(t.tx.Calls, call)
+}
+
+func (t *changefeedTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if t.tx == nil || len(t.calls) == 0 {
+		return
+	}
+	call := &t.tx.Calls[t.calls[len(t.calls)-1]]
+	t.calls = t.calls[:len(t.calls)-1]
+
+	call.Output = common.CopyBytes(output)
+	call.GasUsed = hexutil.Uint64(gasUsed)
+	call.Reverted = reverted
+	if err != nil {
+		call.Error = err.Error()
+	}
+}
+
+// changes returns the collection the state changes are currently recorded into.
+func (t *changefeedTracer) changes() *changefeedChanges {
+	if t.tx != nil {
+		return &t.tx.State
+	}
+	if t.block != nil {
+		return &t.block.State
+	}
+	return nil
+}
+
+func (t *changefeedTracer) OnBalanceChange(a common.Address, prev, cur *big.Int, reason tracing.BalanceChangeReason) {
+	if c := t.changes(); c != nil {
+		c.Balances = append(c.Balances, changefeedBalance{
+			Address: a,
+			Prev:    new(big.Int).Set(prev),
+			New:     new(big.Int).Set(cur),
+			Reason:  uint8(reason),
+		})
+	}
+}
+
+func (t *changefeedTracer) OnNonceChange(a common.Address, prev, new uint64) {
+	if c := t.changes(); c != nil {
+		c.Nonces = append(c.Nonces, changefeedNonce{Address: a, Prev: hexutil.Uint64(prev), New: hexutil.Uint64(new)})
+	}
+}
+
+func (t *changefeedTracer) OnCodeChange(a common.Address, prevCodeHash common.Hash, prev []byte, codeHash common.Hash, code []byte) {
+	if c := t.changes(); c != nil {
+		c.Codes = append(c.Codes, changefeedCode{Address: a, PrevCodeHash: prevCodeHash, CodeHash: codeHash, Code: common.CopyBytes(code)})
+	}
+}
+
+func (t *changefeedTracer) OnStorageChange(a common.Address, k, prev, new common.Hash) {
+	if c := t.changes(); c != nil {
+		c.Storage = append(c.Storage, changefeedStorage{Address: a, Slot: k, Prev: prev, New: new})
+	}
+}
+
+func (t *changefeedTracer) OnLog(l *types.Log) {
+	if c := t.changes(); c != nil {
+		c.Logs = append(c.Logs, changefeedLog{Address: l.Address, Topics: l.Topics, Data: common.CopyBytes(l.Data)})
+	}
+}
+
+func (t *changefeedTracer) OnClose() {
+	// Report the blocks made canonical after the last executed one
+	t.sync()
+	if err := t.writer.close(); err != nil {
+		log.Error("Failed to close changefeed segment", "err", err)
+	}
+}
+
+// write appends the record to the changefeed. The failures are only logged,
+// as there is no way to propagate them to the block processing.
+func (t *changefeedTracer) write(record *changefeedRecord) {
+	if err := t.writer.write(record, uint64(record.Number)); err != nil {
+		log.Error("Failed to write changefeed record", "number", record.Number, "hash", record.Hash, "err", err)
+	}
+}
+
+// changefeedWriter writes the records into segment files, named after their
+// sequence number and the number of the first block they contain. The segment
+// being written carries a ".partial" suffix, which is dropped once the segment
+// is complete.
+type changefeedWriter struct {
+	dir      string
+	format   string
+	compress bool
+	maxSize  uint64
+	seq      uint64 // Sequence number of the next segment
+
+	file *os.File
+	gz   *gzip.Writer
+	buf  *bufio.Writer
+	out  io.Writer
+	name string
+	size uint64
+}
+
+// write appends a single record to the current segment, opening a new one if
+// there's none or the current one is full.
+func (w *changefeedWriter) write(record *changefeedRecord, number uint64) error {
+	if w.file != nil && w.size >= w.maxSize {
+		if err := w.close(); err != nil {
+			return err
+		}
+	}
+	if w.file == nil {
+		if err := w.open(number); err != nil {
+			return err
+		}
+	}
+	var (
+		enc []byte
+		err error
+	)
+	if w.format == "rlp" {
+		enc, err = rlp.EncodeToBytes(record)
+	} else {
+		enc, err = json.Marshal(record)
+		enc = append(enc, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := w.out.Write(enc); err != nil {
+		return err
+	}
+	w.size += uint64(len(enc))
+
+	// Make the record available to the readers tailing the segment.
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Flush()
+	}
+	return nil
+}
+
+// open creates a new segment starting with the given block.
+func (w *changefeedWriter) open(number uint64) error {
+	name := fmt.Sprintf("changefeed-%06d-%020d.%s", w.seq, number, w.format)
+	if w.format == "json" {
+		name += "l"
+	}
+	if w.compress {
+		name += ".gz"
+	}
+	file, err := os.OpenFile(filepath.Join(w.dir, name+changefeedPartial), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w.file, w.name, w.size = file, name, 0
+	w.seq++
+	w.buf = bufio.NewWriter(file)
+	w.out, w.gz = w.buf, nil
+	if w.compress {
+		w.gz = gzip.NewWriter(w.buf)
+		w.out = w.gz
+	}
+	return nil
+}
+
+// close finalizes the current segment, if any.
+func (w *changefeedWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	file := w.file
+	w.file = nil
+
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := w.buf.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(w.dir, w.name+changefeedPartial), filepath.Join(w.dir, w.name))
+}
+
+// nextChangefeedSeq returns the sequence number following the last segment in
+// the given directory.
+func nextChangefeedSeq(dir string) (uint64, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "changefeed-*"))
+	if err != nil {
+		return 0, err
+	}
+	var next uint64
+	for _, name := range names {
+		var seq uint64
+		if _, err := fmt.Sscanf(filepath.Base(name), "changefeed-%06d-", &seq); err == nil && seq >= next {
+			next = seq + 1
+		}
+	}
+	return next, nil
+}
@@ -0,0 +1,205 @@
+package live
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// readChangefeed decodes all the records of the given json segment file.
+func readChangefeed(t *testing.T, path string) []map[string]json.RawMessage {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open segment: %v", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("failed to open gzip stream: %v", err)
+	}
+	var (
+		records []map[string]json.RawMessage
+		scanner = bufio.NewScanner(gz)
+	)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var record map[string]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("failed to decode record: %v", err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read segment: %v", err)
+	}
+	return records
+}
+
+func TestChangefeedReorg(t *testing.T) {
+	var (
+		dir = t.TempDir()
+		db  = rawdb.NewMemoryDatabase()
+	)
+	hooks, err := newChangefeedTracer(json.RawMessage(`{"path":"`+dir+`"}`), db)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	var (
+		addr = common.HexToAddress("0x01")
+		b1   = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+		b2   = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2), ParentHash: b1.Hash()})
+		b2x  = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2), ParentHash: b1.Hash(), Extra: []byte("fork")})
+		b3   = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(3), ParentHash: b2x.Hash()})
+		b3y  = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(3), ParentHash: b2.Hash()})
+		tx   = types.NewTx(&types.LegacyTx{Nonce: 1})
+	)
+	execute := func(block *types.Block) {
+		hooks.OnBlockStart(tracing.BlockEvent{Block: block})
+		hooks.OnTxStart(&tracing.VMContext{}, tx, addr)
+		hooks.OnEnter(0, byte(vm.CALL), addr, addr, nil, 21000, big.NewInt(1))
+		hooks.OnStorageChange(addr, common.Hash{1}, common.Hash{}, common.Hash{2})
+		hooks.OnLog(&types.Log{Address: addr, Topics: []common.Hash{{3}}})
+		hooks.OnExit(0, nil, 21000, nil, false)
+		hooks.OnTxEnd(&types.Receipt{}, nil)
+		hooks.OnBalanceChange(addr, big.NewInt(0), big.NewInt(2), tracing.BalanceIncreaseWithdrawal)
+		hooks.OnBlockEnd(nil)
+	}
+	setHead := func(blocks ...*types.Block) {
+		for _, block := range blocks {
+			rawdb.WriteHeaderNumber(db, block.Hash(), block.NumberU64())
+			rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		}
+		rawdb.WriteHeadBlockHash(db, blocks[len(blocks)-1].Hash())
+	}
+	execute(b1)
+	setHead(b1)
+	execute(b2)
+	setHead(b2)
+
+	// Blocks executed before becoming canonical must not revert the chain,
+	// nor be reported unless they become canonical.
+	execute(b2x)
+	execute(b3y)
+	execute(b3)
+	setHead(b2x, b3)
+
+	// A failed block must not be reported
+	hooks.OnBlockStart(tracing.BlockEvent{Block: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(4), ParentHash: b3.Hash()})})
+	hooks.OnBlockEnd(vm.ErrOutOfGas)
+	hooks.OnClose()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 || filepath.Base(files[0]) != "changefeed-000000-00000000000000000001.jsonl.gz" {
+		t.Fatalf("unexpected segment files: %v", files)
+	}
+	records := readChangefeed(t, files[0])
+
+	want := []struct {
+		typ  string
+		hash common.Hash
+	}{
+		{changefeedBlock, b1.Hash()},
+		{changefeedBlock, b2.Hash()},
+		{changefeedRevert, b2.Hash()},
+		{changefeedBlock, b2x.Hash()},
+		{changefeedBlock, b3.Hash()},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("record count mismatch: have %d, want %d", len(records), len(want))
+	}
+	for i, record := range records {
+		var (
+			typ  string
+			hash common.Hash
+		)
+		json.Unmarshal(record["type"], &typ)
+		json.Unmarshal(record["hash"], &hash)
+		if typ != want[i].typ || hash != want[i].hash {
+			t.Errorf("record %d: have %s %x, want %s %x", i, typ, hash, want[i].typ, want[i].hash)
+		}
+	}
+	// Check the contents of a block record
+	var block struct {
+		State struct {
+			Balances []struct {
+				New *hexutil.Big `json:"new"`
+			} `json:"balances"`
+		} `json:"state"`
+		Txs []struct {
+			Hash  common.Hash `json:"hash"`
+			State struct {
+				Storage []json.RawMessage `json:"storage"`
+				Logs    []json.RawMessage `json:"logs"`
+			} `json:"state"`
+			Calls []struct {
+				Type    string         `json:"type"`
+				GasUsed hexutil.Uint64 `json:"gasUsed"`
+			} `json:"calls"`
+		} `json:"txs"`
+	}
+	enc, _ := json.Marshal(records[0])
+	if err := json.Unmarshal(enc, &block); err != nil {
+		t.Fatalf("failed to decode block record: %v", err)
+	}
+	if len(block.Txs) != 1 || block.Txs[0].Hash != tx.Hash() {
+		t.Fatalf("unexpected transactions: %+v", block.Txs)
+	}
+	if len(block.Txs[0].State.Storage) != 1 || len(block.Txs[0].State.Logs) != 1 || len(block.Txs[0].Calls) != 1 {
+		t.Fatalf("unexpected transaction changes: %+v", block.Txs[0])
+	}
+	if call := block.Txs[0].Calls[0]; call.Type != "CALL" || call.GasUsed != 21000 {
+		t.Fatalf("unexpected call frame: %+v", call)
+	}
+	if len(block.State.Balances) != 1 || block.State.Balances[0].New.ToInt().Cmp(big.NewInt(2)) != 0 {
+		t.Fatalf("unexpected block changes: %+v", block.State)
+	}
+}
+
+func TestChangefeedRotation(t *testing.T) {
+	dir := t.TempDir()
+	w := &changefeedWriter{dir: dir, format: "rlp", maxSize: 1}
+	for i := uint64(1); i <= 3; i++ {
+		if err := w.write(&changefeedRecord{Type: changefeedBlock, Number: 0}, i); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*"+changefeedPartial)); len(files) != 1 {
+		t.Fatalf("unexpected partial segments: %v", files)
+	}
+	if err := w.close(); err != nil {
+		t.Fatalf("failed to close segment: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 3 {
+		t.Fatalf("unexpected segment files: %v", files)
+	}
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read segment: %v", err)
+		}
+		var record changefeedRecord
+		if err := rlp.DecodeBytes(data, &record); err != nil {
+			t.Fatalf("failed to decode segment %d: %v", i, err)
+		}
+	}
+	// The sequence continues after a restart
+	if seq, err := nextChangefeedSeq(dir); err != nil || seq != 3 {
+		t.Fatalf("unexpected next sequence: %d %v", seq, err)
+	}
+}
@@ -0,0 +1,248 @@
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func init() {
+	tracers.LiveDirectory.RegisterWithDB("mux", newMuxTracer)
+}
+
+// muxTracer is a live tracer which runs multiple live tracers in one go. Its
+// config maps the names of the tracers to their configs.
+type muxTracer struct {
+	tracers []*tracing.Hooks
+}
+
+// MuxTracerIncludes reports whether the live tracer with the given name and
+// config is, or multiplexes, the given tracer.
+func MuxTracerIncludes(name string, cfg json.RawMessage, tracer string) bool {
+	if name == tracer {
+		return true
+	}
+	if name != "mux" || cfg == nil {
+		return false
+	}
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(cfg, &config); err != nil {
+		return false
+	}
+	_, ok := config[tracer]
+	return ok
+}
+
+func newMuxTracer(cfg json.RawMessage, db ethdb.Database) (*tracing.Hooks, error) {
+	var config map[string]json.RawMessage
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %v", err)
+		}
+	}
+	// Run the tracers in a stable order across restarts
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := &muxTracer{tracers: make([]*tracing.Hooks, 0, len(names))}
+	for _, name := range names {
+		hooks, err := tracers.LiveDirectory.NewWithDB(name, config[name], db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tracer %s: %v", name, err)
+		}
+		t.tracers = append(t.tracers, hooks)
+	}
+	return &tracing.Hooks{
+		OnTxStart:         t.OnTxStart,
+		OnTxEnd:           t.OnTxEnd,
+		OnEnter:           t.OnEnter,
+		OnExit:            t.OnExit,
+		OnOpcode:          t.OnOpcode,
+		OnFault:           t.OnFault,
+		OnGasChange:       t.OnGasChange,
+		OnBlockchainInit:  t.OnBlockchainInit,
+		OnClose:           t.OnClose,
+		OnBlockStart:      t.OnBlockStart,
+		OnBlockEnd:        t.OnBlockEnd,
+		OnSkippedBlock:    t.OnSkippedBlock,
+		OnGenesisBlock:    t.OnGenesisBlock,
+		OnSystemCallStart: t.OnSystemCallStart,
+		OnSystemCallEnd:   t.OnSystemCallEnd,
+		OnBalanceChange:   t.OnBalanceChange,
+		OnNonceChange:     t.OnNonceChange,
+		OnCodeChange:      t.OnCodeChange,
+		OnStorageChange:   t.OnStorageChange,
+		OnLog:             t.OnLog,
+	}, nil
+}
+
+func (t *muxTracer) OnOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	for _, t := range t.tracers {
+		if t.OnOpcode != nil {
+			t.OnOpcode(pc, op, gas, cost, scope, rData, depth, err)
+		}
+	}
+}
+
+func (t *muxTracer) OnFault(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, depth int, err error) {
+	for _, t := range t.tracers {
+		if t.OnFault != nil {
+			t.OnFault(pc, op, gas, cost, scope, depth, err)
+		}
+	}
+}
+
+func (t *muxTracer) OnGasChange(old, new uint64, reason tracing.GasChangeReason) {
+	for _, t := range t.tracers {
+		if t.OnGasChange != nil {
+			t.OnGasChange(old, new, reason)
+		}
+	}
+}
+
+func (t *muxTracer) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	for _, t := range t.tracers {
+		if t.OnEnter != nil {
+			t.OnEnter(depth, typ, from, to, input, gas, value)
+		}
+	}
+}
+
+func (t *muxTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	for _, t := range t.tracers {
+		if t.OnExit != nil {
+			t.OnExit(depth, output, gasUsed, err, reverted)
+		}
+	}
+}
+
+func (t *muxTracer) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	for _, t := range t.tracers {
+		if t.OnTxStart != nil {
+			t.OnTxStart(env, tx, from)
+		}
+	}
+}
+
+func (t *muxTracer) OnTxEnd(receipt *types.Receipt, err error) {
+	for _, t := range t.tracers {
+		if t.OnTxEnd != nil {
+			t.OnTxEnd(receipt, err)
+		}
+	}
+}
+
+func (t *muxTracer) OnBlockchainInit(chainConfig *params.ChainConfig) {
+	for _, t := range t.tracers {
+		if t.OnBlockchainInit != nil {
+			t.OnBlockchainInit(chainConfig)
+		}
+	}
+}
+
+func (t *muxTracer) OnClose() {
+	for _, t := range t.tracers {
+		if t.OnClose != nil {
+			t.OnClose()
+		}
+	}
+}
+
+func (t *muxTracer) OnBlockStart(event tracing.BlockEvent) {
+	for _, t := range t.tracers {
+		if t.OnBlockStart != nil {
+			t.OnBlockStart(event)
+		}
+	}
+}
+
+func (t *muxTracer) OnBlockEnd(err error) {
+	for _, t := range t.tracers {
+		if t.OnBlockEnd != nil {
+			t.OnBlockEnd(err)
+		}
+	}
+}
+
+func (t *muxTracer) OnSkippedBlock(event tracing.BlockEvent) {
+	for _, t := range t.tracers {
+		if t.OnSkippedBlock != nil {
+			t.OnSkippedBlock(event)
+		}
+	}
+}
+
+func (t *muxTracer) OnGenesisBlock(genesis *types.Block, alloc types.GenesisAlloc) {
+	for _, t := range t.tracers {
+		if t.OnGenesisBlock != nil {
+			t.OnGenesisBlock(genesis, alloc)
+		}
+	}
+}
+
+func (t *muxTracer) OnSystemCallStart() {
+	for _, t := range t.tracers {
+		if t.OnSystemCallStart != nil {
+			t.OnSystemCallStart()
+		}
+	}
+}
+
+func (t *muxTracer) OnSystemCallEnd() {
+	for _, t := range t.tracers {
+		if t.OnSystemCallEnd != nil {
+			t.OnSystemCallEnd()
+		}
+	}
+}
+
+func (t *muxTracer) OnBalanceChange(a common.Address, prev, new *big.Int, reason tracing.BalanceChangeReason) {
+	for _, t := range t.tracers {
+		if t.OnBalanceChange != nil {
+			t.OnBalanceChange(a, prev, new, reason)
+		}
+	}
+}
+
+func (t *muxTracer) OnNonceChange(a common.Address, prev, new uint64) {
+	for _, t := range t.tracers {
+		if t.OnNonceChange != nil {
+			t.OnNonceChange(a, prev, new)
+		}
+	}
+}
+
+func (t *muxTracer) OnCodeChange(a common.Address, prevCodeHash common.Hash, prev []byte, codeHash common.Hash, code []byte) {
+	for _, t := range t.tracers {
+		if t.OnCodeChange != nil {
+			t.OnCodeChange(a, prevCodeHash, prev, codeHash, code)
+		}
+	}
+}
+
+func (t *muxTracer) OnStorageChange(a common.Address, k, prev, new common.Hash) {
+	for _, t := range t.tracers {
+		if t.OnStorageChange != nil {
+			t.OnStorageChange(a, k, prev, new)
+		}
+	}
+}
+
+func (t *muxTracer) OnLog(log *types.Log) {
+	for _, t := range t.tracers {
+		if t.OnLog != nil {
+			t.OnLog(log)
+		}
+	}
+}
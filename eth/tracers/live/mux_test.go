@@ -0,0 +1,65 @@
+package live
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func TestMuxTracer(t *testing.T) {
+	// The tracers needing the chain database can't be created without it
+	if _, err := tracers.LiveDirectory.New("mux", json.RawMessage(`{"noop":{},"statediff":{}}`)); err == nil {
+		t.Fatal("expected error creating statediff tracer without database")
+	}
+	chaindb := rawdb.NewMemoryDatabase()
+	hooks, err := tracers.LiveDirectory.NewWithDB("mux", json.RawMessage(`{"noop":{},"statediff":{}}`), chaindb)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	var (
+		a  = common.HexToAddress("0xa")
+		b1 = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+	)
+	hooks.OnBlockStart(tracing.BlockEvent{Block: b1})
+	hooks.OnBalanceChange(a, big.NewInt(0), big.NewInt(5), tracing.BalanceIncreaseWithdrawal)
+	hooks.OnBlockEnd(nil)
+	hooks.OnClose()
+
+	diff, err := ReadBlockStateDiff(chaindb, 1, b1.Hash())
+	if err != nil {
+		t.Fatalf("failed to read state diff: %v", err)
+	}
+	if len(diff.Accounts) != 1 || diff.Accounts[0].Address != a || diff.Accounts[0].PostBalance.Int64() != 5 {
+		t.Fatalf("unexpected state diff: %+v", diff.Accounts)
+	}
+}
+
+func TestMuxTracerIncludes(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   bool
+	}{
+		{"statediff", "", true},
+		{"noop", "", false},
+		{"mux", "", false},
+		{"mux", `{"noop":{}}`, false},
+		{"mux", `{"noop":{},"statediff":{"limit":1}}`, true},
+		{"mux", `invalid`, false},
+	}
+	for i, tt := range tests {
+		var config json.RawMessage
+		if tt.config != "" {
+			config = json.RawMessage(tt.config)
+		}
+		if have := MuxTracerIncludes(tt.name, config, "statediff"); have != tt.want {
+			t.Errorf("test %d: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package live
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func init() {
+	tracers.LiveDirectory.RegisterWithDB("statediff", newStateDiffTracer)
+}
+
+var (
+	// ErrStateDiffDisabled is returned if the state diffs are requested, but the
+	// statediff live tracer is not running.
+	ErrStateDiffDisabled = errors.New("statediff live tracer is not enabled")
+
+	// ErrStateDiffNotFound is returned if the state diff of the requested block
+	// is not recorded, e.g. it was imported before the tracer was enabled.
+	ErrStateDiffNotFound = errors.New("state diff not found")
+)
+
+// The fields of an account which are modified in a block.
+const (
+	stateDiffBalance = 1 << iota
+	stateDiffNonce
+	stateDiffCode
+)
+
+// stateDiffConfig is the configuration of the statediff tracer.
+type stateDiffConfig struct {
+	Limit uint64 `json:"limit"` // Number of recent blocks to keep the diffs for, 0 means all
+}
+
+// StateDiffSlot is the modification of a single storage slot.
+type StateDiffSlot struct {
+	Slot common.Hash
+	Pre  common.Hash
+	Post common.Hash
+}
+
+// StateDiffAccount is the compact diff of a single account, storing only the
+// fields which are flagged as modified.
+type StateDiffAccount struct {
+	Address     common.Address
+	Fields      uint8
+	PreBalance  *big.Int
+	PostBalance *big.Int
+	PreNonce    uint64
+	PostNonce   uint64
+	PreCode     []byte
+	PostCode    []byte
+	Storage     []StateDiffSlot
+}
+
+// stateDiffAccountJSON is the prestate/poststate of an account in the format of
+// the prestateTracer in diff mode.
+type stateDiffAccountJSON struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   *hexutil.Uint64             `json:"nonce,omitempty"`
+	Code    *hexutil.Bytes              `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// BlockStateDiff is the state diff of a block, consisting of the original and
+// the final values of the state modified by the block.
+type BlockStateDiff struct {
+	Accounts []StateDiffAccount
+}
+
+// MarshalJSON implements json.Marshaler, encoding the diff in the same format
+// as the prestateTracer does in diff mode.
+func (d *BlockStateDiff) MarshalJSON() ([]byte, error) {
+	var (
+		pre  = make(map[common.Address]*stateDiffAccountJSON)
+		post = make(map[common.Address]*stateDiffAccountJSON)
+	)
+	for _, acc := range d.Accounts {
+		var a, b stateDiffAccountJSON
+		if acc.Fields&stateDiffBalance != 0 {
+			a.Balance, b.Balance = (*hexutil.Big)(acc.PreBalance), (*hexutil.Big)(acc.PostBalance)
+		}
+		if acc.Fields&stateDiffNonce != 0 {
+			preNonce, postNonce := hexutil.Uint64(acc.PreNonce), hexutil.Uint64(acc.PostNonce)
+			a.Nonce, b.Nonce = &preNonce, &postNonce
+		}
+		if acc.Fields&stateDiffCode != 0 {
+			preCode, postCode := hexutil.Bytes(acc.PreCode), hexutil.Bytes(acc.PostCode)
+			a.Code, b.Code = &preCode, &postCode
+		}
+		if len(acc.Storage) > 0 {
+			a.Storage, b.Storage = make(map[common.Hash]common.Hash), make(map[common.Hash]common.Hash)
+			for _, slot := range acc.Storage {
+				a.Storage[slot.Slot], b.Storage[slot.Slot] = slot.Pre, slot.Post
+			}
+		}
+		pre[acc.Address], post[acc.Address] = &a, &b
+	}
+	return json.Marshal(map[string]interface{}{"pre": pre, "post": post})
+}
+
+// ReadBlockStateDiff retrieves the state diff of the given block recorded by the
+// statediff live tracer into the chain database.
+func ReadBlockStateDiff(db ethdb.KeyValueReader, number uint64, hash common.Hash) (*BlockStateDiff, error) {
+	data := rawdb.ReadStateDiff(db, number, hash)
+	if len(data) == 0 {
+		return nil, ErrStateDiffNotFound
+	}
+	diff := new(BlockStateDiff)
+	if err := rlp.DecodeBytes(data, diff); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// stateDiffTracked is the state of an account touched by the block being
+// processed.
+type stateDiffTracked struct {
+	StateDiffAccount
+	slots map[common.Hash]*StateDiffSlot
+}
+
+// stateDiffTracer is a live tracer which records the prestate and poststate of
+// the state modified by every imported block into the chain database.
+//
+// The state hooks are not invoked when the modifications are reverted, so the
+// final values of the state touched by a transaction are read back from the
+// state once the transaction is finished.
+type stateDiffTracer struct {
+	db    ethdb.KeyValueStore
+	limit uint64
+	tail  uint64 // Number of the oldest block whose diff may still be stored
+
+	env      *tracing.VMContext
+	block    *types.Block
+	accounts map[common.Address]*stateDiffTracked
+	touched  map[common.Address]map[common.Hash]struct{} // State touched by the current transaction
+}
+
+func newStateDiffTracer(cfg json.RawMessage, db ethdb.Database) (*tracing.Hooks, error) {
+	var config stateDiffConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %v", err)
+		}
+	}
+	if db == nil {
+		return nil, errors.New("statediff tracer requires the chain database")
+	}
+	t := &stateDiffTracer{db: db, limit: config.Limit}
+	return &tracing.Hooks{
+		OnBlockStart:    t.OnBlockStart,
+		OnBlockEnd:      t.OnBlockEnd,
+		OnTxStart:       t.OnTxStart,
+		OnTxEnd:         t.OnTxEnd,
+		OnBalanceChange: t.OnBalanceChange,
+		OnNonceChange:   t.OnNonceChange,
+		OnCodeChange:    t.OnCodeChange,
+		OnStorageChange: t.OnStorageChange,
+	}, nil
+}
+
+func (t *stateDiffTracer) OnBlockStart(ev tracing.BlockEvent) {
+	t.block = ev.Block
+	t.accounts = make(map[common.Address]*stateDiffTracked)
+	t.env, t.touched = nil, nil
+}
+
+func (t *stateDiffTracer) OnTxStart(vm *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	t.env = vm
+	t.touched = make(map[common.Address]map[common.Hash]struct{})
+}
+
+// OnTxEnd replaces the final values of the state touched by the transaction
+// with the actual ones, dropping the effects of the reverted modifications.
+func (t *stateDiffTracer) OnTxEnd(receipt *types.Receipt, err error) {
+	if t.accounts != nil && t.env != nil && t.env.StateDB != nil {
+		db := t.env.StateDB
+		for addr, slots := range t.touched {
+			acc := t.accounts[addr]
+			if acc.Fields&stateDiffBalance != 0 {
+				acc.PostBalance = db.GetBalance(addr).ToBig()
+			}
+			if acc.Fields&stateDiffNonce != 0 {
+				acc.PostNonce = db.GetNonce(addr)
+			}
+			if acc.Fields&stateDiffCode != 0 {
+				acc.PostCode = common.CopyBytes(db.GetCode(addr))
+			}
+			for slot := range slots {
+				acc.slots[slot].Post = db.GetState(addr, slot)
+			}
+		}
+	}
+	t.env, t.touched = nil, nil
+}
+
+// account returns the tracked account, marking it as touched by the current
+// transaction.
+func (t *stateDiffTracer) account(addr common.Address) *stateDiffTracked {
+	if t.accounts == nil {
+		return nil
+	}
+	acc := t.accounts[addr]
+	if acc == nil {
+		acc = &stateDiffTracked{
+			StateDiffAccount: StateDiffAccount{Address: addr},
+			slots:            make(map[common.Hash]*StateDiffSlot),
+		}
+		t.accounts[addr] = acc
+	}
+	if t.touched != nil && t.touched[addr] == nil {
+		t.touched[addr] = make(map[common.Hash]struct{})
+	}
+	return acc
+}
+
+func (t *stateDiffTracer) OnBalanceChange(a common.Address, prev, cur *big.Int, reason tracing.BalanceChangeReason) {
+	if acc := t.account(a); acc != nil {
+		if acc.Fields&stateDiffBalance == 0 {
+			acc.Fields |= stateDiffBalance
+			acc.PreBalance = new(big.Int).Set(prev)
+		}
+		acc.PostBalance = new(big.Int).Set(cur)
+	}
+}
+
+func (t *stateDiffTracer) OnNonceChange(a common.Address, prev, cur uint64) {
+	if acc := t.account(a); acc != nil {
+		if acc.Fields&stateDiffNonce == 0 {
+			acc.Fields |= stateDiffNonce
+			acc.PreNonce = prev
+		}
+		acc.PostNonce = cur
+	}
+}
+
+func (t *stateDiffTracer) OnCodeChange(a common.Address, prevCodeHash common.Hash, prev []byte, codeHash common.Hash, code []byte) {
+	if acc := t.account(a); acc != nil {
+		if acc.Fields&stateDiffCode == 0 {
+			acc.Fields |= stateDiffCode
+			acc.PreCode = common.CopyBytes(prev)
+		}
+		acc.PostCode = common.CopyBytes(code)
+	}
+}
+
+func (t *stateDiffTracer) OnStorageChange(a common.Address, k, prev, cur common.Hash) {
+	if acc := t.account(a); acc != nil {
+		slot := acc.slots[k]
+		if slot == nil {
+			slot = &StateDiffSlot{Slot: k, Pre: prev}
+			acc.slots[k] = slot
+		}
+		slot.Post = cur
+		if t.touched != nil {
+			t.touched[a][k] = struct{}{}
+		}
+	}
+}
+
+func (t *stateDiffTracer) OnBlockEnd(err error) {
+	block, accounts := t.block, t.accounts
+	t.block, t.accounts, t.env, t.touched = nil, nil, nil, nil
+	if block == nil || err != nil {
+		return
+	}
+	diff := &BlockStateDiff{Accounts: make([]StateDiffAccount, 0, len(accounts))}
+	for _, acc := range accounts {
+		// Drop the fields which are restored to the original value
+		if acc.Fields&stateDiffBalance != 0 && acc.PreBalance.Cmp(acc.PostBalance) == 0 {
+			acc.Fields &^= stateDiffBalance
+			acc.PreBalance, acc.PostBalance = nil, nil
+		}
+		if acc.Fields&stateDiffNonce != 0 && acc.PreNonce == acc.PostNonce {
+			acc.Fields &^= stateDiffNonce
+			acc.PreNonce, acc.PostNonce = 0, 0
+		}
+		if acc.Fields&stateDiffCode != 0 && bytes.Equal(acc.PreCode, acc.PostCode) {
+			acc.Fields &^= stateDiffCode
+			acc.PreCode, acc.PostCode = nil, nil
+		}
+		for _, slot := range acc.slots {
+			if slot.Pre != slot.Post {
+				acc.Storage = append(acc.Storage, *slot)
+			}
+		}
+		if acc.Fields == 0 && len(acc.Storage) == 0 {
+			continue
+		}
+		sort.Slice(acc.Storage, func(i, j int) bool {
+			return bytes.Compare(acc.Storage[i].Slot[:], acc.Storage[j].Slot[:]) < 0
+		})
+		diff.Accounts = append(diff.Accounts, acc.StateDiffAccount)
+	}
+	sort.Slice(diff.Accounts, func(i, j int) bool {
+		return bytes.Compare(diff.Accounts[i].Address[:], diff.Accounts[j].Address[:]) < 0
+	})
+	enc, err := rlp.EncodeToBytes(diff)
+	if err != nil {
+		log.Error("Failed to encode state diff", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+		return
+	}
+	rawdb.WriteStateDiff(t.db, block.NumberU64(), block.Hash(), enc)
+
+	// Prune the diffs of all the blocks falling out of the retained range. The
+	// first pass after startup starts from genesis to catch the leftovers of an
+	// earlier run with a larger (or no) limit.
+	if t.limit != 0 && block.NumberU64() >= t.limit {
+		if limit := block.NumberU64() - t.limit + 1; limit > t.tail {
+			rawdb.DeleteStateDiffRange(t.db, t.tail, limit)
+			t.tail = limit
+		}
+	}
+}
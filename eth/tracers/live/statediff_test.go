@@ -0,0 +1,94 @@
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+// stateDiffTestDB is the final state the tracer reads back at the end of the
+// transactions.
+type stateDiffTestDB struct {
+	balances map[common.Address]*uint256.Int
+	nonces   map[common.Address]uint64
+	storage  map[common.Address]map[common.Hash]common.Hash
+}
+
+func (db *stateDiffTestDB) GetBalance(addr common.Address) *uint256.Int {
+	if b := db.balances[addr]; b != nil {
+		return b
+	}
+	return new(uint256.Int)
+}
+func (db *stateDiffTestDB) GetNonce(addr common.Address) uint64 { return db.nonces[addr] }
+func (db *stateDiffTestDB) GetCode(addr common.Address) []byte  { return nil }
+func (db *stateDiffTestDB) GetState(addr common.Address, slot common.Hash) common.Hash {
+	return db.storage[addr][slot]
+}
+func (db *stateDiffTestDB) Exist(addr common.Address) bool { return true }
+func (db *stateDiffTestDB) GetRefund() uint64              { return 0 }
+
+func TestStateDiffTracer(t *testing.T) {
+	chaindb := rawdb.NewMemoryDatabase()
+	hooks, err := newStateDiffTracer(json.RawMessage(`{"limit":1}`), chaindb)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+
+	var (
+		a, b   = common.HexToAddress("0xa"), common.HexToAddress("0xb")
+		s1, s2 = common.HexToHash("0x1"), common.HexToHash("0x2")
+		db     = &stateDiffTestDB{
+			balances: map[common.Address]*uint256.Int{b: uint256.NewInt(7)},
+			nonces:   map[common.Address]uint64{b: 1},
+			storage:  map[common.Address]map[common.Hash]common.Hash{b: {s2: common.HexToHash("0x3")}},
+		}
+		b1 = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+		b2 = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2), ParentHash: b1.Hash()})
+	)
+	// Leave a diff behind from an earlier run which kept more blocks
+	stale := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0)})
+	rawdb.WriteStateDiff(chaindb, 0, stale.Hash(), []byte{0xc1, 0xc0})
+
+	hooks.OnBlockStart(tracing.BlockEvent{Block: b1})
+	hooks.OnTxStart(&tracing.VMContext{StateDB: db}, types.NewTx(&types.LegacyTx{}), b)
+	hooks.OnNonceChange(b, 0, 1)
+	hooks.OnBalanceChange(b, big.NewInt(10), big.NewInt(7), tracing.BalanceDecreaseGasBuy)
+	hooks.OnStorageChange(b, s1, common.Hash{}, common.HexToHash("0x1")) // reverted
+	hooks.OnStorageChange(b, s2, common.HexToHash("0x3"), common.HexToHash("0x4"))
+	hooks.OnStorageChange(b, s2, common.HexToHash("0x4"), common.HexToHash("0x3"))
+	hooks.OnTxEnd(&types.Receipt{}, nil)
+	hooks.OnBalanceChange(a, big.NewInt(0), big.NewInt(5), tracing.BalanceIncreaseWithdrawal)
+	hooks.OnBlockEnd(nil)
+
+	diff, err := ReadBlockStateDiff(chaindb, 1, b1.Hash())
+	if err != nil {
+		t.Fatalf("failed to read state diff: %v", err)
+	}
+	have, err := json.Marshal(diff)
+	if err != nil {
+		t.Fatalf("failed to encode state diff: %v", err)
+	}
+	want := `{"post":{"0x000000000000000000000000000000000000000a":{"balance":"0x5"},"0x000000000000000000000000000000000000000b":{"balance":"0x7","nonce":"0x1"}},"pre":{"0x000000000000000000000000000000000000000a":{"balance":"0x0"},"0x000000000000000000000000000000000000000b":{"balance":"0xa","nonce":"0x0"}}}`
+	if string(have) != want {
+		t.Fatalf("state diff mismatch\nhave: %s\nwant: %s", have, want)
+	}
+	// Import the next block, the diff of the previous one is pruned
+	hooks.OnBlockStart(tracing.BlockEvent{Block: b2})
+	hooks.OnBlockEnd(nil)
+	if _, err := ReadBlockStateDiff(chaindb, 2, b2.Hash()); err != nil {
+		t.Fatalf("failed to read state diff: %v", err)
+	}
+	for _, block := range []*types.Block{stale, b1} {
+		if _, err := ReadBlockStateDiff(chaindb, block.NumberU64(), block.Hash()); !errors.Is(err, ErrStateDiffNotFound) {
+			t.Fatalf("expected pruned state diff of block %d, got %v", block.NumberU64(), err)
+		}
+	}
+}
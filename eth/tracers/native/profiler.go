@@ -0,0 +1,325 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/google/pprof/profile"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("opcodeProfiler", newOpcodeProfiler, false)
+}
+
+// opcodeProfilerConfig is the configuration of the opcode profiler.
+type opcodeProfilerConfig struct {
+	Format string `json:"format"` // Output format, "folded" (default) or "pprof"
+	Metric string `json:"metric"` // Sample value of the folded output, "gas" (default) or "time"
+	WithPC bool   `json:"withPC"` // Whether to distinguish the opcodes by program counter
+}
+
+// profileSample is the aggregated cost of an opcode at a given call stack.
+type profileSample struct {
+	stack []string // Call stack, from the outermost frame to the opcode itself
+	count uint64
+	gas   uint64
+	time  time.Duration
+}
+
+// profileFrame is a call frame being executed.
+type profileFrame struct {
+	name     string
+	startGas uint64
+	entered  time.Time // Time the frame was entered, to measure its total time
+	executed bool      // Whether any opcode was executed in the frame
+
+	// The last executed opcode of the frame, whose cost is resolved when the
+	// frame advances to the next opcode, or exits.
+	pending   string
+	gasBefore uint64
+	started   time.Time // Time the pending opcode started executing
+	childGas  uint64
+	childTime time.Duration
+}
+
+// opcodeProfiler aggregates the gas used and the wall time spent per opcode,
+// keyed by the call stack of contract addresses the opcode is executed in.
+// The cost of the call and create opcodes excludes the cost of the nested
+// frame, so every sample carries the self cost only.
+//
+// The output is either the folded stack format, consumable by the flamegraph
+// tools, or the gzipped pprof protobuf format. The profiles of multiple
+// transactions, e.g. of an entire block, can be combined by concatenating the
+// folded outputs, or by merging the pprof profiles.
+type opcodeProfiler struct {
+	config    opcodeProfilerConfig
+	frames    []*profileFrame
+	samples   map[string]*profileSample
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newOpcodeProfiler returns a native go tracer which profiles the executed
+// opcodes of a transaction.
+func newOpcodeProfiler(ctx *tracers.Context, cfg json.RawMessage) (*tracers.Tracer, error) {
+	var config opcodeProfilerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	switch config.Format {
+	case "":
+		config.Format = "folded"
+	case "folded", "pprof":
+	default:
+		return nil, fmt.Errorf("unsupported profile format %q", config.Format)
+	}
+	switch config.Metric {
+	case "":
+		config.Metric = "gas"
+	case "gas", "time":
+	default:
+		return nil, fmt.Errorf("unsupported profile metric %q", config.Metric)
+	}
+	t := &opcodeProfiler{
+		config:  config,
+		samples: make(map[string]*profileSample),
+	}
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnEnter:  t.OnEnter,
+			OnExit:   t.OnExit,
+			OnOpcode: t.OnOpcode,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+// OnEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *opcodeProfiler) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.frames = append(t.frames, &profileFrame{name: to.Hex(), startGas: gas, entered: time.Now()})
+}
+
+// OnExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *opcodeProfiler) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	now := time.Now()
+
+	var leftover uint64
+	if frame.startGas > gasUsed {
+		leftover = frame.startGas - gasUsed
+	}
+	t.resolve(frame, leftover, now)
+
+	// Attribute the gas of the frames without code, i.e. the precompiles, to
+	// the frame itself.
+	if !frame.executed && gasUsed > 0 {
+		t.record(append(t.stack(), "PRECOMPILE"), gasUsed, now.Sub(frame.entered))
+	}
+	t.frames = t.frames[:len(t.frames)-1]
+	if len(t.frames) > 0 {
+		parent := t.frames[len(t.frames)-1]
+		parent.childGas += gasUsed
+		parent.childTime += now.Sub(frame.entered)
+	}
+}
+
+// OnOpcode implements the EVMLogger interface to trace a single step of VM execution.
+func (t *opcodeProfiler) OnOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	var (
+		frame = t.frames[len(t.frames)-1]
+		now   = time.Now()
+	)
+	frame.executed = true
+	t.resolve(frame, gas, now)
+
+	name := vm.OpCode(op).String()
+	if t.config.WithPC {
+		name = fmt.Sprintf("%s@%d", name, pc)
+	}
+	frame.pending, frame.gasBefore = name, gas
+	frame.childGas, frame.childTime = 0, 0
+	frame.started = now
+}
+
+// resolve records the cost of the pending opcode of the frame, given the gas
+// available and the time after its execution.
+func (t *opcodeProfiler) resolve(frame *profileFrame, gas uint64, now time.Time) {
+	if frame.pending == "" {
+		return
+	}
+	var used uint64
+	if frame.gasBefore > gas+frame.childGas {
+		used = frame.gasBefore - gas - frame.childGas
+	}
+	elapsed := now.Sub(frame.started) - frame.childTime
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	t.record(append(t.stack(), frame.pending), used, elapsed)
+	frame.pending = ""
+}
+
+// stack returns the names of the current call frames.
+func (t *opcodeProfiler) stack() []string {
+	stack := make([]string, 0, len(t.frames)+1)
+	for _, frame := range t.frames {
+		stack = append(stack, frame.name)
+	}
+	return stack
+}
+
+// record adds the cost to the sample of the given stack.
+func (t *opcodeProfiler) record(stack []string, gas uint64, elapsed time.Duration) {
+	key := strings.Join(stack, ";")
+	sample := t.samples[key]
+	if sample == nil {
+		sample = &profileSample{stack: stack}
+		t.samples[key] = sample
+	}
+	sample.count++
+	sample.gas += gas
+	sample.time += elapsed
+}
+
+// sortedSamples returns the samples ordered by their stacks.
+func (t *opcodeProfiler) sortedSamples() []*profileSample {
+	keys := make([]string, 0, len(t.samples))
+	for key := range t.samples {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	samples := make([]*profileSample, len(keys))
+	for i, key := range keys {
+		samples[i] = t.samples[key]
+	}
+	return samples
+}
+
+// folded encodes the samples in the folded stack format, one line per stack
+// followed by the value of the configured metric.
+func (t *opcodeProfiler) folded() string {
+	var buf strings.Builder
+	for _, sample := range t.sortedSamples() {
+		value := sample.gas
+		if t.config.Metric == "time" {
+			value = uint64(sample.time.Nanoseconds())
+		}
+		fmt.Fprintf(&buf, "%s %d\n", strings.Join(sample.stack, ";"), value)
+	}
+	return buf.String()
+}
+
+// pprof encodes the samples as a gzipped pprof protobuf profile, carrying the
+// opcode count, the gas used and the time spent as sample values.
+func (t *opcodeProfiler) pprof() ([]byte, error) {
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "gas", Unit: "gas"},
+			{Type: "time", Unit: "nanoseconds"},
+		},
+		DefaultSampleType: "gas",
+	}
+	if t.config.Metric == "time" {
+		prof.DefaultSampleType = "time"
+	}
+	locations := make(map[string]*profile.Location)
+	location := func(name string) *profile.Location {
+		if loc, ok := locations[name]; ok {
+			return loc
+		}
+		fn := &profile.Function{ID: uint64(len(prof.Function) + 1), Name: name, SystemName: name}
+		prof.Function = append(prof.Function, fn)
+
+		loc := &profile.Location{ID: uint64(len(prof.Location) + 1), Line: []profile.Line{{Function: fn}}}
+		prof.Location = append(prof.Location, loc)
+		locations[name] = loc
+		return loc
+	}
+	for _, sample := range t.sortedSamples() {
+		// The locations of a pprof sample are ordered from the leaf
+		locs := make([]*profile.Location, len(sample.stack))
+		for i, name := range sample.stack {
+			locs[len(locs)-1-i] = location(name)
+		}
+		prof.Sample = append(prof.Sample, &profile.Sample{
+			Location: locs,
+			Value:    []int64{int64(sample.count), int64(sample.gas), sample.time.Nanoseconds()},
+		})
+	}
+	var buf bytes.Buffer
+	if err := prof.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GetResult returns the profile, a json string in the folded stack format or
+// hex encoded bytes in the pprof format, and any error arising from the encoding
+// or forceful termination (via `Stop`).
+func (t *opcodeProfiler) GetResult() (json.RawMessage, error) {
+	var (
+		res []byte
+		err error
+	)
+	if t.config.Format == "pprof" {
+		var prof []byte
+		if prof, err = t.pprof(); err != nil {
+			return nil, err
+		}
+		res, err = json.Marshal(hexutil.Bytes(prof))
+	} else {
+		res, err = json.Marshal(t.folded())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *opcodeProfiler) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+// Tests that the time of nested calls is attributed to the callee frames, not
+// to the call opcodes of the callers, and that the self times add up.
+func TestOpcodeProfilerNestedTime(t *testing.T) {
+	tracer, err := tracers.DefaultDirectory.New("opcodeProfiler", new(tracers.Context), json.RawMessage(`{"metric":"time"}`))
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	var (
+		a, b, c = common.Address{0xa}, common.Address{0xb}, common.Address{0xc}
+		hooks   = tracer.Hooks
+		delay   = 20 * time.Millisecond
+		start   = time.Now()
+	)
+	// A calls B, which calls C, each of the callees sleeping in an opcode
+	hooks.OnEnter(0, byte(vm.CALL), common.Address{}, a, nil, 100000, nil)
+	hooks.OnOpcode(0, byte(vm.PUSH1), 100000, 3, nil, nil, 1, nil)
+	hooks.OnOpcode(2, byte(vm.CALL), 99997, 100, nil, nil, 1, nil)
+	{
+		hooks.OnEnter(1, byte(vm.CALL), a, b, nil, 50000, nil)
+		hooks.OnOpcode(0, byte(vm.PUSH1), 50000, 3, nil, nil, 2, nil)
+		time.Sleep(delay)
+		hooks.OnOpcode(2, byte(vm.CALL), 49997, 100, nil, nil, 2, nil)
+		{
+			hooks.OnEnter(2, byte(vm.CALL), b, c, nil, 20000, nil)
+			hooks.OnOpcode(0, byte(vm.PUSH1), 20000, 3, nil, nil, 3, nil)
+			time.Sleep(delay)
+			hooks.OnOpcode(2, byte(vm.STOP), 19997, 0, nil, nil, 3, nil)
+			hooks.OnExit(2, nil, 3, nil, false)
+		}
+		hooks.OnOpcode(7, byte(vm.STOP), 49894, 0, nil, nil, 2, nil)
+		hooks.OnExit(1, nil, 106, nil, false)
+	}
+	hooks.OnOpcode(7, byte(vm.STOP), 99891, 0, nil, nil, 1, nil)
+	hooks.OnExit(0, nil, 109, nil, false)
+	total := time.Since(start)
+
+	result, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve result: %v", err)
+	}
+	var folded string
+	if err := json.Unmarshal(result, &folded); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	times := make(map[string]time.Duration)
+	for _, line := range strings.Split(strings.TrimSpace(folded), "\n") {
+		stack, value, _ := strings.Cut(line, " ")
+		nanos, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			t.Fatalf("invalid sample %q: %v", line, err)
+		}
+		times[stack] = time.Duration(nanos)
+	}
+	var (
+		frameA = a.Hex()
+		frameB = frameA + ";" + b.Hex()
+		frameC = frameB + ";" + c.Hex()
+		sum    time.Duration
+	)
+	for _, elapsed := range times {
+		sum += elapsed
+	}
+	// The self times can't exceed the total time of the transaction
+	if sum > total {
+		t.Errorf("self times exceed the total time: %v > %v", sum, total)
+	}
+	// The sleeps are attributed to the opcodes they happened in
+	if times[frameB+";PUSH1"] < delay || times[frameC+";PUSH1"] < delay {
+		t.Errorf("sleeping opcodes not timed: %v, %v", times[frameB+";PUSH1"], times[frameC+";PUSH1"])
+	}
+	// The calls carry their self time only, excluding the nested frames
+	if call := times[frameA+";CALL"]; call >= delay {
+		t.Errorf("outer call includes nested time: %v", call)
+	}
+	if call := times[frameB+";CALL"]; call >= delay {
+		t.Errorf("inner call includes nested time: %v", call)
+	}
+	// The self times of the frames add up to the total time of the outer call
+	var nested time.Duration
+	for stack, elapsed := range times {
+		if strings.HasPrefix(stack, frameB+";") || stack == frameA+";CALL" {
+			nested += elapsed
+		}
+	}
+	if nested > total || nested < 2*delay {
+		t.Errorf("nested times don't add up: %v, want between %v and %v", nested, 2*delay, total)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("stateDiffTracer", newStateDiffTracer, false)
+}
+
+// diffValue is a Parity-style state diff of a single value. It's encoded as
+// "=" if the value is unchanged, {"+": new} if it's created, {"-": old} if
+// it's removed and {"*": {"from": old, "to": new}} if it's modified.
+type diffValue struct {
+	kind string
+	from interface{}
+	to   interface{}
+}
+
+func newDiffValue(existed, exists bool, from, to interface{}, equal bool) *diffValue {
+	switch {
+	case !existed:
+		return &diffValue{kind: "+", to: to}
+	case !exists:
+		return &diffValue{kind: "-", from: from}
+	case equal:
+		return &diffValue{kind: "="}
+	default:
+		return &diffValue{kind: "*", from: from, to: to}
+	}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d *diffValue) MarshalJSON() ([]byte, error) {
+	switch d.kind {
+	case "+":
+		return json.Marshal(map[string]interface{}{"+": d.to})
+	case "-":
+		return json.Marshal(map[string]interface{}{"-": d.from})
+	case "*":
+		return json.Marshal(map[string]interface{}{"*": map[string]interface{}{"from": d.from, "to": d.to}})
+	default:
+		return json.Marshal(d.kind)
+	}
+}
+
+// accountDiff is the Parity-style state diff of a single account.
+type accountDiff struct {
+	Balance *diffValue                 `json:"balance"`
+	Code    *diffValue                 `json:"code"`
+	Nonce   *diffValue                 `json:"nonce"`
+	Storage map[common.Hash]*diffValue `json:"storage"`
+}
+
+// stateDiffTracer reports the state modifications of a tx in the format of the
+// Parity `stateDiff` output. The touched state is collected the same way as
+// the prestateTracer does.
+type stateDiffTracer struct {
+	*prestateTracer
+	diff map[common.Address]*accountDiff
+}
+
+// newStateDiffTracer returns a native go tracer which produces Parity-style
+// state diffs.
+func newStateDiffTracer(ctx *tracers.Context, _ json.RawMessage) (*tracers.Tracer, error) {
+	t := &stateDiffTracer{
+		prestateTracer: &prestateTracer{
+			pre:     stateMap{},
+			post:    stateMap{},
+			created: make(map[common.Address]bool),
+			deleted: make(map[common.Address]bool),
+		},
+		diff: make(map[common.Address]*accountDiff),
+	}
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnTxStart: t.OnTxStart,
+			OnTxEnd:   t.OnTxEnd,
+			OnOpcode:  t.OnOpcode,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+// OnTxEnd compares the collected prestate with the final state of the touched
+// accounts. It's invoked after the state is finalised, so the destructed and
+// the removed empty accounts are not existent anymore.
+func (t *stateDiffTracer) OnTxEnd(receipt *types.Receipt, err error) {
+	if err != nil {
+		return
+	}
+	for addr, pre := range t.pre {
+		var (
+			existed = !pre.empty
+			exists  = t.env.StateDB.Exist(addr)
+		)
+		if !existed && !exists {
+			continue
+		}
+		var (
+			balance = t.env.StateDB.GetBalance(addr).ToBig()
+			nonce   = t.env.StateDB.GetNonce(addr)
+			code    = t.env.StateDB.GetCode(addr)
+			diff    = &accountDiff{
+				Balance: newDiffValue(existed, exists, (*hexutil.Big)(pre.Balance), (*hexutil.Big)(balance), pre.Balance.Cmp(balance) == 0),
+				Nonce:   newDiffValue(existed, exists, hexutil.Uint64(pre.Nonce), hexutil.Uint64(nonce), pre.Nonce == nonce),
+				Code:    newDiffValue(existed, exists, hexutil.Bytes(pre.Code), hexutil.Bytes(code), bytes.Equal(pre.Code, code)),
+				Storage: make(map[common.Hash]*diffValue),
+			}
+		)
+		for key, val := range pre.Storage {
+			var cur common.Hash
+			if exists {
+				cur = t.env.StateDB.GetState(addr, key)
+			}
+			switch {
+			case !existed && cur == (common.Hash{}):
+			case !exists && val == (common.Hash{}):
+			case existed && exists && val == cur:
+			default:
+				diff.Storage[key] = newDiffValue(existed, exists, val, cur, false)
+			}
+		}
+		// Omit the accounts which are left untouched at the end.
+		if existed && exists && len(diff.Storage) == 0 && diff.Balance.kind == "=" && diff.Nonce.kind == "=" && diff.Code.kind == "=" {
+			continue
+		}
+		t.diff[addr] = diff
+	}
+}
+
+// GetResult returns the json-encoded state diff, and any error arising from
+// the encoding or forceful termination (via `Stop`).
+func (t *stateDiffTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.diff)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
@@ -0,0 +1,389 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("transferTracer", newTransferTracer, false)
+	tracers.LiveDirectory.Register("transfers", newTransferLiveTracer)
+}
+
+// Asset types of the reported transfers.
+const (
+	transferETH     = "ETH"
+	transferERC20   = "ERC20"
+	transferERC721  = "ERC721"
+	transferERC1155 = "ERC1155"
+)
+
+var (
+	// Transfer(address,address,uint256), shared by ERC-20 and ERC-721. The two
+	// standards are told apart by the token id of ERC-721 being indexed.
+	transferEventTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+	// TransferSingle(address,address,address,uint256,uint256) of ERC-1155.
+	transferSingleEventTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+
+	// TransferBatch(address,address,address,uint256[],uint256[]) of ERC-1155.
+	transferBatchEventTopic = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// assetTransfer is a single movement of an asset.
+type assetTransfer struct {
+	Type   string          `json:"type"`
+	Token  *common.Address `json:"token,omitempty"` // Contract of the token, nil for ether
+	From   common.Address  `json:"from"`
+	To     common.Address  `json:"to"`
+	Amount *hexutil.Big    `json:"amount"`
+	ID     *hexutil.Big    `json:"id,omitempty"` // Token id of the non-fungible and multi tokens
+}
+
+// transferTracer collects the asset movements of a transaction: the ether
+// transferred by the call frames, including the internal ones, and the tokens
+// transferred according to the ERC-20, ERC-721 and ERC-1155 transfer events.
+// The movements of the reverted call frames are discarded.
+//
+// Example:
+//
+//	> debug.traceTransaction("0x...", {tracer: "transferTracer"})
+//	[
+//	  {type: "ETH", from: "0x...", to: "0x...", amount: "0xde0b6b3a7640000"},
+//	  {type: "ERC20", token: "0x...", from: "0x...", to: "0x...", amount: "0x3e8"}
+//	]
+type transferTracer struct {
+	frames    [][]assetTransfer // Movements of the call frames being executed
+	transfers []assetTransfer   // Movements of the transaction
+	interrupt atomic.Bool       // Atomic flag to signal execution interruption
+	reason    error             // Textual reason for the interruption
+}
+
+// newTransferTracer returns a native go tracer which collects the asset
+// movements of a transaction.
+func newTransferTracer(ctx *tracers.Context, _ json.RawMessage) (*tracers.Tracer, error) {
+	t := new(transferTracer)
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnTxStart: t.OnTxStart,
+			OnEnter:   t.OnEnter,
+			OnExit:    t.OnExit,
+			OnLog:     t.OnLog,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+// OnTxStart resets the tracer for a new transaction.
+func (t *transferTracer) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	t.frames = t.frames[:0]
+	t.transfers = nil
+}
+
+// OnEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *transferTracer) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	var frame []assetTransfer
+
+	// Delegate and static calls carry no value, the value of call-code is
+	// sent back to the caller itself.
+	switch vm.OpCode(typ) {
+	case vm.CALL, vm.CREATE, vm.CREATE2, vm.SELFDESTRUCT:
+		if value != nil && value.Sign() > 0 && from != to {
+			frame = append(frame, assetTransfer{
+				Type:   transferETH,
+				From:   from,
+				To:     to,
+				Amount: (*hexutil.Big)(new(big.Int).Set(value)),
+			})
+		}
+	}
+	t.frames = append(t.frames, frame)
+}
+
+// OnExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *transferTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	// The movements of a failed frame, including the nested ones, are undone
+	if err != nil {
+		return
+	}
+	if len(t.frames) == 0 {
+		t.transfers = append(t.transfers, frame...)
+		return
+	}
+	t.frames[len(t.frames)-1] = append(t.frames[len(t.frames)-1], frame...)
+}
+
+// OnLog decodes the token transfer events.
+func (t *transferTracer) OnLog(l *types.Log) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	transfers := decodeTransferLog(l)
+	t.frames[len(t.frames)-1] = append(t.frames[len(t.frames)-1], transfers...)
+}
+
+// GetResult returns the json-encoded list of asset movements, and any error
+// arising from the encoding or forceful termination (via `Stop`).
+func (t *transferTracer) GetResult() (json.RawMessage, error) {
+	transfers := t.transfers
+	if transfers == nil {
+		transfers = make([]assetTransfer, 0)
+	}
+	res, err := json.Marshal(transfers)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *transferTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// decodeTransferLog decodes the token movements of a log, returning nil if
+// it's not a well-formed transfer event.
+func decodeTransferLog(l *types.Log) []assetTransfer {
+	if len(l.Topics) == 0 {
+		return nil
+	}
+	token := l.Address
+	switch l.Topics[0] {
+	case transferEventTopic:
+		switch {
+		case len(l.Topics) == 3 && len(l.Data) == 32:
+			return []assetTransfer{{
+				Type:   transferERC20,
+				Token:  &token,
+				From:   common.BytesToAddress(l.Topics[1].Bytes()),
+				To:     common.BytesToAddress(l.Topics[2].Bytes()),
+				Amount: (*hexutil.Big)(new(big.Int).SetBytes(l.Data)),
+			}}
+		case len(l.Topics) == 4 && len(l.Data) == 0:
+			return []assetTransfer{{
+				Type:   transferERC721,
+				Token:  &token,
+				From:   common.BytesToAddress(l.Topics[1].Bytes()),
+				To:     common.BytesToAddress(l.Topics[2].Bytes()),
+				Amount: (*hexutil.Big)(big.NewInt(1)),
+				ID:     (*hexutil.Big)(l.Topics[3].Big()),
+			}}
+		}
+	case transferSingleEventTopic:
+		if len(l.Topics) == 4 && len(l.Data) == 64 {
+			return []assetTransfer{{
+				Type:   transferERC1155,
+				Token:  &token,
+				From:   common.BytesToAddress(l.Topics[2].Bytes()),
+				To:     common.BytesToAddress(l.Topics[3].Bytes()),
+				Amount: (*hexutil.Big)(new(big.Int).SetBytes(l.Data[32:64])),
+				ID:     (*hexutil.Big)(new(big.Int).SetBytes(l.Data[:32])),
+			}}
+		}
+	case transferBatchEventTopic:
+		if len(l.Topics) != 4 {
+			return nil
+		}
+		ids, ok := decodeUint256Array(l.Data, 0)
+		if !ok {
+			return nil
+		}
+		amounts, ok := decodeUint256Array(l.Data, 32)
+		if !ok || len(ids) != len(amounts) {
+			return nil
+		}
+		var (
+			from      = common.BytesToAddress(l.Topics[2].Bytes())
+			to        = common.BytesToAddress(l.Topics[3].Bytes())
+			transfers = make([]assetTransfer, len(ids))
+		)
+		for i := range ids {
+			transfers[i] = assetTransfer{
+				Type:   transferERC1155,
+				Token:  &token,
+				From:   from,
+				To:     to,
+				Amount: (*hexutil.Big)(amounts[i]),
+				ID:     (*hexutil.Big)(ids[i]),
+			}
+		}
+		return transfers
+	}
+	return nil
+}
+
+// decodeUint256Array decodes an abi-encoded dynamic uint256 array, whose offset
+// is stored in the head of the data at the given position.
+func decodeUint256Array(data []byte, head int) ([]*big.Int, bool) {
+	if len(data) < head+32 {
+		return nil, false
+	}
+	offset := new(big.Int).SetBytes(data[head : head+32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return nil, false
+	}
+	start := int(offset.Uint64())
+	length := new(big.Int).SetBytes(data[start : start+32])
+	if !length.IsUint64() || length.Uint64() > uint64(len(data)-start-32)/32 {
+		return nil, false
+	}
+	values := make([]*big.Int, length.Uint64())
+	for i := range values {
+		pos := start + 32 + 32*i
+		values[i] = new(big.Int).SetBytes(data[pos : pos+32])
+	}
+	return values, true
+}
+
+// transferLiveConfig is the configuration of the live transfer tracer.
+type transferLiveConfig struct {
+	Path string `json:"path"` // File to append the asset movements of the blocks to
+}
+
+// transferLiveTx is the asset movements of a transaction in a block.
+type transferLiveTx struct {
+	Hash      common.Hash     `json:"hash"`
+	Index     hexutil.Uint    `json:"index"`
+	Transfers []assetTransfer `json:"transfers"`
+}
+
+// transferLiveBlock is the asset movements of a block.
+type transferLiveBlock struct {
+	Number hexutil.Uint64   `json:"number"`
+	Hash   common.Hash      `json:"hash"`
+	Txs    []transferLiveTx `json:"txs"`
+}
+
+// transferLiveTracer runs the transfer tracer over the processed blocks, and
+// appends the asset movements of every successfully processed block as a json
+// line to a file. Blocks without any movements are skipped.
+type transferLiveTracer struct {
+	transferTracer
+
+	file  *os.File
+	out   *bufio.Writer
+	block *transferLiveBlock
+	tx    *types.Transaction
+	index int
+}
+
+// newTransferLiveTracer returns a live tracer which records the asset
+// movements of the processed blocks.
+func newTransferLiveTracer(cfg json.RawMessage) (*tracing.Hooks, error) {
+	var config transferLiveConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	if config.Path == "" {
+		return nil, errors.New("transfer tracer output path is required")
+	}
+	file, err := os.OpenFile(config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	t := &transferLiveTracer{file: file, out: bufio.NewWriter(file)}
+	return &tracing.Hooks{
+		OnBlockStart: t.OnBlockStart,
+		OnBlockEnd:   t.OnBlockEnd,
+		OnTxStart:    t.OnTxStart,
+		OnTxEnd:      t.OnTxEnd,
+		OnEnter:      t.OnEnter,
+		OnExit:       t.OnExit,
+		OnLog:        t.OnLog,
+		OnClose:      t.OnClose,
+	}, nil
+}
+
+func (t *transferLiveTracer) OnBlockStart(ev tracing.BlockEvent) {
+	t.block = &transferLiveBlock{
+		Number: hexutil.Uint64(ev.Block.NumberU64()),
+		Hash:   ev.Block.Hash(),
+	}
+	t.index = 0
+}
+
+func (t *transferLiveTracer) OnBlockEnd(err error) {
+	block := t.block
+	t.block = nil
+	if err != nil || block == nil || len(block.Txs) == 0 {
+		return
+	}
+	blob, err := json.Marshal(block)
+	if err != nil {
+		log.Error("Failed to encode asset transfers", "number", uint64(block.Number), "err", err)
+		return
+	}
+	if _, err := t.out.Write(append(blob, '\n')); err != nil {
+		log.Error("Failed to write asset transfers", "number", uint64(block.Number), "err", err)
+		return
+	}
+	if err := t.out.Flush(); err != nil {
+		log.Error("Failed to write asset transfers", "number", uint64(block.Number), "err", err)
+	}
+}
+
+func (t *transferLiveTracer) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	t.transferTracer.OnTxStart(env, tx, from)
+	t.tx = tx
+}
+
+func (t *transferLiveTracer) OnTxEnd(receipt *types.Receipt, err error) {
+	if err == nil && t.block != nil && len(t.transfers) > 0 {
+		t.block.Txs = append(t.block.Txs, transferLiveTx{
+			Hash:      t.tx.Hash(),
+			Index:     hexutil.Uint(t.index),
+			Transfers: t.transfers,
+		})
+	}
+	t.index++
+}
+
+func (t *transferLiveTracer) OnClose() {
+	if err := t.out.Flush(); err != nil {
+		log.Error("Failed to write asset transfers", "err", err)
+	}
+	t.file.Close()
+}
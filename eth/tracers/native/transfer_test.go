@@ -0,0 +1,143 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	transferSender = common.HexToAddress("0x01")
+	transferWallet = common.HexToAddress("0x02")
+	transferToken  = common.HexToAddress("0x03")
+	transferOther  = common.HexToAddress("0x04")
+)
+
+// simulateTransfers runs a transaction against the given hooks, which calls a
+// wallet with value, emitting a token transfer of each standard. The ERC-721
+// transfer is emitted in a reverted frame.
+func simulateTransfers(hooks *tracing.Hooks) {
+	var (
+		topic = func(sig string) common.Hash { return crypto.Keccak256Hash([]byte(sig)) }
+		word  = func(v int64) []byte { return common.LeftPadBytes(big.NewInt(v).Bytes(), 32) }
+		from  = common.BytesToHash(transferWallet.Bytes())
+		to    = common.BytesToHash(transferOther.Bytes())
+		batch []byte
+	)
+	// Offsets of the ids and amounts, followed by the arrays [7, 8] and [1, 2]
+	for _, v := range []int64{64, 160, 2, 7, 8, 2, 1, 2} {
+		batch = append(batch, word(v)...)
+	}
+	hooks.OnTxStart(&tracing.VMContext{}, types.NewTx(&types.LegacyTx{}), transferSender)
+	hooks.OnEnter(0, byte(vm.CALL), transferSender, transferWallet, nil, 100000, big.NewInt(10))
+
+	hooks.OnEnter(1, byte(vm.CALL), transferWallet, transferToken, nil, 50000, big.NewInt(0))
+	hooks.OnLog(&types.Log{Address: transferToken, Topics: []common.Hash{topic("Transfer(address,address,uint256)"), from, to}, Data: word(1000)})
+	hooks.OnExit(1, nil, 10000, nil, false)
+
+	hooks.OnEnter(1, byte(vm.CALL), transferWallet, transferToken, nil, 50000, big.NewInt(2))
+	hooks.OnLog(&types.Log{Address: transferToken, Topics: []common.Hash{topic("Transfer(address,address,uint256)"), from, to, common.BigToHash(big.NewInt(5))}})
+	hooks.OnExit(1, nil, 10000, vm.ErrExecutionReverted, true)
+
+	hooks.OnEnter(1, byte(vm.DELEGATECALL), transferWallet, transferToken, nil, 50000, big.NewInt(10))
+	hooks.OnLog(&types.Log{Address: transferWallet, Topics: []common.Hash{topic("TransferSingle(address,address,address,uint256,uint256)"), from, from, to}, Data: append(word(6), word(3)...)})
+	hooks.OnLog(&types.Log{Address: transferWallet, Topics: []common.Hash{topic("TransferBatch(address,address,address,uint256[],uint256[])"), from, from, to}, Data: batch})
+	hooks.OnExit(1, nil, 10000, nil, false)
+
+	hooks.OnEnter(1, byte(vm.CALL), transferWallet, transferOther, nil, 2300, big.NewInt(3))
+	hooks.OnExit(1, nil, 0, nil, false)
+
+	hooks.OnExit(0, nil, 50000, nil, false)
+	if hooks.OnTxEnd != nil {
+		hooks.OnTxEnd(&types.Receipt{}, nil)
+	}
+}
+
+func TestTransferTracer(t *testing.T) {
+	tracer, err := tracers.DefaultDirectory.New("transferTracer", &tracers.Context{}, nil)
+	require.NoError(t, err)
+
+	simulateTransfers(tracer.Hooks)
+	res, err := tracer.GetResult()
+	require.NoError(t, err)
+
+	want := `[
+		{"type":"ETH","from":"0x0000000000000000000000000000000000000001","to":"0x0000000000000000000000000000000000000002","amount":"0xa"},
+		{"type":"ERC20","token":"0x0000000000000000000000000000000000000003","from":"0x0000000000000000000000000000000000000002","to":"0x0000000000000000000000000000000000000004","amount":"0x3e8"},
+		{"type":"ERC1155","token":"0x0000000000000000000000000000000000000002","from":"0x0000000000000000000000000000000000000002","to":"0x0000000000000000000000000000000000000004","amount":"0x3","id":"0x6"},
+		{"type":"ERC1155","token":"0x0000000000000000000000000000000000000002","from":"0x0000000000000000000000000000000000000002","to":"0x0000000000000000000000000000000000000004","amount":"0x1","id":"0x7"},
+		{"type":"ERC1155","token":"0x0000000000000000000000000000000000000002","from":"0x0000000000000000000000000000000000000002","to":"0x0000000000000000000000000000000000000004","amount":"0x2","id":"0x8"},
+		{"type":"ETH","from":"0x0000000000000000000000000000000000000002","to":"0x0000000000000000000000000000000000000004","amount":"0x3"}
+	]`
+	require.JSONEq(t, want, string(res))
+}
+
+func TestTransferLiveTracer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transfers.jsonl")
+	hooks, err := tracers.LiveDirectory.New("transfers", json.RawMessage(`{"path":"`+path+`"}`))
+	require.NoError(t, err)
+
+	var (
+		b1 = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+		b2 = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2)})
+		b3 = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(3)})
+	)
+	hooks.OnBlockStart(tracing.BlockEvent{Block: b1})
+	simulateTransfers(hooks)
+	hooks.OnBlockEnd(nil)
+
+	// Blocks without movements and failed blocks are not recorded
+	hooks.OnBlockStart(tracing.BlockEvent{Block: b2})
+	hooks.OnBlockEnd(nil)
+	hooks.OnBlockStart(tracing.BlockEvent{Block: b3})
+	simulateTransfers(hooks)
+	hooks.OnBlockEnd(vm.ErrOutOfGas)
+	hooks.OnClose()
+
+	blob, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(blob)), "\n")
+	require.Len(t, lines, 1)
+
+	var block struct {
+		Number string      `json:"number"`
+		Hash   common.Hash `json:"hash"`
+		Txs    []struct {
+			Index     string            `json:"index"`
+			Transfers []json.RawMessage `json:"transfers"`
+		} `json:"txs"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &block))
+	require.Equal(t, "0x1", block.Number)
+	require.Equal(t, b1.Hash(), block.Hash)
+	require.Len(t, block.Txs, 1)
+	require.Equal(t, "0x0", block.Txs[0].Index)
+	require.Len(t, block.Txs[0].Transfers, 6)
+}
@@ -0,0 +1,276 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/tracers/internal"
+	"github.com/holiman/uint256"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("vmTracer", newVMTracer, false)
+}
+
+// vmTraceFrame is the Parity-style virtual machine trace of a single call frame.
+type vmTraceFrame struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []*vmTraceOp  `json:"ops"`
+}
+
+// vmTraceOp is a single executed instruction within a vmTraceFrame.
+type vmTraceOp struct {
+	Cost uint64        `json:"cost"`
+	Ex   *vmTraceEx    `json:"ex"`
+	Pc   uint64        `json:"pc"`
+	Sub  *vmTraceFrame `json:"sub"`
+}
+
+// vmTraceEx holds the effects of an executed instruction. It's nil if the
+// instruction failed.
+type vmTraceEx struct {
+	Mem   *vmTraceMem   `json:"mem"`
+	Push  []string      `json:"push"`
+	Store *vmTraceStore `json:"store"`
+	Used  uint64        `json:"used"`
+}
+
+// vmTraceMem is the memory region written by an instruction.
+type vmTraceMem struct {
+	Data hexutil.Bytes `json:"data"`
+	Off  uint64        `json:"off"`
+}
+
+// vmTraceStore is the storage slot written by an instruction.
+type vmTraceStore struct {
+	Key string `json:"key"`
+	Val string `json:"val"`
+}
+
+// vmTraceScope tracks the instruction of a call frame whose effects are not
+// known yet. The effects are resolved when the next instruction of the same
+// frame is reached, or when the frame is exited.
+type vmTraceScope struct {
+	frame   *vmTraceFrame
+	pending *vmTraceOp
+	gasLeft uint64 // Gas left after paying for the pending instruction
+	pushes  int    // Number of stack items reported for the pending instruction
+	memOff  uint64 // Offset of the memory written by the pending instruction
+	memSize uint64 // Size of the memory written by the pending instruction
+	store   *vmTraceStore
+}
+
+// vmTracer reports the executed instructions of a tx in the format of the
+// Parity `vmTrace` output, with nested call frames attached to the instruction
+// which initiated them.
+type vmTracer struct {
+	env       *tracing.VMContext
+	root      *vmTraceFrame
+	stack     []*vmTraceScope
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newVMTracer returns a native go tracer which produces Parity-style vm traces.
+func newVMTracer(ctx *tracers.Context, _ json.RawMessage) (*tracers.Tracer, error) {
+	t := &vmTracer{}
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnTxStart: t.OnTxStart,
+			OnEnter:   t.OnEnter,
+			OnExit:    t.OnExit,
+			OnOpcode:  t.OnOpcode,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+func (t *vmTracer) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	t.env = env
+}
+
+// OnEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *vmTracer) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	frame := &vmTraceFrame{Ops: make([]*vmTraceOp, 0)}
+	switch op := vm.OpCode(typ); op {
+	case vm.CREATE, vm.CREATE2:
+		frame.Code = common.CopyBytes(input)
+	case vm.SELFDESTRUCT:
+		// Selfdestructs are not reported as sub traces, but the scope
+		// is still tracked to keep the stack balanced.
+	default:
+		frame.Code = t.env.StateDB.GetCode(to)
+	}
+	if depth == 0 {
+		t.root = frame
+	} else if len(t.stack) > 0 && vm.OpCode(typ) != vm.SELFDESTRUCT {
+		if parent := t.stack[len(t.stack)-1]; parent.pending != nil {
+			parent.pending.Sub = frame
+		}
+	}
+	t.stack = append(t.stack, &vmTraceScope{frame: frame})
+}
+
+// OnExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *vmTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if t.interrupt.Load() || len(t.stack) == 0 {
+		return
+	}
+	scope := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+
+	// The last instruction of the frame is either a halting one, or the one
+	// failing the execution. Only the former has effects to be reported.
+	if scope.pending != nil && (err == nil || errors.Is(err, vm.ErrExecutionReverted)) {
+		scope.pending.Ex = &vmTraceEx{Push: make([]string, 0), Used: scope.gasLeft}
+	}
+	scope.pending = nil
+}
+
+// OnOpcode implements the EVMLogger interface to trace a single step of VM execution.
+func (t *vmTracer) OnOpcode(pc uint64, opcode byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	if t.interrupt.Load() || len(t.stack) == 0 {
+		return
+	}
+	s := t.stack[len(t.stack)-1]
+	t.resolve(s, gas, scope)
+
+	op := &vmTraceOp{Cost: cost, Pc: pc}
+	s.frame.Ops = append(s.frame.Ops, op)
+	s.pending = op
+	if gas >= cost {
+		s.gasLeft = gas - cost
+	}
+	s.pushes = vmTracePushes(vm.OpCode(opcode))
+	s.memOff, s.memSize, s.store = 0, 0, nil
+
+	stack := scope.StackData()
+	switch vm.OpCode(opcode) {
+	case vm.MSTORE:
+		s.setMemory(stack, 0, -32)
+	case vm.MSTORE8:
+		s.setMemory(stack, 0, -1)
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY, vm.MCOPY:
+		s.setMemory(stack, 0, 2)
+	case vm.EXTCODECOPY:
+		s.setMemory(stack, 1, 3)
+	case vm.CALL, vm.CALLCODE:
+		s.setMemory(stack, 5, 6)
+	case vm.DELEGATECALL, vm.STATICCALL:
+		s.setMemory(stack, 4, 5)
+	case vm.SSTORE:
+		if len(stack) >= 2 {
+			s.store = &vmTraceStore{
+				Key: internal.StackBack(stack, 0).Hex(),
+				Val: internal.StackBack(stack, 1).Hex(),
+			}
+		}
+	}
+}
+
+// resolve fills the effects of the pending instruction of the given frame based
+// on the state observed right before the next instruction is executed.
+func (t *vmTracer) resolve(s *vmTraceScope, gas uint64, scope tracing.OpContext) {
+	if s.pending == nil {
+		return
+	}
+	ex := &vmTraceEx{Push: make([]string, 0, s.pushes), Store: s.store, Used: gas}
+	stack := scope.StackData()
+	for i := min(s.pushes, len(stack)) - 1; i >= 0; i-- {
+		ex.Push = append(ex.Push, internal.StackBack(stack, i).Hex())
+	}
+	if s.memSize > 0 {
+		if data, err := internal.GetMemoryCopyPadded(scope.MemoryData(), int64(s.memOff), int64(s.memSize)); err == nil {
+			ex.Mem = &vmTraceMem{Data: data, Off: s.memOff}
+		}
+	}
+	s.pending.Ex = ex
+	s.pending = nil
+}
+
+// setMemory records the memory region which will be written by the pending
+// instruction. The offset and the size are positions counted from the top of
+// the stack, a negative size denotes a fixed size of the absolute value.
+func (s *vmTraceScope) setMemory(stack []uint256.Int, off int, size int) {
+	if len(stack) <= off || len(stack) <= size {
+		return
+	}
+	offset := internal.StackBack(stack, off)
+	if !offset.IsUint64() {
+		return
+	}
+	s.memOff = offset.Uint64()
+	if size < 0 {
+		s.memSize = uint64(-size)
+		return
+	}
+	if length := internal.StackBack(stack, size); length.IsUint64() {
+		s.memSize = length.Uint64()
+	}
+}
+
+// GetResult returns the json-encoded vm trace, and any error arising from the
+// encoding or forceful termination (via `Stop`).
+func (t *vmTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.root)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *vmTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// vmTracePushes returns the number of stack items reported as pushed by the
+// given instruction. Following Parity, the whole affected stack range is
+// reported for DUP and SWAP.
+func vmTracePushes(op vm.OpCode) int {
+	switch {
+	case op >= vm.PUSH0 && op <= vm.PUSH32:
+		return 1
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 2
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	}
+	switch op {
+	case vm.STOP, vm.POP, vm.MSTORE, vm.MSTORE8, vm.SSTORE, vm.TSTORE, vm.JUMP, vm.JUMPI, vm.JUMPDEST,
+		vm.LOG0, vm.LOG1, vm.LOG2, vm.LOG3, vm.LOG4, vm.RETURN, vm.REVERT, vm.SELFDESTRUCT, vm.INVALID,
+		vm.CALLDATACOPY, vm.CODECOPY, vm.EXTCODECOPY, vm.RETURNDATACOPY, vm.MCOPY:
+		return 0
+	}
+	return 1
+}
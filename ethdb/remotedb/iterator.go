@@ -0,0 +1,118 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotedb
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// rangeResult is a page of database entries returned by debug_dbRange.
+type rangeResult struct {
+	Keys   []hexutil.Bytes `json:"keys"`
+	Values []hexutil.Bytes `json:"values"`
+	Next   *hexutil.Bytes  `json:"next"`
+}
+
+// iterator iterates the entries of the remote database, retrieving them in
+// pages with debug_dbRange. As the remote database is live, the pages may not
+// reflect a consistent view of the database.
+type iterator struct {
+	remote *rpc.Client
+	prefix []byte
+	next   []byte // Start of the next page, nil if the last one was retrieved
+
+	keys   []hexutil.Bytes
+	values []hexutil.Bytes
+	pos    int
+	err    error
+}
+
+// newIterator creates an iterator over the entries of the remote database with
+// the given key prefix, starting at the given key.
+func newIterator(remote *rpc.Client, prefix []byte, start []byte) *iterator {
+	if start == nil {
+		start = []byte{}
+	}
+	return &iterator{
+		remote: remote,
+		prefix: prefix,
+		next:   start,
+	}
+}
+
+// Next moves the iterator to the next key/value pair, retrieving the next page
+// if the current one is exhausted.
+func (it *iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.pos+1 < len(it.keys) {
+		it.pos++
+		return true
+	}
+	for it.next != nil {
+		var page rangeResult
+		if err := it.remote.Call(&page, "debug_dbRange", hexutil.Bytes(it.prefix), hexutil.Bytes(it.next), iteratorPageSize); err != nil {
+			it.err = err
+			break
+		}
+		if len(page.Keys) != len(page.Values) {
+			it.err = errors.New("remote returned mismatching keys and values")
+			break
+		}
+		it.keys, it.values, it.pos = page.Keys, page.Values, 0
+		if page.Next != nil {
+			it.next = *page.Next
+		} else {
+			it.next = nil
+		}
+		if len(it.keys) > 0 {
+			return true
+		}
+	}
+	it.keys, it.values = nil, nil
+	return false
+}
+
+// Error returns any accumulated error.
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done.
+func (it *iterator) Key() []byte {
+	if it.pos >= len(it.keys) {
+		return nil
+	}
+	return it.keys[it.pos]
+}
+
+// Value returns the value of the current key/value pair, or nil if done.
+func (it *iterator) Value() []byte {
+	if it.pos >= len(it.values) {
+		return nil
+	}
+	return it.values[it.pos]
+}
+
+// Release releases the retrieved entries.
+func (it *iterator) Release() {
+	it.keys, it.values, it.next = nil, nil, nil
+}
@@ -15,21 +15,56 @@
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
 // Package remotedb implements the key-value database layer based on a remote geth
-// node. Under the hood, it utilises the `debug_dbGet` method to implement a
-// read-only database.
+// node. Under the hood, it utilises the `debug_db*` methods to implement a
+// read-only database, iterating the remote one page by page.
 // There really are no guarantees in this database, since the local geth does not
 // exclusive access, but it can be used for basic diagnostics of a remote node.
 package remotedb
 
 import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
-// Database is a key-value lookup for a remote database via debug_dbGet.
+const (
+	// getManyLimit is the maximum number of keys retrieved with a single call
+	// to the remote node.
+	getManyLimit = 1024
+
+	// iteratorPageSize is the number of entries retrieved at once by iterators.
+	iteratorPageSize = 1024
+)
+
+var (
+	// errReadOnly is returned when attempting to modify the remote database.
+	errReadOnly = errors.New("remote database is read-only")
+
+	// errNotSupported is returned for operations unavailable remotely.
+	errNotSupported = errors.New("not supported by remote database")
+)
+
+// ancientKey identifies an item of the ancient store.
+type ancientKey struct {
+	kind   string
+	number uint64
+}
+
+// Database is a key-value lookup for a remote database via the debug_db* RPC
+// methods. Only the retrieved values which can't change are cached, that is
+// ancient items and content-addressed entries like hash-keyed trie nodes and
+// contract code.
 type Database struct {
-	remote *rpc.Client
+	remote   *rpc.Client
+	values   *lru.SizeConstrainedCache[string, []byte]
+	ancients *lru.SizeConstrainedCache[ancientKey, []byte]
 }
 
 func (db *Database) Has(key []byte) (bool, error) {
@@ -40,14 +75,74 @@ func (db *Database) Has(key []byte) (bool, error) {
 }
 
 func (db *Database) Get(key []byte) ([]byte, error) {
+	if value, ok := db.values.Get(string(key)); ok {
+		return common.CopyBytes(value), nil
+	}
 	var resp hexutil.Bytes
 	err := db.remote.Call(&resp, "debug_dbGet", hexutil.Bytes(key))
 	if err != nil {
 		return nil, err
 	}
+	if cacheable(key, resp) {
+		db.values.Add(string(key), common.CopyBytes(resp))
+	}
 	return resp, nil
 }
 
+// GetMany retrieves the values of multiple keys, batching the lookups of the
+// uncached ones. Missing keys are reported with nil values.
+func (db *Database) GetMany(keys [][]byte) ([][]byte, error) {
+	var (
+		values  = make([][]byte, len(keys))
+		missing []int
+	)
+	for i, key := range keys {
+		if value, ok := db.values.Get(string(key)); ok {
+			values[i] = common.CopyBytes(value)
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	for len(missing) > 0 {
+		batch := missing
+		if len(batch) > getManyLimit {
+			batch = batch[:getManyLimit]
+		}
+		missing = missing[len(batch):]
+
+		req := make([]hexutil.Bytes, len(batch))
+		for i, index := range batch {
+			req[i] = keys[index]
+		}
+		var resp []*hexutil.Bytes
+		if err := db.remote.Call(&resp, "debug_dbGetMany", req); err != nil {
+			return nil, err
+		}
+		if len(resp) != len(batch) {
+			return nil, errors.New("remote returned invalid number of values")
+		}
+		for i, index := range batch {
+			if resp[i] != nil {
+				values[index] = *resp[i]
+				if cacheable(keys[index], *resp[i]) {
+					db.values.Add(string(keys[index]), common.CopyBytes(*resp[i]))
+				}
+			}
+		}
+	}
+	return values, nil
+}
+
+// cacheable reports whether the given entry is content-addressed, keyed by the
+// hash of its value. The other entries may be modified by the remote node at any
+// time, so caching them would return stale values.
+func cacheable(key, value []byte) bool {
+	if ok, hash := rawdb.IsCodeKey(key); ok {
+		key = hash
+	}
+	return len(key) == common.HashLength && bytes.Equal(key, crypto.Keccak256(value))
+}
+
 func (db *Database) HasAncient(kind string, number uint64) (bool, error) {
 	if _, err := db.Ancient(kind, number); err != nil {
 		return false, nil
@@ -56,16 +151,30 @@ func (db *Database) HasAncient(kind string, number uint64) (bool, error) {
 }
 
 func (db *Database) Ancient(kind string, number uint64) ([]byte, error) {
+	if blob, ok := db.ancients.Get(ancientKey{kind, number}); ok {
+		return common.CopyBytes(blob), nil
+	}
 	var resp hexutil.Bytes
 	err := db.remote.Call(&resp, "debug_dbAncient", kind, number)
 	if err != nil {
 		return nil, err
 	}
+	db.ancients.Add(ancientKey{kind, number}, common.CopyBytes(resp))
 	return resp, nil
 }
 
 func (db *Database) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
-	panic("not supported")
+	var resp []hexutil.Bytes
+	err := db.remote.Call(&resp, "debug_dbAncientRange", kind, start, count, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	blobs := make([][]byte, len(resp))
+	for i, blob := range resp {
+		blobs[i] = blob
+		db.ancients.Add(ancientKey{kind, start + uint64(i)}, common.CopyBytes(blob))
+	}
+	return blobs, nil
 }
 
 func (db *Database) Ancients() (uint64, error) {
@@ -75,11 +184,15 @@ func (db *Database) Ancients() (uint64, error) {
 }
 
 func (db *Database) Tail() (uint64, error) {
-	panic("not supported")
+	var resp uint64
+	err := db.remote.Call(&resp, "debug_dbAncientTail")
+	return resp, err
 }
 
 func (db *Database) AncientSize(kind string) (uint64, error) {
-	panic("not supported")
+	var resp uint64
+	err := db.remote.Call(&resp, "debug_dbAncientSize", kind)
+	return resp, err
 }
 
 func (db *Database) ReadAncients(fn func(op ethdb.AncientReaderOp) error) (err error) {
@@ -87,23 +200,23 @@ func (db *Database) ReadAncients(fn func(op ethdb.AncientReaderOp) error) (err e
 }
 
 func (db *Database) Put(key []byte, value []byte) error {
-	panic("not supported")
+	return errReadOnly
 }
 
 func (db *Database) Delete(key []byte) error {
-	panic("not supported")
+	return errReadOnly
 }
 
 func (db *Database) ModifyAncients(f func(ethdb.AncientWriteOp) error) (int64, error) {
-	panic("not supported")
+	return 0, errReadOnly
 }
 
 func (db *Database) TruncateHead(n uint64) (uint64, error) {
-	panic("not supported")
+	return 0, errReadOnly
 }
 
 func (db *Database) TruncateTail(n uint64) (uint64, error) {
-	panic("not supported")
+	return 0, errReadOnly
 }
 
 func (db *Database) Sync() error {
@@ -111,7 +224,7 @@ func (db *Database) Sync() error {
 }
 
 func (db *Database) MigrateTable(s string, f func([]byte) ([]byte, error)) error {
-	panic("not supported")
+	return errReadOnly
 }
 
 func (db *Database) NewBatch() ethdb.Batch {
@@ -123,15 +236,19 @@ func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
 }
 
 func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
-	panic("not supported")
+	return newIterator(db.remote, prefix, start)
 }
 
 func (db *Database) Stat(property string) (string, error) {
-	panic("not supported")
+	var resp string
+	err := db.remote.Call(&resp, "debug_dbStat", property)
+	return resp, err
 }
 
+// AncientDatadir reports no ancient directory, as the remote one isn't accessible
+// locally. Stores derived from it are hence ephemeral and empty.
 func (db *Database) AncientDatadir() (string, error) {
-	panic("not supported")
+	return "", nil
 }
 
 func (db *Database) Compact(start []byte, limit []byte) error {
@@ -139,7 +256,7 @@ func (db *Database) Compact(start []byte, limit []byte) error {
 }
 
 func (db *Database) NewSnapshot() (ethdb.Snapshot, error) {
-	panic("not supported")
+	return nil, errNotSupported
 }
 
 func (db *Database) Close() error {
@@ -147,8 +264,14 @@ func (db *Database) Close() error {
 	return nil
 }
 
-func New(client *rpc.Client) ethdb.Database {
+// New creates a database reading from the given remote node, caching up to the
+// given number of megabytes of the retrieved data. Only the immutable data is
+// cached, other entries are retrieved from the remote node on every read.
+func New(client *rpc.Client, cache int) ethdb.Database {
+	size := uint64(cache) * 1024 * 1024 / 2
 	return &Database{
-		remote: client,
+		remote:   client,
+		values:   lru.NewSizeConstrainedCache[string, []byte](size),
+		ancients: lru.NewSizeConstrainedCache[ancientKey, []byte](size),
 	}
 }
@@ -0,0 +1,145 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotedb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testBackend serves the debug API of a local database.
+type testBackend struct {
+	ethapi.Backend
+	db ethdb.Database
+}
+
+func (b *testBackend) ChainDb() ethdb.Database { return b.db }
+
+// newTestDatabase creates a remote database reading from the given local one
+// through an in-process RPC server.
+func newTestDatabase(t *testing.T, local ethdb.Database) ethdb.Database {
+	server := rpc.NewServer()
+	if err := server.RegisterName("debug", ethapi.NewDebugAPI(&testBackend{db: local})); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	return New(rpc.DialInProc(server), 16)
+}
+
+// Tests that the remote database reads single and multiple values.
+func TestRemoteGet(t *testing.T) {
+	local := rawdb.NewMemoryDatabase()
+	local.Put([]byte("a"), []byte("1"))
+	local.Put([]byte("b"), []byte("2"))
+
+	db := newTestDatabase(t, local)
+	if value, err := db.Get([]byte("a")); err != nil || !bytes.Equal(value, []byte("1")) {
+		t.Fatalf("value mismatch: have %q (%v), want %q", value, err, "1")
+	}
+	if has, _ := db.Has([]byte("c")); has {
+		t.Fatalf("missing key reported present")
+	}
+	values, err := db.(*Database).GetMany([][]byte{[]byte("a"), []byte("c"), []byte("b")})
+	if err != nil {
+		t.Fatalf("failed to get values: %v", err)
+	}
+	if len(values) != 3 || !bytes.Equal(values[0], []byte("1")) || values[1] != nil || !bytes.Equal(values[2], []byte("2")) {
+		t.Fatalf("values mismatch: have %q", values)
+	}
+	// Values must not be affected by modifications of the callers
+	values[0][0] = 'x'
+	if value, _ := db.Get([]byte("a")); !bytes.Equal(value, []byte("1")) {
+		t.Fatalf("value modified: have %q", value)
+	}
+	if err := db.Put([]byte("a"), []byte("2")); err != errReadOnly {
+		t.Fatalf("write error mismatch: have %v, want %v", err, errReadOnly)
+	}
+}
+
+// Tests that only the content-addressed values are cached, while the changes of
+// the other entries are picked up.
+func TestRemoteCache(t *testing.T) {
+	var (
+		local = rawdb.NewMemoryDatabase()
+		node  = []byte("trie node")
+		code  = []byte("contract code")
+		hash  = crypto.Keccak256Hash(code)
+	)
+	local.Put(crypto.Keccak256(node), node)
+	rawdb.WriteCode(local, hash, code)
+	local.Put([]byte("head"), []byte("1"))
+
+	db := newTestDatabase(t, local)
+	for _, key := range [][]byte{crypto.Keccak256(node), []byte("head")} {
+		if _, err := db.Get(key); err != nil {
+			t.Fatalf("failed to get value %x: %v", key, err)
+		}
+	}
+	if _, err := db.(*Database).GetMany([][]byte{append(rawdb.CodePrefix, hash.Bytes()...)}); err != nil {
+		t.Fatalf("failed to get code: %v", err)
+	}
+	local.Delete(crypto.Keccak256(node))
+	rawdb.DeleteCode(local, hash)
+	local.Put([]byte("head"), []byte("2"))
+
+	if value, err := db.Get(crypto.Keccak256(node)); err != nil || !bytes.Equal(value, node) {
+		t.Fatalf("trie node mismatch: have %q (%v), want %q", value, err, node)
+	}
+	if value := rawdb.ReadCode(db, hash); !bytes.Equal(value, code) {
+		t.Fatalf("code mismatch: have %q, want %q", value, code)
+	}
+	if value, err := db.Get([]byte("head")); err != nil || !bytes.Equal(value, []byte("2")) {
+		t.Fatalf("stale value: have %q (%v), want %q", value, err, "2")
+	}
+}
+
+// Tests that the remote iterator walks all entries of a prefix across pages.
+func TestRemoteIterator(t *testing.T) {
+	local := rawdb.NewMemoryDatabase()
+	for i := uint32(0); i < 2*iteratorPageSize+10; i++ {
+		key := binary.BigEndian.AppendUint32([]byte("p"), i)
+		local.Put(key, key[1:])
+	}
+	local.Put([]byte("q"), []byte("other"))
+
+	db := newTestDatabase(t, local)
+	for _, start := range []uint32{0, 5, iteratorPageSize + 3} {
+		it := db.NewIterator([]byte("p"), binary.BigEndian.AppendUint32(nil, start))
+		want := start
+		for it.Next() {
+			key := binary.BigEndian.AppendUint32([]byte("p"), want)
+			if !bytes.Equal(it.Key(), key) || !bytes.Equal(it.Value(), key[1:]) {
+				t.Fatalf("start %d: entry mismatch: have %x=%x, want %x", start, it.Key(), it.Value(), key)
+			}
+			want++
+		}
+		if err := it.Error(); err != nil {
+			t.Fatalf("start %d: iteration failed: %v", start, err)
+		}
+		it.Release()
+		if want != 2*iteratorPageSize+10 {
+			t.Fatalf("start %d: iteration stopped early at %d", start, want)
+		}
+	}
+}
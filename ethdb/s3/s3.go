@@ -0,0 +1,224 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package s3 implements an object store for the cold ancient data of the chain
+// freezer on top of an S3 compatible storage service. Only the few operations
+// needed by the freezer are supported, using path-style requests so that any
+// S3 compatible service (e.g. MinIO) can be used.
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	// requestTimeout is the timeout of the requests not transferring data
+	// files, which might take considerably longer to upload.
+	requestTimeout = time.Minute
+
+	// minUploadRate is the slowest transfer rate in bytes per second tolerated
+	// for uploads, on top of the base request timeout. It bounds the time hung
+	// connections can block the freezer for.
+	minUploadRate = 1024 * 1024
+
+	// unsignedPayload is the content hash of requests whose body isn't signed,
+	// avoiding to read the data files twice when uploading them.
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// emptyPayload is the content hash of requests without body.
+var emptyPayload = sha256Hex(nil)
+
+// Config contains the settings of the object store.
+type Config struct {
+	URL       string // Path-style URL of the bucket and key prefix, e.g. https://s3.us-east-1.amazonaws.com/bucket/prefix
+	Region    string // Region of the bucket, used for signing the requests
+	AccessKey string // Access key ID of the credentials
+	SecretKey string // Secret access key of the credentials
+}
+
+// Store is an object store based on an S3 compatible bucket.
+type Store struct {
+	base   *url.URL // URL of the bucket including the key prefix
+	region string
+	creds  aws.Credentials
+	signer *v4.Signer
+	client *http.Client
+}
+
+// New creates an object store accessing the bucket of the given config.
+func New(config Config) (*Store, error) {
+	base, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bucket URL: %v", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("invalid bucket URL scheme %q", base.Scheme)
+	}
+	if strings.Trim(base.Path, "/") == "" {
+		return nil, errors.New("bucket URL is missing the bucket name")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	return &Store{
+		base:   base,
+		region: config.Region,
+		creds: aws.Credentials{
+			AccessKeyID:     config.AccessKey,
+			SecretAccessKey: config.SecretKey,
+		},
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			o.DisableURIPathEscaping = true // S3 keys are escaped only once
+		}),
+		client: new(http.Client), // Requests are bounded by their contexts
+	}, nil
+}
+
+// Upload stores the given content under the key.
+func (s *Store) Upload(key string, data io.Reader, size int64) error {
+	// Empty bodies would be sent chunked, which S3 refuses
+	if size == 0 {
+		data = http.NoBody
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout(size))
+	defer cancel()
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, data, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return responseError(res)
+	}
+	return nil
+}
+
+// uploadTimeout returns the time an upload of the given size is allowed to take.
+func uploadTimeout(size int64) time.Duration {
+	return requestTimeout + time.Duration(size/minUploadRate)*time.Second
+}
+
+// ReadRange reads at most length bytes of an object, starting at the given
+// offset. Fewer bytes are returned if the object ends before.
+func (s *Store) ReadRange(key string, offset, length int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil, emptyPayload)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		return io.ReadAll(io.LimitReader(res.Body, length))
+	case http.StatusOK:
+		// The range was ignored, skip to the requested part of the object
+		if _, err := io.CopyN(io.Discard, res.Body, offset); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return io.ReadAll(io.LimitReader(res.Body, length))
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil, nil // Offset beyond the end of the object
+	default:
+		return nil, responseError(res)
+	}
+}
+
+// Delete removes an object from the store. Deleting a missing object is not
+// an error.
+func (s *Store) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil, emptyPayload)
+	if err != nil {
+		return err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		return responseError(res)
+	}
+	return nil
+}
+
+// newRequest creates a signed request for the object with the given key.
+func (s *Store) newRequest(ctx context.Context, method string, key string, body io.Reader, payloadHash string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.base.JoinPath(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := s.signer.SignHTTP(ctx, s.creds, req, payloadHash, "s3", s.region, time.Now()); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// responseError creates an error from a failed response, including the error
+// code returned by the service if any.
+func responseError(res *http.Response) error {
+	var failure struct {
+		Code string `xml:"Code"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	if xml.Unmarshal(body, &failure) == nil && failure.Code != "" {
+		return fmt.Errorf("s3 request failed: %s (%s)", res.Status, failure.Code)
+	}
+	return fmt.Errorf("s3 request failed: %s", res.Status)
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
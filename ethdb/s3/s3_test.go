@@ -0,0 +1,145 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package s3
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer is a minimal in-memory S3 compatible service, serving path-style
+// object requests of a single bucket.
+type fakeServer struct {
+	accessKey string
+	objects   map[string][]byte
+	lock      sync.Mutex
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>AccessDenied</Code></Error>")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(data)) != r.ContentLength {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = data
+
+	case http.MethodGet:
+		data, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil {
+			w.Write(data)
+			return
+		}
+		if start >= len(data) {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		end = min(end, len(data)-1)
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start : end+1])
+
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestStore(t *testing.T) (*Store, *fakeServer) {
+	fake := &fakeServer{accessKey: "key", objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := New(Config{URL: server.URL + "/bucket/prefix", AccessKey: "key", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, fake
+}
+
+func TestStore(t *testing.T) {
+	store, fake := newTestStore(t)
+
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	if err := store.Upload("table.0000.cdat", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	fake.lock.Lock()
+	_, ok := fake.objects["/bucket/prefix/table.0000.cdat"]
+	fake.lock.Unlock()
+	if !ok {
+		t.Fatal("object not stored under the key prefix")
+	}
+	tests := []struct {
+		offset, length int64
+		want           []byte
+	}{
+		{0, 10, data[:10]},
+		{995, 10, data[995:]},
+		{1000, 10, nil},
+		{2000, 10, nil},
+	}
+	for _, test := range tests {
+		have, err := store.ReadRange("table.0000.cdat", test.offset, test.length)
+		if err != nil {
+			t.Fatalf("failed to read range %d+%d: %v", test.offset, test.length, err)
+		}
+		if !bytes.Equal(have, test.want) {
+			t.Errorf("range %d+%d mismatch: have %q, want %q", test.offset, test.length, have, test.want)
+		}
+	}
+	if err := store.Delete("table.0000.cdat"); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if _, err := store.ReadRange("table.0000.cdat", 0, 10); err == nil || !strings.Contains(err.Error(), "NoSuchKey") {
+		t.Fatalf("deleted object still readable: %v", err)
+	}
+	if err := store.Delete("table.0000.cdat"); err != nil {
+		t.Fatalf("failed to delete missing object: %v", err)
+	}
+	// Requests with invalid credentials must be refused
+	fake.lock.Lock()
+	fake.accessKey = "other"
+	fake.lock.Unlock()
+	if err := store.Upload("empty", bytes.NewReader(nil), 0); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Fatalf("unauthorized upload accepted: %v", err)
+	}
+}
@@ -170,6 +170,10 @@ func (l *Log) Data(ctx context.Context) hexutil.Bytes {
 	return l.log.Data
 }
 
+func (l *Log) Removed(ctx context.Context) bool {
+	return l.log.Removed
+}
+
 // AccessTuple represents EIP-2930
 type AccessTuple struct {
 	address     common.Address
@@ -1284,6 +1288,8 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 type Resolver struct {
 	backend      ethapi.Backend
 	filterSystem *filters.FilterSystem
+	events       *filters.EventSystem // Event system backing the subscriptions, created on first use
+	eventsOnce   sync.Once
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -371,6 +371,83 @@ func TestGraphQLConcurrentResolvers(t *testing.T) {
 	}
 }
 
+// Tests the execution traces, state diffs and storage iteration.
+func TestGraphQLTraces(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		dadStr  = "0x0000000000000000000000000000000000000dad"
+		dad     = common.HexToAddress(dadStr)
+		genesis = &core.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+				dad: {
+					// SSTORE(0, 42), SSTORE(1, 43)
+					Code:    common.Hex2Bytes("602a600055602b600155"),
+					Balance: big.NewInt(0),
+				},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+		stack  = createNode(t)
+	)
+	defer stack.Close()
+
+	var tx *types.Transaction
+	handler, _ := newGQLService(t, stack, false, genesis, 1, func(i int, gen *core.BlockGen) {
+		tx, _ = types.SignNewTx(key, signer, &types.LegacyTx{To: &dad, Gas: 100000, GasPrice: big.NewInt(params.InitialBaseFee)})
+		gen.AddTx(tx)
+	})
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	var (
+		slot0 = crypto.Keccak256Hash(common.Hash{}.Bytes())
+		slot1 = crypto.Keccak256Hash(common.BigToHash(common.Big1).Bytes())
+	)
+	for i, tt := range []struct {
+		body string
+		want string
+	}{
+		{
+			body: fmt.Sprintf(`{ transaction(hash: "%s") { callTrace { type from to value input calls { type } } } }`, tx.Hash()),
+			want: fmt.Sprintf(`{"transaction":{"callTrace":{"type":"CALL","from":"%s","to":"%s","value":"0x0","input":"0x","calls":[]}}}`, strings.ToLower(addr.Hex()), dadStr),
+		},
+		{
+			body: fmt.Sprintf(`{ transaction(hash: "%s") { stateDiff { address pre { nonce storage { slot value } } post { nonce storage { slot value } } } } }`, tx.Hash()),
+			want: fmt.Sprintf(`{"transaction":{"stateDiff":[{"address":"%s","pre":{"nonce":null,"storage":[]},"post":{"nonce":null,"storage":[]}},{"address":"%s","pre":{"nonce":null,"storage":[]},"post":{"nonce":null,"storage":[{"slot":"%s","value":"%s"},{"slot":"%s","value":"%s"}]}},{"address":"%s","pre":{"nonce":null,"storage":[]},"post":{"nonce":"0x1","storage":[]}}]}}`,
+				common.Address{}, dadStr, common.Hash{}, common.BigToHash(big.NewInt(42)), common.BigToHash(common.Big1), common.BigToHash(big.NewInt(43)), strings.ToLower(addr.Hex())),
+		},
+		{
+			body: "{ block { accountsTouched { address } } }",
+			want: fmt.Sprintf(`{"block":{"accountsTouched":[{"address":"%s"},{"address":"%s"},{"address":"%s"}]}}`, common.Address{}, dadStr, strings.ToLower(addr.Hex())),
+		},
+		{
+			body: fmt.Sprintf(`{ block { account(address: "%s") { storageRange(count: 1) { entries { key value } next } } } }`, dadStr),
+			want: fmt.Sprintf(`{"block":{"account":{"storageRange":{"entries":[{"key":"%s","value":"%s"}],"next":"%s"}}}}`, slot0, common.BigToHash(big.NewInt(42)), slot1),
+		},
+		{
+			body: fmt.Sprintf(`{ block { account(address: "%s") { storageRange(start: "%s") { entries { key value } next } } } }`, dadStr, slot1),
+			want: fmt.Sprintf(`{"block":{"account":{"storageRange":{"entries":[{"key":"%s","value":"%s"}],"next":null}}}}`, slot1, common.BigToHash(big.NewInt(43))),
+		},
+	} {
+		res := handler.Schema.Exec(context.Background(), tt.body, "", map[string]interface{}{})
+		if res.Errors != nil {
+			t.Fatalf("failed to execute query for testcase #%d: %v", i, res.Errors)
+		}
+		have, err := json.Marshal(res.Data)
+		if err != nil {
+			t.Fatalf("failed to encode graphql response for testcase #%d: %s", i, err)
+		}
+		if string(have) != tt.want {
+			t.Errorf("response unmatch for testcase #%d.\nhave:\n%s\nwant:\n%s", i, have, tt.want)
+		}
+	}
+}
+
 func TestWithdrawals(t *testing.T) {
 	var (
 		key, _ = crypto.GenerateKey()
@@ -36,6 +36,7 @@ const schema string = `
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ethereum account at a particular block.
@@ -54,6 +55,29 @@ const schema string = `
         # Storage provides access to the storage of a contract account, indexed
         # by its 32 byte slot identifier.
         storage(slot: Bytes32!): Bytes32!
+        # StorageRange iterates the storage of a contract account in the order
+        # of the hashed slots, starting at the given hashed slot. At most count
+        # entries are returned, 256 if omitted and 1024 at most.
+        storageRange(start: Bytes32, count: Long): StorageRange!
+    }
+
+    # StorageRange is a page of the storage of a contract account.
+    type StorageRange {
+        # Entries are the storage slots of the page.
+        entries: [StorageEntry!]!
+        # Next is the hashed slot the next page starts at, or null if this is
+        # the last page.
+        next: Bytes32
+    }
+
+    # StorageEntry is a storage slot of a contract account.
+    type StorageEntry {
+        # Key is the hash of the slot identifier.
+        key: Bytes32!
+        # Slot is the slot identifier, if its preimage is known to the node.
+        slot: Bytes32
+        # Value is the value stored in the slot.
+        value: Bytes32!
     }
 
     # Log is an Ethereum event log.
@@ -69,6 +93,9 @@ const schema string = `
         data: Bytes!
         # Transaction is the transaction that generated this log entry.
         transaction: Transaction!
+        # Removed is true if the log was reverted due to a chain reorganisation.
+        # It is only ever set on logs delivered by a subscription.
+        removed: Boolean!
     }
 
     # EIP-2718
@@ -170,6 +197,73 @@ const schema string = `
         rawReceipt: Bytes!
         # BlobVersionedHashes is a set of hash outputs from the blobs in the transaction.
         blobVersionedHashes: [Bytes32!]
+        # CallTrace is the tree of calls made by the transaction, as reported by
+        # the call tracer. If the transaction is pending, this field will be null.
+        callTrace: CallFrame
+        # StateDiff lists the accounts modified by the transaction along with
+        # their state before and after it, as reported by the prestate tracer.
+        # If the transaction is pending, this field will be null.
+        stateDiff: [AccountDiff!]
+    }
+
+    # CallFrame is a call made during the execution of a transaction.
+    type CallFrame {
+        # Type is the type of the call: CALL, CALLCODE, DELEGATECALL, STATICCALL,
+        # CREATE, CREATE2 or SELFDESTRUCT.
+        type: String!
+        # From is the address of the caller.
+        from: Address!
+        # To is the address of the callee, null if contract creation failed.
+        to: Address
+        # Value is the value transferred by the call, in wei.
+        value: BigInt
+        # Gas is the gas provided to the call.
+        gas: Long!
+        # GasUsed is the gas consumed by the call, including its subcalls.
+        gasUsed: Long!
+        # Input is the call data, or the init code of a contract creation.
+        input: Bytes!
+        # Output is the data returned by the call.
+        output: Bytes
+        # Error is the error the call failed with, if any.
+        error: String
+        # RevertReason is the decoded reason of a reverted call, if available.
+        revertReason: String
+        # Calls are the subcalls made by the call, in execution order.
+        calls: [CallFrame!]!
+    }
+
+    # AccountDiff is the modification of an account by a transaction.
+    type AccountDiff {
+        # Address is the address of the modified account.
+        address: Address!
+        # Pre is the state of the account before the transaction, limited to the
+        # modified storage slots. It is null if the account was created by the
+        # transaction.
+        pre: AccountState
+        # Post is the state of the modified fields after the transaction. It is
+        # null if the account was destroyed by the transaction.
+        post: AccountState
+    }
+
+    # AccountState is a partial account state. Fields are null if they are
+    # unset, or left unmodified in a post state.
+    type AccountState {
+        # Balance is the balance of the account, in wei.
+        balance: BigInt
+        # Nonce is the nonce of the account.
+        nonce: Long
+        # Code is the code of the account.
+        code: Bytes
+        # Storage lists the modified storage slots. Slots cleared by the
+        # transaction are absent from the post state.
+        storage: [StorageSlot!]!
+    }
+
+    # StorageSlot is a storage slot and its value.
+    type StorageSlot {
+        slot: Bytes32!
+        value: Bytes32!
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -279,6 +373,10 @@ const schema string = `
         blobGasUsed: Long
         # ExcessBlobGas is a running total of blob gas consumed in excess of the target, prior to the block.
         excessBlobGas: Long
+        # AccountsTouched lists the accounts accessed by the transactions of this
+        # block, as reported by the prestate tracer, and the recipients of its
+        # withdrawals, ordered by address. The accounts are resolved at this block.
+        accountsTouched: [Account!]!
     }
 
     # CallData represents the data associated with a local contract call.
@@ -391,4 +489,17 @@ const schema string = `
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscription delivers live chain events over the graphql-ws websocket
+    # protocols. A single subscription may be active per operation.
+    type Subscription {
+        # NewBlocks delivers each block added to the canonical chain.
+        newBlocks: Block!
+        # PendingTransactions delivers each transaction entering the pool.
+        pendingTransactions: Transaction!
+        # NewLogs delivers the logs matching the filter as they are included in
+        # or reverted from the canonical chain. It is named differently from the
+        # logs query as both are served by the same resolver.
+        newLogs(filter: BlockFilterCriteria!): Log!
+    }
 `
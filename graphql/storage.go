@@ -0,0 +1,184 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	storageRangeDefault = 256  // Number of storage entries returned if not specified
+	storageRangeLimit   = 1024 // Maximum number of storage entries returned at once
+)
+
+// errStorageRangeHistorical is returned if the storage of a historical state is
+// requested from the path-based state scheme, which only keeps the tries of the
+// recent states, while the historical ones are reconstructed from the state
+// histories that can't be iterated.
+var errStorageRangeHistorical = errors.New("storage range of historical state is not available in path scheme")
+
+// snapshotBackend is implemented by backends giving access to the state
+// snapshot, which is preferred over the tries to iterate storage.
+type snapshotBackend interface {
+	Snapshots() *snapshot.Tree
+}
+
+// storageIterator iterates the storage slots of an account by hashed slot.
+// It's implemented by the snapshot iterators and trieStorageIterator.
+type storageIterator interface {
+	Next() bool
+	Hash() common.Hash
+	Slot() []byte
+	Error() error
+}
+
+// trieStorageIterator wraps a trie iterator into a storageIterator.
+type trieStorageIterator struct {
+	it *trie.Iterator
+}
+
+func (it *trieStorageIterator) Next() bool        { return it.it.Next() }
+func (it *trieStorageIterator) Hash() common.Hash { return common.BytesToHash(it.it.Key) }
+func (it *trieStorageIterator) Slot() []byte      { return it.it.Value }
+func (it *trieStorageIterator) Error() error      { return it.it.Err }
+
+// StorageRange is a page of the storage of an account.
+type StorageRange struct {
+	entries []*StorageEntry
+	next    *common.Hash
+}
+
+func (s *StorageRange) Entries() []*StorageEntry {
+	return s.entries
+}
+
+func (s *StorageRange) Next() *common.Hash {
+	return s.next
+}
+
+// StorageEntry is a storage slot of an account.
+type StorageEntry struct {
+	key   common.Hash
+	slot  *common.Hash
+	value common.Hash
+}
+
+func (e *StorageEntry) Key() common.Hash {
+	return e.key
+}
+
+func (e *StorageEntry) Slot() *common.Hash {
+	return e.slot
+}
+
+func (e *StorageEntry) Value() common.Hash {
+	return e.value
+}
+
+// StorageRange returns a page of the account's storage, iterating the snapshot
+// if it covers the account's state, or the storage trie otherwise.
+func (a *Account) StorageRange(ctx context.Context, args struct {
+	Start *common.Hash
+	Count *Long
+}) (*StorageRange, error) {
+	count := storageRangeDefault
+	if args.Count != nil {
+		if *args.Count <= 0 || *args.Count > storageRangeLimit {
+			return nil, fmt.Errorf("storage range count must be between 1 and %d", storageRangeLimit)
+		}
+		count = int(*args.Count)
+	}
+	var start common.Hash
+	if args.Start != nil {
+		start = *args.Start
+	}
+	statedb, header, err := a.r.backend.StateAndHeaderByNumberOrHash(ctx, a.blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	accountHash := crypto.Keccak256Hash(a.address.Bytes())
+
+	// The snapshot only covers the recent states and may be under construction,
+	// fall back to the trie if it can't serve the range.
+	if backend, ok := a.r.backend.(snapshotBackend); ok {
+		if snaps := backend.Snapshots(); snaps != nil {
+			if it, err := snaps.StorageIterator(header.Root, accountHash, start); err == nil {
+				result, err := newStorageRange(statedb, it, count)
+				it.Release()
+				if err == nil {
+					return result, nil
+				}
+			}
+		}
+	}
+	storageRoot := statedb.GetStorageRoot(a.address)
+	if storageRoot == types.EmptyRootHash || storageRoot == (common.Hash{}) {
+		return &StorageRange{entries: []*StorageEntry{}}, nil
+	}
+	triedb := statedb.Database().TrieDB()
+	if triedb.Scheme() == rawdb.PathScheme {
+		if _, err := triedb.Reader(header.Root); err != nil {
+			return nil, errStorageRangeHistorical
+		}
+	}
+	tr, err := trie.NewStateTrie(trie.StorageTrieID(header.Root, accountHash, storageRoot), triedb)
+	if err != nil {
+		return nil, err
+	}
+	nodeIt, err := tr.NodeIterator(start.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return newStorageRange(statedb, &trieStorageIterator{trie.NewIterator(nodeIt)}, count)
+}
+
+// newStorageRange collects a page of at most count storage entries.
+func newStorageRange(statedb *state.StateDB, it storageIterator, count int) (*StorageRange, error) {
+	result := &StorageRange{entries: []*StorageEntry{}}
+	for len(result.entries) < count && it.Next() {
+		_, content, _, err := rlp.Split(it.Slot())
+		if err != nil {
+			return nil, err
+		}
+		entry := &StorageEntry{key: it.Hash(), value: common.BytesToHash(content)}
+		if preimage := statedb.Database().TrieDB().Preimage(entry.key); preimage != nil {
+			slot := common.BytesToHash(preimage)
+			entry.slot = &slot
+		}
+		result.entries = append(result.entries, entry)
+	}
+	// Add the next key so clients can continue iterating
+	if it.Next() {
+		next := it.Hash()
+		result.next = &next
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// subscriptionBufferSize is the number of events buffered for a subscriber.
+// Subscribers falling further behind are dropped, so that a slow client can
+// never stall the event system shared with the other subscriptions.
+const subscriptionBufferSize = 128
+
+// forward relays the events of an event system subscription to the returned
+// channel, converting each batch with the given function. The subscription is
+// torn down and the channel closed when the context is cancelled or the
+// subscriber lags behind.
+func forward[E, T any](ctx context.Context, sub *filters.Subscription, events <-chan E, convert func(E) []T) <-chan T {
+	out := make(chan T, subscriptionBufferSize)
+	go func() {
+		defer close(out)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				for _, item := range convert(ev) {
+					select {
+					case out <- item:
+					default:
+						log.Debug("Dropping lagging GraphQL subscriber", "id", sub.ID)
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// eventSystem returns the event system of the subscriptions, creating it if
+// this is the first one.
+func (r *Resolver) eventSystem() *filters.EventSystem {
+	r.eventsOnce.Do(func() {
+		r.events = filters.NewEventSystem(r.filterSystem)
+	})
+	return r.events
+}
+
+// NewBlocks subscribes to the blocks added to the canonical chain.
+func (r *Resolver) NewBlocks(ctx context.Context) (<-chan *Block, error) {
+	headers := make(chan *types.Header, subscriptionBufferSize)
+	sub := r.eventSystem().SubscribeNewHeads(headers)
+
+	return forward(ctx, sub, headers, func(header *types.Header) []*Block {
+		var (
+			hash         = header.Hash()
+			numberOrHash = rpc.BlockNumberOrHashWithHash(hash, false)
+		)
+		return []*Block{{r: r, numberOrHash: &numberOrHash, hash: hash, header: header}}
+	}), nil
+}
+
+// PendingTransactions subscribes to the transactions entering the pool.
+func (r *Resolver) PendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	txs := make(chan []*types.Transaction, subscriptionBufferSize)
+	sub := r.eventSystem().SubscribePendingTxs(txs)
+
+	return forward(ctx, sub, txs, func(txs []*types.Transaction) []*Transaction {
+		ret := make([]*Transaction, len(txs))
+		for i, tx := range txs {
+			ret[i] = &Transaction{r: r, hash: tx.Hash(), tx: tx}
+		}
+		return ret
+	}), nil
+}
+
+// NewLogs subscribes to the logs matching the filter criteria, as they are
+// included in or reverted from the canonical chain.
+func (r *Resolver) NewLogs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	var crit ethereum.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	logs := make(chan []*types.Log, subscriptionBufferSize)
+	sub, err := r.eventSystem().SubscribeLogs(crit, logs)
+	if err != nil {
+		return nil, err
+	}
+	return forward(ctx, sub, logs, func(logs []*types.Log) []*Log {
+		ret := make([]*Log, len(logs))
+		for i, log := range logs {
+			ret[i] = &Log{r: r, transaction: &Transaction{r: r, hash: log.TxHash}, log: log}
+		}
+		return ret
+	}), nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/gorilla/websocket"
+)
+
+// Tests that blocks and logs are delivered to the subscribers over the
+// graphql-transport-ws protocol.
+func TestGraphQLSubscriptions(t *testing.T) {
+	// Use a pre-merge config, the shared one is modified by other tests
+	config := *params.AllEthashProtocolChanges
+	config.TerminalTotalDifficulty = nil
+	config.ShanghaiTime = nil
+
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		dadStr  = "0x0000000000000000000000000000000000000dad"
+		dad     = common.HexToAddress(dadStr)
+		genesis = &core.Genesis{
+			Config:     &config,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+				dad: {
+					// LOG0(0, 0), LOG0(0, 0), RETURN(0, 0)
+					Code:    common.Hex2Bytes("60006000a060006000a060006000f3"),
+					Balance: big.NewInt(0),
+				},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+		stack  = createNode(t)
+	)
+	defer stack.Close()
+
+	ethBackend, err := eth.New(stack, &ethconfig.Config{
+		Genesis:        genesis,
+		NetworkId:      1337,
+		TrieCleanCache: 5,
+		TrieDirtyCache: 5,
+		TrieTimeout:    60 * time.Minute,
+		SnapshotCache:  5,
+		StateScheme:    rawdb.HashScheme,
+	})
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	filterSystem := filters.NewFilterSystem(ethBackend.APIBackend, filters.Config{})
+	if _, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{}, []string{}); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	// Connect and subscribe to the blocks and the logs of the contract
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(stack.HTTPEndpoint(), "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("could not dial graphql websocket: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	send := func(msg string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("could not send message: %v", err)
+		}
+	}
+	recv := func() *wsMessage {
+		msg := new(wsMessage)
+		if err := conn.ReadJSON(msg); err != nil {
+			t.Fatalf("could not read message: %v", err)
+		}
+		return msg
+	}
+	send(`{"type":"connection_init"}`)
+	if msg := recv(); msg.Type != "connection_ack" {
+		t.Fatalf("unexpected init response: %s", msg.Type)
+	}
+	send(`{"id":"blocks","type":"subscribe","payload":{"query":"subscription { newBlocks { number } }"}}`)
+	send(`{"id":"logs","type":"subscribe","payload":{"query":"subscription { newLogs(filter: {addresses: [\"` + dadStr + `\"]}) { index removed } }"}}`)
+
+	// Messages are handled in order, the pong confirms the subscriptions are live
+	send(`{"type":"ping"}`)
+	if msg := recv(); msg.Type != "pong" {
+		t.Fatalf("unexpected ping response: %s", msg.Type)
+	}
+	chain, _ := core.GenerateChain(genesis.Config, ethBackend.BlockChain().Genesis(), ethash.NewFaker(), ethBackend.ChainDb(), 1, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{To: &dad, Gas: 100000, GasPrice: big.NewInt(params.InitialBaseFee)})
+		gen.AddTx(tx)
+	})
+	if _, err := ethBackend.BlockChain().InsertChain(chain); err != nil {
+		t.Fatalf("could not import blocks: %v", err)
+	}
+	// Collect the events of both subscriptions
+	have := make(map[string][]string)
+	for len(have["blocks"]) < 1 || len(have["logs"]) < 2 {
+		msg := recv()
+		if msg.Type != "next" {
+			t.Fatalf("unexpected message for %s: %s %s", msg.ID, msg.Type, msg.Payload)
+		}
+		var res struct {
+			Data   json.RawMessage
+			Errors []interface{}
+		}
+		if err := json.Unmarshal(msg.Payload, &res); err != nil || len(res.Errors) > 0 {
+			t.Fatalf("invalid result for %s: %s", msg.ID, msg.Payload)
+		}
+		have[msg.ID] = append(have[msg.ID], string(res.Data))
+	}
+	if want := `{"newBlocks":{"number":"0x1"}}`; have["blocks"][0] != want {
+		t.Errorf("block mismatch: have %s, want %s", have["blocks"][0], want)
+	}
+	for i, log := range have["logs"] {
+		if want := fmt.Sprintf(`{"newLogs":{"index":"%#x","removed":false}}`, i); log != want {
+			t.Errorf("log %d mismatch: have %s, want %s", i, log, want)
+		}
+	}
+	// Stop a subscription and fill up the operation slots of the connection,
+	// the operations above the limit are rejected
+	send(`{"id":"blocks","type":"complete"}`)
+	for i := 1; i < wsMaxOperations; i++ {
+		send(fmt.Sprintf(`{"id":"op%d","type":"subscribe","payload":{"query":"subscription { newBlocks { number } }"}}`, i))
+	}
+	send(`{"id":"over","type":"subscribe","payload":{"query":"subscription { newBlocks { number } }"}}`)
+	if msg := recv(); msg.ID != "over" || msg.Type != "error" {
+		t.Fatalf("unexpected response above the operation limit: %s %s %s", msg.ID, msg.Type, msg.Payload)
+	}
+	// Check that a duplicate id closes the connection
+	send(`{"id":"logs","type":"subscribe","payload":{"query":"subscription { newBlocks { number } }"}}`)
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, wsCloseDuplicateID) {
+		t.Fatalf("unexpected error for duplicate subscription: %v", err)
+	}
+}
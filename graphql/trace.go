@@ -0,0 +1,326 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native" // Register the native tracers used below
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxTracedBlocks is the maximum number of blocks a single query may trace
+	// to resolve the accounts touched by them.
+	maxTracedBlocks = 16
+
+	// blockTraceTimeout is the time allowed to trace all the transactions of
+	// a block, the same as the default timeout of a single transaction traced
+	// through the debug API.
+	blockTraceTimeout = 5 * time.Second
+)
+
+var (
+	errTracingUnsupported = errors.New("tracing not supported by the backend")
+	errTooManyTraces      = fmt.Errorf("too many blocks traced in a single query (max %d)", maxTracedBlocks)
+)
+
+// traceBudgetKey is the context key of the number of blocks traced by a query.
+type traceBudgetKey struct{}
+
+// withTraceBudget attaches a fresh budget of block traces to the context of a
+// query. Subscriptions are not budgeted, they trace a single block per event.
+func withTraceBudget(ctx context.Context) context.Context {
+	return context.WithValue(ctx, traceBudgetKey{}, new(atomic.Int32))
+}
+
+// spendTraceBudget accounts a block trace to the query, reporting whether it's
+// within the budget. Contexts without a budget are not limited.
+func spendTraceBudget(ctx context.Context) bool {
+	budget, ok := ctx.Value(traceBudgetKey{}).(*atomic.Int32)
+	return !ok || budget.Add(1) <= maxTracedBlocks
+}
+
+// trace runs the given tracer on a transaction, decoding its result.
+func (r *Resolver) trace(ctx context.Context, hash common.Hash, tracer string, config string, result interface{}) error {
+	backend, ok := r.backend.(tracers.Backend)
+	if !ok {
+		return errTracingUnsupported
+	}
+	res, err := tracers.NewAPI(backend).TraceTransaction(ctx, hash, &tracers.TraceConfig{
+		Tracer:       &tracer,
+		TracerConfig: json.RawMessage(config),
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(res.(json.RawMessage), result)
+}
+
+// callFrame is a call frame as encoded by the call tracer.
+type callFrame struct {
+	Type         string          `json:"type"`
+	From         common.Address  `json:"from"`
+	To           *common.Address `json:"to"`
+	Value        *hexutil.Big    `json:"value"`
+	Gas          hexutil.Uint64  `json:"gas"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	Input        hexutil.Bytes   `json:"input"`
+	Output       *hexutil.Bytes  `json:"output"`
+	Error        *string         `json:"error"`
+	RevertReason *string         `json:"revertReason"`
+	Calls        []*callFrame    `json:"calls"`
+}
+
+// CallFrame is a call made during the execution of a transaction.
+type CallFrame struct {
+	frame *callFrame
+}
+
+func (c *CallFrame) Type() string {
+	return c.frame.Type
+}
+
+func (c *CallFrame) From() common.Address {
+	return c.frame.From
+}
+
+func (c *CallFrame) To() *common.Address {
+	return c.frame.To
+}
+
+func (c *CallFrame) Value() *hexutil.Big {
+	return c.frame.Value
+}
+
+func (c *CallFrame) Gas() hexutil.Uint64 {
+	return c.frame.Gas
+}
+
+func (c *CallFrame) GasUsed() hexutil.Uint64 {
+	return c.frame.GasUsed
+}
+
+func (c *CallFrame) Input() hexutil.Bytes {
+	return c.frame.Input
+}
+
+func (c *CallFrame) Output() *hexutil.Bytes {
+	return c.frame.Output
+}
+
+func (c *CallFrame) Error() *string {
+	return c.frame.Error
+}
+
+func (c *CallFrame) RevertReason() *string {
+	return c.frame.RevertReason
+}
+
+func (c *CallFrame) Calls() []*CallFrame {
+	calls := make([]*CallFrame, len(c.frame.Calls))
+	for i, call := range c.frame.Calls {
+		calls[i] = &CallFrame{call}
+	}
+	return calls
+}
+
+func (t *Transaction) CallTrace(ctx context.Context) (*CallFrame, error) {
+	_, block := t.resolve(ctx)
+	// Pending tx
+	if block == nil {
+		return nil, nil
+	}
+	frame := new(callFrame)
+	if err := t.r.trace(ctx, t.hash, "callTracer", "{}", frame); err != nil {
+		return nil, err
+	}
+	return &CallFrame{frame}, nil
+}
+
+// accountState is an account as encoded by the prestate tracer.
+type accountState struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   *uint64                     `json:"nonce"`
+	Code    *hexutil.Bytes              `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// AccountState is a partial account state reported by the prestate tracer.
+type AccountState struct {
+	state *accountState
+}
+
+func (a *AccountState) Balance() *hexutil.Big {
+	return a.state.Balance
+}
+
+func (a *AccountState) Nonce() *hexutil.Uint64 {
+	return (*hexutil.Uint64)(a.state.Nonce)
+}
+
+func (a *AccountState) Code() *hexutil.Bytes {
+	return a.state.Code
+}
+
+func (a *AccountState) Storage() []*StorageSlot {
+	slots := make([]*StorageSlot, 0, len(a.state.Storage))
+	for slot, value := range a.state.Storage {
+		slots = append(slots, &StorageSlot{slot, value})
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return bytes.Compare(slots[i].slot[:], slots[j].slot[:]) < 0
+	})
+	return slots
+}
+
+// StorageSlot is a storage slot and its value.
+type StorageSlot struct {
+	slot  common.Hash
+	value common.Hash
+}
+
+func (s *StorageSlot) Slot() common.Hash {
+	return s.slot
+}
+
+func (s *StorageSlot) Value() common.Hash {
+	return s.value
+}
+
+// AccountDiff is the modification of an account by a transaction.
+type AccountDiff struct {
+	address common.Address
+	pre     *accountState
+	post    *accountState
+}
+
+func (d *AccountDiff) Address() common.Address {
+	return d.address
+}
+
+func (d *AccountDiff) Pre() *AccountState {
+	if d.pre == nil {
+		return nil
+	}
+	return &AccountState{d.pre}
+}
+
+func (d *AccountDiff) Post() *AccountState {
+	if d.post == nil {
+		return nil
+	}
+	return &AccountState{d.post}
+}
+
+func (t *Transaction) StateDiff(ctx context.Context) (*[]*AccountDiff, error) {
+	_, block := t.resolve(ctx)
+	// Pending tx
+	if block == nil {
+		return nil, nil
+	}
+	var diff struct {
+		Pre  map[common.Address]*accountState `json:"pre"`
+		Post map[common.Address]*accountState `json:"post"`
+	}
+	if err := t.r.trace(ctx, t.hash, "prestateTracer", `{"diffMode":true}`, &diff); err != nil {
+		return nil, err
+	}
+	addresses := make(map[common.Address]struct{})
+	for addr := range diff.Pre {
+		addresses[addr] = struct{}{}
+	}
+	for addr := range diff.Post {
+		addresses[addr] = struct{}{}
+	}
+	ret := make([]*AccountDiff, 0, len(addresses))
+	for addr := range addresses {
+		ret = append(ret, &AccountDiff{address: addr, pre: diff.Pre[addr], post: diff.Post[addr]})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return bytes.Compare(ret[i].address[:], ret[j].address[:]) < 0
+	})
+	return &ret, nil
+}
+
+func (b *Block) AccountsTouched(ctx context.Context) ([]*Account, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	backend, ok := b.r.backend.(tracers.Backend)
+	if !ok {
+		return nil, errTracingUnsupported
+	}
+	touched := make(map[common.Address]struct{})
+	if len(block.Transactions()) > 0 {
+		if !spendTraceBudget(ctx) {
+			return nil, errTooManyTraces
+		}
+		ctx, cancel := context.WithTimeout(ctx, blockTraceTimeout)
+		defer cancel()
+
+		tracer := "prestateTracer"
+		stream, err := tracers.NewAPI(backend).TraceBlockByHash(ctx, block.Hash(), &tracers.TraceConfig{Tracer: &tracer})
+		if err != nil {
+			return nil, err
+		}
+		results, err := stream.Collect()
+		if err != nil {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("tracing block %#x: %w", block.Hash(), ctx.Err())
+		}
+		for i, result := range results {
+			if result.Error != "" {
+				return nil, fmt.Errorf("failed to trace transaction %d: %s", i, result.Error)
+			}
+			var prestate map[common.Address]json.RawMessage
+			if err := json.Unmarshal(result.Result.(json.RawMessage), &prestate); err != nil {
+				return nil, err
+			}
+			for addr := range prestate {
+				touched[addr] = struct{}{}
+			}
+		}
+	}
+	for _, w := range block.Withdrawals() {
+		touched[w.Address] = struct{}{}
+	}
+	var (
+		ret          = make([]*Account, 0, len(touched))
+		numberOrHash = rpc.BlockNumberOrHashWithHash(block.Hash(), false)
+	)
+	for addr := range touched {
+		ret = append(ret, &Account{r: b.r, address: addr, blockNrOrHash: numberOrHash})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return bytes.Compare(ret[i].address[:], ret[j].address[:]) < 0
+	})
+	return ret, nil
+}
@@ -0,0 +1,412 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// The websocket server speaks both the graphql-transport-ws protocol and its
+// predecessor from the subscriptions-transport-ws library, negotiated through
+// the websocket subprotocol. Clients not requesting either get the former.
+const (
+	wsProtocol       = "graphql-transport-ws"
+	wsLegacyProtocol = "graphql-ws"
+)
+
+const (
+	wsInitTimeout      = 10 * time.Second // Time allowed for the client to initialise the connection
+	wsWriteTimeout     = 10 * time.Second // Time allowed to write a message to the client
+	wsKeepAlive        = 15 * time.Second // Interval of the keep-alive messages of the legacy protocol
+	wsMessageSizeLimit = 1024 * 1024      // Maximum size of a client message
+	wsMaxOperations    = 100              // Maximum number of operations running at once on a connection
+)
+
+// Close codes of the graphql-transport-ws protocol.
+const (
+	wsCloseBadRequest      = 4400
+	wsCloseUnauthorized    = 4401
+	wsCloseInitTimeout     = 4408
+	wsCloseDuplicateID     = 4409
+	wsCloseTooManyInitReqs = 4429
+)
+
+var (
+	// errInvalidMessage is returned when reading a malformed client message.
+	errInvalidMessage = errors.New("invalid message")
+
+	// errTooManyOperations is returned if the client starts an operation while
+	// the maximum number of operations is running on the connection.
+	errTooManyOperations = errors.New("too many operations")
+
+	// errRequestTimeout is returned if an operation isn't executed within the
+	// execution timeout of the HTTP requests.
+	errRequestTimeout = errors.New("request timed out")
+)
+
+// wsMessage is a message of the graphql websocket protocols.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsOperation is the payload of a subscribe (or legacy start) message.
+type wsOperation struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// wsHandler serves GraphQL operations, subscriptions in particular, over
+// websocket connections.
+type wsHandler struct {
+	schema   *graphql.Schema
+	upgrader websocket.Upgrader
+}
+
+// newWSHandler creates a websocket handler, accepting connections from
+// the given origins.
+func newWSHandler(schema *graphql.Schema, origins []string) *wsHandler {
+	return &wsHandler{
+		schema: schema,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{wsProtocol, wsLegacyProtocol},
+			CheckOrigin:  wsOriginValidator(origins),
+		},
+	}
+}
+
+// wsOriginValidator returns a check for the origin of the websocket requests,
+// which accepts requests without an origin, as sent by non-browser clients.
+func wsOriginValidator(origins []string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, allowed := range origins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+		log.Warn("Rejected GraphQL WebSocket connection", "origin", origin)
+		return false
+	}
+}
+
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL WebSocket upgrade failed", "err", err)
+		return
+	}
+	conn.SetReadLimit(wsMessageSizeLimit)
+
+	// The operations are subject to the same execution timeout as the queries
+	// served over HTTP, derived from the server of the upgraded request.
+	timeout, _ := rpc.ContextRequestTimeout(r.Context())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &wsConn{
+		schema:  h.schema,
+		conn:    conn,
+		legacy:  conn.Subprotocol() == wsLegacyProtocol,
+		timeout: timeout,
+		ctx:     ctx,
+		cancel:  cancel,
+		ops:     make(map[string]context.CancelFunc),
+	}
+	c.run()
+}
+
+// wsConn is a websocket connection of a GraphQL client.
+type wsConn struct {
+	schema *graphql.Schema
+	conn   *websocket.Conn
+	legacy bool // Whether the connection speaks the legacy protocol
+
+	timeout time.Duration // Execution timeout of the operations, 0 if unlimited
+
+	ctx    context.Context // Context of the connection, cancelled when it is closed
+	cancel context.CancelFunc
+
+	ops     map[string]context.CancelFunc // Running operations by client assigned id
+	opsLock sync.Mutex
+	wg      sync.WaitGroup
+
+	writeLock sync.Mutex
+}
+
+// run serves the connection until it is closed by either side.
+func (c *wsConn) run() {
+	defer func() {
+		c.cancel()
+		c.conn.Close()
+		c.wg.Wait()
+	}()
+	// Wait for the client to initialise the connection
+	c.conn.SetReadDeadline(time.Now().Add(wsInitTimeout))
+	msg, err := c.read()
+	if err == errInvalidMessage {
+		c.close(wsCloseBadRequest, "Invalid message")
+		return
+	}
+	if err != nil {
+		c.close(wsCloseInitTimeout, "Connection initialisation timeout")
+		return
+	}
+	if msg.Type != "connection_init" {
+		c.close(wsCloseUnauthorized, "Unauthorized")
+		return
+	}
+	c.conn.SetReadDeadline(time.Time{})
+	if err := c.write(&wsMessage{Type: "connection_ack"}); err != nil {
+		return
+	}
+	if c.legacy {
+		c.wg.Add(1)
+		go c.keepAlive()
+	}
+	// Serve the client messages until the connection fails
+	for {
+		msg, err := c.read()
+		if err != nil {
+			if err == errInvalidMessage {
+				c.close(wsCloseBadRequest, "Invalid message")
+			}
+			return
+		}
+		switch msg.Type {
+		case "connection_init":
+			if !c.legacy {
+				c.close(wsCloseTooManyInitReqs, "Too many initialisation requests")
+				return
+			}
+		case "connection_terminate":
+			if c.legacy {
+				return
+			}
+		case "ping":
+			if !c.legacy {
+				c.write(&wsMessage{Type: "pong", Payload: msg.Payload})
+			}
+		case "pong":
+		case "subscribe", "start":
+			if !c.subscribe(msg) {
+				return
+			}
+		case "complete", "stop":
+			c.finish(msg.ID)
+		default:
+			if !c.legacy {
+				c.close(wsCloseBadRequest, fmt.Sprintf("Invalid message type %q", msg.Type))
+				return
+			}
+			c.writeError(msg.ID, fmt.Errorf("invalid message type %q", msg.Type))
+		}
+	}
+}
+
+// subscribe starts the operation requested by a client message, streaming its
+// results until it's done or stopped by the client. It returns false if the
+// request is fatal to the connection.
+func (c *wsConn) subscribe(msg *wsMessage) bool {
+	var op wsOperation
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &op) != nil {
+		if !c.legacy {
+			c.close(wsCloseBadRequest, "Invalid subscribe message")
+			return false
+		}
+		c.writeError(msg.ID, fmt.Errorf("invalid start message"))
+		return true
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+
+	c.opsLock.Lock()
+	if _, ok := c.ops[msg.ID]; ok {
+		c.opsLock.Unlock()
+		cancel()
+		if !c.legacy {
+			c.close(wsCloseDuplicateID, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+			return false
+		}
+		c.writeError(msg.ID, fmt.Errorf("operation %s already exists", msg.ID))
+		return true
+	}
+	if len(c.ops) >= wsMaxOperations {
+		c.opsLock.Unlock()
+		cancel()
+		c.writeError(msg.ID, errTooManyOperations)
+		return true
+	}
+	c.ops[msg.ID] = cancel
+	c.opsLock.Unlock()
+
+	// Queries and mutations are executed before Subscribe returns, so they are
+	// aborted if not done within the timeout. Subscriptions only have their set
+	// up limited, the events are resolved within the per-event limit of the
+	// schema.
+	var (
+		timer    *time.Timer
+		timedOut atomic.Bool
+	)
+	if c.timeout > 0 {
+		timer = time.AfterFunc(c.timeout, func() {
+			timedOut.Store(true)
+			cancel()
+		})
+	}
+	results, err := c.schema.Subscribe(ctx, op.Query, op.OperationName, op.Variables)
+	if timer != nil {
+		timer.Stop()
+	}
+	if err != nil {
+		c.finish(msg.ID)
+		c.writeError(msg.ID, err)
+		return true
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		failed := false
+		for result := range results {
+			// Drain the results after a failure, the channel is only closed once
+			// the operation is cancelled.
+			if failed || ctx.Err() != nil {
+				continue
+			}
+			res := result.(*graphql.Response)
+
+			// Requests failing validation are rejected by the new protocol with a
+			// dedicated message, terminating the operation.
+			if !c.legacy && res.Data == nil && len(res.Errors) > 0 {
+				if c.finish(msg.ID) {
+					payload, _ := json.Marshal(res.Errors)
+					c.write(&wsMessage{ID: msg.ID, Type: "error", Payload: payload})
+				}
+				failed = true
+				continue
+			}
+			typ := "next"
+			if c.legacy {
+				typ = "data"
+			}
+			payload, _ := json.Marshal(res)
+			if err := c.write(&wsMessage{ID: msg.ID, Type: typ, Payload: payload}); err != nil {
+				failed = true
+				cancel()
+			}
+		}
+		if c.finish(msg.ID) && !failed {
+			if timedOut.Load() {
+				c.writeError(msg.ID, errRequestTimeout)
+			} else {
+				c.write(&wsMessage{ID: msg.ID, Type: "complete"})
+			}
+		}
+	}()
+	return true
+}
+
+// finish removes a running operation, reporting whether it was still running,
+// i.e. not stopped by the client.
+func (c *wsConn) finish(id string) bool {
+	c.opsLock.Lock()
+	defer c.opsLock.Unlock()
+
+	cancel, ok := c.ops[id]
+	if ok {
+		cancel()
+		delete(c.ops, id)
+	}
+	return ok
+}
+
+// keepAlive periodically sends keep-alive messages to legacy protocol clients.
+func (c *wsConn) keepAlive() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(wsKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.write(&wsMessage{Type: "ka"}); err != nil {
+				return
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// read reads the next client message.
+func (c *wsConn) read() (*wsMessage, error) {
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	msg := new(wsMessage)
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, errInvalidMessage
+	}
+	return msg, nil
+}
+
+// write sends a message to the client.
+func (c *wsConn) write(msg *wsMessage) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteJSON(msg)
+}
+
+// writeError sends an error of an operation to the client.
+func (c *wsConn) writeError(id string, err error) {
+	var payload []byte
+	if c.legacy {
+		payload, _ = json.Marshal(map[string]string{"message": err.Error()})
+	} else {
+		payload, _ = json.Marshal([]map[string]string{{"message": err.Error()}})
+	}
+	c.write(&wsMessage{ID: id, Type: "error", Payload: payload})
+}
+
+// close closes the connection with the given protocol close code.
+func (c *wsConn) close(code int, reason string) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteTimeout))
+}
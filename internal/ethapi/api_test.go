@@ -47,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -606,6 +607,12 @@ func (b testBackend) TxPoolContentFrom(addr common.Address) ([]*types.Transactio
 func (b testBackend) SubscribeNewTxsEvent(events chan<- core.NewTxsEvent) event.Subscription {
 	panic("implement me")
 }
+func (b testBackend) SubscribeDropTxsEvent(events chan<- txpool.DropTxsEvent) event.Subscription {
+	panic("implement me")
+}
+func (b testBackend) TxPoolReplacements(from *common.Address) []*txpool.Replacement {
+	panic("implement me")
+}
 func (b testBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
 func (b testBackend) Engine() consensus.Engine         { return b.chain.Engine() }
 func (b testBackend) GetLogs(ctx context.Context, blockHash common.Hash, number uint64) ([][]*types.Log, error) {
@@ -975,6 +982,122 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestSimulateV1(t *testing.T) {
+	t.Parallel()
+	// Initialize test accounts
+	var (
+		accounts = newAccounts(3)
+		genesis  = &core.Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: types.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		genBlocks = 10
+		signer    = types.HomesteadSigner{}
+	)
+	api := NewBlockChainAPI(newTestBackend(t, genBlocks, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: uint64(i), To: &accounts[1].addr, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: b.BaseFee(), Data: nil}), signer, accounts[0].key)
+		b.AddTx(tx)
+		b.SetPoS()
+	}))
+	var (
+		latest = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		value  = (*hexutil.Big)(big.NewInt(1000))
+		nonce  = hexutil.Uint64(0)
+		gas    = hexutil.Uint64(params.TxGas)
+		number = (*hexutil.Big)(big.NewInt(int64(genBlocks + 3)))
+	)
+	var testSuite = []struct {
+		name      string
+		opts      simOpts
+		expectErr error
+		check     func(t *testing.T, results []map[string]interface{})
+	}{
+		{
+			name:      "empty input",
+			opts:      simOpts{},
+			expectErr: &invalidParamsError{message: "empty input"},
+		},
+		{
+			name:      "too many blocks",
+			opts:      simOpts{BlockStateCalls: make([]simBlock, maxSimulateBlocks+1)},
+			expectErr: &clientLimitExceededError{message: "too many blocks"},
+		},
+		{
+			name: "block numbers out of order",
+			opts: simOpts{BlockStateCalls: []simBlock{
+				{BlockOverrides: &BlockOverrides{Number: (*hexutil.Big)(big.NewInt(int64(genBlocks)))}},
+			}},
+			expectErr: &invalidBlockNumberError{message: fmt.Sprintf("block numbers must be in order: %d <= %d", genBlocks, genBlocks)},
+		},
+		{
+			name: "chained transfers with gap filling",
+			opts: simOpts{
+				TraceTransfers: true,
+				BlockStateCalls: []simBlock{
+					{Calls: []TransactionArgs{{From: &accounts[1].addr, To: &accounts[2].addr, Value: value}}},
+					{
+						BlockOverrides: &BlockOverrides{Number: number},
+						Calls:          []TransactionArgs{{From: &accounts[2].addr, To: &accounts[0].addr, Value: value}},
+					},
+				},
+			},
+			check: func(t *testing.T, results []map[string]interface{}) {
+				if len(results) != 3 {
+					t.Fatalf("unexpected number of blocks, want 3, have %d", len(results))
+				}
+				for i, res := range results {
+					if have, want := res["number"].(*hexutil.Big).ToInt().Int64(), int64(genBlocks+1+i); have != want {
+						t.Errorf("block %d: number mismatch, want %d, have %d", i, want, have)
+					}
+					if i > 0 && res["parentHash"].(common.Hash) != results[i-1]["hash"].(common.Hash) {
+						t.Errorf("block %d: parent hash mismatch", i)
+					}
+				}
+				calls := results[2]["calls"].([]simCallResult)
+				if len(calls) != 1 || calls[0].Status != hexutil.Uint64(types.ReceiptStatusSuccessful) {
+					t.Fatalf("unexpected call results: %v", calls)
+				}
+				if len(calls[0].Logs) != 1 || calls[0].Logs[0].Address != transferAddress {
+					t.Fatalf("missing transfer log: %v", calls[0].Logs)
+				}
+				if calls[0].Logs[0].BlockHash != results[2]["hash"].(common.Hash) {
+					t.Errorf("log block hash not repaired")
+				}
+			},
+		},
+		{
+			name: "nonce checked in validation mode",
+			opts: simOpts{
+				Validation: true,
+				BlockStateCalls: []simBlock{
+					{Calls: []TransactionArgs{{From: &accounts[0].addr, To: &accounts[1].addr, Value: value, Nonce: &nonce, Gas: &gas}}},
+				},
+			},
+			expectErr: &invalidTxError{Message: fmt.Sprintf("err: nonce too low: address %v, tx: 0 state: %d (supplied gas %d)", accounts[0].addr, genBlocks, params.TxGas), Code: errCodeNonceTooLow},
+		},
+	}
+	for _, tc := range testSuite {
+		results, err := api.SimulateV1(context.Background(), tc.opts, &latest)
+		if tc.expectErr != nil {
+			if err == nil {
+				t.Errorf("test %s: want error %v, have nothing", tc.name, tc.expectErr)
+				continue
+			}
+			if !reflect.DeepEqual(err, tc.expectErr) {
+				t.Errorf("test %s: error mismatch, want %v, have %v", tc.name, tc.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %s: want no error, have %v", tc.name, err)
+			continue
+		}
+		tc.check(t, results)
+	}
+}
+
 func TestSignTransaction(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
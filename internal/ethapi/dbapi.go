@@ -17,10 +17,27 @@
 package ethapi
 
 import (
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
+const (
+	// dbGetManyLimit is the maximum number of keys retrieved by a single
+	// debug_dbGetMany call.
+	dbGetManyLimit = 1024
+
+	// dbRangeLimit is the maximum number of entries returned by a single
+	// debug_dbRange call.
+	dbRangeLimit = 10000
+
+	// dbRangeMaxBytes is the soft size limit of the entries returned by a single
+	// debug_dbRange or debug_dbAncientRange call. At least one entry is returned
+	// regardless.
+	dbRangeMaxBytes = 4 * 1024 * 1024
+)
+
 // DbGet returns the raw value of a key stored in the database.
 func (api *DebugAPI) DbGet(key string) (hexutil.Bytes, error) {
 	blob, err := common.ParseHexOrString(key)
@@ -41,3 +58,95 @@ func (api *DebugAPI) DbAncient(kind string, number uint64) (hexutil.Bytes, error
 func (api *DebugAPI) DbAncients() (uint64, error) {
 	return api.b.ChainDb().Ancients()
 }
+
+// DbGetMany returns the raw values of multiple keys stored in the database.
+// Missing keys are reported as null.
+func (api *DebugAPI) DbGetMany(keys []hexutil.Bytes) ([]*hexutil.Bytes, error) {
+	if len(keys) > dbGetManyLimit {
+		return nil, fmt.Errorf("too many keys requested: %d > %d", len(keys), dbGetManyLimit)
+	}
+	values := make([]*hexutil.Bytes, len(keys))
+	for i, key := range keys {
+		if value, err := api.b.ChainDb().Get(key); err == nil {
+			values[i] = (*hexutil.Bytes)(&value)
+		}
+	}
+	return values, nil
+}
+
+// DbRangeResult is a page of database entries returned by debug_dbRange.
+type DbRangeResult struct {
+	Keys   []hexutil.Bytes `json:"keys"`
+	Values []hexutil.Bytes `json:"values"`
+	Next   *hexutil.Bytes  `json:"next"` // Start of the next page, nil if done
+}
+
+// DbRange iterates the database entries with the given key prefix, starting at
+// the given key (the prefix excluded). At most count entries are returned, less
+// if their size exceeds a few megabytes. The iteration can be continued from the
+// returned next key.
+func (api *DebugAPI) DbRange(prefix hexutil.Bytes, start hexutil.Bytes, count int) (*DbRangeResult, error) {
+	if count <= 0 || count > dbRangeLimit {
+		return nil, fmt.Errorf("invalid entry count %d, must be between 1 and %d", count, dbRangeLimit)
+	}
+	it := api.b.ChainDb().NewIterator(prefix, start)
+	defer it.Release()
+
+	var (
+		result = &DbRangeResult{Keys: []hexutil.Bytes{}, Values: []hexutil.Bytes{}}
+		size   int
+	)
+	for it.Next() {
+		if len(result.Keys) >= count || size >= dbRangeMaxBytes {
+			next := hexutil.Bytes(common.CopyBytes(it.Key()[len(prefix):]))
+			result.Next = &next
+			break
+		}
+		result.Keys = append(result.Keys, common.CopyBytes(it.Key()))
+		result.Values = append(result.Values, common.CopyBytes(it.Value()))
+		size += len(it.Key()) + len(it.Value())
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DbStat returns a particular internal stat of the database.
+// It is a mapping to the `KeyValueStater.Stat` method
+func (api *DebugAPI) DbStat(property string) (string, error) {
+	return api.b.ChainDb().Stat(property)
+}
+
+// DbAncientRange retrieves multiple ancient binary blobs in sequence.
+// It is a mapping to the `AncientReaderOp.AncientRange` method. The size of
+// the returned items is limited to a few megabytes, even if maxBytes is zero.
+func (api *DebugAPI) DbAncientRange(kind string, start, count, maxBytes uint64) ([]hexutil.Bytes, error) {
+	if count > dbRangeLimit {
+		return nil, fmt.Errorf("too many items requested: %d > %d", count, dbRangeLimit)
+	}
+	if maxBytes == 0 || maxBytes > dbRangeMaxBytes {
+		maxBytes = dbRangeMaxBytes
+	}
+	blobs, err := api.b.ChainDb().AncientRange(kind, start, count, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]hexutil.Bytes, len(blobs))
+	for i, blob := range blobs {
+		items[i] = blob
+	}
+	return items, nil
+}
+
+// DbAncientTail returns the number of the first stored item in the ancient store.
+// It is a mapping to the `AncientReaderOp.Tail` method
+func (api *DebugAPI) DbAncientTail() (uint64, error) {
+	return api.b.ChainDb().Tail()
+}
+
+// DbAncientSize returns the ancient size of the specified category.
+// It is a mapping to the `AncientReaderOp.AncientSize` method
+func (api *DebugAPI) DbAncientSize(kind string) (uint64, error) {
+	return api.b.ChainDb().AncientSize(kind)
+}
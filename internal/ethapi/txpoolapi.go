@@ -0,0 +1,272 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// defaultTxPoolPageSize is the number of transactions returned by
+	// txpool_contentPaged if no limit is requested.
+	defaultTxPoolPageSize = 100
+
+	// maxTxPoolPageSize is the maximum number of transactions returned by a
+	// single txpool_contentPaged request.
+	maxTxPoolPageSize = 1000
+)
+
+// TxPoolContentQuery is the filter and the pagination of txpool_contentPaged.
+type TxPoolContentQuery struct {
+	From     *common.Address `json:"from"`     // Sender of the transactions
+	To       *common.Address `json:"to"`       // Recipient of the transactions
+	MinTip   *hexutil.Big    `json:"minTip"`   // Minimum effective tip at the current base fee
+	Type     *hexutil.Uint64 `json:"type"`     // Transaction type
+	HasBlobs *bool           `json:"hasBlobs"` // Whether the transactions carry blobs
+	Status   string          `json:"status"`   // Either "pending" or "queued", both if empty
+	Offset   hexutil.Uint    `json:"offset"`   // Number of matching transactions to skip
+	Limit    hexutil.Uint    `json:"limit"`    // Maximum number of transactions to return
+}
+
+// TxPoolContentItem is a transaction of the pool along with its status.
+type TxPoolContentItem struct {
+	Status string `json:"status"`
+	*RPCTransaction
+}
+
+// TxPoolContentPage is a page of the transactions matching a content query.
+type TxPoolContentPage struct {
+	Transactions []*TxPoolContentItem `json:"transactions"`
+	Total        hexutil.Uint         `json:"total"` // Number of all the matching transactions
+	Next         *hexutil.Uint        `json:"next"`  // Offset of the next page, nil if this is the last
+}
+
+// ContentPaged returns the transactions of the pool matching the query, one page
+// at a time. The transactions are ordered by status (pending first), sender and
+// nonce, so the pages are consistent as long as the pool doesn't change.
+func (s *TxPoolAPI) ContentPaged(query TxPoolContentQuery) (*TxPoolContentPage, error) {
+	switch query.Status {
+	case "", "pending", "queued":
+	default:
+		return nil, fmt.Errorf("invalid status %q", query.Status)
+	}
+	limit := int(query.Limit)
+	if limit == 0 {
+		limit = defaultTxPoolPageSize
+	}
+	if limit > maxTxPoolPageSize {
+		return nil, fmt.Errorf("page limit %d exceeds maximum %d", limit, maxTxPoolPageSize)
+	}
+	var (
+		pending, queue map[common.Address][]*types.Transaction
+		curHeader      = s.b.CurrentHeader()
+	)
+	if query.From != nil {
+		p, q := s.b.TxPoolContentFrom(*query.From)
+		pending = map[common.Address][]*types.Transaction{*query.From: p}
+		queue = map[common.Address][]*types.Transaction{*query.From: q}
+	} else {
+		pending, queue = s.b.TxPoolContent()
+	}
+	var (
+		page  = &TxPoolContentPage{Transactions: make([]*TxPoolContentItem, 0)}
+		total int
+	)
+	collect := func(status string, content map[common.Address][]*types.Transaction) {
+		if query.Status != "" && query.Status != status {
+			return
+		}
+		senders := make([]common.Address, 0, len(content))
+		for addr := range content {
+			senders = append(senders, addr)
+		}
+		slices.SortFunc(senders, func(a, b common.Address) int { return a.Cmp(b) })
+
+		for _, addr := range senders {
+			for _, tx := range content[addr] {
+				if !query.matches(tx, curHeader.BaseFee) {
+					continue
+				}
+				if total >= int(query.Offset) && len(page.Transactions) < limit {
+					page.Transactions = append(page.Transactions, &TxPoolContentItem{
+						Status:         status,
+						RPCTransaction: NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig()),
+					})
+				}
+				total++
+			}
+		}
+	}
+	collect("pending", pending)
+	collect("queued", queue)
+
+	page.Total = hexutil.Uint(total)
+	if next := int(query.Offset) + len(page.Transactions); next < total {
+		page.Next = new(hexutil.Uint)
+		*page.Next = hexutil.Uint(next)
+	}
+	return page, nil
+}
+
+// matches returns whether the transaction passes the filters of the query.
+func (query *TxPoolContentQuery) matches(tx *types.Transaction, baseFee *big.Int) bool {
+	if query.To != nil && (tx.To() == nil || *tx.To() != *query.To) {
+		return false
+	}
+	if query.Type != nil && uint64(tx.Type()) != uint64(*query.Type) {
+		return false
+	}
+	if query.HasBlobs != nil && (len(tx.BlobHashes()) > 0) != *query.HasBlobs {
+		return false
+	}
+	if query.MinTip != nil {
+		tip, err := tx.EffectiveGasTip(baseFee)
+		if err != nil || tip.Cmp(query.MinTip.ToInt()) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// RPCTxReplacement is a transaction replacement recorded by the pool.
+type RPCTxReplacement struct {
+	From         common.Address `json:"from"`
+	Nonce        hexutil.Uint64 `json:"nonce"`
+	Replaced     common.Hash    `json:"replaced"`
+	Replacement  common.Hash    `json:"replacement"`
+	OldGasFeeCap *hexutil.Big   `json:"oldMaxFeePerGas"`
+	OldGasTipCap *hexutil.Big   `json:"oldMaxPriorityFeePerGas"`
+	NewGasFeeCap *hexutil.Big   `json:"newMaxFeePerGas"`
+	NewGasTipCap *hexutil.Big   `json:"newMaxPriorityFeePerGas"`
+	Timestamp    hexutil.Uint64 `json:"timestamp"`
+}
+
+// Replacements returns the most recent transaction replacements, oldest first,
+// optionally filtered by the sender account.
+func (s *TxPoolAPI) Replacements(from *common.Address) []*RPCTxReplacement {
+	replacements := s.b.TxPoolReplacements(from)
+
+	result := make([]*RPCTxReplacement, len(replacements))
+	for i, r := range replacements {
+		result[i] = &RPCTxReplacement{
+			From:         r.From,
+			Nonce:        hexutil.Uint64(r.Old.Nonce()),
+			Replaced:     r.Old.Hash(),
+			Replacement:  r.New.Hash(),
+			OldGasFeeCap: (*hexutil.Big)(r.Old.GasFeeCap()),
+			OldGasTipCap: (*hexutil.Big)(r.Old.GasTipCap()),
+			NewGasFeeCap: (*hexutil.Big)(r.New.GasFeeCap()),
+			NewGasTipCap: (*hexutil.Big)(r.New.GasTipCap()),
+			Timestamp:    hexutil.Uint64(r.Time.Unix()),
+		}
+	}
+	return result
+}
+
+// TxPoolEventFilter limits the events delivered by the txpool_subscribe
+// events subscription.
+type TxPoolEventFilter struct {
+	Senders []common.Address `json:"senders"` // Senders of the transactions, all if empty
+}
+
+// RPCTxPoolEvent is a notification of a transaction entering or leaving the pool.
+type RPCTxPoolEvent struct {
+	Type        string          `json:"type"` // Either "added", "dropped" or "replaced"
+	Hash        common.Hash     `json:"hash"`
+	From        common.Address  `json:"from"`
+	Reason      string          `json:"reason,omitempty"`      // Reason of the eviction, if dropped or replaced
+	ReplacedBy  *common.Hash    `json:"replacedBy,omitempty"`  // Hash of the replacement, if replaced
+	Transaction *RPCTransaction `json:"transaction,omitempty"` // Omitted if not retained in memory by the pool
+}
+
+// Events creates a subscription which is notified of the transactions becoming
+// executable in the pool, and of the ones leaving the pool without inclusion,
+// along with the reason of their eviction.
+func (s *TxPoolAPI) Events(ctx context.Context, filter *TxPoolEventFilter) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	var senders map[common.Address]struct{}
+	if filter != nil && len(filter.Senders) > 0 {
+		senders = make(map[common.Address]struct{}, len(filter.Senders))
+		for _, addr := range filter.Senders {
+			senders[addr] = struct{}{}
+		}
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		var (
+			added   = make(chan core.NewTxsEvent, 128)
+			dropped = make(chan txpool.DropTxsEvent, 128)
+			addSub  = s.b.SubscribeNewTxsEvent(added)
+			dropSub = s.b.SubscribeDropTxsEvent(dropped)
+			config  = s.b.ChainConfig()
+		)
+		defer addSub.Unsubscribe()
+		defer dropSub.Unsubscribe()
+
+		notify := func(ev *RPCTxPoolEvent, tx *types.Transaction) {
+			if senders != nil {
+				if _, ok := senders[ev.From]; !ok {
+					return
+				}
+			}
+			if tx != nil {
+				ev.Transaction = NewRPCPendingTransaction(tx, s.b.CurrentHeader(), config)
+			}
+			notifier.Notify(rpcSub.ID, ev)
+		}
+		for {
+			select {
+			case ev := <-added:
+				signer := types.LatestSigner(config)
+				for _, tx := range ev.Txs {
+					from, _ := types.Sender(signer, tx)
+					notify(&RPCTxPoolEvent{Type: "added", Hash: tx.Hash(), From: from}, tx)
+				}
+			case ev := <-dropped:
+				for _, drop := range ev.Drops {
+					event := &RPCTxPoolEvent{Type: "dropped", Hash: drop.Hash, From: drop.From, Reason: drop.Reason.String()}
+					if drop.Reason == txpool.DropReplaced && drop.Replacement != nil {
+						hash := drop.Replacement.Hash()
+						event.Type, event.ReplacedBy = "replaced", &hash
+					}
+					notify(event, drop.Tx)
+				}
+			case <-rpcSub.Err():
+				return
+			case <-addSub.Err():
+				return
+			case <-dropSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -0,0 +1,279 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// txPoolBackend serves a fixed transaction pool content and forwards the pool
+// events fed by the tests.
+type txPoolBackend struct {
+	Backend
+	pending, queued map[common.Address][]*types.Transaction
+	replacements    []*txpool.Replacement
+	txFeed          event.Feed
+	dropFeed        event.Feed
+}
+
+func (b *txPoolBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+
+func (b *txPoolBackend) CurrentHeader() *types.Header {
+	return &types.Header{Number: big.NewInt(1), GasLimit: 30_000_000, GasUsed: 15_000_000, BaseFee: big.NewInt(params.GWei)}
+}
+
+func (b *txPoolBackend) TxPoolContent() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
+	return b.pending, b.queued
+}
+
+func (b *txPoolBackend) TxPoolContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction) {
+	return b.pending[addr], b.queued[addr]
+}
+
+func (b *txPoolBackend) TxPoolReplacements(from *common.Address) []*txpool.Replacement {
+	return b.replacements
+}
+
+func (b *txPoolBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.txFeed.Subscribe(ch)
+}
+
+func (b *txPoolBackend) SubscribeDropTxsEvent(ch chan<- txpool.DropTxsEvent) event.Subscription {
+	return b.dropFeed.Subscribe(ch)
+}
+
+// newPoolTx creates a signed dynamic fee transaction paying the given tip.
+func newPoolTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, tip int64) *types.Transaction {
+	t.Helper()
+
+	to := common.Address{0xaa}
+	tx, err := types.SignNewTx(key, types.LatestSigner(params.TestChainConfig), &types.DynamicFeeTx{
+		ChainID:   params.TestChainConfig.ChainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(tip),
+		GasFeeCap: big.NewInt(10 * params.GWei),
+		Gas:       params.TxGas,
+		To:        &to,
+	})
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+// Tests that the pool content is paged in a stable order, and that the page
+// cursors and bounds are respected.
+func TestTxPoolContentPaged(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+		backend = &txPoolBackend{
+			pending: map[common.Address][]*types.Transaction{
+				addr1: {newPoolTx(t, key1, 0, params.GWei), newPoolTx(t, key1, 1, params.GWei), newPoolTx(t, key1, 2, 2*params.GWei)},
+				addr2: {newPoolTx(t, key2, 0, 2*params.GWei), newPoolTx(t, key2, 1, params.GWei), newPoolTx(t, key2, 2, params.GWei)},
+			},
+			queued: map[common.Address][]*types.Transaction{
+				addr1: {newPoolTx(t, key1, 5, params.GWei), newPoolTx(t, key1, 6, 2*params.GWei)},
+			},
+		}
+		api = NewTxPoolAPI(backend)
+	)
+	// Assemble the expected order: pending before queued, by sender and nonce
+	senders := []common.Address{addr1, addr2}
+	slices.SortFunc(senders, func(a, b common.Address) int { return a.Cmp(b) })
+
+	var want []common.Hash
+	for _, addr := range senders {
+		for _, tx := range backend.pending[addr] {
+			want = append(want, tx.Hash())
+		}
+	}
+	for _, tx := range backend.queued[addr1] {
+		want = append(want, tx.Hash())
+	}
+	// Walk the pages following the cursors
+	var (
+		have   []common.Hash
+		offset = hexutil.Uint(0)
+		pages  int
+	)
+	for {
+		page, err := api.ContentPaged(TxPoolContentQuery{Offset: offset, Limit: 3})
+		if err != nil {
+			t.Fatalf("failed to retrieve page at %d: %v", offset, err)
+		}
+		if int(page.Total) != len(want) {
+			t.Fatalf("total mismatch: have %d, want %d", page.Total, len(want))
+		}
+		for _, item := range page.Transactions {
+			have = append(have, item.Hash)
+		}
+		if pages++; page.Next == nil {
+			break
+		}
+		if *page.Next != offset+3 {
+			t.Fatalf("cursor mismatch: have %d, want %d", *page.Next, offset+3)
+		}
+		offset = *page.Next
+	}
+	if pages != 3 || !slices.Equal(have, want) {
+		t.Fatalf("paged content mismatch: %d pages, have %x, want %x", pages, have, want)
+	}
+	// Pages beyond the content are empty, limits above the maximum rejected
+	if page, err := api.ContentPaged(TxPoolContentQuery{Offset: 100}); err != nil || len(page.Transactions) != 0 || page.Next != nil || int(page.Total) != len(want) {
+		t.Fatalf("unexpected page beyond content: %+v, %v", page, err)
+	}
+	if _, err := api.ContentPaged(TxPoolContentQuery{Limit: maxTxPoolPageSize + 1}); err == nil {
+		t.Fatal("page limit above maximum accepted")
+	}
+	if _, err := api.ContentPaged(TxPoolContentQuery{Status: "mined"}); err == nil {
+		t.Fatal("invalid status accepted")
+	}
+	// Filters are applied before paging
+	minTip := (*hexutil.Big)(big.NewInt(2 * params.GWei))
+	page, err := api.ContentPaged(TxPoolContentQuery{From: &addr1, MinTip: minTip, Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to retrieve filtered page: %v", err)
+	}
+	if page.Total != 2 || len(page.Transactions) != 1 || page.Transactions[0].Status != "pending" || page.Next == nil || *page.Next != 1 {
+		t.Fatalf("unexpected filtered page: %+v", page)
+	}
+	if page, err = api.ContentPaged(TxPoolContentQuery{From: &addr1, MinTip: minTip, Offset: *page.Next}); err != nil {
+		t.Fatalf("failed to retrieve filtered page: %v", err)
+	}
+	if len(page.Transactions) != 1 || page.Transactions[0].Status != "queued" || page.Transactions[0].Hash != backend.queued[addr1][1].Hash() || page.Next != nil {
+		t.Fatalf("unexpected last filtered page: %+v", page)
+	}
+	if page, err = api.ContentPaged(TxPoolContentQuery{Status: "queued"}); err != nil || page.Total != 2 {
+		t.Fatalf("unexpected queued page: %+v, %v", page, err)
+	}
+}
+
+// Tests that the recorded replacements are reported with the fees of both the
+// replaced and the replacement transactions.
+func TestTxPoolReplacements(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		old     = newPoolTx(t, key, 3, params.GWei)
+		repl    = newPoolTx(t, key, 3, 2*params.GWei)
+		backend = &txPoolBackend{replacements: []*txpool.Replacement{{From: addr, Old: old, New: repl, Time: time.Unix(1000, 0)}}}
+	)
+	replacements := NewTxPoolAPI(backend).Replacements(nil)
+	if len(replacements) != 1 {
+		t.Fatalf("replacement count mismatch: have %d, want 1", len(replacements))
+	}
+	r := replacements[0]
+	if r.From != addr || r.Nonce != 3 || r.Replaced != old.Hash() || r.Replacement != repl.Hash() || r.Timestamp != 1000 {
+		t.Fatalf("replacement mismatch: %+v", r)
+	}
+	if r.OldGasTipCap.ToInt().Int64() != params.GWei || r.NewGasTipCap.ToInt().Int64() != 2*params.GWei {
+		t.Fatalf("replacement tips mismatch: have %v -> %v", r.OldGasTipCap, r.NewGasTipCap)
+	}
+}
+
+// Tests that the pool event subscription reports additions, replacements and
+// drops, filtered by sender.
+func TestTxPoolEvents(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+		backend = new(txPoolBackend)
+		server  = rpc.NewServer()
+	)
+	if err := server.RegisterName("txpool", NewTxPoolAPI(backend)); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	defer server.Stop()
+
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	events := make(chan *RPCTxPoolEvent, 8)
+	sub, err := client.Subscribe(context.Background(), "txpool", events, "events", &TxPoolEventFilter{Senders: []common.Address{addr1}})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Wait for the subscription to attach to the feeds before firing events
+	for backend.txFeed.Send(core.NewTxsEvent{}) == 0 || backend.dropFeed.Send(txpool.DropTxsEvent{}) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	var (
+		added    = newPoolTx(t, key1, 0, params.GWei)
+		filtered = newPoolTx(t, key2, 0, params.GWei)
+		replaced = newPoolTx(t, key1, 0, 2*params.GWei)
+	)
+	next := func() *RPCTxPoolEvent {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(time.Second):
+			t.Fatal("event timeout")
+		}
+		return nil
+	}
+	backend.txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{filtered, added}})
+	if ev := next(); ev.Type != "added" || ev.Hash != added.Hash() || ev.From != addr1 || ev.Transaction == nil {
+		t.Fatalf("unexpected added event: %+v", ev)
+	}
+	backend.dropFeed.Send(txpool.DropTxsEvent{Drops: []*txpool.TxDrop{
+		{Hash: filtered.Hash(), Tx: filtered, From: addr2, Reason: txpool.DropUnderpriced},
+		{Hash: added.Hash(), Tx: added, From: addr1, Reason: txpool.DropReplaced, Replacement: replaced},
+		{Hash: replaced.Hash(), From: addr1, Reason: txpool.DropOverflow},
+	}})
+	if ev := next(); ev.Type != "replaced" || ev.Hash != added.Hash() || ev.Reason != "replaced" || ev.ReplacedBy == nil || *ev.ReplacedBy != replaced.Hash() {
+		t.Fatalf("unexpected replaced event: %+v", ev)
+	}
+	if ev := next(); ev.Type != "dropped" || ev.Hash != replaced.Hash() || ev.Reason != "overflow" || ev.Transaction != nil {
+		t.Fatalf("unexpected dropped event: %+v", ev)
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event: %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,407 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package builder implements the block builder side of the builder API, which
+// builds blocks for the upcoming proposals announced by a beacon node and bids
+// them to a relay.
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/donovanhide/eventsource"
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	bls "github.com/protolambda/bls12-381-util"
+)
+
+var (
+	submitMeter     = metrics.NewRegisteredMeter("miner/builder/submit", nil)
+	submitFailMeter = metrics.NewRegisteredMeter("miner/builder/submit/fail", nil)
+)
+
+// forkVersions are the genesis fork versions of the known beacon networks, used
+// in the signature domain of the bids.
+var forkVersions = map[common.Hash][4]byte{
+	params.MainnetGenesisHash: {0x00, 0x00, 0x00, 0x00},
+	params.SepoliaGenesisHash: {0x90, 0x00, 0x00, 0x69},
+	params.HoleskyGenesisHash: {0x01, 0x01, 0x70, 0x00},
+}
+
+// Config contains the settings of the block builder.
+type Config struct {
+	BeaconEndpoint     string        // Beacon node API to receive the upcoming proposals from
+	RelayEndpoint      string        // Relay to submit the built blocks to (builder disabled if empty)
+	SecretKeyFile      string        // File containing the hex encoded BLS secret key signing the bids
+	GenesisForkVersion string        `toml:",omitempty"` // Genesis fork version of the beacon network, derived for known networks
+	SubmitInterval     time.Duration // Interval of submitting improved blocks to the relay
+	SubmitDeadline     time.Duration // Time into the slot after which no more blocks are submitted
+}
+
+// DefaultConfig contains the default settings of the block builder.
+var DefaultConfig = Config{
+	SubmitInterval: 500 * time.Millisecond,
+	SubmitDeadline: 4 * time.Second,
+}
+
+// Backend is the interface of the node the builder is attached to.
+type Backend interface {
+	BlockChain() *core.BlockChain
+	Miner() *miner.Miner
+}
+
+// Builder receives the payload attributes of the upcoming proposals from a
+// beacon node, builds continuously improving blocks for the slots with a
+// proposer registered with the relay, and bids them to the relay.
+type Builder struct {
+	config Config
+	chain  *core.BlockChain
+	miner  *miner.Miner
+	relay  *relayClient
+
+	secretKey *bls.SecretKey
+	publicKey [48]byte
+	domain    [32]byte
+
+	slot   uint64             // Slot of the last payload attributes handled
+	parent common.Hash        // Parent block of the last payload attributes handled
+	cancel context.CancelFunc // Stops the submissions of the current slot
+
+	ctx      context.Context
+	closeCtx context.CancelFunc
+	wg       sync.WaitGroup
+	lock     sync.Mutex
+}
+
+// Register creates a block builder and attaches it to the node.
+func Register(stack *node.Node, backend Backend, config Config) error {
+	b, err := New(backend.BlockChain(), backend.Miner(), config)
+	if err != nil {
+		return err
+	}
+	stack.RegisterLifecycle(b)
+	return nil
+}
+
+// New creates a block builder bidding the blocks built by the given miner.
+func New(chain *core.BlockChain, miner *miner.Miner, config Config) (*Builder, error) {
+	if config.RelayEndpoint == "" {
+		return nil, errors.New("no relay endpoint configured")
+	}
+	if config.SubmitInterval <= 0 {
+		config.SubmitInterval = DefaultConfig.SubmitInterval
+	}
+	if config.SubmitDeadline <= 0 {
+		config.SubmitDeadline = DefaultConfig.SubmitDeadline
+	}
+	secretKey, err := loadSecretKey(config.SecretKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := bls.SkToPk(secretKey)
+	if err != nil {
+		return nil, err
+	}
+	forkVersion, ok := forkVersions[chain.Genesis().Hash()]
+	if config.GenesisForkVersion != "" {
+		blob, err := hexutil.Decode(config.GenesisForkVersion)
+		if err != nil || len(blob) != len(forkVersion) {
+			return nil, fmt.Errorf("invalid genesis fork version %q", config.GenesisForkVersion)
+		}
+		copy(forkVersion[:], blob)
+	} else if !ok {
+		return nil, errors.New("genesis fork version of the network unknown")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Builder{
+		config:    config,
+		chain:     chain,
+		miner:     miner,
+		relay:     newRelayClient(config.RelayEndpoint),
+		secretKey: secretKey,
+		publicKey: publicKey.Serialize(),
+		domain:    builderDomain(forkVersion),
+		ctx:       ctx,
+		closeCtx:  cancel,
+	}, nil
+}
+
+// loadSecretKey reads the hex encoded BLS secret key from the given file.
+func loadSecretKey(path string) (*bls.SecretKey, error) {
+	if path == "" {
+		return nil, errors.New("no builder secret key configured")
+	}
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := hexutil.Decode(strings.TrimSpace(string(blob)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid builder secret key: %v", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("invalid builder secret key length: have %d, want 32", len(raw))
+	}
+	var (
+		key    = new(bls.SecretKey)
+		serial [32]byte
+	)
+	copy(serial[:], raw)
+	if err := key.Deserialize(&serial); err != nil {
+		return nil, fmt.Errorf("invalid builder secret key: %v", err)
+	}
+	return key, nil
+}
+
+// Start implements node.Lifecycle, starting to follow the upcoming proposals
+// of the beacon node.
+func (b *Builder) Start() error {
+	if b.config.BeaconEndpoint == "" {
+		log.Warn("Block builder started without beacon endpoint, no blocks will be built")
+		return nil
+	}
+	b.wg.Add(1)
+	go b.loop()
+
+	log.Info("Started block builder", "relay", b.config.RelayEndpoint, "pubkey", hexutil.Encode(b.publicKey[:]))
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the building and the submission
+// of the blocks.
+func (b *Builder) Stop() error {
+	b.closeCtx()
+	b.wg.Wait()
+	return nil
+}
+
+// loop subscribes to the payload attributes events of the beacon node, and
+// builds the blocks of the announced proposals until the builder is stopped.
+func (b *Builder) loop() {
+	defer b.wg.Done()
+
+	for {
+		stream := b.subscribe()
+		if stream == nil {
+			return
+		}
+	events:
+		for {
+			select {
+			case <-b.ctx.Done():
+				stream.Close()
+				return
+
+			case ev, ok := <-stream.Events:
+				if !ok {
+					break events
+				}
+				if ev.Event() != "payload_attributes" {
+					continue
+				}
+				attrs := new(payloadAttributesEvent)
+				if err := json.Unmarshal([]byte(ev.Data()), attrs); err != nil {
+					log.Warn("Invalid payload attributes event", "err", err)
+					continue
+				}
+				b.onPayloadAttributes(attrs)
+
+			case err, ok := <-stream.Errors:
+				if !ok {
+					break events
+				}
+				log.Debug("Beacon event stream error", "err", err)
+			}
+		}
+		stream.Close()
+	}
+}
+
+// subscribe establishes the payload attributes event stream of the beacon node,
+// retrying until successful. It only returns nil if the builder is stopped.
+func (b *Builder) subscribe() *eventsource.Stream {
+	for {
+		url := strings.TrimRight(b.config.BeaconEndpoint, "/") + "/eth/v1/events?topics=payload_attributes"
+		req, err := http.NewRequestWithContext(b.ctx, http.MethodGet, url, nil)
+		if err == nil {
+			var stream *eventsource.Stream
+			if stream, err = eventsource.SubscribeWithRequest("", req); err == nil {
+				return stream
+			}
+		}
+		log.Warn("Failed to subscribe to payload attributes", "err", err)
+
+		select {
+		case <-time.After(5 * time.Second):
+		case <-b.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// onPayloadAttributes starts building the block of an upcoming proposal, if its
+// proposer is registered with the relay, superseding the previous slot.
+func (b *Builder) onPayloadAttributes(ev *payloadAttributesEvent) {
+	var (
+		slot   = uint64(ev.Data.ProposalSlot)
+		parent = ev.Data.ParentBlockHash
+	)
+	// Beacon nodes may announce the same proposal multiple times, ignore those
+	b.lock.Lock()
+	if slot < b.slot || (slot == b.slot && parent == b.parent) {
+		b.lock.Unlock()
+		return
+	}
+	if b.cancel != nil {
+		b.cancel()
+		b.cancel = nil
+	}
+	b.slot, b.parent = slot, parent
+	b.lock.Unlock()
+
+	header := b.chain.GetHeaderByHash(parent)
+	if header == nil {
+		log.Debug("Skipping proposal with unknown parent", "slot", slot, "parent", parent)
+		return
+	}
+	// Retrieve the registration without holding the lock, the relay may be slow
+	reg, err := b.relay.validator(b.ctx, slot)
+	if err != nil {
+		log.Warn("Failed to retrieve proposer registrations", "slot", slot, "err", err)
+		return
+	}
+	if reg == nil {
+		log.Debug("Skipping proposal of unregistered proposer", "slot", slot)
+		return
+	}
+	if reg.Entry.Message.GasLimit == 0 {
+		log.Debug("Skipping proposal of proposer without gas limit", "slot", slot)
+		return
+	}
+	// Build towards the gas limit preferred by the proposer, relays reject bids
+	// not following the registration
+	attrs := ev.Data.PayloadAttributes
+	args := &miner.BuildPayloadArgs{
+		Parent:       parent,
+		Timestamp:    uint64(attrs.Timestamp),
+		FeeRecipient: reg.Entry.Message.FeeRecipient,
+		Random:       attrs.PrevRandao,
+		Withdrawals:  ev.withdrawals(),
+		BeaconRoot:   attrs.ParentBeaconBlockRoot,
+		GasLimit:     uint64(reg.Entry.Message.GasLimit),
+		Version:      engine.PayloadV2,
+	}
+	if args.BeaconRoot != nil {
+		args.Version = engine.PayloadV3
+	}
+	gasLimit := core.CalcGasLimit(header.GasLimit, args.GasLimit)
+
+	payload, err := b.miner.BuildPayload(args)
+	if err != nil {
+		log.Warn("Failed to start building block", "slot", slot, "err", err)
+		return
+	}
+	deadline := time.Unix(int64(args.Timestamp), 0).Add(b.config.SubmitDeadline)
+	ctx, cancel := context.WithDeadline(b.ctx, deadline)
+
+	// Only start submitting if the proposal wasn't superseded in the meantime
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.slot != slot || b.parent != parent || b.cancel != nil {
+		cancel()
+		payload.Resolve() // terminate the building
+		return
+	}
+	b.cancel = cancel
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer cancel()
+		b.submitLoop(ctx, slot, reg, gasLimit, payload)
+	}()
+}
+
+// submitLoop periodically submits the best block built for the slot to the
+// relay, if it improved since the last submission, until the context expires.
+func (b *Builder) submitLoop(ctx context.Context, slot uint64, reg *validatorRegistration, gasLimit uint64, payload *miner.Payload) {
+	defer payload.Resolve() // terminate the building
+
+	ticker := time.NewTicker(b.config.SubmitInterval)
+	defer ticker.Stop()
+
+	best := new(big.Int)
+	for {
+		if envelope := payload.Peek(); envelope != nil && envelope.BlockValue.Cmp(best) > 0 {
+			if err := b.submit(ctx, slot, reg, gasLimit, envelope); err != nil {
+				submitFailMeter.Mark(1)
+				log.Warn("Failed to submit block", "slot", slot, "hash", envelope.ExecutionPayload.BlockHash, "err", err)
+			} else {
+				submitMeter.Mark(1)
+				best = envelope.BlockValue
+				log.Info("Submitted block to relay", "slot", slot, "number", envelope.ExecutionPayload.Number,
+					"hash", envelope.ExecutionPayload.BlockHash, "txs", len(envelope.ExecutionPayload.Transactions), "value", envelope.BlockValue)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// submit signs a bid for the built block and submits it to the relay. The gas
+// limit is the one the registration of the proposer requires for the block.
+func (b *Builder) submit(ctx context.Context, slot uint64, reg *validatorRegistration, gasLimit uint64, envelope *engine.ExecutionPayloadEnvelope) error {
+	data := envelope.ExecutionPayload
+	if data.GasLimit != gasLimit {
+		return fmt.Errorf("gas limit mismatch: have %d, want %d", data.GasLimit, gasLimit)
+	}
+	bid := &BidTrace{
+		Slot:                 decimal64(slot),
+		ParentHash:           data.ParentHash,
+		BlockHash:            data.BlockHash,
+		BuilderPubkey:        b.publicKey[:],
+		ProposerPubkey:       reg.Entry.Message.Pubkey,
+		ProposerFeeRecipient: reg.Entry.Message.FeeRecipient,
+		GasLimit:             decimal64(gasLimit),
+		GasUsed:              decimal64(data.GasUsed),
+		Value:                (*math.Decimal256)(envelope.BlockValue),
+	}
+	root := signingRoot(bid, b.domain)
+	signature := bls.Sign(b.secretKey, root[:]).Serialize()
+
+	return b.relay.submitBlock(ctx, newSubmitBlockRequest(bid, envelope, signature[:]))
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/params"
+	bls "github.com/protolambda/bls12-381-util"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBLSKey  = "0x263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3"
+	testFeeAddr = common.HexToAddress("0xfee")
+)
+
+// Tests the builder signature domain against the mainnet one used by relays.
+func TestBuilderDomain(t *testing.T) {
+	have := builderDomain([4]byte{})
+	want := common.HexToHash("0x00000001f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a9")
+	if have != want {
+		t.Fatalf("mainnet builder domain mismatch: have %x, want %x", have, want)
+	}
+}
+
+// testBackend is a miner backend built on a post-merge chain.
+type testBackend struct {
+	chain *core.BlockChain
+	pool  *txpool.TxPool
+}
+
+func (b *testBackend) BlockChain() *core.BlockChain { return b.chain }
+func (b *testBackend) TxPool() *txpool.TxPool       { return b.pool }
+
+// Tests that the builder builds a block for a registered proposer and submits
+// it signed to the relay.
+func TestSubmitBlock(t *testing.T) {
+	// Create a post-merge chain with a pending transaction
+	var (
+		engine = beacon.New(ethash.NewFaker())
+		gspec  = &core.Genesis{
+			Config:     params.MergedTestChainConfig,
+			Alloc:      types.GenesisAlloc{testAddr: {Balance: big.NewInt(params.Ether)}},
+			Difficulty: common.Big0,
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+		}
+	)
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	poolConfig := legacypool.DefaultConfig
+	poolConfig.Journal = ""
+	pool, _ := txpool.New(poolConfig.PriceLimit, chain, []txpool.SubPool{legacypool.New(poolConfig, chain)})
+	defer pool.Close()
+
+	tx := types.MustSignNewTx(testKey, types.LatestSigner(gspec.Config), &types.DynamicFeeTx{
+		ChainID:   gspec.Config.ChainID,
+		Nonce:     0,
+		To:        &common.Address{},
+		Gas:       params.TxGas,
+		GasTipCap: big.NewInt(params.GWei),
+		GasFeeCap: big.NewInt(10 * params.GWei),
+	})
+	if errs := pool.Add([]*types.Transaction{tx}, true, true); errs[0] != nil {
+		t.Fatalf("failed to add transaction: %v", errs[0])
+	}
+	minerConfig := miner.DefaultConfig
+	minerConfig.Recommit = 100 * time.Millisecond
+	m := miner.New(&testBackend{chain, pool}, minerConfig, engine)
+
+	// Create a mock relay with a registered proposer for the next slot, asking
+	// for a gas limit below the genesis one (the miner's ceiling is above it)
+	var (
+		registeredGasLimit = uint64(4_000_000)
+		proposer           = hexutil.MustDecode("0x" + fmt.Sprintf("%096x", 1))
+		submissions        = make(chan *submitBlockRequest, 16)
+	)
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/relay/v1/builder/validators":
+			reg := new(validatorRegistration)
+			reg.Slot = 1
+			reg.Entry.Message.FeeRecipient = testFeeAddr
+			reg.Entry.Message.Pubkey = proposer
+			reg.Entry.Message.GasLimit = decimal64(registeredGasLimit)
+			json.NewEncoder(w).Encode([]*validatorRegistration{reg})
+
+		case "/relay/v1/builder/blocks":
+			req := new(submitBlockRequest)
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			submissions <- req
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer relay.Close()
+
+	keyfile := filepath.Join(t.TempDir(), "builder.key")
+	if err := os.WriteFile(keyfile, []byte(testBLSKey), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	b, err := New(chain, m, Config{
+		RelayEndpoint:      relay.URL,
+		SecretKeyFile:      keyfile,
+		GenesisForkVersion: "0x00000000",
+		SubmitInterval:     50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to create builder: %v", err)
+	}
+	defer b.Stop()
+
+	// Announce the proposal of the next slot and wait for the submission
+	var (
+		ev      = new(payloadAttributesEvent)
+		genesis = chain.Genesis()
+		root    = common.Hash{0x01}
+	)
+	ev.Data.ProposalSlot = 1
+	ev.Data.ParentBlockHash = genesis.Hash()
+	ev.Data.PayloadAttributes.Timestamp = decimal64(time.Now().Unix())
+	ev.Data.PayloadAttributes.Withdrawals = []*withdrawal{}
+	ev.Data.PayloadAttributes.ParentBeaconBlockRoot = &root
+	b.onPayloadAttributes(ev)
+
+	var req *submitBlockRequest
+	select {
+	case req = <-submissions:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no block submitted")
+	}
+	bid := req.Message
+	if bid.Slot != 1 || bid.ParentHash != genesis.Hash() || bid.BlockHash != req.ExecutionPayload.BlockHash {
+		t.Fatalf("bid mismatch: slot %d, parent %x, hash %x", bid.Slot, bid.ParentHash, bid.BlockHash)
+	}
+	if bid.ProposerFeeRecipient != testFeeAddr || req.ExecutionPayload.FeeRecipient != testFeeAddr {
+		t.Fatalf("fee recipient mismatch: have %x, want %x", req.ExecutionPayload.FeeRecipient, testFeeAddr)
+	}
+	if len(req.ExecutionPayload.Transactions) != 1 || (*big.Int)(bid.Value).Sign() <= 0 {
+		t.Fatalf("block content mismatch: txs %d, value %v", len(req.ExecutionPayload.Transactions), bid.Value)
+	}
+	if want := core.CalcGasLimit(genesis.GasLimit(), registeredGasLimit); uint64(bid.GasLimit) != want || uint64(req.ExecutionPayload.GasLimit) != want {
+		t.Fatalf("gas limit mismatch: have bid %d, block %d, want %d", bid.GasLimit, req.ExecutionPayload.GasLimit, want)
+	}
+	if req.BlobsBundle == nil || req.ExecutionPayload.BlobGasUsed == nil {
+		t.Fatalf("deneb fields missing from submission")
+	}
+	// Verify the bid signature with the builder's public key
+	var (
+		pubkey    = new(bls.Pubkey)
+		signature = new(bls.Signature)
+		rawkey    [48]byte
+		rawsig    [96]byte
+	)
+	copy(rawkey[:], bid.BuilderPubkey)
+	copy(rawsig[:], req.Signature)
+	if err := pubkey.Deserialize(&rawkey); err != nil {
+		t.Fatalf("invalid builder pubkey: %v", err)
+	}
+	if err := signature.Deserialize(&rawsig); err != nil {
+		t.Fatalf("invalid signature: %v", err)
+	}
+	msg := signingRoot(bid, builderDomain([4]byte{}))
+	if !bls.Verify(pubkey, msg[:], signature) {
+		t.Fatalf("bid signature invalid")
+	}
+}
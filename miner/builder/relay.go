@@ -0,0 +1,119 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// relayRequestTimeout is the maximum time a request to the relay may take.
+	relayRequestTimeout = 5 * time.Second
+
+	// relayErrorBodyLimit is the maximum size of an error response of the relay
+	// included in the returned error.
+	relayErrorBodyLimit = 512
+)
+
+// relayClient is a client of the relay side of the builder API.
+type relayClient struct {
+	url    string
+	client *http.Client
+
+	validators map[uint64]*validatorRegistration // Registrations of the upcoming proposers by slot
+	lock       sync.Mutex
+}
+
+// newRelayClient creates a client for the relay at the given endpoint.
+func newRelayClient(url string) *relayClient {
+	return &relayClient{
+		url:        strings.TrimRight(url, "/"),
+		client:     &http.Client{Timeout: relayRequestTimeout},
+		validators: make(map[uint64]*validatorRegistration),
+	}
+}
+
+// do sends a request to the relay, decoding the response into result if it's
+// not nil.
+func (c *relayClient) do(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		blob, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(blob)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, relayErrorBodyLimit))
+		return fmt.Errorf("relay responded with %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+// validator retrieves the registration of the proposer of the given slot, or
+// nil if the proposer is not registered with the relay. The registrations are
+// cached, only refreshed if the slot is unknown.
+func (c *relayClient) validator(ctx context.Context, slot uint64) (*validatorRegistration, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if reg, ok := c.validators[slot]; ok {
+		return reg, nil
+	}
+	var regs []*validatorRegistration
+	if err := c.do(ctx, http.MethodGet, "/relay/v1/builder/validators", nil, &regs); err != nil {
+		return nil, err
+	}
+	// Replace the cache with the current registrations, dropping past slots
+	c.validators = make(map[uint64]*validatorRegistration, len(regs))
+	for _, reg := range regs {
+		c.validators[uint64(reg.Slot)] = reg
+	}
+	return c.validators[slot], nil
+}
+
+// submitBlock submits a signed block to the relay.
+func (c *relayClient) submitBlock(ctx context.Context, req *submitBlockRequest) error {
+	return c.do(ctx, http.MethodPost, "/relay/v1/builder/blocks", req, nil)
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+)
+
+// domainApplicationBuilder is the signature domain type of the builder API
+// messages, defined by the builder specs.
+var domainApplicationBuilder = [4]byte{0x00, 0x00, 0x00, 0x01}
+
+// merkleize computes the root of the binary merkle tree of the given chunks,
+// padded with zero chunks to the next power of two.
+func merkleize(chunks [][32]byte) [32]byte {
+	size := 1
+	for size < len(chunks) {
+		size <<= 1
+	}
+	layer := make([][32]byte, size)
+	copy(layer, chunks)
+
+	for len(layer) > 1 {
+		for i := 0; i < len(layer)/2; i++ {
+			layer[i] = sha256.Sum256(append(layer[2*i][:], layer[2*i+1][:]...))
+		}
+		layer = layer[:len(layer)/2]
+	}
+	return layer[0]
+}
+
+// uint64Chunk returns the SSZ chunk of an uint64.
+func uint64Chunk(v uint64) (chunk [32]byte) {
+	binary.LittleEndian.PutUint64(chunk[:], v)
+	return chunk
+}
+
+// uint256Chunk returns the SSZ chunk of an uint256.
+func uint256Chunk(v *big.Int) (chunk [32]byte) {
+	v.FillBytes(chunk[:])
+	for i := 0; i < len(chunk)/2; i++ {
+		chunk[i], chunk[len(chunk)-1-i] = chunk[len(chunk)-1-i], chunk[i]
+	}
+	return chunk
+}
+
+// bytesRoot returns the SSZ hash tree root of a fixed size byte vector.
+func bytesRoot(b []byte) [32]byte {
+	chunks := make([][32]byte, (len(b)+31)/32)
+	for i := range chunks {
+		copy(chunks[i][:], b[i*32:])
+	}
+	return merkleize(chunks)
+}
+
+// hashTreeRoot computes the SSZ hash tree root of the bid.
+func (bid *BidTrace) hashTreeRoot() [32]byte {
+	value := new(big.Int)
+	if bid.Value != nil {
+		value = (*big.Int)(bid.Value)
+	}
+	return merkleize([][32]byte{
+		uint64Chunk(uint64(bid.Slot)),
+		bid.ParentHash,
+		bid.BlockHash,
+		bytesRoot(bid.BuilderPubkey),
+		bytesRoot(bid.ProposerPubkey),
+		bytesRoot(bid.ProposerFeeRecipient[:]),
+		uint64Chunk(uint64(bid.GasLimit)),
+		uint64Chunk(uint64(bid.GasUsed)),
+		uint256Chunk(value),
+	})
+}
+
+// builderDomain computes the signature domain of the builder API messages for
+// the network with the given genesis fork version. The builder domain is not
+// bound to a genesis validators root.
+func builderDomain(forkVersion [4]byte) (domain [32]byte) {
+	var version [32]byte
+	copy(version[:], forkVersion[:])
+	forkDataRoot := merkleize([][32]byte{version, {}})
+
+	copy(domain[:], domainApplicationBuilder[:])
+	copy(domain[4:], forkDataRoot[:28])
+	return domain
+}
+
+// signingRoot computes the root signed by the builder for a bid in the given
+// signature domain.
+func signingRoot(bid *BidTrace, domain [32]byte) [32]byte {
+	return merkleize([][32]byte{bid.hashTreeRoot(), domain})
+}
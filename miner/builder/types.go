@@ -0,0 +1,197 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// decimal64 is a uint64 marshalled as a decimal string, as done by the beacon
+// and the builder APIs.
+type decimal64 uint64
+
+// MarshalText implements encoding.TextMarshaler.
+func (d decimal64) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(d), 10)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *decimal64) UnmarshalText(input []byte) error {
+	v, err := strconv.ParseUint(string(input), 10, 64)
+	if err != nil {
+		return err
+	}
+	*d = decimal64(v)
+	return nil
+}
+
+// BidTrace is the message of a block submission signed by the builder.
+type BidTrace struct {
+	Slot                 decimal64        `json:"slot"`
+	ParentHash           common.Hash      `json:"parent_hash"`
+	BlockHash            common.Hash      `json:"block_hash"`
+	BuilderPubkey        hexutil.Bytes    `json:"builder_pubkey"`
+	ProposerPubkey       hexutil.Bytes    `json:"proposer_pubkey"`
+	ProposerFeeRecipient common.Address   `json:"proposer_fee_recipient"`
+	GasLimit             decimal64        `json:"gas_limit"`
+	GasUsed              decimal64        `json:"gas_used"`
+	Value                *math.Decimal256 `json:"value"`
+}
+
+// withdrawal is a validator withdrawal in the beacon API encoding.
+type withdrawal struct {
+	Index          decimal64      `json:"index"`
+	ValidatorIndex decimal64      `json:"validator_index"`
+	Address        common.Address `json:"address"`
+	Amount         decimal64      `json:"amount"`
+}
+
+// executionPayload is an execution payload in the beacon API encoding. The blob
+// fields are only present from Deneb on.
+type executionPayload struct {
+	ParentHash    common.Hash      `json:"parent_hash"`
+	FeeRecipient  common.Address   `json:"fee_recipient"`
+	StateRoot     common.Hash      `json:"state_root"`
+	ReceiptsRoot  common.Hash      `json:"receipts_root"`
+	LogsBloom     hexutil.Bytes    `json:"logs_bloom"`
+	PrevRandao    common.Hash      `json:"prev_randao"`
+	BlockNumber   decimal64        `json:"block_number"`
+	GasLimit      decimal64        `json:"gas_limit"`
+	GasUsed       decimal64        `json:"gas_used"`
+	Timestamp     decimal64        `json:"timestamp"`
+	ExtraData     hexutil.Bytes    `json:"extra_data"`
+	BaseFeePerGas *math.Decimal256 `json:"base_fee_per_gas"`
+	BlockHash     common.Hash      `json:"block_hash"`
+	Transactions  []hexutil.Bytes  `json:"transactions"`
+	Withdrawals   []*withdrawal    `json:"withdrawals"`
+	BlobGasUsed   *decimal64       `json:"blob_gas_used,omitempty"`
+	ExcessBlobGas *decimal64       `json:"excess_blob_gas,omitempty"`
+}
+
+// submitBlockRequest is the body of a block submission to the relay.
+type submitBlockRequest struct {
+	Message          *BidTrace             `json:"message"`
+	ExecutionPayload *executionPayload     `json:"execution_payload"`
+	BlobsBundle      *engine.BlobsBundleV1 `json:"blobs_bundle,omitempty"`
+	Signature        hexutil.Bytes         `json:"signature"`
+}
+
+// newSubmitBlockRequest converts a built payload into the relay submission
+// format. The blobs bundle is only attached to Deneb payloads.
+func newSubmitBlockRequest(bid *BidTrace, envelope *engine.ExecutionPayloadEnvelope, signature []byte) *submitBlockRequest {
+	data := envelope.ExecutionPayload
+
+	payload := &executionPayload{
+		ParentHash:    data.ParentHash,
+		FeeRecipient:  data.FeeRecipient,
+		StateRoot:     data.StateRoot,
+		ReceiptsRoot:  data.ReceiptsRoot,
+		LogsBloom:     data.LogsBloom,
+		PrevRandao:    data.Random,
+		BlockNumber:   decimal64(data.Number),
+		GasLimit:      decimal64(data.GasLimit),
+		GasUsed:       decimal64(data.GasUsed),
+		Timestamp:     decimal64(data.Timestamp),
+		ExtraData:     data.ExtraData,
+		BaseFeePerGas: (*math.Decimal256)(data.BaseFeePerGas),
+		BlockHash:     data.BlockHash,
+		Transactions:  make([]hexutil.Bytes, len(data.Transactions)),
+		Withdrawals:   make([]*withdrawal, len(data.Withdrawals)),
+	}
+	for i, tx := range data.Transactions {
+		payload.Transactions[i] = tx
+	}
+	for i, w := range data.Withdrawals {
+		payload.Withdrawals[i] = &withdrawal{
+			Index:          decimal64(w.Index),
+			ValidatorIndex: decimal64(w.Validator),
+			Address:        w.Address,
+			Amount:         decimal64(w.Amount),
+		}
+	}
+	req := &submitBlockRequest{
+		Message:          bid,
+		ExecutionPayload: payload,
+		Signature:        signature,
+	}
+	if data.BlobGasUsed != nil && data.ExcessBlobGas != nil {
+		payload.BlobGasUsed = (*decimal64)(data.BlobGasUsed)
+		payload.ExcessBlobGas = (*decimal64)(data.ExcessBlobGas)
+		req.BlobsBundle = envelope.BlobsBundle
+	}
+	return req
+}
+
+// validatorRegistration is the registration of an upcoming block proposer,
+// as returned by the relay.
+type validatorRegistration struct {
+	Slot           decimal64 `json:"slot"`
+	ValidatorIndex decimal64 `json:"validator_index"`
+	Entry          struct {
+		Message struct {
+			FeeRecipient common.Address `json:"fee_recipient"`
+			GasLimit     decimal64      `json:"gas_limit"`
+			Timestamp    decimal64      `json:"timestamp"`
+			Pubkey       hexutil.Bytes  `json:"pubkey"`
+		} `json:"message"`
+		Signature hexutil.Bytes `json:"signature"`
+	} `json:"entry"`
+}
+
+// payloadAttributesEvent is the payload_attributes event of the beacon node,
+// published when a new block proposal is upcoming.
+type payloadAttributesEvent struct {
+	Version string `json:"version"`
+	Data    struct {
+		ProposerIndex     decimal64   `json:"proposer_index"`
+		ProposalSlot      decimal64   `json:"proposal_slot"`
+		ParentBlockNumber decimal64   `json:"parent_block_number"`
+		ParentBlockRoot   common.Hash `json:"parent_block_root"`
+		ParentBlockHash   common.Hash `json:"parent_block_hash"`
+		PayloadAttributes struct {
+			Timestamp             decimal64      `json:"timestamp"`
+			PrevRandao            common.Hash    `json:"prev_randao"`
+			SuggestedFeeRecipient common.Address `json:"suggested_fee_recipient"`
+			Withdrawals           []*withdrawal  `json:"withdrawals"`
+			ParentBeaconBlockRoot *common.Hash   `json:"parent_beacon_block_root"`
+		} `json:"payload_attributes"`
+	} `json:"data"`
+}
+
+// withdrawals converts the withdrawals of the event into the execution layer
+// format.
+func (ev *payloadAttributesEvent) withdrawals() types.Withdrawals {
+	if ev.Data.PayloadAttributes.Withdrawals == nil {
+		return nil
+	}
+	withdrawals := make(types.Withdrawals, len(ev.Data.PayloadAttributes.Withdrawals))
+	for i, w := range ev.Data.PayloadAttributes.Withdrawals {
+		withdrawals[i] = &types.Withdrawal{
+			Index:     uint64(w.Index),
+			Validator: uint64(w.ValidatorIndex),
+			Address:   w.Address,
+			Amount:    uint64(w.Amount),
+		}
+	}
+	return withdrawals
+}
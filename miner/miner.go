@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -48,6 +49,12 @@ type Config struct {
 	GasCeil             uint64         // Target gas ceiling for mined blocks.
 	GasPrice            *big.Int       // Minimum gas price for mining a transaction
 	Recommit            time.Duration  // The time interval for miner to re-create mining work.
+
+	Ordering        string           `toml:",omitempty"` // Transaction ordering policy ("price" or "firstseen")
+	SenderAllowlist []common.Address `toml:",omitempty"` // Senders exclusively included in mined blocks, anyone if empty
+	SenderDenylist  []common.Address `toml:",omitempty"` // Senders never included in mined blocks
+	MaxSenderGas    uint64           `toml:",omitempty"` // Maximum gas included from a single sender per block (0 = unlimited)
+	LocalGasReserve uint64           `toml:",omitempty"` // Block gas reserved for local transactions
 }
 
 // DefaultConfig contains default settings for miner.
@@ -60,6 +67,19 @@ var DefaultConfig = Config{
 	// for payload generation. It should be enough for Geth to
 	// run 3 rounds.
 	Recommit: 2 * time.Second,
+
+	Ordering: OrderingPrice,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() Config {
+	conf := *config
+	if conf.LocalGasReserve > conf.GasCeil {
+		log.Warn("Sanitizing invalid local gas reservation", "provided", conf.LocalGasReserve, "updated", conf.GasCeil)
+		conf.LocalGasReserve = conf.GasCeil
+	}
+	return conf
 }
 
 // Miner is the main object which takes care of submitting new work to consensus
@@ -73,10 +93,12 @@ type Miner struct {
 	chain       *core.BlockChain
 	pending     *pending
 	pendingMu   sync.Mutex // Lock protects the pending block
+	policy      *policy    // Transaction ordering and selection policy
 }
 
 // New creates a new miner with provided config.
 func New(eth Backend, config Config, engine consensus.Engine) *Miner {
+	config = config.sanitize()
 	return &Miner{
 		config:      &config,
 		chainConfig: eth.BlockChain().Config(),
@@ -84,6 +106,7 @@ func New(eth Backend, config Config, engine consensus.Engine) *Miner {
 		txpool:      eth.TxPool(),
 		chain:       eth.BlockChain(),
 		pending:     &pending{},
+		policy:      newPolicy(&config),
 	}
 }
 
@@ -194,3 +194,50 @@ func TestTransactionTimeSort(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the first-seen ordering retrieves the transactions in their arrival
+// order regardless of their price, while honouring the account nonces.
+func TestTransactionFirstSeenSort(t *testing.T) {
+	t.Parallel()
+	// Generate a batch of accounts to start with
+	keys := make([]*ecdsa.PrivateKey, 5)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	signer := types.HomesteadSigner{}
+
+	// Generate two transactions per account, the cheaper ones arriving earlier
+	groups := map[common.Address][]*txpool.LazyTransaction{}
+	for start, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		for nonce := 0; nonce < 2; nonce++ {
+			tx, _ := types.SignTx(types.NewTransaction(uint64(nonce), common.Address{}, big.NewInt(100), 100, big.NewInt(int64(start+1)), nil), signer, key)
+			tx.SetTime(time.Unix(0, int64(start+nonce*len(keys))))
+
+			groups[addr] = append(groups[addr], &txpool.LazyTransaction{
+				Hash:      tx.Hash(),
+				Tx:        tx,
+				Time:      tx.Time(),
+				GasFeeCap: uint256.MustFromBig(tx.GasFeeCap()),
+				GasTipCap: uint256.MustFromBig(tx.GasTipCap()),
+				Gas:       tx.Gas(),
+				BlobGas:   tx.BlobGas(),
+			})
+		}
+	}
+	txset := newTransactionsByTimeAndNonce(groups, nil)
+
+	txs := types.Transactions{}
+	for tx, _ := txset.Peek(); tx != nil; tx, _ = txset.Peek() {
+		txs = append(txs, tx.Tx)
+		txset.Shift()
+	}
+	if len(txs) != 2*len(keys) {
+		t.Fatalf("expected %d transactions, found %d", 2*len(keys), len(txs))
+	}
+	for i := 1; i < len(txs); i++ {
+		if txs[i-1].Time().After(txs[i].Time()) {
+			t.Errorf("invalid received time ordering: tx #%d (T=%v) > tx #%d (T=%v)", i-1, txs[i-1].Time(), i, txs[i].Time())
+		}
+	}
+}
@@ -41,6 +41,7 @@ type BuildPayloadArgs struct {
 	Random       common.Hash           // The provided randomness value
 	Withdrawals  types.Withdrawals     // The provided withdrawals
 	BeaconRoot   *common.Hash          // The provided beaconRoot (Cancun)
+	GasLimit     uint64                // Gas limit to target instead of the configured ceiling (0 = gas ceil)
 	Version      engine.PayloadVersion // Versioning byte for payload id calculation.
 }
 
@@ -55,6 +56,9 @@ func (args *BuildPayloadArgs) Id() engine.PayloadID {
 	if args.BeaconRoot != nil {
 		hasher.Write(args.BeaconRoot[:])
 	}
+	if args.GasLimit != 0 {
+		binary.Write(hasher, binary.BigEndian, args.GasLimit)
+	}
 	var out engine.PayloadID
 	copy(out[:], hasher.Sum(nil)[:8])
 	out[0] = byte(args.Version)
@@ -140,6 +144,18 @@ func (payload *Payload) Resolve() *engine.ExecutionPayloadEnvelope {
 	return engine.BlockToExecutableData(payload.empty, big.NewInt(0), nil)
 }
 
+// Peek returns the latest built full payload without terminating the background
+// thread for updating payload, or nil if no full block was built yet.
+func (payload *Payload) Peek() *engine.ExecutionPayloadEnvelope {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	if payload.full == nil {
+		return nil
+	}
+	return engine.BlockToExecutableData(payload.full, payload.fullFees, payload.sidecars)
+}
+
 // ResolveEmpty is basically identical to Resolve, but it expects empty block only.
 // It's only used in tests.
 func (payload *Payload) ResolveEmpty() *engine.ExecutionPayloadEnvelope {
@@ -188,6 +204,7 @@ func (miner *Miner) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
 		random:      args.Random,
 		withdrawals: args.Withdrawals,
 		beaconRoot:  args.BeaconRoot,
+		gasLimit:    args.GasLimit,
 		noTxs:       true,
 	}
 	empty := miner.generateWork(emptyParams)
@@ -219,6 +236,7 @@ func (miner *Miner) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
 			random:      args.Random,
 			withdrawals: args.Withdrawals,
 			beaconRoot:  args.BeaconRoot,
+			gasLimit:    args.GasLimit,
 			noTxs:       false,
 		}
 
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -126,7 +127,8 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 		t.Fatalf("core.NewBlockChain failed: %v", err)
 	}
 	pool := legacypool.New(testTxPoolConfig, chain)
-	txpool, _ := txpool.New(testTxPoolConfig.PriceLimit, chain, []txpool.SubPool{pool})
+	bundlePool := bundlepool.New(bundlepool.DefaultConfig, chain)
+	txpool, _ := txpool.New(testTxPoolConfig.PriceLimit, chain, []txpool.SubPool{pool, bundlePool})
 
 	return &testWorkerBackend{
 		db:      db,
@@ -273,3 +275,126 @@ func TestPayloadId(t *testing.T) {
 		ids[id] = i
 	}
 }
+
+func TestCommitBundles(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = newTestWorkerBackend(t, params.TestChainConfig, ethash.NewFaker(), db, 0)
+		w       = New(backend, testConfig, ethash.NewFaker())
+		signer  = types.LatestSigner(params.TestChainConfig)
+	)
+	transfer := func(nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(params.InitialBaseFee),
+		})
+	}
+	var (
+		valid   = &txpool.Bundle{Txs: []*types.Transaction{transfer(0), transfer(1)}, BlockNumber: 1}
+		invalid = &txpool.Bundle{Txs: []*types.Transaction{transfer(2), transfer(4)}, BlockNumber: 1}
+		later   = &txpool.Bundle{Txs: []*types.Transaction{transfer(2)}, BlockNumber: 2}
+	)
+	for _, bundle := range []*txpool.Bundle{valid, invalid, later} {
+		if err := backend.txPool.AddBundle(bundle); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	// The bundle transactions must not leak into the public pool
+	if pending, _ := backend.txPool.Stats(); pending != 0 {
+		t.Fatalf("bundle transactions exposed as pending: %d", pending)
+	}
+	res := w.generateWork(&generateParams{
+		timestamp:   uint64(time.Now().Unix()),
+		parentHash:  backend.chain.CurrentBlock().Hash(),
+		coinbase:    testBankAddress,
+		withdrawals: types.Withdrawals{},
+	})
+	if res.err != nil {
+		t.Fatalf("failed to generate work: %v", res.err)
+	}
+	// Only the valid bundle targeting the block is included, the invalid one
+	// is dropped entirely, including its valid first transaction.
+	txs := res.block.Transactions()
+	if len(txs) != len(valid.Txs) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(txs), len(valid.Txs))
+	}
+	for i, tx := range valid.Txs {
+		if txs[i].Hash() != tx.Hash() {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, txs[i].Hash(), tx.Hash())
+		}
+	}
+	if used := res.block.GasUsed(); used != 2*params.TxGas {
+		t.Errorf("gas used mismatch: have %d, want %d", used, 2*params.TxGas)
+	}
+}
+
+func TestBuildingPolicy(t *testing.T) {
+	signer := types.LatestSigner(params.TestChainConfig)
+	transfer := func(nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(params.InitialBaseFee),
+		})
+	}
+	tests := []struct {
+		config Config
+		local  bool
+		bundle bool // Whether the transactions are submitted as a bundle
+		want   int
+	}{
+		{config: testConfig, want: 3},
+		{config: Config{Ordering: OrderingFirstSeen}, want: 3},
+		{config: Config{SenderDenylist: []common.Address{testBankAddress}}, want: 0},
+		{config: Config{SenderAllowlist: []common.Address{testUserAddress}}, want: 0},
+		{config: Config{SenderAllowlist: []common.Address{testBankAddress}}, want: 3},
+		{config: Config{MaxSenderGas: 2 * params.TxGas}, want: 2},
+		{config: Config{LocalGasReserve: params.GenesisGasLimit - params.TxGas}, want: 1},
+		{config: Config{LocalGasReserve: params.GenesisGasLimit - params.TxGas}, local: true, want: 3},
+		{config: testConfig, bundle: true, want: 3},
+		{config: Config{SenderDenylist: []common.Address{testBankAddress}}, bundle: true, want: 0},
+		{config: Config{SenderAllowlist: []common.Address{testUserAddress}}, bundle: true, want: 0},
+		{config: Config{SenderAllowlist: []common.Address{testBankAddress}}, bundle: true, want: 3},
+		{config: Config{MaxSenderGas: 2 * params.TxGas}, bundle: true, want: 0},
+		{config: Config{MaxSenderGas: 3 * params.TxGas}, bundle: true, want: 3},
+	}
+	for i, tt := range tests {
+		var (
+			db      = rawdb.NewMemoryDatabase()
+			backend = newTestWorkerBackend(t, params.TestChainConfig, ethash.NewFaker(), db, 0)
+		)
+		config := tt.config
+		config.GasCeil, config.Recommit = testConfig.GasCeil, testConfig.Recommit
+
+		w := New(backend, config, ethash.NewFaker())
+		txs := []*types.Transaction{transfer(0), transfer(1), transfer(2)}
+		if tt.bundle {
+			if err := backend.txPool.AddBundle(&txpool.Bundle{Txs: txs, BlockNumber: 1}); err != nil {
+				t.Fatalf("test %d: failed to add bundle: %v", i, err)
+			}
+		} else {
+			for _, err := range backend.txPool.Add(txs, tt.local, true) {
+				if err != nil {
+					t.Fatalf("test %d: failed to add transaction: %v", i, err)
+				}
+			}
+		}
+		res := w.generateWork(&generateParams{
+			timestamp:   uint64(time.Now().Unix()),
+			parentHash:  backend.chain.CurrentBlock().Hash(),
+			coinbase:    testBankAddress,
+			withdrawals: types.Withdrawals{},
+		})
+		if res.err != nil {
+			t.Fatalf("test %d: failed to generate work: %v", i, res.err)
+		}
+		if have := len(res.block.Transactions()); have != tt.want {
+			t.Errorf("test %d: transaction count mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
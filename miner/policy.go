@@ -0,0 +1,140 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+)
+
+const (
+	// OrderingPrice includes the transactions in the order of their effective
+	// tip, maximizing the fees earned by the block.
+	OrderingPrice = "price"
+
+	// OrderingFirstSeen includes the transactions in the order they were first
+	// seen by the pool, regardless of their tip above the miner's minimum.
+	OrderingFirstSeen = "firstseen"
+)
+
+// txOrdering is a set of pending transactions retrieved in the order they are
+// to be included into the block, honouring the account nonces.
+type txOrdering interface {
+	// Peek returns the next transaction to include along with its effective tip.
+	Peek() (*txpool.LazyTransaction, *uint256.Int)
+
+	// Shift replaces the next transaction with the following one of the same account.
+	Shift()
+
+	// Pop removes the next transaction along with all of the same account.
+	Pop()
+
+	// Empty returns whether all the transactions were retrieved.
+	Empty() bool
+
+	// Clear removes all the transactions.
+	Clear()
+}
+
+// policy is the transaction ordering and selection policy of the block building,
+// derived from the miner configuration. It applies equally to the pending block
+// and to the payloads built for the consensus client.
+type policy struct {
+	ordering     string                      // Transaction ordering, one of the Ordering* constants
+	allow        map[common.Address]struct{} // Senders exclusively included, nil if anyone
+	deny         map[common.Address]struct{} // Senders never included
+	maxSenderGas uint64                      // Maximum gas included from a single sender (0 = unlimited)
+	localReserve uint64                      // Block gas remote transactions are not allowed to use
+}
+
+// newPolicy creates the block building policy from the miner configuration.
+// Unknown orderings fall back to the default price ordering.
+func newPolicy(config *Config) *policy {
+	p := &policy{
+		ordering:     config.Ordering,
+		maxSenderGas: config.MaxSenderGas,
+		localReserve: config.LocalGasReserve,
+	}
+	switch p.ordering {
+	case OrderingPrice, OrderingFirstSeen:
+	default:
+		if p.ordering != "" {
+			log.Warn("Unknown transaction ordering, using default", "ordering", p.ordering, "default", OrderingPrice)
+		}
+		p.ordering = OrderingPrice
+	}
+	if len(config.SenderAllowlist) > 0 {
+		p.allow = make(map[common.Address]struct{}, len(config.SenderAllowlist))
+		for _, addr := range config.SenderAllowlist {
+			p.allow[addr] = struct{}{}
+		}
+	}
+	p.deny = make(map[common.Address]struct{}, len(config.SenderDenylist))
+	for _, addr := range config.SenderDenylist {
+		p.deny[addr] = struct{}{}
+	}
+	return p
+}
+
+// filter removes the transactions of the senders excluded by the policy from
+// the given pending set.
+func (p *policy) filter(txs map[common.Address][]*txpool.LazyTransaction) {
+	for addr := range txs {
+		if !p.allowed(addr) {
+			delete(txs, addr)
+		}
+	}
+}
+
+// allowed reports whether the transactions of the given sender may be included.
+func (p *policy) allowed(addr common.Address) bool {
+	if _, ok := p.deny[addr]; ok {
+		return false
+	}
+	if p.allow != nil {
+		if _, ok := p.allow[addr]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// order creates the transaction set retrieving the given pending transactions
+// in the order defined by the policy.
+//
+// Note, the input map is reowned so the caller should not interact any more with
+// if after providing it to the constructor.
+func (p *policy) order(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, baseFee *big.Int) txOrdering {
+	if p.ordering == OrderingFirstSeen {
+		return newTransactionsByTimeAndNonce(txs, baseFee)
+	}
+	return newTransactionsByPriceAndNonce(signer, txs, baseFee)
+}
+
+// before returns whether the transaction a is to be included before b, used to
+// merge the plain and the blob transaction sets.
+func (p *policy) before(a *txpool.LazyTransaction, atip *uint256.Int, b *txpool.LazyTransaction, btip *uint256.Int) bool {
+	if p.ordering == OrderingFirstSeen {
+		return !b.Time.Before(a.Time)
+	}
+	return !atip.Lt(btip)
+}
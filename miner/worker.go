@@ -56,6 +56,8 @@ type environment struct {
 	receipts []*types.Receipt
 	sidecars []*types.BlobTxSidecar
 	blobs    int
+
+	senderGas map[common.Address]uint64 // Gas used by each sender, if limited by the policy
 }
 
 const (
@@ -84,6 +86,7 @@ type generateParams struct {
 	random      common.Hash       // The randomness generated by beacon chain, empty before the merge
 	withdrawals types.Withdrawals // List of withdrawals to include in block (shanghai field)
 	beaconRoot  *common.Hash      // The beacon root (cancun field).
+	gasLimit    uint64            // Gas limit to target instead of the configured ceiling (0 = gas ceil)
 	noTxs       bool              // Flag whether an empty block without any transaction is expected
 }
 
@@ -144,11 +147,16 @@ func (miner *Miner) prepareWork(genParams *generateParams) (*environment, error)
 		}
 		timestamp = parent.Time + 1
 	}
-	// Construct the sealing block header.
+	// Construct the sealing block header, moving the gas limit towards the
+	// requested target if any, or the configured ceiling otherwise.
+	gasCeil := miner.config.GasCeil
+	if genParams.gasLimit != 0 {
+		gasCeil = genParams.gasLimit
+	}
 	header := &types.Header{
 		ParentHash: parent.Hash(),
 		Number:     new(big.Int).Add(parent.Number, common.Big1),
-		GasLimit:   core.CalcGasLimit(parent.GasLimit, miner.config.GasCeil),
+		GasLimit:   core.CalcGasLimit(parent.GasLimit, gasCeil),
 		Time:       timestamp,
 		Coinbase:   genParams.coinbase,
 	}
@@ -165,7 +173,7 @@ func (miner *Miner) prepareWork(genParams *generateParams) (*environment, error)
 		header.BaseFee = eip1559.CalcBaseFee(miner.chainConfig, parent)
 		if !miner.chainConfig.IsLondon(parent.Number) {
 			parentGasLimit := parent.GasLimit * miner.chainConfig.ElasticityMultiplier()
-			header.GasLimit = core.CalcGasLimit(parentGasLimit, miner.config.GasCeil)
+			header.GasLimit = core.CalcGasLimit(parentGasLimit, gasCeil)
 		}
 	}
 	// Run the consensus preparation with the default or customized consensus engine.
@@ -273,7 +281,10 @@ func (miner *Miner) applyTransaction(env *environment, tx *types.Transaction) (*
 	return receipt, err
 }
 
-func (miner *Miner) commitTransactions(env *environment, plainTxs, blobTxs *transactionsByPriceAndNonce, interrupt *atomic.Int32) error {
+// commitTransactions applies the transactions of the given sets in the order
+// defined by the block building policy, leaving the reserved amount of gas
+// unused.
+func (miner *Miner) commitTransactions(env *environment, plainTxs, blobTxs txOrdering, reserve uint64, interrupt *atomic.Int32) error {
 	gasLimit := env.header.GasLimit
 	if env.gasPool == nil {
 		env.gasPool = new(core.GasPool).AddGas(gasLimit)
@@ -286,8 +297,8 @@ func (miner *Miner) commitTransactions(env *environment, plainTxs, blobTxs *tran
 			}
 		}
 		// If we don't have enough gas for any further transactions then we're done.
-		if env.gasPool.Gas() < params.TxGas {
-			log.Trace("Not enough gas for further transactions", "have", env.gasPool, "want", params.TxGas)
+		if env.gasPool.Gas() < params.TxGas+reserve {
+			log.Trace("Not enough gas for further transactions", "have", env.gasPool, "want", params.TxGas, "reserved", reserve)
 			break
 		}
 		// If we don't have enough blob space for any further blob transactions,
@@ -300,7 +311,7 @@ func (miner *Miner) commitTransactions(env *environment, plainTxs, blobTxs *tran
 		// Retrieve the next transaction and abort if all done.
 		var (
 			ltx *txpool.LazyTransaction
-			txs *transactionsByPriceAndNonce
+			txs txOrdering
 		)
 		pltx, ptip := plainTxs.Peek()
 		bltx, btip := blobTxs.Peek()
@@ -311,18 +322,18 @@ func (miner *Miner) commitTransactions(env *environment, plainTxs, blobTxs *tran
 		case bltx == nil:
 			txs, ltx = plainTxs, pltx
 		default:
-			if ptip.Lt(btip) {
-				txs, ltx = blobTxs, bltx
-			} else {
+			if miner.policy.before(pltx, ptip, bltx, btip) {
 				txs, ltx = plainTxs, pltx
+			} else {
+				txs, ltx = blobTxs, bltx
 			}
 		}
 		if ltx == nil {
 			break
 		}
 		// If we don't have enough space for the next transaction, skip the account.
-		if env.gasPool.Gas() < ltx.Gas {
-			log.Trace("Not enough gas left for transaction", "hash", ltx.Hash, "left", env.gasPool.Gas(), "needed", ltx.Gas)
+		if env.gasPool.Gas() < ltx.Gas+reserve {
+			log.Trace("Not enough gas left for transaction", "hash", ltx.Hash, "left", env.gasPool.Gas(), "needed", ltx.Gas, "reserved", reserve)
 			txs.Pop()
 			continue
 		}
@@ -349,6 +360,12 @@ func (miner *Miner) commitTransactions(env *environment, plainTxs, blobTxs *tran
 			txs.Pop()
 			continue
 		}
+		// If the sender would exceed its gas allowance in the block, skip it
+		if limit := miner.policy.maxSenderGas; limit > 0 && env.senderGas[from]+ltx.Gas > limit {
+			log.Trace("Sender gas allowance exceeded", "hash", ltx.Hash, "sender", from, "used", env.senderGas[from], "needed", ltx.Gas, "limit", limit)
+			txs.Pop()
+			continue
+		}
 		// Start executing the transaction
 		env.state.SetTxContext(tx.Hash(), env.tcount)
 
@@ -361,6 +378,12 @@ func (miner *Miner) commitTransactions(env *environment, plainTxs, blobTxs *tran
 
 		case errors.Is(err, nil):
 			// Everything ok, collect the logs and shift in the next transaction from the same account
+			if miner.policy.maxSenderGas > 0 {
+				if env.senderGas == nil {
+					env.senderGas = make(map[common.Address]uint64)
+				}
+				env.senderGas[from] += env.receipts[len(env.receipts)-1].GasUsed
+			}
 			txs.Shift()
 
 		default:
@@ -373,10 +396,102 @@ func (miner *Miner) commitTransactions(env *environment, plainTxs, blobTxs *tran
 	return nil
 }
 
+// commitBundles applies the bundles targeting the sealing block at the top of
+// it. A bundle is only included if all of its transactions are valid, and none
+// of them fails without being allowed to revert.
+func (miner *Miner) commitBundles(env *environment, interrupt *atomic.Int32) error {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	for _, bundle := range miner.txpool.Bundles(env.header.Number.Uint64(), env.header.Time) {
+		// Check interruption signal and abort building if it's fired.
+		if interrupt != nil {
+			if signal := interrupt.Load(); signal != commitInterruptNone {
+				return signalToErr(signal)
+			}
+		}
+		if err := miner.commitBundle(env, bundle); err != nil {
+			log.Debug("Bundle skipped", "hash", bundle.Hash(), "err", err)
+		}
+	}
+	return nil
+}
+
+// commitBundle applies all the transactions of a bundle. If any of them fails,
+// the state, the gas pool and the collected transactions are reverted.
+func (miner *Miner) commitBundle(env *environment, bundle *txpool.Bundle) error {
+	// The bundle transactions are subject to the same sender policy as the
+	// pending ones, so the whole bundle is skipped if any sender is excluded
+	// or would exceed its gas allowance.
+	var (
+		senders = make([]common.Address, len(bundle.Txs))
+		needed  = make(map[common.Address]uint64)
+	)
+	for i, tx := range bundle.Txs {
+		from, err := types.Sender(env.signer, tx)
+		if err != nil {
+			return err
+		}
+		if !miner.policy.allowed(from) {
+			return fmt.Errorf("sender %x excluded by the building policy", from)
+		}
+		needed[from] += tx.Gas()
+		if limit := miner.policy.maxSenderGas; limit > 0 && env.senderGas[from]+needed[from] > limit {
+			return fmt.Errorf("sender %x gas allowance exceeded: used %d, needed %d, limit %d", from, env.senderGas[from], needed[from], limit)
+		}
+		senders[i] = from
+	}
+	// The snapshot is kept across the transactions of the bundle, so that it
+	// can be reverted as a whole.
+	var (
+		snap     = env.state.MultiTxSnapshot()
+		gp       = env.gasPool.Gas()
+		gasUsed  = env.header.GasUsed
+		tcount   = env.tcount
+		txs      = len(env.txs)
+		receipts = len(env.receipts)
+	)
+	for _, tx := range bundle.Txs {
+		env.state.SetTxContext(tx.Hash(), env.tcount)
+
+		receipt, err := core.ApplyTransaction(miner.chainConfig, miner.chain, &env.coinbase, env.gasPool, env.state, env.header, tx, &env.header.GasUsed, vm.Config{})
+		if err == nil && receipt.Status == types.ReceiptStatusFailed && !bundle.CanRevert(tx.Hash()) {
+			err = fmt.Errorf("transaction %x reverted", tx.Hash())
+		}
+		if err != nil {
+			env.state.RevertToSnapshot(snap)
+			env.gasPool.SetGas(gp)
+			env.header.GasUsed = gasUsed
+			env.tcount = tcount
+			env.txs, env.receipts = env.txs[:txs], env.receipts[:receipts]
+			return err
+		}
+		env.txs = append(env.txs, tx)
+		env.receipts = append(env.receipts, receipt)
+		env.tcount++
+	}
+	env.state.ReleaseMultiTxSnapshot()
+
+	if miner.policy.maxSenderGas > 0 {
+		if env.senderGas == nil {
+			env.senderGas = make(map[common.Address]uint64)
+		}
+		for i, receipt := range env.receipts[receipts:] {
+			env.senderGas[senders[i]] += receipt.GasUsed
+		}
+	}
+	return nil
+}
+
 // fillTransactions retrieves the pending transactions from the txpool and fills them
-// into the given sealing block. The transaction selection and ordering strategy can
-// be customized with the plugin in the future.
+// into the given sealing block. The transaction selection and ordering strategy is
+// defined by the block building policy of the miner configuration.
 func (miner *Miner) fillTransactions(interrupt *atomic.Int32, env *environment) error {
+	// Include the bundles targeting the block first, the pending transactions
+	// are skipped if their nonces were consumed by a bundle.
+	if err := miner.commitBundles(env, interrupt); err != nil {
+		return err
+	}
 	miner.confMu.RLock()
 	tip := miner.config.GasPrice
 	miner.confMu.RUnlock()
@@ -397,6 +512,10 @@ func (miner *Miner) fillTransactions(interrupt *atomic.Int32, env *environment)
 	filter.OnlyPlainTxs, filter.OnlyBlobTxs = false, true
 	pendingBlobTxs := miner.txpool.Pending(filter)
 
+	// Drop the senders excluded by the block building policy
+	miner.policy.filter(pendingPlainTxs)
+	miner.policy.filter(pendingBlobTxs)
+
 	// Split the pending transactions into locals and remotes.
 	localPlainTxs, remotePlainTxs := make(map[common.Address][]*txpool.LazyTransaction), pendingPlainTxs
 	localBlobTxs, remoteBlobTxs := make(map[common.Address][]*txpool.LazyTransaction), pendingBlobTxs
@@ -412,19 +531,26 @@ func (miner *Miner) fillTransactions(interrupt *atomic.Int32, env *environment)
 		}
 	}
 	// Fill the block with all available pending transactions.
+	available := env.gasPool.Gas()
 	if len(localPlainTxs) > 0 || len(localBlobTxs) > 0 {
-		plainTxs := newTransactionsByPriceAndNonce(env.signer, localPlainTxs, env.header.BaseFee)
-		blobTxs := newTransactionsByPriceAndNonce(env.signer, localBlobTxs, env.header.BaseFee)
+		plainTxs := miner.policy.order(env.signer, localPlainTxs, env.header.BaseFee)
+		blobTxs := miner.policy.order(env.signer, localBlobTxs, env.header.BaseFee)
 
-		if err := miner.commitTransactions(env, plainTxs, blobTxs, interrupt); err != nil {
+		if err := miner.commitTransactions(env, plainTxs, blobTxs, 0, interrupt); err != nil {
 			return err
 		}
 	}
 	if len(remotePlainTxs) > 0 || len(remoteBlobTxs) > 0 {
-		plainTxs := newTransactionsByPriceAndNonce(env.signer, remotePlainTxs, env.header.BaseFee)
-		blobTxs := newTransactionsByPriceAndNonce(env.signer, remoteBlobTxs, env.header.BaseFee)
+		// Keep the part of the local gas reservation not consumed by the local
+		// transactions free from remote ones
+		var reserve uint64
+		if used := available - env.gasPool.Gas(); used < miner.policy.localReserve {
+			reserve = miner.policy.localReserve - used
+		}
+		plainTxs := miner.policy.order(env.signer, remotePlainTxs, env.header.BaseFee)
+		blobTxs := miner.policy.order(env.signer, remoteBlobTxs, env.header.BaseFee)
 
-		if err := miner.commitTransactions(env, plainTxs, blobTxs, interrupt); err != nil {
+		if err := miner.commitTransactions(env, plainTxs, blobTxs, reserve, interrupt); err != nil {
 			return err
 		}
 	}
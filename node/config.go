@@ -26,6 +26,7 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -137,6 +138,10 @@ type Config struct {
 	// HTTPPathPrefix specifies a path prefix on which http-rpc is to be served.
 	HTTPPathPrefix string `toml:",omitempty"`
 
+	// HTTPH2C enables HTTP/2 over cleartext TCP connections (h2c) on the HTTP RPC
+	// interface, allowing requests to be multiplexed over a single connection.
+	HTTPH2C bool `toml:",omitempty"`
+
 	// AuthAddr is the listening address on which authenticated APIs are provided.
 	AuthAddr string `toml:",omitempty"`
 
@@ -204,6 +209,14 @@ type Config struct {
 	// BatchResponseMaxSize is the maximum number of bytes returned from a batched rpc call.
 	BatchResponseMaxSize int `toml:",omitempty"`
 
+	// RPCLimits configures API keys and call limits on the HTTP and WebSocket RPC
+	// interfaces.
+	RPCLimits RPCLimitConfig `toml:",omitempty"`
+
+	// RPCAudit configures the log of the calls served by the HTTP and WebSocket RPC
+	// interfaces.
+	RPCAudit RPCAuditConfig `toml:",omitempty"`
+
 	// JWTSecret is the path to the hex-encoded jwt secret.
 	JWTSecret string `toml:",omitempty"`
 
@@ -211,6 +224,10 @@ type Config struct {
 	EnablePersonal bool `toml:"-"`
 
 	DBEngine string `toml:",omitempty"`
+
+	// AncientTiering configures the offloading of cold ancient data of the chain
+	// database to a remote object store.
+	AncientTiering *rawdb.FreezerTiering `toml:"-"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -21,6 +21,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/nat"
@@ -66,7 +67,15 @@ var DefaultConfig = Config{
 	WSModules:            []string{"net", "web3"},
 	BatchRequestLimit:    1000,
 	BatchResponseMaxSize: 25 * 1000 * 1000,
-	GraphQLVirtualHosts:  []string{"localhost"},
+	RPCAudit: RPCAuditConfig{
+		SlowThreshold: 5 * time.Second,
+		Failed:        true,
+		Redact:        []string{"personal"},
+		MaxParamsSize: 4096,
+		MaxSize:       100,
+		MaxBackups:    10,
+	},
+	GraphQLVirtualHosts: []string{"localhost"},
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   50,
@@ -57,14 +57,15 @@ type Node struct {
 	state         int           // Tracks state of node lifecycle
 
 	lock          sync.Mutex
-	lifecycles    []Lifecycle // All registered backends, services, and auxiliary services that have a lifecycle
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	http          *httpServer //
-	ws            *httpServer //
-	httpAuth      *httpServer //
-	wsAuth        *httpServer //
-	ipc           *ipcServer  // Stores information about the ipc http server
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	lifecycles    []Lifecycle  // All registered backends, services, and auxiliary services that have a lifecycle
+	rpcAPIs       []rpc.API    // List of APIs currently provided by the node
+	http          *httpServer  //
+	ws            *httpServer  //
+	httpAuth      *httpServer  //
+	wsAuth        *httpServer  //
+	ipc           *ipcServer   // Stores information about the ipc http server
+	inprocHandler *rpc.Server  // In-process RPC request handler to process the API requests
+	rpcAudit      *rpcAuditLog // Log of the calls served by the HTTP and WS servers, if enabled
 
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
@@ -387,6 +388,19 @@ func (n *Node) startRPC() error {
 		}
 		apis = append(apis, api)
 	}
+	// Set up the client limits shared by the HTTP and WebSocket endpoints.
+	var limiter *rpcLimiter
+	if n.config.RPCLimits.enabled() {
+		var err error
+		if limiter, err = newRPCLimiter(n.config.RPCLimits); err != nil {
+			return err
+		}
+	}
+	if n.config.RPCAudit.File != "" {
+		config := n.config.RPCAudit
+		config.File = n.config.ResolvePath(config.File)
+		n.rpcAudit = newRPCAuditLog(config)
+	}
 	if err := n.startInProc(apis); err != nil {
 		return err
 	}
@@ -405,6 +419,8 @@ func (n *Node) startRPC() error {
 	rpcConfig := rpcEndpointConfig{
 		batchItemLimit:         n.config.BatchRequestLimit,
 		batchResponseSizeLimit: n.config.BatchResponseMaxSize,
+		limiter:                limiter,
+		audit:                  n.rpcAudit,
 	}
 
 	initHttp := func(server *httpServer, port int) error {
@@ -416,6 +432,7 @@ func (n *Node) startRPC() error {
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
+			h2c:                n.config.HTTPH2C,
 			rpcEndpointConfig:  rpcConfig,
 		}); err != nil {
 			return err
@@ -533,6 +550,10 @@ func (n *Node) stopRPC() {
 	n.wsAuth.stop()
 	n.ipc.stop()
 	n.stopInProc()
+	if n.rpcAudit != nil {
+		n.rpcAudit.close()
+		n.rpcAudit = nil
+	}
 }
 
 // startInProc registers all RPC APIs on the inproc server.
@@ -763,6 +784,7 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 			Cache:             cache,
 			Handles:           handles,
 			ReadOnly:          readonly,
+			AncientTiering:    n.config.AncientTiering,
 		})
 	}
 
@@ -0,0 +1,154 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"io"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// RPCAuditConfig configures the log of the calls served by the HTTP and WebSocket
+// RPC interfaces.
+type RPCAuditConfig struct {
+	// File is the path of the log, which is written as JSON lines. Empty disables
+	// the log.
+	File string `toml:",omitempty"`
+
+	// All enables logging every call. Otherwise only slow and failed calls are
+	// logged.
+	All bool `toml:",omitempty"`
+
+	// SlowThreshold is the duration from which calls are logged as slow. Zero
+	// disables logging slow calls.
+	SlowThreshold time.Duration `toml:",omitempty"`
+
+	// Failed enables logging calls which returned an error.
+	Failed bool `toml:",omitempty"`
+
+	// SampleRatio makes only one in SampleRatio calls be logged, picked at random.
+	// Zero or one logs all calls.
+	SampleRatio int `toml:",omitempty"`
+
+	// Redact lists methods or namespaces whose parameters are left out of the log.
+	Redact []string `toml:",omitempty"`
+
+	// MaxParamsSize is the number of bytes of the parameters logged for a call,
+	// longer parameters are truncated. Zero logs the full parameters.
+	MaxParamsSize int `toml:",omitempty"`
+
+	// MaxSize is the size in megabytes from which the log is rotated. Up to
+	// MaxBackups rotated files are retained for MaxAge days, compressed if
+	// Compress is set.
+	MaxSize    int  `toml:",omitempty"`
+	MaxBackups int  `toml:",omitempty"`
+	MaxAge     int  `toml:",omitempty"`
+	Compress   bool `toml:",omitempty"`
+}
+
+// rpcAuditLog writes the calls selected by the audit config to a rotating file.
+// It is shared by all RPC servers of the node.
+type rpcAuditLog struct {
+	config RPCAuditConfig
+	redact map[string]bool
+	out    io.WriteCloser
+	log    log.Logger
+}
+
+// newRPCAuditLog creates an audit log writing to the file in the config.
+func newRPCAuditLog(config RPCAuditConfig) *rpcAuditLog {
+	out := &lumberjack.Logger{
+		Filename:   config.File,
+		MaxSize:    config.MaxSize,
+		MaxBackups: config.MaxBackups,
+		MaxAge:     config.MaxAge,
+		Compress:   config.Compress,
+	}
+	a := &rpcAuditLog{
+		config: config,
+		redact: make(map[string]bool),
+		out:    out,
+		log:    log.NewLogger(log.JSONHandlerWithLevel(out, log.LevelTrace)),
+	}
+	for _, name := range config.Redact {
+		a.redact[name] = true
+	}
+	return a
+}
+
+// RecordCall implements rpc.CallRecorder, logging the call if it's selected by
+// the config.
+func (a *rpcAuditLog) RecordCall(call *rpc.CallInfo) {
+	var reason string
+	switch {
+	case call.Err != nil && a.config.Failed:
+		reason = "failed"
+	case a.config.SlowThreshold > 0 && call.Duration >= a.config.SlowThreshold:
+		reason = "slow"
+	case a.config.All:
+		reason = "audit"
+	default:
+		return
+	}
+	if a.config.SampleRatio > 1 && rand.Intn(a.config.SampleRatio) != 0 {
+		return
+	}
+	ctx := []interface{}{
+		"reason", reason,
+		"method", call.Method,
+		"params", a.params(call),
+		"transport", call.Peer.Transport,
+		"remote", call.Peer.RemoteAddr,
+	}
+	if call.Peer.HTTP.ClientID != "" {
+		ctx = append(ctx, "client", call.Peer.HTTP.ClientID)
+	}
+	ctx = append(ctx, "duration", call.Duration, "size", call.Size)
+
+	level := slog.LevelInfo
+	if call.Err != nil {
+		ctx = append(ctx, "err", call.Err.Error(), "code", call.Err.ErrorCode())
+		level = slog.LevelWarn
+	} else if reason == "slow" {
+		level = slog.LevelWarn
+	}
+	a.log.Write(level, "Served RPC call", ctx...)
+}
+
+// params returns the parameters of a call as logged, redacted or truncated as
+// configured.
+func (a *rpcAuditLog) params(call *rpc.CallInfo) string {
+	namespace, _, _ := strings.Cut(call.Method, "_")
+	if a.redact[call.Method] || a.redact[namespace] {
+		return "<redacted>"
+	}
+	if max := a.config.MaxParamsSize; max > 0 && len(call.Params) > max {
+		return string(call.Params[:max]) + "..."
+	}
+	return string(call.Params)
+}
+
+// close flushes and closes the log output.
+func (a *rpcAuditLog) close() error {
+	return a.out.Close()
+}
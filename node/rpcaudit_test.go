@@ -0,0 +1,99 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Tests that slow and failed calls are written to the audit log, along with all
+// calls if requested, and that parameters are redacted.
+func TestRPCAuditLog(t *testing.T) {
+	type entry struct{ method, reason, params string }
+	var (
+		greet   = entry{"test_greet", "audit", "[]"}
+		sleep   = entry{"test_sleep", "slow", "[]"}
+		missing = entry{"test_missing", "failed", "[]"}
+		modules = entry{"rpc_modules", "audit", "<redacted>"}
+	)
+	tests := []struct {
+		all  bool
+		want []entry
+	}{
+		{all: false, want: []entry{sleep, missing, missing}},
+		{all: true, want: []entry{greet, sleep, missing, modules, missing}},
+	}
+	for _, tt := range tests {
+		all, want := tt.all, tt.want
+		path := filepath.Join(t.TempDir(), "audit.log")
+		audit := newRPCAuditLog(RPCAuditConfig{
+			File:          path,
+			All:           all,
+			SlowThreshold: time.Second,
+			Failed:        true,
+			Redact:        []string{"rpc"},
+		})
+		conf := &httpConfig{Modules: []string{"test"}, rpcEndpointConfig: rpcEndpointConfig{audit: audit}}
+		srv := createAndStartServer(t, conf, false, &wsConfig{}, nil)
+		url := fmt.Sprintf("http://%v", srv.listenAddr())
+
+		rpcRequest(t, url, "test_greet").Body.Close()
+		rpcRequest(t, url, "test_sleep").Body.Close()
+		rpcRequest(t, url, "test_missing").Body.Close()
+		batchRpcRequest(t, url, []string{"rpc_modules", "test_missing"}).Body.Close()
+		srv.stop()
+		audit.close()
+
+		// Read back the log entries.
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entries []map[string]interface{}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			entry := make(map[string]interface{})
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid log entry %s: %v", scanner.Text(), err)
+			}
+			entries = append(entries, entry)
+		}
+		file.Close()
+
+		if len(entries) != len(want) {
+			t.Fatalf("all %v: entry count mismatch: have %d, want %d", all, len(entries), len(want))
+		}
+		for i, w := range want {
+			e := entries[i]
+			if e["method"] != w.method || e["reason"] != w.reason || e["params"] != w.params {
+				t.Errorf("all %v: entry %d mismatch: have %v, want %+v", all, i, e, w)
+			}
+			if e["transport"] != "http" || e["remote"] == "" {
+				t.Errorf("all %v: entry %d lacks the caller: %v", all, i, e)
+			}
+			if w.reason == "failed" && e["code"] != float64(-32601) {
+				t.Errorf("all %v: entry %d has wrong error code: %v", all, i, e["code"])
+			}
+		}
+	}
+}
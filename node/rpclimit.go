@@ -0,0 +1,366 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// errcodeLimitExceeded is the JSON-RPC error code of rejected calls.
+	errcodeLimitExceeded = -32005
+
+	// rpcLimitClientExpiry is the time after which the limits of idle clients are
+	// dropped. Their buckets are refilled by then anyway.
+	rpcLimitClientExpiry = 10 * time.Minute
+)
+
+var (
+	rpcLimitRejectedMeter = metrics.NewRegisteredMeter("rpc/limit/rejected", nil)
+	rpcLimitCostMeter     = metrics.NewRegisteredMeter("rpc/limit/cost", nil)
+)
+
+// RPCLimitConfig configures client authentication and call limits on the HTTP
+// and WebSocket RPC interfaces.
+type RPCLimitConfig struct {
+	// APIKeys maps client names to their API keys. If any keys are configured, only
+	// requests carrying a known key are served. The key is sent as bearer token
+	// or in the X-API-Key header.
+	APIKeys map[string]string `toml:",omitempty"`
+
+	// APIKeyFile is the path of a file holding additional API keys, one name=key
+	// pair per line.
+	APIKeyFile string `toml:",omitempty"`
+
+	// ClientRate is the number of cost units a client may spend per second, with
+	// bursts of up to ClientBurst units. Clients are identified by their API key,
+	// or by their IP address if no keys are configured. Zero means no limit.
+	ClientRate  float64 `toml:",omitempty"`
+	ClientBurst int     `toml:",omitempty"`
+
+	// MethodRates limits the number of calls per second a single client may make
+	// to a method. Entries may name a method or a whole namespace.
+	MethodRates map[string]float64 `toml:",omitempty"`
+
+	// MethodCosts assigns the cost charged against the client rate to methods.
+	// Entries may name a method or a whole namespace, methods without a cost
+	// cost one unit.
+	MethodCosts map[string]int `toml:",omitempty"`
+
+	// NamespaceConcurrency caps the number of calls to a namespace which may run
+	// concurrently, across all clients.
+	NamespaceConcurrency map[string]int `toml:",omitempty"`
+}
+
+// enabled reports whether any authentication or limits are configured.
+func (c *RPCLimitConfig) enabled() bool {
+	return len(c.APIKeys) > 0 || c.APIKeyFile != "" || c.ClientRate > 0 ||
+		len(c.MethodRates) > 0 || len(c.NamespaceConcurrency) > 0
+}
+
+// limitError is the error returned for calls rejected by the limiter.
+type limitError struct {
+	message    string
+	retryAfter time.Duration // zero if retrying won't help
+}
+
+func (e *limitError) Error() string  { return e.message }
+func (e *limitError) ErrorCode() int { return errcodeLimitExceeded }
+
+// ErrorData returns the number of seconds after which the call may be retried.
+func (e *limitError) ErrorData() interface{} {
+	if e.retryAfter <= 0 {
+		return nil
+	}
+	return map[string]interface{}{"retryAfter": int(math.Ceil(e.retryAfter.Seconds()))}
+}
+
+// clientLimits tracks the rate limits of a single client.
+type clientLimits struct {
+	total    *rate.Limiter            // limit of the total cost, nil if unlimited
+	methods  map[string]*rate.Limiter // limits of individual methods and namespaces
+	cost     metrics.Counter          // total cost of the client, nil for anonymous clients
+	lastSeen time.Time
+}
+
+// apiKey is the API key of a client.
+type apiKey struct {
+	name string
+	key  []byte
+}
+
+// rpcLimiter authenticates RPC clients and limits the calls they make. It is
+// shared by all RPC servers of the node, so clients can't circumvent the limits
+// by spreading their calls over different endpoints.
+type rpcLimiter struct {
+	config  RPCLimitConfig
+	keys    []apiKey                 // API keys of the known clients
+	running map[string]chan struct{} // semaphores of the concurrency capped namespaces
+
+	lock        sync.Mutex
+	clients     map[string]*clientLimits
+	lastCleanup time.Time
+}
+
+// newRPCLimiter creates a limiter from the given config, loading the API keys.
+func newRPCLimiter(config RPCLimitConfig) (*rpcLimiter, error) {
+	l := &rpcLimiter{
+		config:  config,
+		running: make(map[string]chan struct{}),
+		clients: make(map[string]*clientLimits),
+	}
+	for name, key := range config.APIKeys {
+		if err := l.addKey(name, key); err != nil {
+			return nil, err
+		}
+	}
+	if config.APIKeyFile != "" {
+		if err := l.loadKeys(config.APIKeyFile); err != nil {
+			return nil, err
+		}
+	}
+	for namespace, limit := range config.NamespaceConcurrency {
+		if limit > 0 {
+			l.running[namespace] = make(chan struct{}, limit)
+		}
+	}
+	return l, nil
+}
+
+// addKey registers the API key of a client.
+func (l *rpcLimiter) addKey(name, key string) error {
+	if name == "" || key == "" {
+		return fmt.Errorf("invalid API key entry %q", name)
+	}
+	for _, k := range l.keys {
+		if string(k.key) == key {
+			return fmt.Errorf("duplicate API key of client %q", name)
+		}
+	}
+	l.keys = append(l.keys, apiKey{name: name, key: []byte(key)})
+	return nil
+}
+
+// loadKeys registers the API keys listed in the given file.
+func (l *rpcLimiter) loadKeys(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open API key file: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, key, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("invalid line in API key file: %q", name)
+		}
+		if err := l.addKey(strings.TrimSpace(name), strings.TrimSpace(key)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// authenticate returns the name of the client owning the API key sent with the
+// request, or false if the key is missing or unknown. Keys are only accepted in
+// headers, as URLs tend to end up in logs. All known keys are compared in constant
+// time to avoid leaking them through the response timing.
+func (l *rpcLimiter) authenticate(r *http.Request) (string, bool) {
+	key := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); key == "" && strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key == "" {
+		return "", false
+	}
+	var (
+		name  string
+		found bool
+	)
+	for _, k := range l.keys {
+		if subtle.ConstantTimeCompare(k.key, []byte(key)) == 1 {
+			name, found = k.name, true
+		}
+	}
+	return name, found
+}
+
+// limitKey returns the key of the entry applying to the given method in a per
+// method config map. Entries of the method take precedence over the ones of its
+// namespace.
+func limitKey[V any](entries map[string]V, method string) (string, bool) {
+	if _, ok := entries[method]; ok {
+		return method, true
+	}
+	namespace, _, _ := strings.Cut(method, "_")
+	_, ok := entries[namespace]
+	return namespace, ok
+}
+
+// cost returns the cost of calling the given method.
+func (l *rpcLimiter) cost(method string) int {
+	if key, ok := limitKey(l.config.MethodCosts, method); ok && l.config.MethodCosts[key] >= 0 {
+		return l.config.MethodCosts[key]
+	}
+	return 1
+}
+
+// client returns the limits of the given client, creating them if needed. The
+// limits of idle clients are dropped periodically. This assumes l.lock is held.
+func (l *rpcLimiter) client(id string, named bool, now time.Time) *clientLimits {
+	if now.Sub(l.lastCleanup) > rpcLimitClientExpiry {
+		for id, c := range l.clients {
+			if now.Sub(c.lastSeen) > rpcLimitClientExpiry {
+				delete(l.clients, id)
+			}
+		}
+		l.lastCleanup = now
+	}
+	c := l.clients[id]
+	if c == nil {
+		c = &clientLimits{methods: make(map[string]*rate.Limiter)}
+		if l.config.ClientRate > 0 {
+			burst := l.config.ClientBurst
+			if burst <= 0 {
+				burst = int(math.Ceil(l.config.ClientRate))
+			}
+			c.total = rate.NewLimiter(rate.Limit(l.config.ClientRate), burst)
+		}
+		if named {
+			c.cost = metrics.GetOrRegisterCounter("rpc/limit/cost/"+id, nil)
+		}
+		l.clients[id] = c
+	}
+	c.lastSeen = now
+	return c
+}
+
+// Acquire implements rpc.CallLimiter, admitting a call if the concurrency cap of
+// its namespace and the rate limits of the calling client permit it.
+func (l *rpcLimiter) Acquire(ctx context.Context, method string) (func(), error) {
+	// Take a slot of the namespace first, it's released if a rate limit is hit.
+	release := func() {}
+	namespace, _, _ := strings.Cut(method, "_")
+	if sema := l.running[namespace]; sema != nil {
+		select {
+		case sema <- struct{}{}:
+			release = func() { <-sema }
+		default:
+			rpcLimitRejectedMeter.Mark(1)
+			return nil, &limitError{
+				message:    fmt.Sprintf("too many concurrent %s calls", namespace),
+				retryAfter: time.Second,
+			}
+		}
+	}
+	if err := l.charge(rpc.PeerInfoFromContext(ctx), method); err != nil {
+		release()
+		rpcLimitRejectedMeter.Mark(1)
+		return nil, err
+	}
+	return release, nil
+}
+
+// charge applies the call of a method to the rate limits of the client.
+func (l *rpcLimiter) charge(info rpc.PeerInfo, method string) error {
+	id, named := info.HTTP.ClientID, true
+	if id == "" {
+		id, named = info.RemoteAddr, false
+		if host, _, err := net.SplitHostPort(id); err == nil {
+			id = host
+		}
+	}
+	var (
+		now  = time.Now()
+		cost = l.cost(method)
+	)
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	c := l.client(id, named, now)
+
+	// Check the method limit before the total one, undoing the reservation of the
+	// method call if the total limit is hit.
+	var reservation *rate.Reservation
+	if key, ok := limitKey(l.config.MethodRates, method); ok && l.config.MethodRates[key] > 0 {
+		limit := l.config.MethodRates[key]
+		limiter := c.methods[key]
+		if limiter == nil {
+			limiter = rate.NewLimiter(rate.Limit(limit), int(math.Max(1, math.Ceil(limit))))
+			c.methods[key] = limiter
+		}
+		reservation = limiter.ReserveN(now, 1)
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			return &limitError{message: fmt.Sprintf("rate limit exceeded for %s", method), retryAfter: delay}
+		}
+	}
+	if c.total != nil && cost > 0 {
+		total := c.total.ReserveN(now, cost)
+		if !total.OK() {
+			if reservation != nil {
+				reservation.CancelAt(now)
+			}
+			return &limitError{message: fmt.Sprintf("cost of %s exceeds the client limit", method)}
+		}
+		if delay := total.DelayFrom(now); delay > 0 {
+			total.CancelAt(now)
+			if reservation != nil {
+				reservation.CancelAt(now)
+			}
+			return &limitError{message: "client rate limit exceeded", retryAfter: delay}
+		}
+	}
+	// Account the cost of the admitted call.
+	rpcLimitCostMeter.Mark(int64(cost))
+	if c.cost != nil {
+		c.cost.Inc(int64(cost))
+	}
+	return nil
+}
+
+// newAPIKeyHandler authenticates the clients of the given handler by their API
+// key, rejecting requests without a known key.
+func newAPIKeyHandler(limiter *rpcLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := limiter.authenticate(r)
+		if !ok {
+			http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(rpc.WithClientID(r.Context(), name)))
+	})
+}
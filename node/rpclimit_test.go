@@ -0,0 +1,144 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startLimitedServer starts an HTTP RPC server with the given limits.
+func startLimitedServer(t *testing.T, config RPCLimitConfig) (*httpServer, string) {
+	t.Helper()
+
+	limiter, err := newRPCLimiter(config)
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	conf := &httpConfig{Modules: []string{"test"}, rpcEndpointConfig: rpcEndpointConfig{limiter: limiter}}
+	srv := createAndStartServer(t, conf, false, &wsConfig{}, nil)
+	return srv, fmt.Sprintf("http://%v", srv.listenAddr())
+}
+
+// readResponse reads the body of an RPC response.
+func readResponse(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// Tests that only clients with a known API key are served if keys are configured.
+func TestRPCLimitAPIKeys(t *testing.T) {
+	keyfile := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(keyfile, []byte("# clients\nbob = bobkey\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	srv, url := startLimitedServer(t, RPCLimitConfig{
+		APIKeys:    map[string]string{"alice": "alicekey"},
+		APIKeyFile: keyfile,
+	})
+	defer srv.stop()
+
+	tests := []struct {
+		url     string
+		headers []string
+		status  int
+	}{
+		{url: url, status: http.StatusUnauthorized},
+		{url: url, headers: []string{"Authorization", "Bearer wrong"}, status: http.StatusUnauthorized},
+		{url: url, headers: []string{"Authorization", "Bearer alicekey"}, status: http.StatusOK},
+		{url: url, headers: []string{"X-API-Key", "bobkey"}, status: http.StatusOK},
+		{url: url + "?apikey=alicekey", status: http.StatusUnauthorized},
+	}
+	for i, tt := range tests {
+		resp := rpcRequest(t, tt.url, "test_greet", tt.headers...)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+// Tests that method and client rate limits reject calls, including the calls
+// within a batch, with a retry hint.
+func TestRPCLimitRates(t *testing.T) {
+	const (
+		greetRes   = `{"jsonrpc":"2.0","id":1,"result":"Hello"}`
+		modulesRes = `{"jsonrpc":"2.0","id":1,"result":{"rpc":"1.0","test":"1.0"}}`
+		methodRes  = `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"rate limit exceeded for test_greet","data":{"retryAfter":60}}}`
+		clientRes  = `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"client rate limit exceeded","data":{"retryAfter":120}}}`
+		costRes    = `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"cost of test_sleep exceeds the client limit"}}`
+	)
+	srv, url := startLimitedServer(t, RPCLimitConfig{
+		ClientRate:  1.0 / 60,
+		ClientBurst: 3,
+		MethodRates: map[string]float64{"test_greet": 1.0 / 60},
+		MethodCosts: map[string]int{"rpc": 2, "test_sleep": 10},
+	})
+	defer srv.stop()
+
+	if res := readResponse(t, rpcRequest(t, url, "test_greet")); res != greetRes {
+		t.Fatalf("first call: have %s, want %s", res, greetRes)
+	}
+	if res := readResponse(t, rpcRequest(t, url, "test_greet")); res != methodRes {
+		t.Fatalf("method limited call: have %s, want %s", res, methodRes)
+	}
+	if res := readResponse(t, rpcRequest(t, url, "test_sleep")); res != costRes {
+		t.Fatalf("expensive call: have %s, want %s", res, costRes)
+	}
+	// The first call of the batch drains the client bucket.
+	want := fmt.Sprintf("[%s,%s]", modulesRes, clientRes)
+	if res := readResponse(t, batchRpcRequest(t, url, []string{"rpc_modules", "rpc_modules"})); res != want {
+		t.Fatalf("batch: have %s, want %s", res, want)
+	}
+}
+
+// Tests that the number of concurrent calls to a namespace is capped.
+func TestRPCLimitConcurrency(t *testing.T) {
+	srv, url := startLimitedServer(t, RPCLimitConfig{
+		NamespaceConcurrency: map[string]int{"test": 1},
+	})
+	defer srv.stop()
+
+	done := make(chan string)
+	go func() {
+		done <- readResponse(t, rpcRequest(t, url, "test_sleep"))
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	want := `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"too many concurrent test calls","data":{"retryAfter":1}}}`
+	if res := readResponse(t, rpcRequest(t, url, "test_greet")); res != want {
+		t.Fatalf("concurrent call: have %s, want %s", res, want)
+	}
+	if res := <-done; res != `{"jsonrpc":"2.0","id":1,"result":null}` {
+		t.Fatalf("running call failed: %s", res)
+	}
+	if res := readResponse(t, rpcRequest(t, url, "test_greet")); res != `{"jsonrpc":"2.0","id":1,"result":"Hello"}` {
+		t.Fatalf("call after release failed: %s", res)
+	}
+}
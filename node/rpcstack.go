@@ -34,6 +34,8 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/rs/cors"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // httpConfig is the JSON-RPC/HTTP configuration.
@@ -42,6 +44,7 @@ type httpConfig struct {
 	CorsAllowedOrigins []string
 	Vhosts             []string
 	prefix             string // path prefix on which to mount http handler
+	h2c                bool   // whether to serve HTTP/2 over cleartext connections
 	rpcEndpointConfig
 }
 
@@ -58,6 +61,8 @@ type rpcEndpointConfig struct {
 	batchItemLimit         int
 	batchResponseSizeLimit int
 	httpBodyLimit          int
+	limiter                *rpcLimiter  // optional client authentication and call limits
+	audit                  *rpcAuditLog // optional log of the served calls
 }
 
 type rpcHandler struct {
@@ -147,6 +152,11 @@ func (h *httpServer) start() error {
 		h.server.WriteTimeout = h.timeouts.WriteTimeout
 		h.server.IdleTimeout = h.timeouts.IdleTimeout
 	}
+	// Accept HTTP/2 connections without TLS if requested. Requests are then
+	// multiplexed over a single connection, HTTP/1 keeps working unchanged.
+	if h.httpConfig.h2c {
+		h.server.Handler = h2c.NewHandler(h, &http2.Server{IdleTimeout: h.timeouts.IdleTimeout})
+	}
 
 	// Start the server.
 	listener, err := net.Listen("tcp", h.endpoint)
@@ -174,7 +184,7 @@ func (h *httpServer) start() error {
 	// Log http endpoint.
 	h.log.Info("HTTP server started",
 		"endpoint", listener.Addr(), "auth", (h.httpConfig.jwtSecret != nil),
-		"prefix", h.httpConfig.prefix,
+		"prefix", h.httpConfig.prefix, "h2c", h.httpConfig.h2c,
 		"cors", strings.Join(h.httpConfig.CorsAllowedOrigins, ","),
 		"vhosts", strings.Join(h.httpConfig.Vhosts, ","),
 	)
@@ -202,8 +212,13 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if ws != nil && isWebsocket(r) {
 		if checkPath(r, h.wsConfig.prefix) {
 			ws.ServeHTTP(w, r)
+			return
+		}
+		// Websocket requests outside of the RPC prefix may still be served
+		// by a registered handler, e.g. the GraphQL subscriptions.
+		if _, pattern := h.mux.Handler(r); pattern == "" {
+			return
 		}
-		return
 	}
 
 	// if http-rpc is enabled, try to serve request
@@ -312,9 +327,19 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
+	if config.audit != nil {
+		srv.SetCallRecorder(config.audit)
+	}
+	var handler http.Handler = srv
+	if config.limiter != nil {
+		srv.SetCallLimiter(config.limiter)
+		if len(config.limiter.keys) > 0 {
+			handler = newAPIKeyHandler(config.limiter, handler)
+		}
+	}
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
+		Handler: NewHTTPHandlerStack(handler, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
 		server:  srv,
 	})
 	return nil
@@ -347,9 +372,19 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
+	if config.audit != nil {
+		srv.SetCallRecorder(config.audit)
+	}
+	handler := srv.WebsocketHandler(config.Origins)
+	if config.limiter != nil {
+		srv.SetCallLimiter(config.limiter)
+		if len(config.limiter.keys) > 0 {
+			handler = newAPIKeyHandler(config.limiter, handler)
+		}
+	}
 	h.wsConfig = config
 	h.wsHandler.Store(&rpcHandler{
-		Handler: NewWSHandlerStack(srv.WebsocketHandler(config.Origins), config.jwtSecret),
+		Handler: NewWSHandlerStack(handler, config.jwtSecret),
 		server:  srv,
 	})
 	return nil
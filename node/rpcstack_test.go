@@ -18,8 +18,11 @@ package node
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -34,6 +37,7 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
 )
 
 const testMethod = "rpc_modules"
@@ -596,6 +600,53 @@ func TestHTTPWriteTimeout(t *testing.T) {
 	})
 }
 
+// TestHTTPH2C makes sure JSON-RPC is served over cleartext HTTP/2 if enabled,
+// while HTTP/1 keeps working.
+func TestHTTPH2C(t *testing.T) {
+	const greetRes = `{"jsonrpc":"2.0","id":1,"result":"Hello"}` + "\n"
+
+	h2client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return new(net.Dialer).DialContext(ctx, network, addr)
+		},
+	}}
+	for _, enabled := range []bool{false, true} {
+		srv := createAndStartServer(t, &httpConfig{Modules: []string{"test"}, h2c: enabled}, false, &wsConfig{}, nil)
+		url := fmt.Sprintf("http://%v", srv.listenAddr())
+		body := `{"jsonrpc":"2.0","id":1,"method":"test_greet","params":[]}`
+
+		// Plain HTTP/1 requests are always served.
+		resp := baseRpcRequest(t, url, body)
+		res, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.ProtoMajor != 1 || string(res) != greetRes {
+			t.Fatalf("h2c %v: wrong HTTP/1 response: proto %s, body %s", enabled, resp.Proto, res)
+		}
+		// HTTP/2 with prior knowledge only if h2c is enabled.
+		req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set("content-type", "application/json")
+		resp, err := h2client.Do(req)
+		if !enabled {
+			if err == nil {
+				resp.Body.Close()
+				t.Fatal("h2c disabled: HTTP/2 request succeeded")
+			}
+			srv.stop()
+			continue
+		}
+		if err != nil {
+			t.Fatalf("h2c enabled: HTTP/2 request failed: %v", err)
+		}
+		res, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.ProtoMajor != 2 || string(res) != greetRes {
+			t.Fatalf("h2c enabled: wrong HTTP/2 response: proto %s, body %s", resp.Proto, res)
+		}
+		srv.stop()
+	}
+}
+
 func apis() []rpc.API {
 	return []rpc.API{
 		{
@@ -41,6 +41,8 @@ type clientConfig struct {
 	idgen              func() ID
 	batchItemLimit     int
 	batchResponseLimit int
+	limiter            CallLimiter
+	recorder           CallRecorder
 }
 
 func (cfg *clientConfig) initHeaders() {
@@ -62,6 +62,9 @@ type handler struct {
 	allowSubscribe       bool
 	batchRequestLimit    int
 	batchResponseMaxSize int
+	streamResults        bool         // whether call results are encoded by the transport
+	limiter              CallLimiter  // decides whether calls may run, if set
+	recorder             CallRecorder // receives information about served calls, if set
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -214,7 +217,10 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 			})
 		}
 
-		responseBytes := 0
+		var (
+			responseBytes = 0
+			served        []servedCall
+		)
 		for {
 			// No need to handle rest of calls if timed out.
 			if cp.ctx.Err() != nil {
@@ -224,8 +230,10 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 			if msg == nil {
 				break
 			}
+			start := time.Now()
 			resp := h.handleCallMsg(cp, msg)
 			callBuffer.pushResponse(resp)
+			served = append(served, servedCall{msg, resp, time.Since(start)})
 			if resp != nil && h.batchResponseMaxSize != 0 {
 				responseBytes += len(resp.Result)
 				if responseBytes > h.batchResponseMaxSize {
@@ -244,9 +252,19 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		for _, n := range cp.notifiers {
 			n.activate()
 		}
+		peer := PeerInfoFromContext(cp.ctx)
+		for _, call := range served {
+			h.recordCall(peer, call.msg, call.resp, call.duration)
+		}
 	})
 }
 
+// servedCall is a call of a batch along with its response.
+type servedCall struct {
+	msg, resp *jsonrpcMessage
+	duration  time.Duration
+}
+
 func (h *handler) respondWithBatchTooLarge(cp *callProc, batch []*jsonrpcMessage) {
 	resp := errorMessage(&invalidRequestError{errMsgBatchTooLarge})
 	// Find the first call and add its "id" field to the error.
@@ -293,8 +311,12 @@ func (h *handler) handleNonBatchCall(cp *callProc, msg *jsonrpcMessage) {
 		})
 	}
 
+	start := time.Now()
 	answer := h.handleCallMsg(cp, msg)
-	if timer != nil {
+
+	// Result streams are produced while the response is written, so they are
+	// still subject to the timeout and hold their limiter slot until then.
+	if timer != nil && !answer.isProduced() {
 		timer.Stop()
 	}
 	h.addSubscriptions(cp.notifiers)
@@ -303,9 +325,16 @@ func (h *handler) handleNonBatchCall(cp *callProc, msg *jsonrpcMessage) {
 			h.conn.writeJSON(cp.ctx, answer, false)
 		})
 	}
+	answer.releaseCall()
+	duration := time.Since(start)
+
+	if timer != nil {
+		timer.Stop()
+	}
 	for _, n := range cp.notifiers {
 		n.activate()
 	}
+	h.recordCall(PeerInfoFromContext(cp.ctx), msg, answer, duration)
 }
 
 // close cancels all requests except for inflightReq and waits for
@@ -462,7 +491,7 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 	start := time.Now()
 	switch {
 	case msg.isNotification():
-		h.handleCall(ctx, msg)
+		h.handleCall(ctx, msg).releaseCall()
 		h.log.Debug("Served "+msg.Method, "duration", time.Since(start))
 		return nil
 
@@ -562,10 +591,33 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 
 // runMethod runs the Go callback for an RPC method.
 func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value) *jsonrpcMessage {
+	release := func() {}
+	if h.limiter != nil && callb != h.unsubscribeCb {
+		var err error
+		if release, err = h.limiter.Acquire(ctx, msg.Method); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	result, err := callb.call(ctx, msg.Method, args)
 	if err != nil {
+		release()
 		return msg.errorResponse(err)
 	}
+	// Streamed results are produced while the response is written, the limiter
+	// slot is released by the caller once that is done.
+	if h.streamResults && result != nil {
+		resp := msg.streamResponse(result)
+		resp.release = release
+		return resp
+	}
+	defer release()
+
+	// Result streams are collected if the transport doesn't stream results
+	if p, ok := result.(resultProducer); ok {
+		if result, err = collectResult(p); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	return msg.response(result)
 }
 
@@ -251,13 +251,22 @@ type httpServerConn struct {
 	io.Reader
 	io.Writer
 	r *http.Request
+
+	aborted bool // set if a streamed response failed after being partially written
 }
 
-func (s *Server) newHTTPServerConn(r *http.Request, w http.ResponseWriter) ServerCodec {
+func (s *Server) newHTTPServerConn(r *http.Request, w http.ResponseWriter) (*httpServerConn, ServerCodec) {
 	body := io.LimitReader(r.Body, int64(s.httpBodyLimit))
 	conn := &httpServerConn{Reader: body, Writer: w, r: r}
 
 	encoder := func(v any, isErrorResponse bool) error {
+		if msg, ok := v.(*jsonrpcMessage); ok && msg.stream != nil {
+			err := writeStreamedResponse(w, msg)
+			if errors.Is(err, errStreamAborted) {
+				conn.aborted = true
+			}
+			return err
+		}
 		if !isErrorResponse {
 			return json.NewEncoder(conn).Encode(v)
 		}
@@ -291,7 +300,7 @@ func (s *Server) newHTTPServerConn(r *http.Request, w http.ResponseWriter) Serve
 	dec := json.NewDecoder(conn)
 	dec.UseNumber()
 
-	return NewFuncCodec(conn, encoder, dec.Decode)
+	return conn, NewFuncCodec(conn, encoder, dec.Decode)
 }
 
 // Close does nothing and always returns nil.
@@ -323,6 +332,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	connInfo.HTTP.Host = r.Host
 	connInfo.HTTP.Origin = r.Header.Get("Origin")
 	connInfo.HTTP.UserAgent = r.Header.Get("User-Agent")
+	connInfo.HTTP.ClientID = clientIDFromContext(r.Context())
 	ctx := r.Context()
 	ctx = context.WithValue(ctx, peerInfoContextKey{}, connInfo)
 
@@ -330,9 +340,16 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// until EOF, writes the response to w, and orders the server to process a
 	// single request.
 	w.Header().Set("content-type", contentType)
-	codec := s.newHTTPServerConn(r, w)
+	conn, codec := s.newHTTPServerConn(r, w)
 	defer codec.close()
 	s.serveSingleRequest(ctx, codec)
+
+	// A streamed response which failed midway can't be completed anymore. Abort
+	// the handler, so the client sees a broken response instead of a truncated
+	// result.
+	if conn.aborted {
+		panic(http.ErrAbortHandler)
+	}
 }
 
 // validateRequest returns a non-zero response code and error message if the
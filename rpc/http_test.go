@@ -18,10 +18,13 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -129,6 +132,238 @@ func TestHTTPRespBodyUnlimited(t *testing.T) {
 	}
 }
 
+// Tests that large slice results are streamed to the client, and the encoding
+// matches the one of buffered responses.
+func TestHTTPStreamedResponse(t *testing.T) {
+	for _, length := range []int{0, 2, 10000} {
+		s := NewServer()
+		s.RegisterName("test", largeSliceService{length})
+
+		body := `{"jsonrpc":"2.0","id":1,"method":"test_largeSlice"}`
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("content-type", contentType)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		s.Stop()
+
+		result, _ := json.Marshal(largeSliceService{length}.LargeSlice())
+		want := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":%s}`+"\n", result)
+		if have := rec.Body.String(); have != want {
+			t.Fatalf("length %d: response mismatch: have %d bytes, want %d bytes", length, len(have), len(want))
+		}
+		if flushed := len(want) > streamFlushSize; rec.Flushed != flushed {
+			t.Fatalf("length %d: flushed mismatch: have %v, want %v", length, rec.Flushed, flushed)
+		}
+	}
+}
+
+// Tests that result streams are written to the client as they are produced, and
+// collected into a regular response by the transports not streaming results.
+func TestHTTPProducedResponse(t *testing.T) {
+	for _, length := range []int{0, 2, 10000} {
+		s := NewServer()
+		s.RegisterName("test", largeSliceService{length})
+
+		body := `{"jsonrpc":"2.0","id":1,"method":"test_producedSlice"}`
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("content-type", contentType)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		result, _ := json.Marshal(largeSliceService{length}.LargeSlice())
+		want := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":%s}`+"\n", result)
+		if have := rec.Body.String(); have != want {
+			t.Fatalf("length %d: response mismatch: have %d bytes, want %d bytes", length, len(have), len(want))
+		}
+		if flushed := len(want) > streamFlushSize; rec.Flushed != flushed {
+			t.Fatalf("length %d: flushed mismatch: have %v, want %v", length, rec.Flushed, flushed)
+		}
+		// The in-process transport collects the stream
+		var have []echoResult
+		if err := DialInProc(s).Call(&have, "test_producedSlice"); err != nil {
+			t.Fatalf("length %d: in-process call failed: %v", length, err)
+		}
+		if len(have) != length {
+			t.Fatalf("length %d: in-process result length mismatch: have %d", length, len(have))
+		}
+		s.Stop()
+	}
+}
+
+// Tests that a failing result stream yields the error of the producer if nothing
+// was written yet, and aborts the response otherwise.
+func TestHTTPProducedResponseError(t *testing.T) {
+	s := NewServer()
+	defer s.Stop()
+	s.RegisterName("small", largeSliceService{2})
+	s.RegisterName("large", largeSliceService{10000})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := DialHTTP(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var r []any
+	err = c.Call(&r, "small_failingProducer")
+	if err == nil || err.Error() != "producer failed" {
+		t.Fatalf("expected producer error response, got %v", err)
+	}
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != errcodeDefault {
+		t.Fatalf("expected error response, got %v", err)
+	}
+	err = c.Call(&r, "large_failingProducer")
+	if err == nil {
+		t.Fatal("expected aborted response")
+	}
+	if _, ok := err.(Error); ok {
+		t.Fatalf("expected transport error, got error response %v", err)
+	}
+	// The in-process transport reports the error of the producer
+	err = DialInProc(s).Call(&r, "large_failingProducer")
+	if err == nil || err.Error() != "producer failed" {
+		t.Fatalf("expected producer error response, got %v", err)
+	}
+}
+
+// Tests that failures encoding a streamed result yield an error response if
+// nothing was written yet, and abort the response otherwise.
+func TestHTTPStreamedResponseError(t *testing.T) {
+	s := NewServer()
+	defer s.Stop()
+	s.RegisterName("small", largeSliceService{1})
+	s.RegisterName("large", largeSliceService{10000})
+	recorder := new(testRecorder)
+	s.SetCallRecorder(recorder)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := DialHTTP(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var r []any
+	err = c.Call(&r, "small_brokenSlice")
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != errcodeMarshalError {
+		t.Fatalf("expected marshal error response, got %v", err)
+	}
+	err = c.Call(&r, "large_brokenSlice")
+	if err == nil {
+		t.Fatal("expected aborted response")
+	}
+	if _, ok := err.(Error); ok {
+		t.Fatalf("expected transport error, got error response %v", err)
+	}
+	// Both calls are recorded as failed, including the aborted one.
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.calls) != 2 {
+		t.Fatalf("recorded call count mismatch: have %d, want 2", len(recorder.calls))
+	}
+	for i, call := range recorder.calls {
+		if call.Err == nil {
+			t.Errorf("call %d (%s) recorded as successful", i, call.Method)
+		}
+	}
+}
+
+// heldLimiter is a call limiter tracking the number of calls holding a slot.
+type heldLimiter struct {
+	held atomic.Int32
+}
+
+func (l *heldLimiter) Acquire(ctx context.Context, method string) (func(), error) {
+	l.held.Add(1)
+	return func() { l.held.Add(-1) }, nil
+}
+
+// heldStreamService streams the number of limiter slots held while producing.
+type heldStreamService struct {
+	limiter *heldLimiter
+}
+
+func (s heldStreamService) Held() ResultStream[int32] {
+	return func(yield func(int32) error) error {
+		return yield(s.limiter.held.Load())
+	}
+}
+
+// Tests that streamed results hold their limiter slot until they are written.
+func TestHTTPStreamedResponseLimiter(t *testing.T) {
+	limiter := new(heldLimiter)
+	s := NewServer()
+	defer s.Stop()
+	s.SetCallLimiter(limiter)
+	s.RegisterName("test", heldStreamService{limiter})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := DialHTTP(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var r []int32
+	if err := c.Call(&r, "test_held"); err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 || r[0] != 1 {
+		t.Fatalf("slot not held while streaming: %v", r)
+	}
+	if held := limiter.held.Load(); held != 0 {
+		t.Fatalf("slot not released: %d held", held)
+	}
+}
+
+// testRecorder collects the recorded calls.
+type testRecorder struct {
+	mu    sync.Mutex
+	calls []*CallInfo
+}
+
+func (r *testRecorder) RecordCall(call *CallInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+// Tests that the call recorder receives the served calls of single and batch
+// requests, including the size of streamed results.
+func TestHTTPCallRecorder(t *testing.T) {
+	s := NewServer()
+	defer s.Stop()
+	s.RegisterName("test", largeSliceService{100})
+	recorder := new(testRecorder)
+	s.SetCallRecorder(recorder)
+
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"test_largeSlice","params":[]}`,
+		`[{"jsonrpc":"2.0","id":2,"method":"test_largeSlice"},{"jsonrpc":"2.0","id":3,"method":"test_missing"}]`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("content-type", contentType)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	result, _ := json.Marshal(largeSliceService{100}.LargeSlice())
+
+	if len(recorder.calls) != 3 {
+		t.Fatalf("recorded call count mismatch: have %d, want 3", len(recorder.calls))
+	}
+	for i, call := range recorder.calls[:2] {
+		if call.Method != "test_largeSlice" || call.Err != nil || call.Size != len(result) || call.Peer.Transport != "http" {
+			t.Errorf("call %d: mismatch: method %s, err %v, size %d (want %d), transport %q", i, call.Method, call.Err, call.Size, len(result), call.Peer.Transport)
+		}
+	}
+	if call := recorder.calls[2]; call.Method != "test_missing" || call.Err == nil || call.Err.ErrorCode() != -32601 {
+		t.Errorf("failed call mismatch: method %s, err %v", call.Method, call.Err)
+	}
+}
+
 // Tests that an HTTP error results in an HTTPError instance
 // being returned with the expected attributes.
 func TestHTTPErrorResponse(t *testing.T) {
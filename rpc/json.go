@@ -66,6 +66,12 @@ type jsonrpcMessage struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 	Error   *jsonError      `json:"error,omitempty"`
 	Result  json.RawMessage `json:"result,omitempty"`
+
+	// stream is the unencoded result of a response, set instead of Result if
+	// the transport encodes the result while writing it to the connection.
+	stream   any
+	streamed int    // size of the streamed result, once written
+	release  func() // releases the limiter slot held until the result is written
 }
 
 func (msg *jsonrpcMessage) isNotification() bool {
@@ -120,6 +126,31 @@ func (msg *jsonrpcMessage) response(result interface{}) *jsonrpcMessage {
 	return &jsonrpcMessage{Version: vsn, ID: msg.ID, Result: enc}
 }
 
+// streamResponse creates a response which leaves encoding the result to the
+// transport, avoiding to buffer large results in the message.
+func (msg *jsonrpcMessage) streamResponse(result interface{}) *jsonrpcMessage {
+	return &jsonrpcMessage{Version: vsn, ID: msg.ID, stream: result}
+}
+
+// isProduced reports whether the message is a response whose result is produced
+// while being written.
+func (msg *jsonrpcMessage) isProduced() bool {
+	if msg == nil {
+		return false
+	}
+	_, ok := msg.stream.(resultProducer)
+	return ok
+}
+
+// releaseCall releases the resources held by the call until its response was
+// written. It is a no-op for responses that hold none.
+func (msg *jsonrpcMessage) releaseCall() {
+	if msg != nil && msg.release != nil {
+		msg.release()
+		msg.release = nil
+	}
+}
+
 func errorMessage(err error) *jsonrpcMessage {
 	msg := &jsonrpcMessage{Version: vsn, ID: null, Error: &jsonError{
 		Code:    errcodeDefault,
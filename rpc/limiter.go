@@ -0,0 +1,48 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import "context"
+
+// CallLimiter decides whether method calls may run on a server. It is consulted
+// for every call, including the calls within a batch.
+type CallLimiter interface {
+	// Acquire is invoked before running the given method. If the call may proceed,
+	// it returns a function releasing the resources held by the call, which is
+	// invoked once the method returns, or once its result was written if it is
+	// streamed to the connection. Otherwise, the returned error is sent to the
+	// client as the response of the call.
+	//
+	// Information about the client is available through PeerInfoFromContext.
+	Acquire(ctx context.Context, method string) (release func(), err error)
+}
+
+type clientIDContextKey struct{}
+
+// WithClientID wraps the given context, adding the identity of an authenticated
+// client. HTTP middleware uses this on the request context to expose the client
+// identity in the PeerInfo of the calls made through the request, including all
+// calls on WebSocket connections established by it.
+func WithClientID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, clientIDContextKey{}, id)
+}
+
+// clientIDFromContext retrieves the client identity set by WithClientID.
+func clientIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(clientIDContextKey{}).(string)
+	return id
+}
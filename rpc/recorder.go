@@ -0,0 +1,60 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CallInfo describes a method call served by a server.
+type CallInfo struct {
+	Method   string
+	Params   json.RawMessage
+	Peer     PeerInfo
+	Duration time.Duration // time spent running the method
+	Size     int           // size of the encoded result in bytes
+	Err      Error         // error response of the call, nil if successful
+}
+
+// CallRecorder receives information about the method calls served by a server,
+// e.g. to maintain an audit log. RecordCall is invoked once the response of a
+// call has been written. Implementations must be safe for concurrent use.
+type CallRecorder interface {
+	RecordCall(call *CallInfo)
+}
+
+// recordCall reports a served call to the recorder of the handler, if any.
+func (h *handler) recordCall(peer PeerInfo, msg, resp *jsonrpcMessage, duration time.Duration) {
+	if h.recorder == nil || resp == nil || !msg.isCall() {
+		return
+	}
+	info := &CallInfo{
+		Method:   msg.Method,
+		Params:   msg.Params,
+		Peer:     peer,
+		Duration: duration,
+		Size:     len(resp.Result),
+	}
+	if resp.stream != nil {
+		info.Size = resp.streamed
+	}
+	if resp.Error != nil {
+		info.Err = resp.Error
+	}
+	h.recorder.RecordCall(info)
+}
@@ -52,6 +52,8 @@ type Server struct {
 	batchItemLimit     int
 	batchResponseLimit int
 	httpBodyLimit      int
+	limiter            CallLimiter
+	recorder           CallRecorder
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -87,6 +89,22 @@ func (s *Server) SetHTTPBodyLimit(limit int) {
 	s.httpBodyLimit = limit
 }
 
+// SetCallLimiter sets the limiter deciding whether method calls may run.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetCallLimiter(limiter CallLimiter) {
+	s.limiter = limiter
+}
+
+// SetCallRecorder sets the recorder receiving information about served calls.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetCallRecorder(recorder CallRecorder) {
+	s.recorder = recorder
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either an RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -112,6 +130,8 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 		idgen:              s.idgen,
 		batchItemLimit:     s.batchItemLimit,
 		batchResponseLimit: s.batchResponseLimit,
+		limiter:            s.limiter,
+		recorder:           s.recorder,
 	}
 	c := initClient(codec, &s.services, cfg)
 	<-codec.closed()
@@ -147,6 +167,8 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 
 	h := newHandler(ctx, codec, s.idgen, &s.services, s.batchItemLimit, s.batchResponseLimit)
 	h.allowSubscribe = false
+	h.limiter = s.limiter
+	h.recorder = s.recorder
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
@@ -160,6 +182,9 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 	if batch {
 		h.handleBatch(reqs)
 	} else {
+		// Results of single calls are streamed to the connection. Batches are
+		// buffered, since their response size is limited.
+		h.streamResults = true
 		h.handleMsg(reqs[0])
 	}
 }
@@ -218,6 +243,10 @@ type PeerInfo struct {
 		UserAgent string
 		Origin    string
 		Host      string
+
+		// Identity of the client, if authenticated by the HTTP middleware.
+		// See WithClientID.
+		ClientID string
 	}
 }
 
@@ -0,0 +1,276 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// streamFlushSize is the amount of encoded response data buffered before it is
+// written out to the connection.
+const streamFlushSize = 64 * 1024
+
+// errStreamAborted is returned if encoding a streamed result fails after parts
+// of the response have already been written.
+var errStreamAborted = errors.New("streamed response aborted")
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// ResultStream is a call result whose array elements are produced one by one
+// while the response is written, so that a large result is never held in memory
+// as a whole. The function passes the elements to yield in order, returning the
+// error of yield if it fails.
+//
+// Single calls over HTTP write the elements to the connection as they are
+// produced. Other transports collect the elements before sending the response.
+type ResultStream[T any] func(yield func(T) error) error
+
+// Collect produces all elements of the stream.
+func (s ResultStream[T]) Collect() ([]T, error) {
+	items := make([]T, 0)
+	if err := s.produce(func(item any) error {
+		items = append(items, item.(T))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the elements as an array.
+func (s ResultStream[T]) MarshalJSON() ([]byte, error) {
+	items, err := s.Collect()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(items)
+}
+
+// produce implements resultProducer. A nil stream has no elements.
+func (s ResultStream[T]) produce(yield func(any) error) error {
+	if s == nil {
+		return nil
+	}
+	return s(func(item T) error { return yield(item) })
+}
+
+// resultProducer is implemented by the result streams of any element type.
+type resultProducer interface {
+	produce(yield func(any) error) error
+}
+
+// producerError is an error returned by the producer of a streamed result, as
+// opposed to a failure encoding or writing the elements.
+type producerError struct {
+	err error
+}
+
+func (e *producerError) Error() string { return e.err.Error() }
+func (e *producerError) Unwrap() error { return e.err }
+
+// collectResult produces all elements of a result stream.
+func collectResult(p resultProducer) ([]any, error) {
+	items := make([]any, 0)
+	if err := p.produce(func(item any) error {
+		items = append(items, item)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// streamWriter buffers encoded response data, writing it out to the connection
+// in chunks of streamFlushSize.
+type streamWriter struct {
+	w       io.Writer
+	buf     bytes.Buffer
+	size    int  // total size of the encoded data
+	written bool // whether any data was written out already
+}
+
+// write appends data to the buffer, flushing it if it grew large enough.
+func (s *streamWriter) write(data []byte) error {
+	s.size += len(data)
+	s.buf.Write(data)
+	if s.buf.Len() < streamFlushSize {
+		return nil
+	}
+	return s.flush()
+}
+
+// flush writes out the buffered data and pushes it to the client.
+func (s *streamWriter) flush() error {
+	s.written = true
+	if _, err := s.w.Write(s.buf.Bytes()); err != nil {
+		return err
+	}
+	s.buf.Reset()
+
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// writeStreamedResponse encodes a response with an unencoded result into w. Slice
+// results are encoded element by element and result streams as their elements
+// are produced, so only a chunk of the response is held in memory at any time.
+//
+// If encoding or producing the result fails before anything was written, an error
+// response is sent instead. Otherwise errStreamAborted is returned, since the
+// response can't be completed anymore. In both cases the error is set on msg, so
+// the call is reported as failed.
+func writeStreamedResponse(w io.Writer, msg *jsonrpcMessage) error {
+	s := &streamWriter{w: w}
+	if err := encodeStreamedResponse(s, msg); err != nil {
+		var (
+			resp *jsonrpcMessage
+			perr *producerError
+		)
+		if errors.As(err, &perr) {
+			resp = msg.errorResponse(perr.err)
+		} else {
+			resp = msg.errorResponse(&internalServerError{errcodeMarshalError, err.Error()})
+		}
+		msg.Error = resp.Error
+		if s.written {
+			return fmt.Errorf("%w: %v", errStreamAborted, err)
+		}
+		return json.NewEncoder(w).Encode(resp)
+	}
+	// Write out the remainder, the HTTP server flushes it when the handler returns.
+	_, err := w.Write(s.buf.Bytes())
+	return err
+}
+
+// encodeStreamedResponse encodes a response with an unencoded result into s.
+func encodeStreamedResponse(s *streamWriter, msg *jsonrpcMessage) error {
+	// Encode the envelope without the result and leave the object open.
+	header, err := json.Marshal(&jsonrpcMessage{Version: msg.Version, ID: msg.ID})
+	if err != nil {
+		return err
+	}
+	header = append(header[:len(header)-1], `,"result":`...)
+	if err := s.write(header); err != nil {
+		return err
+	}
+	start := s.size
+	if err := encodeStreamedResult(s, msg.stream); err != nil {
+		return err
+	}
+	msg.streamed = s.size - start
+	return s.write([]byte("}\n"))
+}
+
+// encodeStreamedResult encodes a call result into s. Result streams and slices are
+// encoded element by element, any other value as a whole.
+func encodeStreamedResult(s *streamWriter, result any) error {
+	if p, ok := result.(resultProducer); ok {
+		return encodeProducedResult(s, p)
+	}
+	v := reflect.ValueOf(result)
+	if !isStreamable(v) {
+		enc, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return s.write(enc)
+	}
+	if err := s.write([]byte{'['}); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			if err := s.write([]byte{','}); err != nil {
+				return err
+			}
+		}
+		// Encode the element by reference, so methods with pointer receivers
+		// are used just like when encoding the slice as a whole.
+		enc, err := json.Marshal(v.Index(i).Addr().Interface())
+		if err != nil {
+			return err
+		}
+		if err := s.write(enc); err != nil {
+			return err
+		}
+	}
+	return s.write([]byte{']'})
+}
+
+// encodeProducedResult encodes the elements of a result stream into s as they are
+// produced. Errors of the producer are returned as producerError.
+func encodeProducedResult(s *streamWriter, p resultProducer) error {
+	if err := s.write([]byte{'['}); err != nil {
+		return err
+	}
+	var (
+		count   int
+		failure error // Encoding or write failure, aborting the producer
+	)
+	err := p.produce(func(item any) error {
+		if count > 0 {
+			if failure = s.write([]byte{','}); failure != nil {
+				return failure
+			}
+		}
+		count++
+
+		var enc []byte
+		if enc, failure = json.Marshal(item); failure != nil {
+			return failure
+		}
+		failure = s.write(enc)
+		return failure
+	})
+	if failure != nil {
+		return failure
+	}
+	if err != nil {
+		return &producerError{err}
+	}
+	return s.write([]byte{']'})
+}
+
+// isStreamable reports whether v is a slice which can be encoded element by
+// element. Byte slices and slices with a custom encoding are encoded as a whole.
+func isStreamable(v reflect.Value) bool {
+	if v.Kind() != reflect.Slice || v.IsNil() {
+		return false
+	}
+	typ := v.Type()
+	if typ.Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	for _, marshaler := range []reflect.Type{jsonMarshalerType, textMarshalerType} {
+		if typ.Implements(marshaler) || reflect.PointerTo(typ).Implements(marshaler) {
+			return false
+		}
+	}
+	return true
+}
@@ -227,3 +227,50 @@ type largeRespService struct {
 func (x largeRespService) LargeResp() string {
 	return strings.Repeat("x", x.length)
 }
+
+// largeSliceService generates slice responses of arbitrary length.
+type largeSliceService struct {
+	length int
+}
+
+func (x largeSliceService) LargeSlice() []echoResult {
+	res := make([]echoResult, x.length)
+	for i := range res {
+		res[i] = echoResult{String: strings.Repeat("x", 100), Int: i}
+	}
+	return res
+}
+
+func (x largeSliceService) BrokenSlice() []any {
+	res := make([]any, x.length)
+	for i := range res[:len(res)-1] {
+		res[i] = echoResult{String: strings.Repeat("x", 100), Int: i}
+	}
+	res[len(res)-1] = new(MarshalErrObj)
+	return res
+}
+
+// ProducedSlice produces the elements of LargeSlice as a result stream.
+func (x largeSliceService) ProducedSlice() ResultStream[echoResult] {
+	return func(yield func(echoResult) error) error {
+		for i := 0; i < x.length; i++ {
+			if err := yield(echoResult{String: strings.Repeat("x", 100), Int: i}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// FailingProducer produces the elements of LargeSlice, but fails instead of
+// producing the last one.
+func (x largeSliceService) FailingProducer() ResultStream[echoResult] {
+	return func(yield func(echoResult) error) error {
+		for i := 0; i < x.length-1; i++ {
+			if err := yield(echoResult{String: strings.Repeat("x", 100), Int: i}); err != nil {
+				return err
+			}
+		}
+		return errors.New("producer failed")
+	}
+}
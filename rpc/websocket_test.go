@@ -209,6 +209,52 @@ func TestWebsocketPeerInfo(t *testing.T) {
 	}
 }
 
+// testLimiter admits the calls of a single client, rejecting one method.
+type testLimiter struct {
+	client   string
+	rejected string
+}
+
+func (l *testLimiter) Acquire(ctx context.Context, method string) (func(), error) {
+	if PeerInfoFromContext(ctx).HTTP.ClientID != l.client || method == l.rejected {
+		return nil, testError{}
+	}
+	return func() {}, nil
+}
+
+// This test checks that the call limiter is consulted for WebSocket calls, and
+// sees the client identity set on the upgrade request.
+func TestWebsocketCallLimiter(t *testing.T) {
+	s := newTestServer()
+	s.SetCallLimiter(&testLimiter{client: "alice", rejected: "test_rets"})
+	defer s.Stop()
+
+	handler := s.WebsocketHandler([]string{"*"})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(WithClientID(r.Context(), "alice")))
+	}))
+	defer ts.Close()
+
+	c, err := DialWebsocket(context.Background(), "ws:"+strings.TrimPrefix(ts.URL, "http:"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var connInfo PeerInfo
+	if err := c.Call(&connInfo, "test_peerInfo"); err != nil {
+		t.Fatal(err)
+	}
+	if connInfo.HTTP.ClientID != "alice" {
+		t.Errorf("wrong HTTP.ClientID %q", connInfo.HTTP.ClientID)
+	}
+	var res string
+	err = c.Call(&res, "test_rets")
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != (testError{}).ErrorCode() {
+		t.Errorf("expected limiter error, got %v", err)
+	}
+}
+
 // This test checks that client handles WebSocket ping frames correctly.
 func TestClientWebsocketPing(t *testing.T) {
 	t.Parallel()
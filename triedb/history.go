@@ -20,6 +20,7 @@ import (
 	"errors"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/ethereum/go-ethereum/triedb/pathdb"
 )
 
@@ -70,3 +71,19 @@ func (db *Database) HistoryRange() (uint64, uint64, error) {
 	}
 	return pdb.HistoryRange()
 }
+
+// HistoricReader constructs a reader for accessing the requested historic state,
+// which has already been flushed out of the in-memory layers but can still be
+// resolved from the retained state histories.
+//
+// This function is only supported by path mode database.
+func (db *Database) HistoricReader(root common.Hash) (*pathdb.HistoricalStateReader, error) {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return nil, errors.New("not supported")
+	}
+	if db.config.IsVerkle {
+		return nil, errors.New("not supported")
+	}
+	return pdb.HistoricReader(root, trie.NewMerkleLoader(db))
+}
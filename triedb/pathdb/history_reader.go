@@ -0,0 +1,311 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie/triestate"
+)
+
+// historyReader wraps the state history freezer and provides the functionality
+// to locate a single account or storage slot within a specific state history
+// object without decoding the entire object.
+type historyReader struct {
+	freezer ethdb.AncientReader
+}
+
+// readAccountIndex resolves the index of the specified account from the state
+// history. The account indexes are fixed size and sorted by address, so binary
+// search is applied here.
+func (r *historyReader) readAccountIndex(id uint64, address common.Address) (*accountIndex, bool, error) {
+	blob := rawdb.ReadStateAccountIndex(r.freezer, id)
+	if len(blob) == 0 {
+		return nil, false, fmt.Errorf("state history not found %d", id)
+	}
+	if len(blob)%accountIndexSize != 0 {
+		return nil, false, fmt.Errorf("invalid account index, len: %d", len(blob))
+	}
+	n := len(blob) / accountIndexSize
+	pos := sort.Search(n, func(i int) bool {
+		off := i * accountIndexSize
+		return bytes.Compare(blob[off:off+common.AddressLength], address.Bytes()) >= 0
+	})
+	if pos == n {
+		return nil, false, nil
+	}
+	var index accountIndex
+	index.decode(blob[pos*accountIndexSize : (pos+1)*accountIndexSize])
+	if index.address != address {
+		return nil, false, nil
+	}
+	return &index, true, nil
+}
+
+// readAccount retrieves the original value of the specified account before the
+// state transition recorded in the state history. The returned flag indicates
+// whether the account was mutated in the transition at all. The account data
+// is in 'slim RLP' format, nil means the account was not present.
+func (r *historyReader) readAccount(id uint64, address common.Address) ([]byte, bool, error) {
+	index, found, err := r.readAccountIndex(id, address)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	data := rawdb.ReadStateAccountHistory(r.freezer, id)
+	last := index.offset + uint32(index.length)
+	if uint32(len(data)) < last {
+		return nil, false, errors.New("account data buffer is corrupted")
+	}
+	return common.CopyBytes(data[index.offset:last]), true, nil
+}
+
+// readStorage retrieves the original value of the specified storage slot before
+// the state transition recorded in the state history. The returned flag indicates
+// whether the slot was mutated in the transition at all. The slot data is in
+// prefix-zero-trimmed RLP format, nil means the slot was not present.
+func (r *historyReader) readStorage(id uint64, address common.Address, slot common.Hash) ([]byte, bool, error) {
+	index, found, err := r.readAccountIndex(id, address)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	if index.storageSlots == 0 {
+		return nil, false, nil
+	}
+	var (
+		blob  = rawdb.ReadStateStorageIndex(r.freezer, id)
+		start = int(index.storageOffset) * slotIndexSize
+		end   = int(index.storageOffset+index.storageSlots) * slotIndexSize
+	)
+	if len(blob) < end {
+		return nil, false, errors.New("storage index buffer is corrupted")
+	}
+	blob = blob[start:end]
+
+	n := int(index.storageSlots)
+	pos := sort.Search(n, func(i int) bool {
+		off := i * slotIndexSize
+		return bytes.Compare(blob[off:off+common.HashLength], slot.Bytes()) >= 0
+	})
+	if pos == n {
+		return nil, false, nil
+	}
+	var sIndex slotIndex
+	sIndex.decode(blob[pos*slotIndexSize : (pos+1)*slotIndexSize])
+	if sIndex.hash != slot {
+		return nil, false, nil
+	}
+	data := rawdb.ReadStateStorageHistory(r.freezer, id)
+	last := sIndex.offset + uint32(sIndex.length)
+	if uint32(len(data)) < last {
+		return nil, false, errors.New("storage data buffer is corrupted")
+	}
+	return common.CopyBytes(data[sIndex.offset:last]), true, nil
+}
+
+// HistoricalStateReader provides the functionality to read the flat state at a
+// historical point which has already been flushed out of the layer tree. The
+// value of a state entry at the state with id N is the original value recorded
+// in the first state history with id > N which contains the mutation of the
+// entry. If no such history exists up to the disk layer, the entry has not been
+// changed since then and the value is resolved from the persistent state.
+//
+// Note the lookup is linear in the distance between the requested state and the
+// disk layer, with each step costing a couple of small freezer reads.
+type HistoricalStateReader struct {
+	id     uint64               // State id of the requested historical state
+	root   common.Hash          // State root of the requested historical state
+	db     *Database            // Database for accessing disk layer and state histories
+	reader *historyReader       // Reader for locating the states in histories
+	loader triestate.TrieLoader // Loader for opening tries of the persistent state
+	lock   sync.Mutex           // Lock for protecting the pinned disk layer
+	disk   *diskLayer           // The pinned disk layer at which the history search terminates
+}
+
+// HistoricReader constructs a reader for accessing the requested historic state.
+// The state must be below the disk layer and all the state histories above it
+// must still be retained in the freezer.
+func (db *Database) HistoricReader(root common.Hash, loader triestate.TrieLoader) (*HistoricalStateReader, error) {
+	if db.freezer == nil {
+		return nil, errors.New("state history is not available")
+	}
+	root = types.TrieRootHash(root)
+	id := rawdb.ReadStateID(db.diskdb, root)
+	if id == nil {
+		return nil, fmt.Errorf("state %#x is not available", root)
+	}
+	dl := db.tree.bottom()
+	if *id > dl.stateID() {
+		return nil, fmt.Errorf("state %#x is not historical", root)
+	}
+	// The history with id N+1 is required for resolving the state N, ensure it's
+	// not pruned yet. The tail refers to the id of the last pruned history.
+	tail, err := db.freezer.Tail()
+	if err != nil {
+		return nil, err
+	}
+	if *id < tail {
+		return nil, fmt.Errorf("%w: state %d, oldest available %d", errStateUnrecoverable, *id, tail)
+	}
+	return &HistoricalStateReader{
+		id:     *id,
+		root:   root,
+		db:     db,
+		reader: &historyReader{freezer: db.freezer},
+		loader: loader,
+		disk:   dl,
+	}, nil
+}
+
+// Root returns the state root of the historical state.
+func (r *HistoricalStateReader) Root() common.Hash {
+	return r.root
+}
+
+// search walks the state histories from the requested state up to the pinned
+// disk layer, returning the first hit found by the given lookup function. If
+// the entry is not found in the histories, the pinned disk layer is returned
+// for resolving the entry from the persistent state.
+func (r *HistoricalStateReader) search(from uint64, lookup func(id uint64) ([]byte, bool, error)) ([]byte, bool, *diskLayer, error) {
+	r.lock.Lock()
+	dl := r.disk
+	r.lock.Unlock()
+
+	for id := from; id <= dl.stateID(); id++ {
+		blob, found, err := lookup(id)
+		if err != nil {
+			return nil, false, nil, err
+		}
+		if found {
+			return blob, true, nil, nil
+		}
+	}
+	return nil, false, dl, nil
+}
+
+// refresh re-pins the reader to the latest disk layer if the current one is
+// already stale. It returns the id of the first history which is not covered by
+// the previous search.
+func (r *HistoricalStateReader) refresh(dl *diskLayer) (uint64, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.disk != dl {
+		return dl.stateID() + 1, true
+	}
+	bottom := r.db.tree.bottom()
+	if bottom == dl {
+		return 0, false
+	}
+	r.disk = bottom
+	return dl.stateID() + 1, true
+}
+
+// resolve searches the state histories with the given lookup function, falling
+// back to the persistent state via the given read function. The search will be
+// continued if the disk layer is shifted in the meantime.
+func (r *HistoricalStateReader) resolve(lookup func(id uint64) ([]byte, bool, error), read func(root common.Hash) ([]byte, error)) ([]byte, error) {
+	from := r.id + 1
+	for {
+		blob, found, dl, err := r.search(from, lookup)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return blob, nil
+		}
+		blob, err = read(dl.rootHash())
+		if err == nil {
+			return blob, nil
+		}
+		// The pinned disk layer might become stale due to the chain progression,
+		// continue the search with the newly generated histories.
+		next, shifted := r.refresh(dl)
+		if !shifted {
+			return nil, err
+		}
+		from = next
+	}
+}
+
+// Account retrieves the account with the specified address at the historical
+// state. Nil is returned if the account was not present.
+func (r *HistoricalStateReader) Account(address common.Address) (*types.StateAccount, error) {
+	blob, err := r.resolve(func(id uint64) ([]byte, bool, error) {
+		return r.reader.readAccount(id, address)
+	}, func(root common.Hash) ([]byte, error) {
+		tr, err := r.loader.OpenTrie(root)
+		if err != nil {
+			return nil, err
+		}
+		blob, err := tr.Get(crypto.Keccak256(address.Bytes()))
+		if err != nil || len(blob) == 0 {
+			return nil, err
+		}
+		// Convert the full account to the slim format for consistency.
+		var acct types.StateAccount
+		if err := rlp.DecodeBytes(blob, &acct); err != nil {
+			return nil, err
+		}
+		return types.SlimAccountRLP(acct), nil
+	})
+	if err != nil || len(blob) == 0 {
+		return nil, err
+	}
+	return types.FullAccount(blob)
+}
+
+// Storage retrieves the storage slot with the specified account address and
+// slot key hash at the historical state. The returned value is in the trimmed
+// RLP format as stored in the storage trie, nil is returned if the slot was not
+// present.
+func (r *HistoricalStateReader) Storage(address common.Address, slot common.Hash) ([]byte, error) {
+	return r.resolve(func(id uint64) ([]byte, bool, error) {
+		return r.reader.readStorage(id, address, slot)
+	}, func(root common.Hash) ([]byte, error) {
+		tr, err := r.loader.OpenTrie(root)
+		if err != nil {
+			return nil, err
+		}
+		addrHash := crypto.Keccak256Hash(address.Bytes())
+		blob, err := tr.Get(addrHash.Bytes())
+		if err != nil || len(blob) == 0 {
+			return nil, err
+		}
+		var acct types.StateAccount
+		if err := rlp.DecodeBytes(blob, &acct); err != nil {
+			return nil, err
+		}
+		if acct.Root == types.EmptyRootHash {
+			return nil, nil
+		}
+		st, err := r.loader.OpenStorageTrie(root, addrHash, acct.Root)
+		if err != nil {
+			return nil, err
+		}
+		return st.Get(slot.Bytes())
+	})
+}
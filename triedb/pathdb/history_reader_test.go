@@ -0,0 +1,101 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestHistoricReader(t *testing.T) {
+	// Redefine the diff layer depth allowance for faster testing.
+	maxDiffLayers = 4
+	defer func() {
+		maxDiffLayers = 128
+	}()
+
+	tester := newTester(t, 0)
+	defer tester.release()
+
+	// Flatten all the diff layers into disk, the persistent state is then
+	// identical with the live state maintained by the tester.
+	if err := tester.db.Commit(tester.lastHash(), false); err != nil {
+		t.Fatalf("Failed to commit state, err: %v", err)
+	}
+	loader := newHashLoader(tester.accounts, tester.storages)
+
+	for i := 0; i < len(tester.roots)-1; i++ {
+		root := tester.roots[i]
+		reader, err := tester.db.HistoricReader(root, loader)
+		if err != nil {
+			t.Fatalf("Failed to open historic reader, index: %d, err: %v", i, err)
+		}
+		for addrHash, addr := range tester.preimages {
+			want := tester.snapAccounts[root][addrHash]
+			acct, err := reader.Account(addr)
+			if err != nil {
+				t.Fatalf("Failed to read account, index: %d, err: %v", i, err)
+			}
+			var got []byte
+			if acct != nil {
+				got = types.SlimAccountRLP(*acct)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("Unexpected account, index: %d, want: %x, got: %x", i, want, got)
+			}
+		}
+		for addrHash, slots := range tester.snapStorages[root] {
+			addr := tester.preimages[addrHash]
+			for hash, want := range slots {
+				got, err := reader.Storage(addr, hash)
+				if err != nil {
+					t.Fatalf("Failed to read storage, index: %d, err: %v", i, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("Unexpected storage, index: %d, want: %x, got: %x", i, want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestHistoricReaderPruned(t *testing.T) {
+	// Redefine the diff layer depth allowance for faster testing.
+	maxDiffLayers = 4
+	defer func() {
+		maxDiffLayers = 128
+	}()
+
+	tester := newTester(t, 2)
+	defer tester.release()
+
+	if err := tester.db.Commit(tester.lastHash(), false); err != nil {
+		t.Fatalf("Failed to commit state, err: %v", err)
+	}
+	loader := newHashLoader(tester.accounts, tester.storages)
+
+	// The states with pruned histories are not accessible anymore
+	if _, err := tester.db.HistoricReader(tester.roots[0], loader); err == nil {
+		t.Fatal("Expected error for state with pruned histories")
+	}
+	// The states covered by the retained histories are still accessible
+	if _, err := tester.db.HistoricReader(tester.roots[len(tester.roots)-2], loader); err != nil {
+		t.Fatalf("Failed to open historic reader, err: %v", err)
+	}
+}